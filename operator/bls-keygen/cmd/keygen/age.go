@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/age"
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+)
+
+// ageSuffix is appended to the path of a key file encrypted to age
+// recipients.
+const ageSuffix = ".age"
+
+// ageOptions holds generate's --encrypt-to flag. The key file is then
+// wrapped with age encryption to the given recipients in memory, so only
+// the .age file, which only those recipients can open, is ever written.
+type ageOptions struct {
+	recipients stringList
+	parsed     []*age.Recipient
+}
+
+func (o *ageOptions) register(fs *flag.FlagSet) {
+	fs.Var(&o.recipients, "encrypt-to", "age recipient (age1...) to encrypt the key file to, writing <key>.age; may be repeated")
+}
+
+func (o *ageOptions) enabled() bool {
+	return len(o.recipients) > 0
+}
+
+// validate parses the recipients and rejects the flags that would leave a
+// key on disk outside the .age file.
+func (o *ageOptions) validate(cfg *config) error {
+	if !o.enabled() {
+		return nil
+	}
+	if cfg.ecdsa.enabled {
+		return usageErrorf("--encrypt-to cannot be combined with --with-ecdsa, the ECDSA keystore would be written unwrapped")
+	}
+	o.parsed = o.parsed[:0]
+	for _, s := range o.recipients {
+		r, err := age.ParseRecipient(strings.TrimSpace(s))
+		if err != nil {
+			return usageErrorf("invalid --encrypt-to: %v", err)
+		}
+		o.parsed = append(o.parsed, r)
+	}
+	return nil
+}
+
+// path returns where the key meant for keyPath is written.
+func (o *ageOptions) path(keyPath string) string {
+	if !o.enabled() || keyPath == stdoutPath {
+		return keyPath
+	}
+	return keyPath + ageSuffix
+}
+
+// seal encrypts the encoded key file data to the recipients and wipes
+// data.
+func (o *ageOptions) seal(data []byte) ([]byte, error) {
+	defer bls.SecretBytes(data).Zero()
+	sealed, err := age.Encrypt(data, o.parsed...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt key file to --encrypt-to: %w", err)
+	}
+	return sealed, nil
+}
+
+// runDecryptAge implements `keygen decrypt-age`: it decrypts a key file
+// written by generate --encrypt-to with the recipient's age identity file
+// and writes the key file inside.
+func runDecryptAge(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("decrypt-age", flag.ContinueOnError)
+	identityFile := fs.String("identity", "", "age identity file (AGE-SECRET-KEY-1... lines, as age-keygen writes)")
+	in := fs.String("in", "", "age-encrypted key file to decrypt")
+	out := fs.String("out", "", "path of the key file to write (default: --in without .age)")
+	force := fs.Bool("force", false, "replace an existing file at --out")
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if *identityFile == "" || *in == "" {
+		return usageErrorf("--identity and --in are required")
+	}
+	if *out == "" {
+		if !strings.HasSuffix(*in, ageSuffix) {
+			return usageErrorf("--in does not end in %s, give --out", ageSuffix)
+		}
+		*out = strings.TrimSuffix(*in, ageSuffix)
+	}
+	if err := checkNewKeyPath(*out, *force); err != nil {
+		return err
+	}
+
+	f, err := os.Open(*identityFile)
+	if err != nil {
+		return fmt.Errorf("failed to read identity file: %w", err)
+	}
+	identities, err := age.ParseIdentities(f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("%s: %w", *identityFile, err)
+	}
+	sealed, err := os.ReadFile(*in)
+	if err != nil {
+		return err
+	}
+	data, err := age.Decrypt(sealed, identities...)
+	if err != nil {
+		if errors.Is(err, age.ErrNoIdentityMatched) {
+			return fmt.Errorf("%s: %w", *in, err)
+		}
+		return fmt.Errorf("failed to decrypt %s: %w", *in, err)
+	}
+	defer bls.SecretBytes(data).Zero()
+	if _, err := writeKeyFile(*out, data, *force); err != nil {
+		return err
+	}
+	slog.Info("age-encrypted key file decrypted", "in", *in, "out", *out)
+	fmt.Fprintf(stdout, "Decrypted %s to %s\n", *in, *out)
+	return nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/age"
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+func TestRunGenerateEncryptToAndDecryptAge(t *testing.T) {
+	t.Setenv("KEY_PASSWORD", testPassword)
+	dir := t.TempDir()
+	id, err := age.GenerateIdentity(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	identityFile := filepath.Join(dir, "ops.agekey")
+	if err := os.WriteFile(identityFile, []byte("# public key: "+id.Recipient().String()+"\n"+id.String()+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(dir, "keys", "bls_key.json")
+	var stdout bytes.Buffer
+	if err := runGenerate([]string{"--out", out, "--encrypt-to", id.Recipient().String(), "--output", "json"}, &stdout); err != nil {
+		t.Fatal(err)
+	}
+	var res generateResult
+	if err := json.Unmarshal(stdout.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	if res.Path != out+".age" {
+		t.Fatalf("path %s, want %s.age", res.Path, out)
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Fatal("an unwrapped key file was written next to the .age file")
+	}
+	sealed, err := os.ReadFile(out + ".age")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(sealed, []byte("age-encryption.org/v1\n")) {
+		t.Fatalf("%s is not an age file", out+".age")
+	}
+
+	other, err := age.GenerateIdentity(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherFile := filepath.Join(dir, "other.agekey")
+	if err := os.WriteFile(otherFile, []byte(other.String()+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := runDecryptAge([]string{"--identity", otherFile, "--in", out + ".age"}, &bytes.Buffer{}); !errors.Is(err, age.ErrNoIdentityMatched) {
+		t.Fatalf("wrong identity: got %v, want ErrNoIdentityMatched", err)
+	}
+
+	if err := runDecryptAge([]string{"--identity", identityFile, "--in", out + ".age"}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	kp, err := blskeys.Load(out, testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := newGenerateResult(&config{format: formatBastion}, out, kp).G1PubKey; got != res.G1PubKey {
+		t.Fatalf("decrypted key %s, generated %s", got, res.G1PubKey)
+	}
+	if err := runDecryptAge([]string{"--identity", identityFile, "--in", out + ".age"}, &bytes.Buffer{}); err == nil {
+		t.Fatal("decrypt-age overwrote an existing key file without --force")
+	}
+}
+
+func TestRunGenerateEncryptToUsage(t *testing.T) {
+	t.Setenv("KEY_PASSWORD", testPassword)
+	out := filepath.Join(t.TempDir(), "bls_key.json")
+	id, err := age.GenerateIdentity(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"--out", out, "--encrypt-to", "age1notarecipient"},
+		{"--out", out, "--encrypt-to", id.String()},
+		{"--out", out, "--encrypt-to", id.Recipient().String(), "--with-ecdsa"},
+	} {
+		if _, err := parseFlags(args); exitCode(err) != exitUsage {
+			t.Errorf("%v: got %v, want a usage error", args, err)
+		}
+	}
+}
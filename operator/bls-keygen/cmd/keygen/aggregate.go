@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+)
+
+// runAggregate implements `keygen aggregate`: it reads a JSON array of hex
+// G1 signatures and prints their aggregate.
+func runAggregate(args []string, stdout io.Writer) (err error) {
+	fs := flag.NewFlagSet("aggregate", flag.ContinueOnError)
+	in := fs.String("signatures", "-", "JSON file holding an array of hex signatures (- for stdin)")
+	var backend backendOption
+	backend.register(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if err := backend.use(); err != nil {
+		return err
+	}
+	defer func(start time.Time) { activeMetrics.observe("aggregate", start, err) }(time.Now())
+
+	var data []byte
+	if *in == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(*in)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read signatures: %w", err)
+	}
+
+	var hexSigs []string
+	if err := json.Unmarshal(data, &hexSigs); err != nil {
+		return fmt.Errorf("signatures must be a JSON array of hex strings: %w", err)
+	}
+
+	sigs := make([]*bls.Signature, len(hexSigs))
+	for i, h := range hexSigs {
+		// Compressed and uncompressed signatures can be mixed; anything
+		// that is not a G1 encoding (e.g. a G2 point) is rejected here.
+		var err error
+		if sigs[i], err = bls.ParseSignature(h); err != nil {
+			return fmt.Errorf("signature %d: %w", i, err)
+		}
+	}
+
+	agg, err := bls.AggregateSignatures(sigs)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "0x%x\n", agg.Bytes())
+	return nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+func TestRunAggregate(t *testing.T) {
+	digest := keccak256([]byte{0xde, 0xad, 0xbe, 0xef})
+
+	var hexSigs []string
+	var pks []*bls.G2PubKey
+	for i := 0; i < 3; i++ {
+		kp, err := blskeys.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig, err := kp.Sign(digest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hexSigs = append(hexSigs, fmt.Sprintf("0x%x", sig.Bytes()))
+		pks = append(pks, kp.G2PubKey)
+	}
+	in := writeJSONFile(t, hexSigs)
+
+	var out bytes.Buffer
+	if err := runAggregate([]string{"--signatures", in}, &out); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := decodeHex(strings.TrimSpace(out.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	agg, err := bls.SignatureFromBytes(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aggPk, err := bls.AggregatePublicKeys(pks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bls.Verify(aggPk, digest, agg) {
+		t.Fatal("aggregate does not verify")
+	}
+}
+
+func TestRunAggregateRejectsBadInput(t *testing.T) {
+	if err := runAggregate([]string{"--signatures", writeJSONFile(t, []string{})}, &bytes.Buffer{}); !errors.Is(err, bls.ErrEmptyAggregate) {
+		t.Fatalf("empty array: got %v, want ErrEmptyAggregate", err)
+	}
+	// A G2 point is not a signature.
+	if err := runAggregate([]string{"--signatures", writeJSONFile(t, []string{knownSignature, knownG2PubKey})}, &bytes.Buffer{}); err == nil {
+		t.Fatal("expected an error when mixing a G2 point into signatures")
+	}
+}
+
+func writeJSONFile(t *testing.T, v interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "in.json")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+)
+
+// errKeyMismatch is returned by assert when the key file holds a different
+// key from the one expected.
+var errKeyMismatch = errors.New("key file does not hold the expected key")
+
+// runAssert implements `keygen assert`: it decrypts the key file and fails
+// unless its G1 public key or operator ID is the expected one, so a
+// deployment can refuse to start an operator with the wrong key mounted. A
+// test-only key fails on --network mainnet too.
+func runAssert(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("assert", flag.ContinueOnError)
+	keyPath := fs.String("key", filepath.Join(defaultKeyDir, defaultKeyFile), "key file to check")
+	expectG1 := fs.String("expect-g1", "", "expected hex G1 public key, compressed or uncompressed")
+	expectID := fs.String("expect-operator-id", "", "expected hex operator ID")
+	var pwSource passwordSource
+	pwSource.register(fs)
+	var network networkOptions
+	network.register(fs)
+	allowTestKey := fs.Bool("allow-test-key", false, "accept a key made with generate --test even for --network mainnet")
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if _, err := network.domain(); err != nil {
+		return err
+	}
+	if *expectG1 == "" && *expectID == "" {
+		return usageErrorf("at least one of --expect-g1 and --expect-operator-id is required")
+	}
+
+	var wantG1 *bls.G1PubKey
+	if *expectG1 != "" {
+		b, err := decodeHex(*expectG1)
+		if err != nil {
+			return usageErrorf("--expect-g1 is not valid hex: %v", err)
+		}
+		if wantG1, err = bls.G1PubKeyFromBytes(b); err != nil {
+			return usageErrorf("--expect-g1: %v", err)
+		}
+	}
+	var wantID []byte
+	if *expectID != "" {
+		var err error
+		if wantID, err = decodeHex(*expectID); err != nil || len(wantID) != 32 {
+			return usageErrorf("--expect-operator-id must be 32 bytes of hex")
+		}
+	}
+
+	if err := checkTestKey(*keyPath, network.name == "mainnet", *allowTestKey); err != nil {
+		return err
+	}
+
+	password, err := pwSource.read()
+	if err != nil {
+		return err
+	}
+	kp, err := loadKey(*keyPath, password)
+	if err != nil {
+		return fmt.Errorf("failed to load key %s: %w", *keyPath, err)
+	}
+	defer kp.PrivateKey.Zero()
+
+	var diffs []string
+	if wantG1 != nil && !bytes.Equal(kp.G1PubKey.Bytes(), wantG1.Bytes()) {
+		diffs = append(diffs, fmt.Sprintf("G1 public key:\n  expected 0x%x\n  found    0x%x", wantG1.Bytes(), kp.G1PubKey.Bytes()))
+	}
+	id := bls.OperatorID(kp.G1PubKey)
+	if wantID != nil && !bytes.Equal(id[:], wantID) {
+		diffs = append(diffs, fmt.Sprintf("operator ID:\n  expected 0x%x\n  found    0x%x", wantID, id))
+	}
+	if len(diffs) > 0 {
+		return fmt.Errorf("%w: %s\n%s", errKeyMismatch, *keyPath, strings.Join(diffs, "\n"))
+	}
+
+	fmt.Fprintf(stdout, "%s holds the expected key (operator ID 0x%x)\n", *keyPath, id)
+	return nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+func TestRunAssertMatches(t *testing.T) {
+	kp, path := writeTestKey(t)
+	t.Setenv("KEY_PASSWORD", testPassword)
+
+	for name, args := range map[string][]string{
+		"g1":          {"--expect-g1", fmt.Sprintf("0x%x", kp.G1PubKey.Bytes())},
+		"operator id": {"--expect-operator-id", fmt.Sprintf("0x%x", bls.OperatorID(kp.G1PubKey))},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if err := runAssert(append([]string{"--key", path}, args...), &bytes.Buffer{}); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestRunAssertMismatch(t *testing.T) {
+	_, path := writeTestKey(t)
+	other, err := blskeys.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("KEY_PASSWORD", testPassword)
+
+	want := fmt.Sprintf("0x%x", other.G1PubKey.Bytes())
+	err = runAssert([]string{"--key", path, "--expect-g1", want}, &bytes.Buffer{})
+	if !errors.Is(err, errKeyMismatch) {
+		t.Fatalf("got %v, want errKeyMismatch", err)
+	}
+	if !strings.Contains(err.Error(), "expected "+want) {
+		t.Errorf("error does not show the expected key:\n%v", err)
+	}
+	if exitCode(err) == exitOK {
+		t.Error("mismatch exits zero")
+	}
+}
+
+func TestRunAssertNeedsExpectation(t *testing.T) {
+	_, path := writeTestKey(t)
+	var usage usageError
+	if err := runAssert([]string{"--key", path}, &bytes.Buffer{}); !errors.As(err, &usage) {
+		t.Fatalf("got %v, want a usage error", err)
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+// auditGenesisHash is the prev_hash of the first entry of an audit log.
+var auditGenesisHash = strings.Repeat("0", 2*sha256.Size)
+
+// errAuditChainBroken is returned by audit-verify for a log whose entries
+// were edited, reordered or removed.
+var errAuditChainBroken = errors.New("audit log hash chain is broken")
+
+// auditEntry is one line of an audit log. Hash is sha256 over the entry's
+// JSON without it, and PrevHash is the Hash of the line before, so editing
+// or removing any line but the last breaks the chain. Entries record only
+// public data: never a password, key or signature.
+type auditEntry struct {
+	Time        string `json:"time"`
+	Operation   string `json:"operation"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+	Result      string `json:"result"`
+	Error       string `json:"error,omitempty"`
+	PrevHash    string `json:"prev_hash"`
+	Hash        string `json:"hash,omitempty"`
+}
+
+// computeHash returns the hash of e's other fields.
+func (e auditEntry) computeHash() (string, error) {
+	e.Hash = ""
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// auditOptions holds the --audit-log flag of the commands that use a key.
+type auditOptions struct {
+	path string
+}
+
+func (o *auditOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.path, "audit-log", "", "append a hash-chained JSON record of the operation to this file (default: disabled)")
+}
+
+// done records that operation finished with err, for the key pk when it
+// is known, and returns err. If the record cannot be written the
+// operation's own error still wins; an operation that succeeded fails
+// instead, as it would otherwise go unrecorded.
+func (o *auditOptions) done(operation string, pk *bls.G1PubKey, err error) error {
+	if o.path == "" {
+		return err
+	}
+	e := auditEntry{
+		Time:      time.Now().UTC().Format(time.RFC3339Nano),
+		Operation: operation,
+		Result:    "ok",
+	}
+	if pk != nil {
+		e.Fingerprint = bls.Fingerprint(pk)
+	}
+	if err != nil {
+		e.Result = "error"
+		e.Error = err.Error()
+	}
+	if auditErr := appendAuditEntry(o.path, e); auditErr != nil {
+		if err != nil {
+			slog.Error("failed to write audit log", "path", o.path, "err", auditErr)
+			return err
+		}
+		return fmt.Errorf("%s succeeded, but failed to write audit log: %w", operation, auditErr)
+	}
+	return err
+}
+
+// appendAuditEntry chains e to the last entry of the log at path and
+// appends it, holding a lock on the log so concurrent commands cannot
+// chain two entries to the same predecessor.
+func appendAuditEntry(path string, e auditEntry) error {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	unlock, err := blskeys.LockFile(cmdContext, f)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	last, err := lastLine(f)
+	if err != nil {
+		return err
+	}
+	e.PrevHash = auditGenesisHash
+	if len(last) > 0 {
+		var prev auditEntry
+		if err := json.Unmarshal(last, &prev); err != nil || prev.Hash == "" {
+			return fmt.Errorf("last entry of %s cannot be read, run keygen audit-verify", path)
+		}
+		e.PrevHash = prev.Hash
+	}
+	if e.Hash, err = e.computeHash(); err != nil {
+		return err
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// lastLine returns the last non-empty line of f, reading backwards from
+// the end so appending stays cheap as the log grows.
+func lastLine(f *os.File) ([]byte, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	var buf []byte
+	for off := info.Size(); off > 0; {
+		n := min(off, 4096)
+		off -= n
+		chunk := make([]byte, n)
+		if _, err := f.ReadAt(chunk, off); err != nil {
+			return nil, err
+		}
+		buf = append(chunk, buf...)
+		trimmed := bytes.TrimRight(buf, "\n")
+		if i := bytes.LastIndexByte(trimmed, '\n'); i >= 0 {
+			return trimmed[i+1:], nil
+		}
+	}
+	return bytes.TrimRight(buf, "\n"), nil
+}
+
+// runAuditVerify implements `keygen audit-verify`: it checks every entry's
+// hash and its link to the entry before, and reports the first line that
+// fails. Truncating the log after an entry cannot be detected from the
+// log alone; compare the printed head hash with a copy kept elsewhere.
+func runAuditVerify(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("audit-verify", flag.ContinueOnError)
+	var audit auditOptions
+	audit.register(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if audit.path == "" {
+		return usageErrorf("--audit-log is required")
+	}
+
+	f, err := os.Open(audit.path)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+
+	n, head, err := verifyAuditLog(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", audit.path, err)
+	}
+	fmt.Fprintf(stdout, "Audit log OK: %d entries, head %s\n", n, head)
+	return nil
+}
+
+// verifyAuditLog checks the hash chain of the log read from r and returns
+// the number of entries and the hash of the last.
+func verifyAuditLog(r io.Reader) (n int, head string, err error) {
+	head = auditGenesisHash
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<20)
+	for line := 1; sc.Scan(); line++ {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var e auditEntry
+		dec := json.NewDecoder(bytes.NewReader(sc.Bytes()))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&e); err != nil {
+			return 0, "", fmt.Errorf("line %d: %w: entry cannot be parsed: %v", line, errAuditChainBroken, err)
+		}
+		if e.PrevHash != head {
+			return 0, "", fmt.Errorf("line %d: %w: prev_hash does not match the entry before", line, errAuditChainBroken)
+		}
+		want, err := e.computeHash()
+		if err != nil {
+			return 0, "", err
+		}
+		if e.Hash != want {
+			return 0, "", fmt.Errorf("line %d: %w: entry does not match its hash", line, errAuditChainBroken)
+		}
+		head = e.Hash
+		n++
+	}
+	if err := sc.Err(); err != nil {
+		return 0, "", fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return n, head, nil
+}
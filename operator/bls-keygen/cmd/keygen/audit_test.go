@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+// writeAuditLog signs twice with a test key, once failing, and returns the
+// path of the audit log the signs appended to.
+func writeAuditLog(t *testing.T) (logPath string, kp *blskeys.KeyPair) {
+	t.Helper()
+	kp, keyPath := writeTestKey(t)
+	t.Setenv("KEY_PASSWORD", testPassword)
+	logPath = filepath.Join(t.TempDir(), "audit.log")
+
+	var out bytes.Buffer
+	if err := runSign([]string{"--key", keyPath, "--message", "0xdeadbeef", "--audit-log", logPath}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if err := runSign([]string{"--key", keyPath, "--message", "0xzz", "--audit-log", logPath}, &out); err == nil {
+		t.Fatal("sign accepted an invalid message")
+	}
+	return logPath, kp
+}
+
+func readAuditEntries(t *testing.T, path string) []auditEntry {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []auditEntry
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var e auditEntry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func TestAuditLogRecordsOperations(t *testing.T) {
+	logPath, kp := writeAuditLog(t)
+
+	entries := readAuditEntries(t, logPath)
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	ok, failed := entries[0], entries[1]
+	if ok.Operation != "sign" || ok.Result != "ok" || ok.Fingerprint != bls.Fingerprint(kp.G1PubKey) {
+		t.Errorf("first entry = %+v", ok)
+	}
+	if ok.PrevHash != auditGenesisHash {
+		t.Errorf("first prev_hash = %s, want the genesis hash", ok.PrevHash)
+	}
+	if failed.Result != "error" || failed.Error == "" {
+		t.Errorf("second entry = %+v", failed)
+	}
+	if failed.PrevHash != ok.Hash {
+		t.Error("second entry is not chained to the first")
+	}
+
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), testPassword) {
+		t.Error("audit log contains the password")
+	}
+	info, err := os.Stat(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("audit log mode = %o, want 600", perm)
+	}
+}
+
+func TestRunAuditVerify(t *testing.T) {
+	logPath, _ := writeAuditLog(t)
+	entries := readAuditEntries(t, logPath)
+
+	var out bytes.Buffer
+	if err := runAuditVerify([]string{"--audit-log", logPath}, &out); err != nil {
+		t.Fatal(err)
+	}
+	want := "Audit log OK: 2 entries, head " + entries[1].Hash
+	if got := strings.TrimSpace(out.String()); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestRunAuditVerifyDetectsTampering(t *testing.T) {
+	for name, tamper := range map[string]func(lines []string) []string{
+		"edited": func(lines []string) []string {
+			lines[0] = strings.Replace(lines[0], `"result":"ok"`, `"result":"error"`, 1)
+			return lines
+		},
+		"removed": func(lines []string) []string {
+			return lines[1:]
+		},
+		"reordered": func(lines []string) []string {
+			return []string{lines[1], lines[0]}
+		},
+		"extra field": func(lines []string) []string {
+			lines[0] = strings.Replace(lines[0], "{", `{"note":"x",`, 1)
+			return lines
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			logPath, _ := writeAuditLog(t)
+			data, err := os.ReadFile(logPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			lines := tamper(strings.Split(strings.TrimSpace(string(data)), "\n"))
+			if err := os.WriteFile(logPath, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			err = runAuditVerify([]string{"--audit-log", logPath}, &bytes.Buffer{})
+			if !errors.Is(err, errAuditChainBroken) {
+				t.Fatalf("got %v, want errAuditChainBroken", err)
+			}
+			if !strings.Contains(err.Error(), "line 1") {
+				t.Errorf("error %q does not name line 1", err)
+			}
+		})
+	}
+}
+
+func TestRunAuditVerifyUsage(t *testing.T) {
+	if err := runAuditVerify(nil, &bytes.Buffer{}); exitCode(err) != exitUsage {
+		t.Fatalf("got %v, want a usage error", err)
+	}
+}
+
+func TestRunGenerateAuditLog(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "audit.log")
+	t.Setenv("KEY_PASSWORD", testPassword)
+	args := []string{"--out", filepath.Join(dir, "keys", "bls.json"), "--audit-log", logPath}
+
+	if err := runGenerate(args, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := runGenerate(args, &bytes.Buffer{}); !errors.Is(err, errKeyExists) {
+		t.Fatalf("second generate: got %v, want errKeyExists", err)
+	}
+
+	entries := readAuditEntries(t, logPath)
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	if e := entries[0]; e.Operation != "generate" || e.Result != "ok" || e.Fingerprint == "" {
+		t.Errorf("first entry = %+v", e)
+	}
+	if e := entries[1]; e.Result != "error" {
+		t.Errorf("second entry = %+v", e)
+	}
+	if err := runAuditVerify([]string{"--audit-log", logPath}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+)
+
+// backupAckAttempts is how many times --require-backup-ack asks for the
+// fingerprint before giving up.
+const backupAckAttempts = 3
+
+var errBackupNotAcknowledged = errors.New("backup was not acknowledged")
+
+// checkBackupAck turns --require-backup-ack off, with a warning, when there
+// is no terminal to acknowledge on.
+func (cfg *config) checkBackupAck() {
+	if cfg.requireBackupAck && !stdinTerminal.isTerminal() {
+		slog.Warn("--require-backup-ack needs an interactive terminal, skipping the acknowledgment")
+		cfg.requireBackupAck = false
+	}
+}
+
+// ackBackup shows how to back up the key just written and has the user
+// type its fingerprint back, so an operator cannot finish generation
+// without having recorded it. The key stays written if they do not.
+func (cfg *config) ackBackup(res *generateResult) error {
+	if !cfg.requireBackupAck {
+		return nil
+	}
+	fp := bls.Fingerprint(res.g1)
+	out := stdinTerminal.out
+	fmt.Fprintln(out)
+	if res.Path == stdoutPath {
+		fmt.Fprintln(out, "Back up the key file written to stdout and its password now.")
+	} else {
+		fmt.Fprintf(out, "Back up %s and its password now.\n", res.Path)
+	}
+	fmt.Fprintln(out, "Without both, the key cannot be recovered and the operator must register a new one.")
+	fmt.Fprintf(out, "Record the key's fingerprint with the backup: %s\n", fp)
+	for i := 0; i < backupAckAttempts; i++ {
+		answer, err := stdinTerminal.askLine("Re-enter the fingerprint to confirm the backup is recorded: ")
+		if err != nil {
+			return fmt.Errorf("key %s was written, but %w: %v", res.Path, errBackupNotAcknowledged, err)
+		}
+		if normalizeFingerprint(answer) == normalizeFingerprint(fp) {
+			fmt.Fprintln(out, "Backup acknowledged.")
+			return nil
+		}
+		fmt.Fprintln(out, "That is not the fingerprint.")
+	}
+	return fmt.Errorf("key %s was written, but %w", res.Path, errBackupNotAcknowledged)
+}
+
+// normalizeFingerprint drops the case, group dashes and spaces of a typed
+// fingerprint.
+func normalizeFingerprint(s string) string {
+	return strings.ToUpper(strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' || r == '\t' || r == '\r' || r == '\n' {
+			return -1
+		}
+		return r
+	}, s))
+}
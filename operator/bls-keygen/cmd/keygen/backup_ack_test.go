@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+// typedFingerprint answers the acknowledgment prompt with the fingerprint
+// of the key at path, once the key exists.
+func typedFingerprint(t *testing.T, path string) func() (string, error) {
+	return func() (string, error) {
+		pk, err := blskeys.LoadPublicKey(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Case and dashes do not matter.
+		return strings.ToLower(strings.ReplaceAll(bls.Fingerprint(pk), "-", "")) + "\n", nil
+	}
+}
+
+func TestRunGenerateRequireBackupAck(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "bls_key.json")
+	t.Setenv("KEY_PASSWORD", testPassword)
+	tty := scriptedTerminal(t, true)
+	answers := []func() (string, error){
+		func() (string, error) { return "AAAA-BBBB-CCCC-DDDD\n", nil },
+		typedFingerprint(t, out),
+	}
+	stdinTerminal.readLine = func() (string, error) {
+		answer := answers[0]
+		answers = answers[1:]
+		return answer()
+	}
+
+	if err := runGenerate([]string{"--out", out, "--require-backup-ack"}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(answers) != 0 {
+		t.Fatal("the fingerprint was not asked for again after a wrong answer")
+	}
+	for _, want := range []string{"Back up " + out, "That is not the fingerprint", "Backup acknowledged"} {
+		if !strings.Contains(tty.String(), want) {
+			t.Errorf("terminal output does not contain %q:\n%s", want, tty)
+		}
+	}
+	if !regexp.MustCompile(`fingerprint with the backup: [A-Z0-9]{4}(-[A-Z0-9]{4}){3}`).MatchString(tty.String()) {
+		t.Errorf("the fingerprint was not shown:\n%s", tty)
+	}
+}
+
+func TestRunGenerateRequireBackupAckFails(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "bls_key.json")
+	t.Setenv("KEY_PASSWORD", testPassword)
+	scriptedTerminal(t, true, "wrong\n", "wrong\n", "wrong\n")
+
+	err := runGenerate([]string{"--out", out, "--require-backup-ack"}, &bytes.Buffer{})
+	if !errors.Is(err, errBackupNotAcknowledged) {
+		t.Fatalf("got %v, want errBackupNotAcknowledged", err)
+	}
+	// The key is kept: deleting it would lose one that may already be
+	// backed up.
+	if _, err := os.Stat(out); err != nil {
+		t.Fatalf("key was not kept: %v", err)
+	}
+}
+
+func TestRunGenerateRequireBackupAckNonInteractive(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "bls_key.json")
+	t.Setenv("KEY_PASSWORD", testPassword)
+	tty := scriptedTerminal(t, false)
+
+	if err := runGenerate([]string{"--out", out, "--require-backup-ack"}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	if tty.Len() != 0 {
+		t.Fatalf("prompted without a terminal:\n%s", tty)
+	}
+}
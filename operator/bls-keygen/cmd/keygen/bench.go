@@ -0,0 +1,128 @@
+package main
+
+import (
+	"crypto/rand"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+)
+
+// benchMessage is the task digest every benchmark signs.
+var benchMessage = keccak256([]byte("bastion bls-keygen bench"))
+
+// benchResult is the throughput of one benchmarked operation.
+type benchResult struct {
+	name    string
+	ops     int
+	elapsed time.Duration
+}
+
+// perSecond is the operation rate over the measured time.
+func (r benchResult) perSecond() float64 {
+	if r.elapsed <= 0 {
+		return 0
+	}
+	return float64(r.ops) / r.elapsed.Seconds()
+}
+
+// runBench implements `keygen bench`: it measures how many signatures,
+// verifications and aggregate verifications per second the host manages
+// with ephemeral keys. No key file is read.
+func runBench(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	duration := fs.Duration("duration", 5*time.Second, "total time to measure for, split evenly between the benchmarks")
+	aggregateN := fs.Int("aggregate-n", 100, "signers in the aggregate benchmark")
+	var backend backendOption
+	backend.register(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if err := backend.use(); err != nil {
+		return err
+	}
+	if *duration <= 0 {
+		return usageErrorf("--duration must be positive, got %s", *duration)
+	}
+	if *aggregateN < 1 {
+		return usageErrorf("--aggregate-n must be at least 1, got %d", *aggregateN)
+	}
+
+	results, err := bench(*duration, *aggregateN)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Backend: %s\n", bls.ActiveBackend())
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "OPERATION\tOPS\tTIME\tOPS/SEC")
+	for _, r := range results {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%.1f\n", r.name, r.ops, r.elapsed.Round(time.Millisecond), r.perSecond())
+	}
+	return w.Flush()
+}
+
+// bench runs the sign, verify and aggregate-of-n benchmarks for a third of
+// d each. The aggregate benchmark aggregates n signatures and public keys
+// over one message and verifies the result, as an AVS aggregator does per
+// task. It stops early when cmdContext is cancelled.
+func bench(d time.Duration, n int) ([]benchResult, error) {
+	kps := make([]*bls.KeyPair, n)
+	sigs := make([]*bls.Signature, n)
+	pks := make([]*bls.G2PubKey, n)
+	for i := range kps {
+		kp, err := bls.GenerateKeyPair(rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate an ephemeral key: %w", err)
+		}
+		defer kp.PrivateKey.Zero()
+		sig, err := kp.Sign(benchMessage)
+		if err != nil {
+			return nil, err
+		}
+		kps[i], sigs[i], pks[i] = kp, sig, kp.G2PubKey
+	}
+
+	share := d / 3
+	var results []benchResult
+	for _, b := range []struct {
+		name string
+		op   func() error
+	}{
+		{"sign", func() error {
+			_, err := kps[0].Sign(benchMessage)
+			return err
+		}},
+		{"verify", func() error {
+			return bls.VerifyE(pks[0], benchMessage, sigs[0])
+		}},
+		{fmt.Sprintf("aggregate-%d", n), func() error {
+			aggSig, err := bls.AggregateSignatures(sigs)
+			if err != nil {
+				return err
+			}
+			aggPK, err := bls.AggregatePublicKeys(pks)
+			if err != nil {
+				return err
+			}
+			return bls.VerifyE(aggPK, benchMessage, aggSig)
+		}},
+	} {
+		r := benchResult{name: b.name}
+		start := time.Now()
+		for r.ops == 0 || time.Since(start) < share {
+			if err := cmdContext.Err(); err != nil {
+				return nil, err
+			}
+			if err := b.op(); err != nil {
+				return nil, fmt.Errorf("%s benchmark: %w", b.name, err)
+			}
+			r.ops++
+		}
+		r.elapsed = time.Since(start)
+		results = append(results, r)
+	}
+	return results, nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBench(t *testing.T) {
+	results, err := bench(30*time.Millisecond, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{"sign", "verify", "aggregate-3"}
+	if len(results) != len(names) {
+		t.Fatalf("got %d results, want %d", len(results), len(names))
+	}
+	for i, r := range results {
+		if r.name != names[i] {
+			t.Errorf("result %d is %s, want %s", i, r.name, names[i])
+		}
+		if r.ops <= 0 || r.elapsed <= 0 {
+			t.Fatalf("%s: ops = %d, elapsed = %s; want both positive", r.name, r.ops, r.elapsed)
+		}
+		if want := float64(r.ops) / r.elapsed.Seconds(); math.Abs(r.perSecond()-want) > 1e-9*want {
+			t.Errorf("%s: %.3f ops/sec, want ops/elapsed = %.3f", r.name, r.perSecond(), want)
+		}
+	}
+}
+
+func TestRunBench(t *testing.T) {
+	var out bytes.Buffer
+	if err := runBench([]string{"--duration", "30ms", "--aggregate-n", "2"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Backend: gnark", "OPS/SEC", "sign", "verify", "aggregate-2"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+
+	for _, args := range [][]string{{"--duration", "0s"}, {"--aggregate-n", "0"}, {"--backend", "nope"}} {
+		if err := runBench(args, &bytes.Buffer{}); exitCode(err) != exitUsage {
+			t.Errorf("%v: got %v, want a usage error", args, err)
+		}
+	}
+}
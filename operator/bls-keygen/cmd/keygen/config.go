@@ -0,0 +1,625 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+const (
+	defaultKeyDir  = "/keys"
+	defaultKeyFile = "bls_key.json"
+
+	outputText = "text"
+	outputJSON = "json"
+
+	// stdoutPath as --out writes the encrypted key to stdout.
+	stdoutPath = "-"
+)
+
+// config is the parsed command line of the generator.
+type config struct {
+	out           string
+	keyDir        string
+	password      passwordSource
+	format        string
+	force         bool
+	dryRun        bool
+	count         int
+	policy        passwordPolicy
+	perms         permCheck
+	log           logOptions
+	registry      registryCheck
+	file          configFile
+	kdf           kdfOptions
+	kdfParams     blskeys.KDFParams
+	network       networkOptions
+	label         labelOption
+	ecdsa         ecdsaOptions
+	backend       backendOption
+	age           ageOptions
+	audit         auditOptions
+	output        string
+	entropyFile   string
+	strictEntropy bool
+	selfTest      bool
+	replaceLink   bool
+	testKey       bool
+	// requireBackupAck makes generate ask for the fingerprint of each key
+	// it writes; see ackBackup.
+	requireBackupAck bool
+	// noVerifyGenerated skips verifyGenerated on each new key.
+	noVerifyGenerated bool
+	// replacePlaceholder lets generate replace an existing key that is a
+	// legacy ECDSA placeholder, and no other.
+	replacePlaceholder bool
+}
+
+// kdfOptions holds the flags choosing the KDF that encrypts a new key.
+type kdfOptions struct {
+	name       string
+	scryptN    int
+	scryptR    int
+	scryptP    int
+	iterations int
+	allowWeak  bool
+}
+
+func (o *kdfOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.name, "kdf", "scrypt", "password KDF: scrypt or pbkdf2")
+	fs.IntVar(&o.scryptN, "scrypt-n", blskeys.DefaultScryptParams.N, "scrypt CPU/memory cost, a power of two")
+	fs.IntVar(&o.scryptR, "scrypt-r", blskeys.DefaultScryptParams.R, "scrypt block size")
+	fs.IntVar(&o.scryptP, "scrypt-p", blskeys.DefaultScryptParams.P, "scrypt parallelism")
+	fs.IntVar(&o.iterations, "pbkdf2-iterations", blskeys.DefaultPBKDF2Params.C, "PBKDF2-HMAC-SHA256 iteration count")
+	fs.BoolVar(&o.allowWeak, "allow-weak-kdf", false, "accept KDF parameters below the safe minimum (CI only)")
+}
+
+// params returns the KDF parameters the flags describe, rejecting weak
+// ones unless --allow-weak-kdf is set.
+func (o *kdfOptions) params() (blskeys.KDFParams, error) {
+	var p blskeys.KDFParams
+	switch o.name {
+	case "scrypt":
+		if o.scryptN < 2 || o.scryptN&(o.scryptN-1) != 0 {
+			return p, usageErrorf("--scrypt-n must be a power of two, got %d", o.scryptN)
+		}
+		if o.scryptR < 1 || o.scryptP < 1 {
+			return p, usageErrorf("--scrypt-r and --scrypt-p must be positive")
+		}
+		p = blskeys.KDFParams{N: o.scryptN, R: o.scryptR, P: o.scryptP, DKLen: blskeys.DefaultScryptParams.DKLen}
+	case "pbkdf2":
+		if o.iterations < 1 {
+			return p, usageErrorf("--pbkdf2-iterations must be positive, got %d", o.iterations)
+		}
+		p = blskeys.DefaultPBKDF2Params
+		p.C = o.iterations
+	default:
+		return p, usageErrorf("unknown kdf %q", o.name)
+	}
+	if err := p.CheckStrength(); err != nil {
+		if !o.allowWeak {
+			return p, fmt.Errorf("%w (use --allow-weak-kdf to override)", err)
+		}
+		slog.Warn("accepting weak KDF parameters", "reason", err)
+	}
+	return p, nil
+}
+
+// networkCustom is the --network value that takes its tag from --dst.
+const networkCustom = "custom"
+
+// networkOptions holds the flags choosing the domain separation tags that
+// signatures and proofs of possession are made under.
+type networkOptions struct {
+	name   string
+	dstHex string
+}
+
+func (o *networkOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.name, "network", "mainnet", "network whose domain separation tags to use: mainnet, holesky, sepolia or custom")
+	fs.StringVar(&o.dstHex, "dst", "", "hex-encoded signature domain separation tag (--network custom)")
+}
+
+// domain returns the domain the flags select.
+func (o *networkOptions) domain() (bls.Domain, error) {
+	if o.name == networkCustom {
+		if o.dstHex == "" {
+			return bls.Domain{}, usageErrorf("--dst is required with --network custom")
+		}
+		dst, err := decodeHex(o.dstHex)
+		if err != nil {
+			return bls.Domain{}, usageErrorf("invalid --dst: %v", err)
+		}
+		d, err := bls.CustomDomain(dst)
+		if err != nil {
+			return bls.Domain{}, usageErrorf("invalid --dst: %v", err)
+		}
+		return d, nil
+	}
+	if o.dstHex != "" {
+		return bls.Domain{}, usageErrorf("--dst requires --network custom")
+	}
+	d, ok := bls.Networks[o.name]
+	if !ok {
+		return bls.Domain{}, usageErrorf("unknown --network %q", o.name)
+	}
+	return d, nil
+}
+
+// metadata returns the metadata of a key file created now for the chosen
+// network.
+func (o *networkOptions) metadata() *blskeys.Metadata {
+	return blskeys.NewMetadata(o.name, time.Now())
+}
+
+// labelOption is the --label flag of the commands that create key files.
+type labelOption struct {
+	text string
+}
+
+func (o *labelOption) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.text, "label", "", "human-readable name recorded in the key file's metadata, e.g. mainnet-primary")
+}
+
+func (o *labelOption) validate() error {
+	if err := blskeys.ValidateLabel(o.text); err != nil {
+		return usageErrorf("invalid --label: %v", err)
+	}
+	return nil
+}
+
+// avsOption is the --avs-id flag of the commands that derive keys from a
+// mnemonic.
+type avsOption struct {
+	id string
+}
+
+func (o *avsOption) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.id, "avs-id", "", "derive this AVS's own key, one more path component hashed from the identifier (default: none)")
+}
+
+// validate rejects an --avs-id that would quietly derive another key than
+// the one meant.
+func (o *avsOption) validate() error {
+	if strings.TrimSpace(o.id) != o.id {
+		return usageErrorf("--avs-id %q has leading or trailing whitespace", o.id)
+	}
+	return nil
+}
+
+// path returns base extended for the AVS, if one is given.
+func (o *avsOption) path(base string) string {
+	return blskeys.AVSPath(base, o.id)
+}
+
+// backendOption is the --backend flag of the commands that generate, sign,
+// verify or aggregate. Keys are the same under every backend, so it only
+// changes which implementation does the work.
+type backendOption struct {
+	name string
+}
+
+func (o *backendOption) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.name, "backend", bls.DefaultBackendName, "BLS implementation to run on: "+strings.Join(bls.Backends(), ", "))
+}
+
+// use switches the bls package to the selected backend for the rest of
+// the run.
+func (o *backendOption) use() error {
+	if err := bls.UseBackend(o.name); err != nil {
+		return usageErrorf("invalid --backend: %v", err)
+	}
+	return nil
+}
+
+// signingOptions are the networkOptions of the commands that sign or
+// verify messages, plus the prefix prepended to every message and the
+// backend doing the work.
+type signingOptions struct {
+	networkOptions
+	backend   backendOption
+	prefixHex string
+}
+
+func (o *signingOptions) register(fs *flag.FlagSet) {
+	o.networkOptions.register(fs)
+	o.backend.register(fs)
+	fs.StringVar(&o.prefixHex, "message-prefix", "", "hex-encoded bytes prepended to keccak256(message) before signing; signer and verifier must agree")
+}
+
+// context switches to the selected backend and returns the signing
+// context the flags select.
+func (o *signingOptions) context() (bls.SigningContext, error) {
+	if err := o.backend.use(); err != nil {
+		return bls.SigningContext{}, err
+	}
+	domain, err := o.domain()
+	if err != nil {
+		return bls.SigningContext{}, err
+	}
+	prefix, err := decodeHex(o.prefixHex)
+	if err != nil {
+		return bls.SigningContext{}, usageErrorf("invalid --message-prefix: %v", err)
+	}
+	c, err := bls.NewSigningContext(domain, prefix)
+	if err != nil {
+		return bls.SigningContext{}, usageErrorf("invalid --message-prefix: %v", err)
+	}
+	return c, nil
+}
+
+// passwordPolicy holds the flags controlling password strength checks for
+// commands that encrypt a key.
+type passwordPolicy struct {
+	allowWeak bool
+	minLength int
+}
+
+func (p *passwordPolicy) register(fs *flag.FlagSet) {
+	fs.BoolVar(&p.allowWeak, "allow-weak-password", false, "accept a password that fails the strength check (test environments only)")
+	fs.IntVar(&p.minLength, "min-password-length", blskeys.DefaultMinPasswordLength, "minimum password length")
+}
+
+// check validates password, downgrading a failure to a warning when
+// --allow-weak-password is set.
+func (p *passwordPolicy) check(password string) error {
+	err := blskeys.PasswordPolicy{MinLength: p.minLength}.Validate(password)
+	if err != nil && p.allowWeak {
+		slog.Warn("accepting weak password", "reason", err)
+		return nil
+	}
+	return err
+}
+
+// permCheck holds the flags controlling the modes key files and
+// directories are written with, whether symlinked key paths are followed,
+// and the permission check run afterwards.
+type permCheck struct {
+	skip        bool
+	fileMode    octalMode
+	dirMode     octalMode
+	allowUnsafe bool
+	noFollow    bool
+	allowRoot   bool
+}
+
+func (c *permCheck) register(fs *flag.FlagSet) {
+	c.fileMode = octalMode(blskeys.KeyFileMode)
+	c.dirMode = octalMode(blskeys.KeyDirMode)
+	fs.BoolVar(&c.skip, "skip-perm-check", false, "do not verify key file and directory permissions after writing (for filesystems without Unix modes)")
+	fs.Var(&c.fileMode, "keyfile-mode", "octal mode for written key files, e.g. 0640 for a sidecar in the same group")
+	fs.Var(&c.dirMode, "keydir-mode", "octal mode for created key directories, e.g. 0750 for a sidecar in the same group")
+	fs.BoolVar(&c.allowUnsafe, "allow-unsafe-perms", false, "accept a world-readable or world-writable --keyfile-mode, or a world-writable --keydir-mode")
+	fs.BoolVar(&c.noFollow, "no-follow-symlinks", false, "refuse a key path that is a symbolic link instead of writing through it to its target")
+	fs.BoolVar(&c.allowRoot, "allow-root", false, "write key files while running as root, which leaves them owned by root")
+}
+
+// geteuid returns the effective user ID, or -1 on platforms without one
+// such as Windows. Tests replace it.
+var geteuid = os.Geteuid
+
+// validate rejects modes that would expose the key to every user, unless
+// --allow-unsafe-perms is set, and modes the owner could not read back. It
+// also refuses to run as root without --allow-root: key files written by
+// root are owned by root, and the operator's service account cannot read
+// them.
+func (c *permCheck) validate() error {
+	if geteuid() == 0 {
+		if !c.allowRoot {
+			return usageErrorf("refusing to write key files as root, they would be unreadable by the service account; run as that user or pass --allow-root")
+		}
+		slog.Warn("running as root, key files will be owned by root")
+	}
+	file, dir := os.FileMode(c.fileMode), os.FileMode(c.dirMode)
+	if file&0400 == 0 {
+		return usageErrorf("--keyfile-mode %04o is not readable by its owner", file)
+	}
+	if dir&0700 != 0700 {
+		return usageErrorf("--keydir-mode %04o does not give its owner full access", dir)
+	}
+	if c.allowUnsafe {
+		if file&0007 != 0 || dir&0002 != 0 {
+			slog.Warn("using unsafe key permissions", "keyfile_mode", fmt.Sprintf("%04o", file), "keydir_mode", fmt.Sprintf("%04o", dir))
+		}
+		return nil
+	}
+	if file&0007 != 0 {
+		return usageErrorf("--keyfile-mode %04o gives every user access to the key; pass --allow-unsafe-perms to use it anyway", file)
+	}
+	if dir&0002 != 0 {
+		return usageErrorf("--keydir-mode %04o is world-writable; pass --allow-unsafe-perms to use it anyway", dir)
+	}
+	return nil
+}
+
+// keyPath returns the file to write for the key path given on the command
+// line. A symlink is followed to its target, which is then written in place
+// so the link survives, unless --no-follow-symlinks refuses it.
+func (c *permCheck) keyPath(path string) (string, error) {
+	if !blskeys.IsSymlink(path) {
+		return path, nil
+	}
+	if c.noFollow {
+		return "", fmt.Errorf("%s: %w (--no-follow-symlinks)", path, blskeys.ErrSymlink)
+	}
+	target, err := blskeys.ResolvePath(path)
+	if err != nil {
+		return "", err
+	}
+	slog.Debug("key path is a symlink, writing its target", "path", path, "target", target)
+	return target, nil
+}
+
+// ensureDir is ensureKeyDir with the configured directory mode.
+func (c *permCheck) ensureDir(dir string) error {
+	return ensureKeyDir(dir, os.FileMode(c.dirMode))
+}
+
+// check gives the key file written to path the configured mode, since
+// blskeys always writes KeyFileMode, and then verifies its permissions.
+func (c *permCheck) check(path string) error {
+	if mode := os.FileMode(c.fileMode); mode != blskeys.KeyFileMode {
+		if err := os.Chmod(path, mode); err != nil {
+			return fmt.Errorf("failed to set key file mode: %w", err)
+		}
+	}
+	if c.skip {
+		return nil
+	}
+	return blskeys.CheckPermissionsMode(path, os.FileMode(c.fileMode), os.FileMode(c.dirMode))
+}
+
+// octalMode is a flag.Value holding permission bits written in octal.
+type octalMode os.FileMode
+
+func (m *octalMode) String() string { return fmt.Sprintf("%04o", uint32(*m)) }
+
+func (m *octalMode) Set(s string) error {
+	v, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || v&^0777 != 0 {
+		return fmt.Errorf("%q is not an octal permission mode such as 0640", s)
+	}
+	*m = octalMode(v)
+	return nil
+}
+
+// parseFlags parses args into a config. --out defaults to bls_key.json inside
+// --keydir, and a relative --out is resolved against --keydir when both are given.
+func parseFlags(args []string) (*config, error) {
+	cfg := &config{}
+	fs := flag.NewFlagSet("keygen", flag.ContinueOnError)
+	fs.StringVar(&cfg.out, "out", "", "path of the key file to write (default /keys/bls_key.json)")
+	fs.StringVar(&cfg.keyDir, "keydir", "", "directory to create the key file in (default /keys)")
+	cfg.password.register(fs)
+	cfg.password.registerConfirm(fs)
+	fs.StringVar(&cfg.format, "format", formatBastion, "key file format: bastion, eip2335, binary for a compact encoding of a bastion key file, or pem for one in a PEM block")
+	fs.BoolVar(&cfg.force, "force", false, "replace an existing key, backing it up first")
+	fs.IntVar(&cfg.count, "count", 1, "number of keys to generate; more than one writes key-0.json ... into --keydir")
+	fs.BoolVar(&cfg.dryRun, "dry-run", false, "run every check and generate a key in memory, but write nothing")
+	fs.StringVar(&cfg.output, "output", outputText, "result output: text, or json for one JSON object per key on stdout")
+	fs.StringVar(&cfg.entropyFile, "entropy-file", "", "file of extra seed material to mix into crypto/rand (air-gapped setups)")
+	fs.BoolVar(&cfg.strictEntropy, "strict-entropy", false, "fail instead of warning when crypto/rand is slow to respond")
+	fs.BoolVar(&cfg.selfTest, "self-test", false, "sign, verify and aggregate with ephemeral keys before generating, and fail if the curve code is broken")
+	fs.BoolVar(&cfg.noVerifyGenerated, "no-verify-generated", false, "skip signing and verifying a random challenge with each new key before it is written")
+	fs.BoolVar(&cfg.replaceLink, "replace-symlink", false, "replace a symlinked --out with a regular file, leaving the link's old target alone, instead of writing through the link")
+	fs.BoolVar(&cfg.requireBackupAck, "require-backup-ack", false, "after writing each key, show backup instructions and require its fingerprint to be typed back (interactive only)")
+	fs.BoolVar(&cfg.replacePlaceholder, "replace-placeholder", false, "replace an existing key file that is a legacy ECDSA placeholder, keeping a backup; other existing keys still need --force")
+	fs.BoolVar(&cfg.testKey, "test", false, "mark the key test-only; register-payload and assert then refuse it on mainnet")
+	cfg.policy.register(fs)
+	cfg.perms.register(fs)
+	cfg.log.register(fs)
+	cfg.registry.register(fs)
+	cfg.file.register(fs)
+	cfg.kdf.register(fs)
+	cfg.network.register(fs)
+	cfg.label.register(fs)
+	cfg.ecdsa.register(fs)
+	cfg.backend.register(fs)
+	cfg.age.register(fs)
+	cfg.audit.register(fs)
+	if err := parseCommandLine(fs, args); err != nil {
+		return nil, err
+	}
+	if err := cfg.file.apply(fs); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, usageErrorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	if cfg.format != formatBastion && cfg.format != formatEIP2335 && cfg.format != formatBinary && cfg.format != formatPEM {
+		return nil, usageErrorf("unknown key file format %q", cfg.format)
+	}
+	if cfg.output != outputText && cfg.output != outputJSON {
+		return nil, usageErrorf("unknown --output %q", cfg.output)
+	}
+	if cfg.count < 1 {
+		return nil, usageErrorf("--count must be at least 1, got %d", cfg.count)
+	}
+	if cfg.testKey && cfg.format == formatEIP2335 {
+		return nil, usageErrorf("--test cannot be recorded in an EIP-2335 keystore, use --format bastion, binary or pem")
+	}
+	if cfg.label.text != "" && cfg.format == formatEIP2335 {
+		return nil, usageErrorf("--label cannot be recorded in an EIP-2335 keystore, use --format bastion, binary or pem")
+	}
+	if err := cfg.label.validate(); err != nil {
+		return nil, err
+	}
+	if cfg.replaceLink && cfg.perms.noFollow {
+		return nil, usageErrorf("--replace-symlink and --no-follow-symlinks are mutually exclusive")
+	}
+	if cfg.count > 1 && cfg.out != "" {
+		return nil, usageErrorf("--out cannot be combined with --count, use --keydir")
+	}
+	if cfg.out == stdoutPath && cfg.output == outputJSON {
+		return nil, usageErrorf("--output json cannot be combined with --out -, the key is the output")
+	}
+	if err := cfg.ecdsa.validate(cfg); err != nil {
+		return nil, err
+	}
+	if err := cfg.age.validate(cfg); err != nil {
+		return nil, err
+	}
+	params, err := cfg.kdf.params()
+	if err != nil {
+		return nil, err
+	}
+	cfg.kdfParams = params
+	if _, err := cfg.network.domain(); err != nil {
+		return nil, err
+	}
+	if err := cfg.perms.validate(); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case cfg.out == stdoutPath:
+	case cfg.out == "" && cfg.keyDir == "":
+		cfg.keyDir = defaultKeyDir
+		cfg.out = filepath.Join(defaultKeyDir, defaultKeyFile)
+	case cfg.out == "":
+		cfg.out = filepath.Join(cfg.keyDir, defaultKeyFile)
+	case cfg.keyDir == "":
+		cfg.keyDir = filepath.Dir(cfg.out)
+	case !filepath.IsAbs(cfg.out):
+		cfg.out = filepath.Join(cfg.keyDir, cfg.out)
+	}
+	return cfg, nil
+}
+
+// keyPaths returns the files runGenerate writes: --out for a single key,
+// otherwise key-<i>.json for each of the --count keys in --keydir, with
+// .age appended under --encrypt-to.
+func (cfg *config) keyPaths() []string {
+	if cfg.count == 1 {
+		return []string{cfg.age.path(cfg.out)}
+	}
+	paths := make([]string, cfg.count)
+	for i := range paths {
+		paths[i] = cfg.age.path(filepath.Join(cfg.keyDir, indexedKeyFile(i)))
+	}
+	return paths
+}
+
+// resolveKeyPaths is keyPaths with the symlink flags applied. Under
+// --replace-symlink links keep their own path; replaceLink removes them
+// just before the new key is written.
+func (cfg *config) resolveKeyPaths() ([]string, error) {
+	paths := cfg.keyPaths()
+	if cfg.replaceLink {
+		return paths, nil
+	}
+	for i, path := range paths {
+		resolved, err := cfg.perms.keyPath(path)
+		if err != nil {
+			return nil, err
+		}
+		paths[i] = resolved
+	}
+	return paths, nil
+}
+
+// resolveECDSAPaths returns the --with-ecdsa keystore path of each of
+// paths, with the symlink flags applied like resolveKeyPaths. It is nil
+// without --with-ecdsa.
+func (cfg *config) resolveECDSAPaths(paths []string) ([]string, error) {
+	if !cfg.ecdsa.enabled {
+		return nil, nil
+	}
+	out := make([]string, len(paths))
+	for i, path := range paths {
+		resolved, err := cfg.perms.keyPath(cfg.ecdsa.path(path))
+		if err != nil {
+			return nil, err
+		}
+		out[i] = resolved
+	}
+	return out, nil
+}
+
+// metadata is the metadata recorded in generated key files.
+func (cfg *config) metadata() *blskeys.Metadata {
+	meta := cfg.network.metadata()
+	meta.TestOnly, meta.Label = cfg.testKey, cfg.label.text
+	return meta
+}
+
+// indexedKeyFile is the name of key i in a multi-key directory.
+func indexedKeyFile(i int) string {
+	return fmt.Sprintf("key-%d.json", i)
+}
+
+// readPassword returns the password of an existing key. --password-file
+// takes precedence over the environment (see envPassword); a single
+// trailing newline in the file is ignored. With neither set, the user is
+// prompted if stdin is a terminal.
+func readPassword(passwordFile string) (string, error) {
+	return resolvePassword(passwordFile, false)
+}
+
+// readNewPassword is readPassword for commands that encrypt a key: an
+// interactive prompt asks for the password twice.
+func readNewPassword(passwordFile string) (string, error) {
+	return resolvePassword(passwordFile, true)
+}
+
+func resolvePassword(passwordFile string, confirm bool) (string, error) {
+	if passwordFile != "" {
+		return readPasswordFile(passwordFile)
+	}
+
+	if password, err := envPassword(); password != "" || err != nil {
+		return password, err
+	}
+	if stdinTerminal.isTerminal() {
+		return stdinTerminal.prompt(confirm)
+	}
+	return "", errNoEnvPassword
+}
+
+// errNoEnvPassword is returned when a password is wanted from the
+// environment and none is set.
+var errNoEnvPassword = errors.New("KEY_PASSWORD or KEY_PASSWORD_FILE environment variable not set")
+
+// envPassword returns the password set in the environment, or "" if there
+// is none. KEY_PASSWORD_FILE, the Docker and Kubernetes secrets convention,
+// names a file holding the password, whose surrounding whitespace is
+// trimmed; it takes precedence over KEY_PASSWORD.
+func envPassword() (string, error) {
+	path := os.Getenv("KEY_PASSWORD_FILE")
+	if path == "" {
+		return os.Getenv("KEY_PASSWORD"), nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read KEY_PASSWORD_FILE: %w", err)
+	}
+	defer bls.SecretBytes(data).Zero()
+	password := strings.TrimSpace(string(data))
+	if password == "" {
+		return "", fmt.Errorf("KEY_PASSWORD_FILE %s is empty", path)
+	}
+	return password, nil
+}
+
+// readPasswordFile reads a password from path, ignoring a single trailing
+// newline.
+func readPasswordFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read password file: %w", err)
+	}
+	password := strings.TrimSuffix(strings.TrimSuffix(string(data), "\n"), "\r")
+	if password == "" {
+		return "", fmt.Errorf("password file %s is empty", path)
+	}
+	return password, nil
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+func TestParseFlags(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		out    string
+		keyDir string
+	}{
+		{"defaults", nil, "/keys/bls_key.json", "/keys"},
+		{"out only", []string{"--out", "/tmp/k/key.json"}, "/tmp/k/key.json", "/tmp/k"},
+		{"keydir only", []string{"--keydir", "/data/keys"}, "/data/keys/bls_key.json", "/data/keys"},
+		{"relative out in keydir", []string{"--keydir", "/data/keys", "--out", "op.json"}, "/data/keys/op.json", "/data/keys"},
+		{"absolute out with keydir", []string{"--keydir", "/data/keys", "--out", "/etc/op.json"}, "/etc/op.json", "/data/keys"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := parseFlags(tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.out != tt.out {
+				t.Errorf("out = %q, want %q", cfg.out, tt.out)
+			}
+			if cfg.keyDir != tt.keyDir {
+				t.Errorf("keyDir = %q, want %q", cfg.keyDir, tt.keyDir)
+			}
+		})
+	}
+}
+
+func TestParseFlagsRejectsBadInput(t *testing.T) {
+	for _, args := range [][]string{
+		{"--format", "pkcs8"},
+		{"--no-such-flag"},
+		{"stray"},
+		{"--count", "0"},
+		{"--count", "2", "--out", "/tmp/k.json"},
+	} {
+		if _, err := parseFlags(args); err == nil {
+			t.Errorf("parseFlags(%q) succeeded, want error", args)
+		}
+	}
+}
+
+func TestReadPasswordPrecedence(t *testing.T) {
+	t.Setenv("KEY_PASSWORD", "from-env")
+
+	got, err := readPassword("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "from-env" {
+		t.Fatalf("password = %q, want env value", got)
+	}
+
+	file := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(file, []byte("from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	got, err = readPassword(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "from-file" {
+		t.Fatalf("password = %q, want file value with newline trimmed", got)
+	}
+
+	t.Setenv("KEY_PASSWORD", "")
+	scriptedTerminal(t, false)
+	if _, err := readPassword(""); err == nil {
+		t.Fatal("expected an error when no password is configured")
+	}
+	if _, err := readPassword(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Fatal("expected an error for a missing password file")
+	}
+}
+
+func TestReadPasswordEnvFile(t *testing.T) {
+	t.Setenv("KEY_PASSWORD", "from-env")
+	file := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(file, []byte("  from-secret \n\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("KEY_PASSWORD_FILE", file)
+
+	// KEY_PASSWORD_FILE wins over KEY_PASSWORD, with whitespace trimmed.
+	got, err := readPassword("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "from-secret" {
+		t.Fatalf("password = %q, want the trimmed KEY_PASSWORD_FILE contents", got)
+	}
+	var src passwordSource
+	src.source = passwordSourceEnv
+	if got, err := src.read(); err != nil || got != "from-secret" {
+		t.Fatalf("--password-source env: got %q, %v", got, err)
+	}
+
+	// --password-file still beats the environment.
+	flagFile := filepath.Join(t.TempDir(), "flag-password")
+	if err := os.WriteFile(flagFile, []byte("from-flag\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := readPassword(flagFile); err != nil || got != "from-flag" {
+		t.Fatalf("--password-file: got %q, %v", got, err)
+	}
+
+	// A KEY_PASSWORD_FILE that cannot be used is an error, not a fallback
+	// to KEY_PASSWORD.
+	if err := os.WriteFile(file, []byte(" \n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readPassword(""); err == nil || !strings.Contains(err.Error(), "empty") {
+		t.Fatalf("empty file: got %v, want an error", err)
+	}
+	t.Setenv("KEY_PASSWORD_FILE", filepath.Join(t.TempDir(), "missing"))
+	if _, err := readPassword(""); err == nil {
+		t.Fatal("missing file: want an error")
+	}
+}
+
+func TestRunSignKeyPasswordFile(t *testing.T) {
+	_, path := writeTestKey(t)
+	t.Setenv("KEY_PASSWORD", "not the password")
+	file := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(file, []byte(testPassword+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("KEY_PASSWORD_FILE", file)
+
+	if err := runSign([]string{"--key", path, "--message", "0x01"}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	cfg, err := parseFlags(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.policy.check("secure_password"); !errors.Is(err, blskeys.ErrWeakPassword) {
+		t.Fatalf("default policy: got %v, want ErrWeakPassword", err)
+	}
+
+	cfg, err = parseFlags([]string{"--allow-weak-password"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.policy.check("secure_password"); err != nil {
+		t.Fatalf("--allow-weak-password: got %v, want nil", err)
+	}
+
+	cfg, err = parseFlags([]string{"--min-password-length", "40"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.policy.check(testPassword); !errors.Is(err, blskeys.ErrWeakPassword) {
+		t.Fatalf("--min-password-length 40: got %v, want ErrWeakPassword", err)
+	}
+}
+
+func TestKDFFlags(t *testing.T) {
+	t.Setenv("KEY_PASSWORD", testPassword)
+	dir := t.TempDir()
+
+	for _, args := range [][]string{
+		{"--kdf", "scrypt", "--scrypt-n", "2048", "--scrypt-r", "4", "--allow-weak-kdf"},
+		{"--kdf", "pbkdf2", "--pbkdf2-iterations", "2000"},
+	} {
+		out := filepath.Join(dir, args[1]+".json")
+		if err := runGenerate(append([]string{"--out", out}, args...), &bytes.Buffer{}); err != nil {
+			t.Fatalf("%v: %v", args, err)
+		}
+		if _, err := blskeys.Load(out, testPassword); err != nil {
+			t.Fatalf("%v: %v", args, err)
+		}
+	}
+}
+
+func TestKDFFlagsRejectWeak(t *testing.T) {
+	for _, args := range [][]string{
+		{"--scrypt-n", "512"},
+		{"--scrypt-r", "1"},
+		{"--kdf", "pbkdf2", "--pbkdf2-iterations", "10"},
+	} {
+		if _, err := parseFlags(args); !errors.Is(err, blskeys.ErrWeakKDF) {
+			t.Errorf("%v: got %v, want ErrWeakKDF", args, err)
+		}
+		if _, err := parseFlags(append(args, "--allow-weak-kdf")); err != nil {
+			t.Errorf("%v --allow-weak-kdf: %v", args, err)
+		}
+	}
+	for _, args := range [][]string{
+		{"--kdf", "argon2"},
+		{"--scrypt-n", "1000"},
+		{"--kdf", "pbkdf2", "--pbkdf2-iterations", "0"},
+	} {
+		if _, err := parseFlags(args); err == nil {
+			t.Errorf("%v: expected an error", args)
+		}
+	}
+}
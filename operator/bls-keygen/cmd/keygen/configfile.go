@@ -0,0 +1,258 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configFileKeys maps the top-level keys of a --config file to the flags
+// they set; the kdf section maps onto the KDF flags.
+var configFileKeys = map[string]string{
+	"keypath":      "out",
+	"keydir":       "keydir",
+	"log_level":    "log-level",
+	"log_format":   "log-format",
+	"metrics_addr": "metrics-addr",
+}
+
+// envPrefix starts the name of every environment variable backing a flag.
+const envPrefix = "BASTION_"
+
+// envName is the environment variable backing flag name: --log-level is
+// BASTION_LOG_LEVEL.
+func envName(flag string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
+
+// envValues returns the environment values of the flags of fs, keyed by
+// flag name.
+func envValues(fs *flag.FlagSet) map[string]string {
+	values := map[string]string{}
+	fs.VisitAll(func(f *flag.Flag) {
+		if v, ok := os.LookupEnv(envName(f.Name)); ok {
+			values[f.Name] = v
+		}
+	})
+	return values
+}
+
+// applyEnv fills every flag of fs not given on the command line from its
+// BASTION_ environment variable. It must run after fs.Parse.
+func applyEnv(fs *flag.FlagSet) error {
+	return setUnset(fs, envValues(fs), nil)
+}
+
+// flagProbe, when set, receives the flag set of a command instead of
+// parseArgs parsing it; knownEnvNames uses it to list every command's flags.
+var flagProbe func(fs *flag.FlagSet)
+
+// probeCommands are the commands knownEnvNames asks for their flags. It is
+// set in init because commands itself reaches parseArgs.
+var probeCommands map[string]func(args []string, stdout io.Writer) error
+
+func init() { probeCommands = commands }
+
+// knownEnvNames returns the environment variable of every flag of every
+// command.
+func knownEnvNames() map[string]bool {
+	known := map[string]bool{}
+	flagProbe = func(fs *flag.FlagSet) {
+		fs.VisitAll(func(f *flag.Flag) { known[envName(f.Name)] = true })
+	}
+	defer func() { flagProbe = nil }()
+	for _, cmd := range probeCommands {
+		cmd(nil, io.Discard)
+	}
+	return known
+}
+
+// unknownEnvWarnings names the BASTION_ variables that match no flag of any
+// command, which are most likely typos. Variables for another command's
+// flags are not reported, so one environment can serve every command.
+func unknownEnvWarnings() []string {
+	var names []string
+	for _, kv := range os.Environ() {
+		if name, _, _ := strings.Cut(kv, "="); strings.HasPrefix(name, envPrefix) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	known := knownEnvNames()
+	sort.Strings(names)
+	var warnings []string
+	for _, name := range names {
+		if !known[name] {
+			warnings = append(warnings, fmt.Sprintf("unknown environment variable %s matches no flag, ignoring it", name))
+		}
+	}
+	return warnings
+}
+
+// setUnset sets every flag of fs named in values that was not given on the
+// command line. source names where a value came from in errors; nil means
+// the environment.
+func setUnset(fs *flag.FlagSet, values map[string]string, source map[string]string) error {
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if set[name] {
+			continue
+		}
+		if err := fs.Set(name, values[name]); err != nil {
+			from, ok := source[name]
+			if !ok {
+				from = envName(name)
+			}
+			return usageErrorf("invalid %s: %w", from, err)
+		}
+	}
+	return nil
+}
+
+// configFile holds the --config flag and what was read from it. Values
+// apply with the precedence flag > config file > environment > default.
+type configFile struct {
+	path     string
+	warnings []string
+}
+
+// fileConfig is the layout of a --config file. YAML is a superset of JSON,
+// so both are accepted.
+type fileConfig struct {
+	KeyPath     string `yaml:"keypath"`
+	KeyDir      string `yaml:"keydir"`
+	LogLevel    string `yaml:"log_level"`
+	LogFormat   string `yaml:"log_format"`
+	MetricsAddr string `yaml:"metrics_addr"`
+	KDF         struct {
+		Function   string `yaml:"function"`
+		N          int    `yaml:"n"`
+		R          int    `yaml:"r"`
+		P          int    `yaml:"p"`
+		Iterations int    `yaml:"iterations"`
+	} `yaml:"kdf"`
+}
+
+func (c *configFile) register(fs *flag.FlagSet) {
+	fs.StringVar(&c.path, "config", "", "read defaults from this YAML or JSON file; flags override it")
+}
+
+// apply fills every flag of fs not given on the command line from the
+// config file, then from the environment. It must run after fs.Parse, in
+// place of applyEnv. --config itself falls back to BASTION_CONFIG.
+func (c *configFile) apply(fs *flag.FlagSet) error {
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	if !set["config"] {
+		c.path = os.Getenv(envName("config"))
+	}
+
+	values := map[string]string{}
+	source := map[string]string{}
+	if c.path != "" {
+		if err := c.load(values); err != nil {
+			return err
+		}
+		names := make([]string, 0, len(values))
+		for name := range values {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if fs.Lookup(name) == nil {
+				c.warnings = append(c.warnings, fmt.Sprintf("%s does not apply to this command, ignoring it", name))
+				delete(values, name)
+				continue
+			}
+			source[name] = name
+		}
+	}
+	for name, v := range envValues(fs) {
+		if _, ok := values[name]; !ok {
+			values[name] = v
+		}
+	}
+	c.warnings = append(c.warnings, unknownEnvWarnings()...)
+	return setUnset(fs, values, source)
+}
+
+// load reads the config file into values, keyed by flag name, and records
+// a warning for every key it does not know.
+func (c *configFile) load(values map[string]string) error {
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	var raw map[string]yaml.Node
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", c.path, err)
+	}
+	var fc fileConfig
+	if err := yaml.Unmarshal(data, &fc); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", c.path, err)
+	}
+
+	keys := make([]string, 0, len(raw))
+	for key := range raw {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if _, ok := configFileKeys[key]; !ok && key != "kdf" {
+			c.warnings = append(c.warnings, fmt.Sprintf("unknown config file key %q", key))
+		}
+	}
+
+	for key, v := range map[string]string{
+		"keypath":      fc.KeyPath,
+		"keydir":       fc.KeyDir,
+		"log_level":    fc.LogLevel,
+		"log_format":   fc.LogFormat,
+		"metrics_addr": fc.MetricsAddr,
+	} {
+		if v != "" {
+			values[configFileKeys[key]] = v
+		}
+	}
+	if fc.KDF.Function != "" {
+		values["kdf"] = fc.KDF.Function
+	}
+	for name, v := range map[string]int{
+		"scrypt-n":          fc.KDF.N,
+		"scrypt-r":          fc.KDF.R,
+		"scrypt-p":          fc.KDF.P,
+		"pbkdf2-iterations": fc.KDF.Iterations,
+	} {
+		if v != 0 {
+			values[name] = strconv.Itoa(v)
+		}
+	}
+	return nil
+}
+
+// warn logs what apply skipped. It runs once the logger is configured.
+func (c *configFile) warn() {
+	for _, w := range c.warnings {
+		if c.path == "" {
+			slog.Warn(w)
+		} else {
+			slog.Warn(w, "config", c.path)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+func writeConfigFile(t *testing.T, name, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestConfigPrecedence(t *testing.T) {
+	yamlPath := writeConfigFile(t, "keygen.yaml", "keydir: /from/config\nlog_level: warn\n")
+
+	tests := []struct {
+		name    string
+		env     string
+		args    []string
+		wantDir string
+	}{
+		{"default", "", nil, defaultKeyDir},
+		{"env", "/from/env", nil, "/from/env"},
+		{"config over env", "/from/env", []string{"--config", yamlPath}, "/from/config"},
+		{"flag over config", "/from/env", []string{"--config", yamlPath, "--keydir", "/from/flag"}, "/from/flag"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.env != "" {
+				t.Setenv("BASTION_KEYDIR", tt.env)
+			}
+			cfg, err := parseFlags(tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.keyDir != tt.wantDir {
+				t.Fatalf("keydir = %s, want %s", cfg.keyDir, tt.wantDir)
+			}
+		})
+	}
+}
+
+func TestConfigFileJSON(t *testing.T) {
+	path := writeConfigFile(t, "keygen.json", `{"keypath": "/k/key.json", "log_format": "json", "kdf": {"n": 2048}}`)
+	cfg, err := parseFlags([]string{"--config", path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.out != "/k/key.json" || cfg.keyDir != "/k" || cfg.log.format != "json" {
+		t.Fatalf("config not applied: out=%s keydir=%s format=%s", cfg.out, cfg.keyDir, cfg.log.format)
+	}
+	if cfg.kdfParams.N != 2048 || cfg.kdfParams.R != blskeys.DefaultScryptParams.R {
+		t.Fatalf("kdf = %+v", cfg.kdfParams)
+	}
+}
+
+func TestConfigFileKDFUsed(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "bls_key.json")
+	path := writeConfigFile(t, "keygen.yaml", "kdf:\n  n: 2048\n")
+	t.Setenv("KEY_PASSWORD", testPassword)
+
+	if err := runGenerate([]string{"--config", path, "--out", out}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kf blskeys.KeyFile
+	if err := json.Unmarshal(data, &kf); err != nil {
+		t.Fatal(err)
+	}
+	if kf.Crypto.KDFParams.N != 2048 {
+		t.Fatalf("key file scrypt n = %d, want 2048", kf.Crypto.KDFParams.N)
+	}
+	if _, err := blskeys.Load(out, testPassword); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConfigFileUnknownKeys(t *testing.T) {
+	logs := captureLogs(t)
+	path := writeConfigFile(t, "keygen.yaml", "keydir: "+t.TempDir()+"\nkey_dir: /typo\nmetrics_addr: :9100\n")
+	t.Setenv("KEY_PASSWORD", testPassword)
+
+	if err := runGenerate([]string{"--config", path, "--dry-run"}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`unknown config file key \"key_dir\"`, "metrics-addr does not apply"} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("logs missing %q:\n%s", want, logs)
+		}
+	}
+}
+
+func TestConfigFileErrors(t *testing.T) {
+	for name, data := range map[string]string{
+		"malformed":    "keydir: [",
+		"bad kdf":      "kdf:\n  n: 1000\n",
+		"bad loglevel": "log_level: loud\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := writeConfigFile(t, "keygen.yaml", data)
+			cfg, err := parseFlags([]string{"--config", path})
+			if err == nil {
+				err = cfg.log.apply()
+			}
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+	if _, err := parseFlags([]string{"--config", filepath.Join(t.TempDir(), "missing.yaml")}); err == nil {
+		t.Fatal("expected an error for a missing config file")
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	for flag, want := range map[string]string{
+		"out":               "BASTION_OUT",
+		"log-level":         "BASTION_LOG_LEVEL",
+		"kdf":               "BASTION_KDF",
+		"pbkdf2-iterations": "BASTION_PBKDF2_ITERATIONS",
+	} {
+		if got := envName(flag); got != want {
+			t.Errorf("envName(%q) = %s, want %s", flag, got, want)
+		}
+	}
+}
+
+func TestEnvSetsFlags(t *testing.T) {
+	t.Setenv("BASTION_KDF", "pbkdf2")
+	t.Setenv("BASTION_PBKDF2_ITERATIONS", "300000")
+	t.Setenv("BASTION_FORCE", "true")
+	t.Setenv("BASTION_COUNT", "2")
+	cfg, err := parseFlags([]string{"--keydir", t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.kdf.name != "pbkdf2" || cfg.kdfParams.C != 300000 {
+		t.Fatalf("kdf = %+v, want pbkdf2 with 300000 iterations", cfg.kdfParams)
+	}
+	if !cfg.force || cfg.count != 2 {
+		t.Fatalf("force = %v, count = %d; want true, 2", cfg.force, cfg.count)
+	}
+
+	// Subcommands read the environment too.
+	kp, path := writeTestKey(t)
+	t.Setenv("KEY_PASSWORD", testPassword)
+	t.Setenv("BASTION_KEY", path)
+	t.Setenv("BASTION_ENCODING", encodingUncompressed)
+	var out bytes.Buffer
+	if err := runSign([]string{"--message", "0x01"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := decodeHex(strings.TrimSpace(out.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := bls.ParseSignatureUncompressed(raw)
+	if err != nil {
+		t.Fatalf("BASTION_ENCODING not applied: %v", err)
+	}
+	if !bls.Verify(kp.G2PubKey, keccak256([]byte{0x01}), sig) {
+		t.Fatal("signature does not verify")
+	}
+}
+
+func TestFlagOverridesEnv(t *testing.T) {
+	t.Setenv("BASTION_KDF", "pbkdf2")
+	t.Setenv("BASTION_OUTPUT", "json")
+	cfg, err := parseFlags([]string{"--kdf", "scrypt", "--output", "text"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.kdf.name != "scrypt" || cfg.output != outputText {
+		t.Fatalf("kdf = %s, output = %s; flags should win over the environment", cfg.kdf.name, cfg.output)
+	}
+
+	t.Setenv("BASTION_COUNT", "many")
+	if _, err := parseFlags(nil); exitCode(err) != exitUsage || !strings.Contains(err.Error(), "BASTION_COUNT") {
+		t.Fatalf("bad env value: got %v, want a usage error naming BASTION_COUNT", err)
+	}
+}
+
+func TestConfigFileFromEnv(t *testing.T) {
+	path := writeConfigFile(t, "keygen.yaml", "keydir: /from/config\n")
+	t.Setenv("BASTION_CONFIG", path)
+	cfg, err := parseFlags(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.keyDir != "/from/config" {
+		t.Fatalf("keydir = %s, want /from/config", cfg.keyDir)
+	}
+}
+
+func TestUnknownEnvWarning(t *testing.T) {
+	logs := captureLogs(t)
+	t.Setenv("KEY_PASSWORD", testPassword)
+	t.Setenv("BASTION_LOG_LEVL", "debug")
+	// A flag of another command is not a typo.
+	t.Setenv("BASTION_MESSAGES_FILE", "messages.jsonl")
+
+	if err := runGenerate([]string{"--keydir", t.TempDir(), "--dry-run"}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(logs.String(), "BASTION_LOG_LEVL") {
+		t.Errorf("no warning for BASTION_LOG_LEVL:\n%s", logs)
+	}
+	if strings.Contains(logs.String(), "BASTION_MESSAGES_FILE") {
+		t.Errorf("warned about another command's flag:\n%s", logs)
+	}
+}
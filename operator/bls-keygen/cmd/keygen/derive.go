@@ -0,0 +1,114 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+// runDerive implements `keygen derive`: it derives a key from a BIP-39
+// mnemonic along an EIP-2334 path and writes it as an encrypted key file.
+// With --avs-id the path gets one more component for that AVS.
+func runDerive(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("derive", flag.ContinueOnError)
+	mnemonicFile := fs.String("mnemonic-file", "", "file holding the BIP-39 mnemonic")
+	path := fs.String("path", blskeys.DefaultDerivationPath, "EIP-2334 derivation path")
+	var avs avsOption
+	avs.register(fs)
+	out := fs.String("out", filepath.Join(defaultKeyDir, defaultKeyFile), "path of the key file to write")
+	var pwSource passwordSource
+	pwSource.register(fs)
+	force := fs.Bool("force", false, "replace an existing key, backing it up first")
+	var policy passwordPolicy
+	policy.register(fs)
+	var perms permCheck
+	perms.register(fs)
+	var kdf kdfOptions
+	kdf.register(fs)
+	var network networkOptions
+	network.register(fs)
+	var label labelOption
+	label.register(fs)
+	var logOpts logOptions
+	logOpts.register(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if err := perms.validate(); err != nil {
+		return err
+	}
+	if err := logOpts.apply(); err != nil {
+		return err
+	}
+	resolved, err := perms.keyPath(*out)
+	if err != nil {
+		return err
+	}
+	*out = resolved
+
+	if *mnemonicFile == "" {
+		return usageErrorf("--mnemonic-file is required")
+	}
+	if err := avs.validate(); err != nil {
+		return err
+	}
+	if err := label.validate(); err != nil {
+		return err
+	}
+	*path = avs.path(*path)
+	params, err := kdf.params()
+	if err != nil {
+		return err
+	}
+	if _, err := network.domain(); err != nil {
+		return err
+	}
+
+	mnemonic, err := os.ReadFile(*mnemonicFile)
+	if err != nil {
+		return fmt.Errorf("failed to read mnemonic file: %w", err)
+	}
+	defer bls.SecretBytes(mnemonic).Zero()
+	password, err := pwSource.readNew()
+	if err != nil {
+		return err
+	}
+	if err := policy.check(password); err != nil {
+		return err
+	}
+
+	if err := checkNewKeyPath(*out, *force); err != nil {
+		return err
+	}
+
+	kp, err := blskeys.DeriveFromMnemonic(string(mnemonic), *path)
+	if err != nil {
+		return err
+	}
+	defer kp.PrivateKey.Zero()
+
+	if err := perms.ensureDir(filepath.Dir(*out)); err != nil {
+		return err
+	}
+	meta := network.metadata()
+	meta.DerivationPath, meta.AVSID, meta.Label = *path, avs.id, label.text
+	data, err := blskeys.MarshalContext(cmdContext, kp, password, params, meta)
+	if err != nil {
+		return err
+	}
+	if _, err := writeKeyFile(*out, data, *force); err != nil {
+		return err
+	}
+	if err := perms.check(*out); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "Derived key %s saved to %s\n", *path, *out)
+	fmt.Fprintf(stdout, "G1 public key: 0x%x\n", kp.G1PubKey.Bytes())
+	return nil
+}
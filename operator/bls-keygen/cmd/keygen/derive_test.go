@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+const testMnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+func TestRunDerive(t *testing.T) {
+	dir := t.TempDir()
+	mnemonicFile := filepath.Join(dir, "mnemonic")
+	if err := os.WriteFile(mnemonicFile, []byte(testMnemonic+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "keys", "bls_key.json")
+	t.Setenv("KEY_PASSWORD", testPassword)
+
+	if err := runDerive([]string{"--mnemonic-file", mnemonicFile, "--path", "m/12381/3600/2/0", "--out", out}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := blskeys.Load(out, testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := blskeys.DeriveFromMnemonic(testMnemonic, "m/12381/3600/2/0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(loaded.PrivateKey.Bytes(), want.PrivateKey.Bytes()) {
+		t.Fatal("derived key file does not match the mnemonic")
+	}
+}
+
+func TestRunDeriveWeakPassword(t *testing.T) {
+	dir := t.TempDir()
+	mnemonicFile := filepath.Join(dir, "mnemonic")
+	if err := os.WriteFile(mnemonicFile, []byte(testMnemonic), 0600); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "bls_key.json")
+	t.Setenv("KEY_PASSWORD", "short")
+
+	if err := runDerive([]string{"--mnemonic-file", mnemonicFile, "--out", out}, &bytes.Buffer{}); !errors.Is(err, blskeys.ErrWeakPassword) {
+		t.Fatalf("got %v, want ErrWeakPassword", err)
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Fatal("key file written despite weak password")
+	}
+
+	if err := runDerive([]string{"--mnemonic-file", mnemonicFile, "--out", out, "--allow-weak-password"}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunDeriveExistingKey(t *testing.T) {
+	dir := t.TempDir()
+	mnemonicFile := filepath.Join(dir, "mnemonic")
+	if err := os.WriteFile(mnemonicFile, []byte(testMnemonic), 0600); err != nil {
+		t.Fatal(err)
+	}
+	existing, out := writeTestKey(t)
+	t.Setenv("KEY_PASSWORD", testPassword)
+
+	if err := runDerive([]string{"--mnemonic-file", mnemonicFile, "--out", out}, &bytes.Buffer{}); !errors.Is(err, errKeyExists) {
+		t.Fatalf("got %v, want errKeyExists", err)
+	}
+	loaded, err := blskeys.Load(out, testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(loaded.PrivateKey.Bytes(), existing.PrivateKey.Bytes()) {
+		t.Fatal("existing key overwritten without --force")
+	}
+
+	if err := runDerive([]string{"--mnemonic-file", mnemonicFile, "--out", out, "--force"}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	backups, err := filepath.Glob(out + ".*.bak")
+	if err != nil || len(backups) != 1 {
+		t.Fatalf("expected one backup of the replaced key, got %v %v", backups, err)
+	}
+}
+
+func TestRunDeriveAVSID(t *testing.T) {
+	dir := t.TempDir()
+	mnemonicFile := filepath.Join(dir, "mnemonic")
+	if err := os.WriteFile(mnemonicFile, []byte(testMnemonic), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("KEY_PASSWORD", testPassword)
+	derive := func(name string, extra ...string) string {
+		t.Helper()
+		out := filepath.Join(dir, name+".json")
+		args := append([]string{"--mnemonic-file", mnemonicFile, "--out", out}, extra...)
+		if err := runDerive(args, &bytes.Buffer{}); err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+	pubkey := func(path string) []byte {
+		t.Helper()
+		pk, err := blskeys.LoadPublicKey(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pk.Bytes()
+	}
+
+	a := derive("a", "--avs-id", "avs-a")
+	again := derive("a-again", "--avs-id", "avs-a")
+	b := derive("b", "--avs-id", "avs-b")
+	plain := derive("plain")
+	if !bytes.Equal(pubkey(a), pubkey(again)) {
+		t.Fatal("the same --avs-id derived different keys")
+	}
+	if bytes.Equal(pubkey(a), pubkey(b)) {
+		t.Fatal("two --avs-id values derived the same key")
+	}
+	if bytes.Equal(pubkey(a), pubkey(plain)) {
+		t.Fatal("--avs-id derived the key without it")
+	}
+
+	meta, err := blskeys.LoadMetadata(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := blskeys.AVSPath(blskeys.DefaultDerivationPath, "avs-a"); meta.DerivationPath != want || meta.AVSID != "avs-a" {
+		t.Errorf("metadata records %s for %q, want %s for avs-a", meta.DerivationPath, meta.AVSID, want)
+	}
+
+	// verify-mnemonic needs the same --avs-id to match.
+	if err := runVerifyMnemonic([]string{"--key", a, "--mnemonic-file", mnemonicFile, "--avs-id", "avs-a"}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := runVerifyMnemonic([]string{"--key", a, "--mnemonic-file", mnemonicFile}, &bytes.Buffer{}); !errors.Is(err, errMnemonicMismatch) {
+		t.Fatalf("without --avs-id: got %v, want errMnemonicMismatch", err)
+	}
+
+	err = runDerive([]string{"--mnemonic-file", mnemonicFile, "--out", filepath.Join(dir, "space.json"), "--avs-id", "avs-a "}, &bytes.Buffer{})
+	if exitCode(err) != exitUsage {
+		t.Fatalf("--avs-id with trailing space: got %v, want a usage error", err)
+	}
+}
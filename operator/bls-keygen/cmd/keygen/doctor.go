@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+// errDoctorFailed is returned by runDoctor when any check fails.
+var errDoctorFailed = errors.New("key file failed one or more checks")
+
+// eip2335FileVersion is the "version" field of an EIP-2335 keystore.
+const eip2335FileVersion = 4
+
+const (
+	checkPass = "PASS"
+	checkWarn = "WARN"
+	checkFail = "FAIL"
+)
+
+// doctorReport collects the outcome of each check runDoctor makes.
+type doctorReport struct {
+	w                  io.Writer
+	pass, warn, failed int
+}
+
+func (r *doctorReport) add(status, check, detail string) {
+	switch status {
+	case checkPass:
+		r.pass++
+	case checkWarn:
+		r.warn++
+	default:
+		r.failed++
+	}
+	fmt.Fprintf(r.w, "%s  %-12s %s\n", status, check, detail)
+}
+
+// runDoctor implements `keygen doctor`: it runs the curve self-test, then
+// checks that a key file parses, is a supported version, has safe
+// permissions, decrypts with the password and holds a consistent key pair,
+// and prints the operator ID. Every check
+// runs even after a failure so one report covers everything.
+func runDoctor(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	keyPath := fs.String("key", filepath.Join(defaultKeyDir, defaultKeyFile), "key file to check")
+	var pwSource passwordSource
+	pwSource.register(fs)
+	fileMode, dirMode := octalMode(blskeys.KeyFileMode), octalMode(blskeys.KeyDirMode)
+	fs.Var(&fileMode, "keyfile-mode", "octal mode the key file is expected to have, as given to generate")
+	fs.Var(&dirMode, "keydir-mode", "octal mode the key directory is expected to allow, as given to generate")
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	r := &doctorReport{w: stdout}
+	defer func() {
+		fmt.Fprintf(stdout, "\n%d passed, %d warnings, %d failed\n", r.pass, r.warn, r.failed)
+	}()
+
+	if err := selfTest(); err != nil {
+		r.add(checkFail, "self-test", err.Error())
+	} else {
+		r.add(checkPass, "self-test", "sign, verify and aggregate work")
+	}
+
+	data, err := os.ReadFile(*keyPath)
+	if err != nil {
+		r.add(checkFail, "parse", err.Error())
+		return errDoctorFailed
+	}
+	var header struct {
+		Version int `json:"version"`
+		Crypto  struct {
+			Ciphertext string `json:"ciphertext"`
+		} `json:"crypto"`
+	}
+	if blskeys.IsBinaryKeyFile(data) {
+		kf, err := blskeys.UnmarshalBinaryKeyFile(data)
+		if err != nil {
+			r.add(checkFail, "parse", err.Error())
+			return errDoctorFailed
+		}
+		header.Version = kf.Version
+		r.add(checkPass, "parse", *keyPath+" (binary)")
+	} else if blskeys.IsPEMKeyFile(data) {
+		kf, err := blskeys.UnmarshalPEMKeyFile(data)
+		if err != nil {
+			r.add(checkFail, "parse", err.Error())
+			return errDoctorFailed
+		}
+		header.Version = kf.Version
+		r.add(checkPass, "parse", *keyPath+" (pem)")
+	} else if err := json.Unmarshal(data, &header); err != nil {
+		r.add(checkFail, "parse", fmt.Sprintf("not a JSON key file: %v", err))
+		return errDoctorFailed
+	} else {
+		r.add(checkPass, "parse", *keyPath)
+	}
+
+	switch {
+	case header.Version == blskeys.CurrentVersion:
+		r.add(checkPass, "version", fmt.Sprintf("%d (current)", header.Version))
+	case header.Version == eip2335FileVersion:
+		r.add(checkPass, "version", "EIP-2335 keystore")
+	case header.Version == 0 && header.Crypto.Ciphertext != "":
+		r.add(checkWarn, "version", "0, encrypted but unversioned (run: keygen migrate)")
+	case header.Version == 0 && blskeys.IsLegacyPlaceholder(data):
+		r.add(checkFail, "version", "0, legacy ECDSA PLACEHOLDER: not a BLS key, it cannot sign AVS responses and must be regenerated (run: keygen generate --replace-placeholder)")
+		return errDoctorFailed
+	case header.Version == 0:
+		r.add(checkWarn, "version", "0, PLAINTEXT private key (run: keygen migrate)")
+	default:
+		r.add(checkFail, "version", fmt.Sprintf("unsupported version %d", header.Version))
+		return errDoctorFailed
+	}
+
+	if meta, err := blskeys.LoadMetadata(*keyPath); err == nil && meta != nil && (meta.CreatedAt != "" || meta.GeneratorVersion != "") {
+		if _, err := meta.Created(); err != nil {
+			r.add(checkWarn, "provenance", err.Error())
+		} else {
+			r.add(checkPass, "provenance", provenance(meta))
+		}
+	}
+
+	if err := blskeys.CheckPermissionsMode(*keyPath, os.FileMode(fileMode), os.FileMode(dirMode)); err != nil {
+		r.add(checkFail, "permissions", err.Error())
+	} else {
+		r.add(checkPass, "permissions", fileMode.String())
+	}
+
+	var kp *blskeys.KeyPair
+	password, err := pwSource.read()
+	if err == nil {
+		kp, err = loadKey(*keyPath, password)
+	}
+	switch {
+	case err == nil:
+		defer kp.PrivateKey.Zero()
+		r.add(checkPass, "decrypt", "password accepted")
+		if header.Version == 0 && header.Crypto.Ciphertext == "" {
+			r.add(checkWarn, "key pair", "legacy file, stored public keys are not BLS keys and were ignored")
+		} else {
+			r.add(checkPass, "key pair", "stored public keys match the private key")
+		}
+		r.add(checkPass, "operator id", fmt.Sprintf("0x%x", bls.OperatorID(kp.G1PubKey)))
+	case errors.Is(err, blskeys.ErrPubPrivMismatch), errors.Is(err, blskeys.ErrNotInSubgroup):
+		r.add(checkPass, "decrypt", "password accepted")
+		r.add(checkFail, "key pair", err.Error())
+	case errors.Is(err, blskeys.ErrDecrypt):
+		r.add(checkFail, "decrypt", "wrong password or corrupted ciphertext")
+	default:
+		r.add(checkFail, "decrypt", err.Error())
+	}
+
+	if r.failed > 0 {
+		return errDoctorFailed
+	}
+	return nil
+}
+
+// provenance describes when and by which build a key was created, from
+// whichever of the two its metadata records.
+func provenance(meta *blskeys.Metadata) string {
+	created, generator := meta.CreatedAt, meta.GeneratorVersion
+	if created == "" {
+		created = "at an unrecorded time"
+	} else {
+		created = "at " + created
+	}
+	if generator == "" {
+		generator = "an unrecorded version"
+	}
+	return fmt.Sprintf("created %s by %s", created, generator)
+}
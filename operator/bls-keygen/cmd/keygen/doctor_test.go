@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+func TestRunDoctorHealthy(t *testing.T) {
+	kp, path := writeTestKey(t)
+	t.Setenv("KEY_PASSWORD", testPassword)
+
+	var out bytes.Buffer
+	if err := runDoctor([]string{"--key", path}, &out); err != nil {
+		t.Fatalf("%v\n%s", err, out.String())
+	}
+	for _, want := range []string{
+		"PASS  self-test",
+		"PASS  parse",
+		"PASS  version",
+		"PASS  permissions",
+		"PASS  decrypt",
+		"PASS  key pair",
+		fmt.Sprintf("0x%x", bls.OperatorID(kp.G1PubKey)),
+		"7 passed, 0 warnings, 0 failed",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("report missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestRunDoctorWrongPassword(t *testing.T) {
+	_, path := writeTestKey(t)
+	t.Setenv("KEY_PASSWORD", "not the password")
+
+	var out bytes.Buffer
+	if err := runDoctor([]string{"--key", path}, &out); !errors.Is(err, errDoctorFailed) {
+		t.Fatalf("got %v, want errDoctorFailed", err)
+	}
+	if !strings.Contains(out.String(), "FAIL  decrypt") || !strings.Contains(out.String(), "PASS  permissions") {
+		t.Fatalf("unexpected report:\n%s", out.String())
+	}
+}
+
+func TestRunDoctorCorrupted(t *testing.T) {
+	_, path := writeTestKey(t)
+	t.Setenv("KEY_PASSWORD", testPassword)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Truncated JSON fails to parse; a flipped ciphertext byte fails the
+	// checksum.
+	if err := os.WriteFile(path, data[:len(data)/2], 0600); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := runDoctor([]string{"--key", path}, &out); !errors.Is(err, errDoctorFailed) {
+		t.Fatalf("got %v, want errDoctorFailed", err)
+	}
+	if !strings.Contains(out.String(), "FAIL  parse") {
+		t.Fatalf("unexpected report:\n%s", out.String())
+	}
+
+	i := bytes.Index(data, []byte(`"ciphertext": "`)) + len(`"ciphertext": "`)
+	if data[i] == '0' {
+		data[i] = '1'
+	} else {
+		data[i] = '0'
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if err := runDoctor([]string{"--key", path}, &out); !errors.Is(err, errDoctorFailed) {
+		t.Fatalf("got %v, want errDoctorFailed", err)
+	}
+	if !strings.Contains(out.String(), "FAIL  decrypt") || !strings.Contains(out.String(), "corrupt") {
+		t.Fatalf("unexpected report:\n%s", out.String())
+	}
+}
+
+func TestRunDoctorUnsafePermissions(t *testing.T) {
+	_, path := writeTestKey(t)
+	t.Setenv("KEY_PASSWORD", testPassword)
+	if err := os.Chmod(path, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := runDoctor([]string{"--key", path}, &out); !errors.Is(err, errDoctorFailed) {
+		t.Fatalf("got %v, want errDoctorFailed", err)
+	}
+	if !strings.Contains(out.String(), "FAIL  permissions") || !strings.Contains(out.String(), "PASS  decrypt") {
+		t.Fatalf("unexpected report:\n%s", out.String())
+	}
+}
+
+func TestRunDoctorShowsProvenance(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bls_key.json")
+	t.Setenv("KEY_PASSWORD", testPassword)
+	if err := runGenerate([]string{"--out", path}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	meta, err := blskeys.LoadMetadata(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, run := range []func([]string, io.Writer) error{runDoctor, runInfo} {
+		var out bytes.Buffer
+		if err := run([]string{"--key", path}, &out); err != nil {
+			t.Fatalf("%v\n%s", err, out.String())
+		}
+		for _, want := range []string{meta.CreatedAt, "keygen " + version} {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("output missing %q:\n%s", want, out.String())
+			}
+		}
+	}
+}
+
+// writePlaceholderKey writes a key file as the ECDSA placeholder build did.
+func writePlaceholderKey(t *testing.T, path string) {
+	t.Helper()
+	pub := append([]byte{0x04}, bytes.Repeat([]byte{0xab}, 64)...)
+	data := fmt.Sprintf(`{
+  "private_key": "0x%x",
+  "public_key": "0x%x",
+  "g1_pub_key": "0x%x",
+  "g2_pub_key": "0x%x"
+}`, bytes.Repeat([]byte{0x11}, 32), pub, pub[:32], pub[32:])
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunDoctorLegacyPlaceholder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bls_key.json")
+	writePlaceholderKey(t, path)
+
+	var out bytes.Buffer
+	if err := runDoctor([]string{"--key", path}, &out); !errors.Is(err, errDoctorFailed) {
+		t.Fatalf("got %v, want errDoctorFailed", err)
+	}
+	for _, want := range []string{"FAIL  version", "legacy ECDSA PLACEHOLDER", "not a BLS key", "generate --replace-placeholder"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("report missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestLoadLegacyPlaceholderHint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bls_key.json")
+	writePlaceholderKey(t, path)
+	t.Setenv("KEY_PASSWORD", testPassword)
+
+	err := runSign([]string{"--key", path, "--message", "0x01"}, io.Discard)
+	if !errors.Is(err, blskeys.ErrLegacyPlaceholder) {
+		t.Fatalf("got %v, want ErrLegacyPlaceholder", err)
+	}
+	if code := exitCode(err); code != exitInvalidKey {
+		t.Fatalf("exit code %d, want %d", code, exitInvalidKey)
+	}
+	if hint := errorHint(err, exitInvalidKey); !strings.Contains(hint, "--replace-placeholder") {
+		t.Fatalf("hint %q does not suggest --replace-placeholder", hint)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"crypto/rand"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+// ecdsaOptions holds generate's --with-ecdsa flags. Each BLS key then gets
+// a companion ECDSA operator key in a go-ethereum keystore, and records
+// the ECDSA key's address in its metadata.
+type ecdsaOptions struct {
+	enabled      bool
+	out          string
+	passwordFile string
+}
+
+func (o *ecdsaOptions) register(fs *flag.FlagSet) {
+	fs.BoolVar(&o.enabled, "with-ecdsa", false, "also generate the operator's ECDSA key as a go-ethereum keystore and record its address in the BLS key's metadata")
+	fs.StringVar(&o.out, "ecdsa-out", "", "path of the ECDSA keystore (default: the BLS key path with .ecdsa.json in place of .json)")
+	fs.StringVar(&o.passwordFile, "ecdsa-password-file", "", "read the ECDSA keystore password from this file instead of sharing the BLS key's")
+}
+
+// validate rejects the ECDSA flags where they cannot apply.
+func (o *ecdsaOptions) validate(cfg *config) error {
+	if !o.enabled {
+		if o.out != "" || o.passwordFile != "" {
+			return usageErrorf("--ecdsa-out and --ecdsa-password-file need --with-ecdsa")
+		}
+		return nil
+	}
+	switch {
+	case cfg.format == formatEIP2335:
+		return usageErrorf("--with-ecdsa cannot record the operator address in an EIP-2335 keystore, use --format bastion or binary")
+	case cfg.out == stdoutPath:
+		return usageErrorf("--with-ecdsa cannot be combined with --out -, there would be two keys on stdout")
+	case o.out != "" && cfg.count > 1:
+		return usageErrorf("--ecdsa-out cannot be combined with --count")
+	}
+	return nil
+}
+
+// path returns where the ECDSA keystore of the BLS key at keyPath goes.
+func (o *ecdsaOptions) path(keyPath string) string {
+	if o.out != "" {
+		return o.out
+	}
+	return strings.TrimSuffix(keyPath, ".json") + ".ecdsa.json"
+}
+
+// password returns the ECDSA keystore password: the contents of
+// --ecdsa-password-file, held to the same policy, or else blsPassword.
+func (o *ecdsaOptions) password(blsPassword string, policy *passwordPolicy) (string, error) {
+	if o.passwordFile == "" {
+		return blsPassword, nil
+	}
+	password, err := readPasswordFile(o.passwordFile)
+	if err != nil {
+		return "", err
+	}
+	if err := policy.check(password); err != nil {
+		return "", fmt.Errorf("ECDSA keystore password: %w (use --allow-weak-password to override)", err)
+	}
+	return password, nil
+}
+
+// generateECDSA creates an ECDSA key and writes it to path, returning its
+// address.
+func generateECDSA(cfg *config, path, password string) (blskeys.ECDSAAddress, error) {
+	k, err := blskeys.GenerateECDSA(rand.Reader)
+	if err != nil {
+		return blskeys.ECDSAAddress{}, fmt.Errorf("failed to generate ECDSA key: %w", err)
+	}
+	defer k.Zero()
+	data, err := blskeys.MarshalECDSAContext(cmdContext, k, password, cfg.kdfParams)
+	if err != nil {
+		return blskeys.ECDSAAddress{}, fmt.Errorf("failed to encrypt ECDSA key: %w", err)
+	}
+	if _, err := writeKeyFile(path, data, cfg.force); err != nil {
+		return blskeys.ECDSAAddress{}, fmt.Errorf("failed to save ECDSA key: %w", err)
+	}
+	if err := cfg.perms.check(path); err != nil {
+		os.Remove(path)
+		return blskeys.ECDSAAddress{}, err
+	}
+	slog.Info("ECDSA operator key generated", "path", path, "address", k.Address.Hex())
+	return k.Address, nil
+}
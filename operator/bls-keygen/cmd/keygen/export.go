@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	bn254fr "github.com/consensys/gnark-crypto/ecc/bn254/fr"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+const (
+	exportEigenSDK = "eigensdk"
+	exportFoundry  = "foundry"
+)
+
+// bn254Order is the big-endian scalar field order of BN254, the curve of
+// eigensdk-go's bls package.
+var bn254Order = func() []byte {
+	b := make([]byte, bls.PrivateKeySize)
+	bn254fr.Modulus().FillBytes(b)
+	return b
+}()
+
+// checkEigenSDKScalar rejects scalars eigensdk-go cannot import as is.
+// bls.NewKeyPairFromString parses the scalar with the BN254
+// fr.Element.SetString, which takes 0x-prefixed big-endian hex and silently
+// reduces anything at or above the BN254 order, so such a key would come
+// out as a different scalar.
+func checkEigenSDKScalar(sk []byte) error {
+	if bytes.Compare(sk, bn254Order) >= 0 {
+		return errors.New("private key is not below the BN254 scalar order; eigensdk-go would import a different scalar")
+	}
+	return nil
+}
+
+// foundryPubKeys is the --format foundry export: the key's public
+// coordinates as decimal strings, for a Foundry script to read with
+// vm.parseJsonUint.
+//
+// G2 coordinates are Fp2 elements x = x0 + x1*u and y = y0 + y1*u, and x0
+// and y0 are the real parts. Each part is named rather than put in an
+// array, so a script picks the order its contract needs: the EIP-2537
+// order register-payload's pubkeyG2 uses is real part first, while
+// EigenLayer's BN254 G2Point is imaginary part first.
+type foundryPubKeys struct {
+	G1 struct {
+		X string `json:"x"`
+		Y string `json:"y"`
+	} `json:"g1"`
+	G2 struct {
+		X0 string `json:"x0"`
+		X1 string `json:"x1"`
+		Y0 string `json:"y0"`
+		Y1 string `json:"y1"`
+	} `json:"g2"`
+}
+
+func newFoundryPubKeys(g1 *bls.G1PubKey, g2 *bls.G2PubKey) *foundryPubKeys {
+	var f foundryPubKeys
+	x, y := g1.Coordinates()
+	f.G1.X, f.G1.Y = x.String(), y.String()
+	x0, x1, y0, y1 := g2.Coordinates()
+	f.G2.X0, f.G2.X1, f.G2.Y0, f.G2.Y1 = x0.String(), x1.String(), y0.String(), y1.String()
+	return &f
+}
+
+// exportFoundryPubKeys returns the --format foundry export of the key file
+// at path. The public keys are public, so no password is needed, but the
+// stored G1 and G2 keys must belong together.
+func exportFoundryPubKeys(path string, pin *keyPin) ([]byte, error) {
+	g1, g2, err := blskeys.LoadPublicKeys(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load public keys from %s: %w", path, err)
+	}
+	if g2 == nil {
+		return nil, fmt.Errorf("%s stores no G2 public key; export --format foundry needs a bastion or binary key file", path)
+	}
+	if !bls.PubKeysMatch(g1, g2) {
+		return nil, fmt.Errorf("%w: the stored G1 and G2 public keys of %s do not match", blskeys.ErrInvalidKey, path)
+	}
+	if err := pin.check(path, g1); err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(newFoundryPubKeys(g1, g2), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// runExport implements `keygen export`: it decrypts the key and prints the
+// plaintext private key for import into another tool, or with --format
+// foundry prints the public key coordinates for the deploy script.
+func runExport(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	keyPath := fs.String("key", filepath.Join(defaultKeyDir, defaultKeyFile), "key file to export")
+	var pwSource passwordSource
+	pwSource.register(fs)
+	var pin keyPin
+	pin.register(fs)
+	format := fs.String("format", exportEigenSDK, "export format: eigensdk (BN254 scalar encoding; eigensdk-go derives BN254 public keys from it, not this key's BLS12-381 ones), or foundry for the public key coordinates in decimal as JSON")
+	out := fs.String("out", "", "write the export to this new file instead of stdout")
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if err := pin.validate(); err != nil {
+		return err
+	}
+	switch *format {
+	case exportEigenSDK:
+	case exportFoundry:
+		data, err := exportFoundryPubKeys(*keyPath, &pin)
+		if err != nil {
+			return err
+		}
+		return writeExport(*out, data, stdout)
+	default:
+		return usageErrorf("unknown export format %q", *format)
+	}
+
+	password, err := pwSource.read()
+	if err != nil {
+		return err
+	}
+	kp, err := loadKey(*keyPath, password)
+	if err != nil {
+		return fmt.Errorf("failed to load key %s: %w", *keyPath, err)
+	}
+	defer kp.PrivateKey.Zero()
+	if err := pin.check(*keyPath, kp.G1PubKey); err != nil {
+		return err
+	}
+
+	sk := kp.PrivateKey.Bytes()
+	defer sk.Zero()
+	if err := checkEigenSDKScalar(sk); err != nil {
+		return err
+	}
+	exported := make(bls.SecretBytes, 2+hex.EncodedLen(len(sk))+1)
+	defer exported.Zero()
+	copy(exported, "0x")
+	hex.Encode(exported[2:], sk)
+	exported[len(exported)-1] = '\n'
+
+	slog.Warn("exporting the PLAINTEXT private key: anyone who sees it controls the operator; do not paste it into logs, shells with history or chat")
+	return writeExport(*out, exported, stdout)
+}
+
+// writeExport writes data to stdout, or to out, which must not exist yet.
+func writeExport(out string, data []byte, stdout io.Writer) error {
+	if out == "" {
+		_, err := stdout.Write(data)
+		return err
+	}
+	f, err := os.OpenFile(out, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("refusing to overwrite %s", out)
+		}
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
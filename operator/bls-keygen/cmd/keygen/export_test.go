@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	bn254fr "github.com/consensys/gnark-crypto/ecc/bn254/fr"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+// writeScalarKey saves a key file holding the given big-endian scalar.
+func writeScalarKey(t *testing.T, scalar []byte) (*blskeys.KeyPair, string) {
+	t.Helper()
+	sk, err := bls.PrivateKeyFromBytes(scalar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kp := bls.NewKeyPair(sk)
+	path := filepath.Join(t.TempDir(), "bls_key.json")
+	if err := blskeys.Save(kp, path, testPassword); err != nil {
+		t.Fatal(err)
+	}
+	return kp, path
+}
+
+func TestRunExportEigenSDK(t *testing.T) {
+	scalar := bytes.Repeat([]byte{0x11}, bls.PrivateKeySize)
+	_, path := writeScalarKey(t, scalar)
+	t.Setenv("KEY_PASSWORD", testPassword)
+
+	var out bytes.Buffer
+	if err := runExport([]string{"--key", path, "--format", "eigensdk"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	exported := strings.TrimSpace(out.String())
+	if len(exported) != 2+2*bls.PrivateKeySize || !strings.HasPrefix(exported, "0x") {
+		t.Fatalf("export %q is not 0x-prefixed 32-byte hex", exported)
+	}
+
+	// bls.NewKeyPairFromString in eigensdk-go is new(fr.Element).SetString
+	// on the BN254 scalar field; parse it the same way.
+	e, err := new(bn254fr.Element).SetString(exported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := e.Bytes(); !bytes.Equal(b[:], scalar) {
+		t.Fatalf("eigensdk-go would import scalar %x, want %x", b, scalar)
+	}
+}
+
+func TestRunExportRejectsScalarAboveBN254Order(t *testing.T) {
+	// Below the BLS12-381 order but above the BN254 one.
+	scalar := bytes.Repeat([]byte{0x40}, bls.PrivateKeySize)
+	_, path := writeScalarKey(t, scalar)
+	t.Setenv("KEY_PASSWORD", testPassword)
+
+	var out bytes.Buffer
+	if err := runExport([]string{"--key", path}, &out); err == nil {
+		t.Fatal("expected an error for a scalar eigensdk-go would reduce")
+	}
+	if out.Len() != 0 {
+		t.Fatal("refused export still printed the key")
+	}
+}
+
+func TestRunExportOut(t *testing.T) {
+	kp, path := writeScalarKey(t, bytes.Repeat([]byte{0x22}, bls.PrivateKeySize))
+	t.Setenv("KEY_PASSWORD", testPassword)
+	out := filepath.Join(t.TempDir(), "sk.hex")
+
+	var stdout bytes.Buffer
+	if err := runExport([]string{"--key", path, "--out", out}, &stdout); err != nil {
+		t.Fatal(err)
+	}
+	if stdout.Len() != 0 {
+		t.Fatal("--out also printed the key")
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(string(data)) != fmt.Sprintf("0x%x", []byte(kp.PrivateKey.Bytes())) {
+		t.Fatal("exported file does not hold the private key")
+	}
+	if info, err := os.Stat(out); err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("exported file mode: %v %v", info.Mode(), err)
+	}
+
+	if err := runExport([]string{"--key", path, "--out", out}, &bytes.Buffer{}); err == nil {
+		t.Fatal("export overwrote an existing file")
+	}
+}
+
+func TestRunExportUnknownFormat(t *testing.T) {
+	_, path := writeTestKey(t)
+	t.Setenv("KEY_PASSWORD", testPassword)
+	if err := runExport([]string{"--key", path, "--format", "raw"}, &bytes.Buffer{}); err == nil {
+		t.Fatal("expected an error for an unknown format")
+	}
+}
+
+// foundryFixture is the --format foundry export of the key with scalar 1,
+// whose public keys are the BLS12-381 generators.
+const foundryFixture = `{
+  "g1": {
+    "x": "3685416753713387016781088315183077757961620795782546409894578378688607592378376318836054947676345821548104185464507",
+    "y": "1339506544944476473020471379941921221584933875938349620426543736416511423956333506472724655353366534992391756441569"
+  },
+  "g2": {
+    "x0": "352701069587466618187139116011060144890029952792775240219908644239793785735715026873347600343865175952761926303160",
+    "x1": "3059144344244213709971259814753781636986470325476647558659373206291635324768958432433509563104347017837885763365758",
+    "y0": "1985150602287291935568054521177171638300868978215655730859378665066344726373823718423869104263333984641494340347905",
+    "y1": "927553665492332455747201965776037880757740193453592970025027978793976877002675564980949289727957565575433344219582"
+  }
+}
+`
+
+func TestRunExportFoundry(t *testing.T) {
+	scalar := make([]byte, bls.PrivateKeySize)
+	scalar[len(scalar)-1] = 1
+	kp, path := writeScalarKey(t, scalar)
+	// The public keys are exported without the password.
+	t.Setenv("KEY_PASSWORD", "")
+
+	var out bytes.Buffer
+	if err := runExport([]string{"--key", path, "--format", "foundry"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != foundryFixture {
+		t.Fatalf("export:\n%s\nwant:\n%s", out.String(), foundryFixture)
+	}
+
+	// X = [x0, x1] is the real-part-first order of register-payload's
+	// pubkeyG2 words.
+	x, _ := kp.G2PubKey.EVMWords()
+	if got := new(big.Int).SetBytes(x[0][:]).String(); !strings.Contains(foundryFixture, `"x0": "`+got+`"`) {
+		t.Fatalf("x0 is not the first EIP-2537 word of X (%s)", got)
+	}
+
+	outFile := filepath.Join(t.TempDir(), "operator.json")
+	if err := runExport([]string{"--key", path, "--format", "foundry", "--out", outFile}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(outFile); err != nil || string(data) != foundryFixture {
+		t.Fatalf("--out wrote %q, %v", data, err)
+	}
+}
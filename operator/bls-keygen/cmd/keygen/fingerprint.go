@@ -0,0 +1,28 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+// runFingerprint implements `keygen fingerprint`: it prints the short
+// fingerprint of a key file's G1 public key, read without the password.
+func runFingerprint(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("fingerprint", flag.ContinueOnError)
+	keyPath := fs.String("key", filepath.Join(defaultKeyDir, defaultKeyFile), "key file to read")
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	pk, err := blskeys.LoadPublicKey(*keyPath)
+	if err != nil {
+		return fmt.Errorf("failed to read public key from %s: %w", *keyPath, err)
+	}
+	fmt.Fprintln(stdout, bls.Fingerprint(pk))
+	return nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+func fingerprintOf(t *testing.T, path string) string {
+	t.Helper()
+	var out bytes.Buffer
+	if err := runFingerprint([]string{"--key", path}, &out); err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimSpace(out.String())
+}
+
+func TestRunFingerprintStableAcrossReencryption(t *testing.T) {
+	kp, path := writeTestKey(t)
+	// A second file holding the same key under another password and format.
+	other := filepath.Join(t.TempDir(), "bls_key.json")
+	if err := blskeys.SaveEIP2335(kp, other, "another password"); err != nil {
+		t.Fatal(err)
+	}
+	first := fingerprintOf(t, path)
+	if err := blskeys.ChangePassword(path, testPassword, "a new password"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := fingerprintOf(t, path); got != first {
+		t.Errorf("fingerprint changed after passwd: %s, was %s", got, first)
+	}
+	if got := fingerprintOf(t, other); got != first {
+		t.Errorf("fingerprint of the EIP-2335 copy is %s, want %s", got, first)
+	}
+	if len(first) != len("XXXX-XXXX-XXXX-XXXX") {
+		t.Errorf("fingerprint %q is not four groups of four", first)
+	}
+}
+
+func TestRunFingerprintDiffersPerKey(t *testing.T) {
+	_, a := writeTestKey(t)
+	_, b := writeTestKey(t)
+	if fingerprintOf(t, a) == fingerprintOf(t, b) {
+		t.Fatal("two different keys have the same fingerprint")
+	}
+}
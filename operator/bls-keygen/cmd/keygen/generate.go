@@ -0,0 +1,601 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+var errKeyExists = errors.New("BLS key already exists, skipping generation (use --force to regenerate)")
+
+var (
+	// entropySource is the source probed at startup; tests replace it.
+	entropySource io.Reader = rand.Reader
+	// entropyTimeout is how long the probe waits for a sample.
+	entropyTimeout = 2 * time.Second
+)
+
+// minEntropyFileSize is the least --entropy-file material accepted without
+// a warning: 256 bits, assuming every byte carries a full byte of entropy.
+const minEntropyFileSize = 32
+
+// errNotDir is the cause of a keyDirError for a key directory path that
+// holds something other than a directory.
+var errNotDir = errors.New("not a directory")
+
+// errKeyPathIsDir is returned when the key file path is a directory.
+var errKeyPathIsDir = errors.New("is a directory")
+
+// keyDirError reports a key directory that could not be created, or that
+// exists but cannot be written to or is not a directory at all.
+type keyDirError struct {
+	Dir    string
+	Exists bool
+	Err    error
+}
+
+func (e *keyDirError) Error() string {
+	if e.Exists && errors.Is(e.Err, errNotDir) {
+		return fmt.Sprintf("keys path %q exists but is not a directory", e.Dir)
+	}
+	if e.Exists {
+		return fmt.Sprintf("key directory %q exists but is not writable: %v", e.Dir, e.Err)
+	}
+	return fmt.Sprintf("failed to create key directory %q: %v", e.Dir, e.Err)
+}
+
+func (e *keyDirError) Unwrap() error { return e.Err }
+
+// ensureKeyDir creates dir with mode if needed and confirms a file can be
+// created in it, so a read-only mount fails here rather than halfway through
+// a write. The mode of an existing directory is left alone.
+func ensureKeyDir(dir string, mode os.FileMode) error {
+	info, statErr := os.Stat(dir)
+	if statErr == nil && !info.IsDir() {
+		// Something, e.g. a file bind-mounted at /keys, is in the way.
+		return &keyDirError{Dir: dir, Exists: true, Err: errNotDir}
+	}
+	if err := os.MkdirAll(dir, mode); err != nil {
+		return &keyDirError{Dir: dir, Err: err}
+	}
+	// MkdirAll's mode is filtered by the umask.
+	if os.IsNotExist(statErr) {
+		if err := os.Chmod(dir, mode); err != nil {
+			return &keyDirError{Dir: dir, Err: err}
+		}
+	}
+	f, err := os.CreateTemp(dir, ".keygen-probe-*")
+	if err != nil {
+		return &keyDirError{Dir: dir, Exists: true, Err: err}
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// runGenerate creates a new key, the default action when no subcommand is given.
+func runGenerate(args []string, stdout io.Writer) (err error) {
+	cfg, err := parseFlags(args)
+	if err != nil {
+		return err
+	}
+	// Each generated key is recorded as it is written; a failure is
+	// recorded once, for the whole run.
+	defer func() {
+		if err != nil && !cfg.dryRun {
+			err = cfg.audit.done("generate", nil, err)
+		}
+	}()
+	if err := cfg.log.apply(); err != nil {
+		return err
+	}
+	if err := cfg.backend.use(); err != nil {
+		return err
+	}
+	cfg.file.warn()
+	cfg.checkBackupAck()
+	if cfg.selfTest {
+		if err := selfTest(); err != nil {
+			return err
+		}
+		slog.Info("self-test passed")
+	}
+	if err := checkEntropy(cfg.strictEntropy); err != nil {
+		return err
+	}
+	extra, err := readEntropyFile(cfg.entropyFile)
+	if err != nil {
+		return err
+	}
+	defer extra.Zero()
+
+	slog.Info("Bastion BLS key generator")
+	slog.Debug("generator options", "out", cfg.out, "format", cfg.format, "force", cfg.force, "dry_run", cfg.dryRun)
+
+	password, err := cfg.password.readNew()
+	if err != nil {
+		return err
+	}
+	if err := cfg.policy.check(password); err != nil {
+		return fmt.Errorf("%w (use --allow-weak-password to override)", err)
+	}
+	var ecdsaPassword string
+	if cfg.ecdsa.enabled {
+		if ecdsaPassword, err = cfg.ecdsa.password(password, &cfg.policy); err != nil {
+			return err
+		}
+	}
+
+	if err := cfg.registry.init(); err != nil {
+		return err
+	}
+
+	paths, err := cfg.resolveKeyPaths()
+	if err != nil {
+		return err
+	}
+	if cfg.dryRun {
+		return dryRun(cfg, paths, extra, stdout)
+	}
+	if cfg.out == stdoutPath {
+		return generateToStdout(cfg, password, extra, stdout)
+	}
+
+	ecdsaPaths, err := cfg.resolveECDSAPaths(paths)
+	if err != nil {
+		return err
+	}
+
+	if err := cfg.perms.ensureDir(cfg.keyDir); err != nil {
+		return err
+	}
+	// Hold the key directory's lock from the existence check until the
+	// keys are written, so replicas starting together cannot both find the
+	// key missing and overwrite each other's.
+	unlock, err := blskeys.LockDir(cmdContext, cfg.keyDir)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// Check if any key already exists before touching anything
+	for _, keyPath := range paths {
+		info, err := os.Stat(keyPath)
+		if err != nil {
+			continue
+		}
+		if info.IsDir() {
+			return keyPathIsDir(keyPath)
+		}
+		if cfg.replacePlaceholder && isPlaceholderFile(keyPath) {
+			slog.Warn("replacing legacy ECDSA placeholder key, it was never a BLS key", "path", keyPath)
+			if err := confirmOverwrite(keyPath); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := cfg.registry.checkShadowing(keyPath, cfg.force); err != nil {
+			return err
+		}
+		if err := checkNewKeyPath(keyPath, cfg.force); err != nil {
+			if errors.Is(err, errKeyExists) {
+				logExistingKey(keyPath)
+			}
+			return err
+		}
+	}
+	for _, path := range ecdsaPaths {
+		if err := checkNewKeyPath(path, cfg.force); err != nil {
+			return err
+		}
+	}
+
+	for i, keyPath := range paths {
+		var ecdsaPath string
+		if cfg.ecdsa.enabled {
+			ecdsaPath = ecdsaPaths[i]
+		}
+		if cfg.replaceLink && blskeys.IsSymlink(keyPath) {
+			// The key at the link's target is left alone, so there is
+			// nothing to back up.
+			if err := replaceLink(keyPath); err != nil {
+				return err
+			}
+		}
+		res, err := generateKey(cfg, keyPath, password, extra, ecdsaPath, ecdsaPassword)
+		if err != nil {
+			return err
+		}
+		if err := cfg.writeResult(stdout, res); err != nil {
+			return err
+		}
+		if err := cfg.registry.report(res.id); err != nil {
+			return fmt.Errorf("key %s was written, but %w", keyPath, err)
+		}
+		if err := cfg.audit.done("generate", res.g1, nil); err != nil {
+			return err
+		}
+		if err := cfg.ackBackup(res); err != nil {
+			return err
+		}
+	}
+	slog.Warn("back up this key securely, the private key is needed to sign AVS responses")
+	return nil
+}
+
+// isPlaceholderFile reports whether the file at path is a key file of the
+// ECDSA placeholder build.
+func isPlaceholderFile(path string) bool {
+	data, err := os.ReadFile(path)
+	return err == nil && blskeys.IsLegacyPlaceholder(data)
+}
+
+// keyPathIsDir reports a key file path that is an existing directory,
+// which --force must not back up and replace as if it were a key.
+func keyPathIsDir(path string) error {
+	return fmt.Errorf("key path %q exists but %w, not a key file", path, errKeyPathIsDir)
+}
+
+// logExistingKey logs the public key of the key file generate found at
+// path, which may have just been written by another replica that won the
+// key directory's lock.
+func logExistingKey(path string) {
+	pk, err := blskeys.LoadPublicKey(path)
+	if err != nil {
+		slog.Warn("existing key file cannot be read", "path", path, "err", err)
+		return
+	}
+	slog.Info("using existing key", "path", path, "g1_pub_key", fmt.Sprintf("0x%x", pk.Bytes()))
+}
+
+// generateResult describes a generated key. With --output json each one
+// is printed to stdout as a single JSON object.
+type generateResult struct {
+	Path       string `json:"path"`
+	G1PubKey   string `json:"g1_pub_key"`
+	G2PubKey   string `json:"g2_pub_key"`
+	OperatorID string `json:"operator_id"`
+	Format     string `json:"format"`
+	Version    int    `json:"version"`
+	DryRun     bool   `json:"dry_run,omitempty"`
+	// ECDSAPath and OperatorAddress describe the --with-ecdsa key.
+	ECDSAPath       string `json:"ecdsa_path,omitempty"`
+	OperatorAddress string `json:"operator_address,omitempty"`
+
+	id [32]byte
+	g1 *bls.G1PubKey
+}
+
+func newGenerateResult(cfg *config, keyPath string, kp *blskeys.KeyPair) *generateResult {
+	res := &generateResult{
+		Path:     keyPath,
+		G1PubKey: fmt.Sprintf("0x%x", kp.G1PubKey.Bytes()),
+		G2PubKey: fmt.Sprintf("0x%x", kp.G2PubKey.Bytes()),
+		Format:   cfg.format,
+		Version:  blskeys.CurrentVersion,
+		DryRun:   cfg.dryRun,
+		id:       bls.OperatorID(kp.G1PubKey),
+		g1:       kp.G1PubKey,
+	}
+	res.OperatorID = fmt.Sprintf("0x%x", res.id)
+	if cfg.format == formatEIP2335 {
+		res.Version = eip2335FileVersion
+	}
+	return res
+}
+
+// writeResult prints res to stdout for --output json. Text output has
+// nothing to add to the log lines.
+func (cfg *config) writeResult(stdout io.Writer, res *generateResult) error {
+	if cfg.output != outputJSON {
+		return nil
+	}
+	return json.NewEncoder(stdout).Encode(res)
+}
+
+// checkEntropy probes entropySource and warns, or fails when strict, if
+// it does not answer within entropyTimeout.
+func checkEntropy(strict bool) error {
+	err := blskeys.CheckEntropy(entropySource, entropyTimeout)
+	if err == nil {
+		return nil
+	}
+	if strict {
+		return fmt.Errorf("entropy check failed: %w", err)
+	}
+	slog.Warn("entropy source is slow, the system may not have gathered enough entropy yet; use --strict-entropy to fail instead", "reason", err)
+	return nil
+}
+
+// readEntropyFile returns the contents of --entropy-file, or nil when it is
+// not set.
+func readEntropyFile(path string) (bls.SecretBytes, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read entropy file: %w", err)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("entropy file %s is empty", path)
+	}
+	if len(data) < minEntropyFileSize {
+		slog.Warn("entropy file is short, it adds little to crypto/rand", "path", path, "bytes", len(data))
+	}
+	return data, nil
+}
+
+// newKeyPair generates a key from crypto/rand, mixed with extra if set,
+// and unless --no-verify-generated checks that it signs and verifies
+// before anything is written.
+func newKeyPair(cfg *config, extra []byte) (kp *blskeys.KeyPair, err error) {
+	if extra != nil {
+		kp, err = blskeys.GenerateWithEntropy(cmdContext, extra)
+	} else {
+		kp, err = blskeys.GenerateContext(cmdContext)
+	}
+	if err != nil || cfg.noVerifyGenerated {
+		return kp, err
+	}
+	if err := verifyGenerated(kp); err != nil {
+		kp.PrivateKey.Zero()
+		return nil, err
+	}
+	return kp, nil
+}
+
+// generateToStdout is runGenerate for --out -: the encrypted key is written
+// to stdout, for piping into a secret manager, and never touches the disk.
+// There is no existing key to skip or back up. Logs stay on stderr.
+func generateToStdout(cfg *config, password string, extra []byte, stdout io.Writer) error {
+	kp, err := newKeyPair(cfg, extra)
+	if err != nil {
+		return fmt.Errorf("failed to generate key: %w", err)
+	}
+	defer kp.PrivateKey.Zero()
+
+	data, err := marshalKey(cfg, kp, password, cfg.metadata())
+	if err != nil {
+		return err
+	}
+	// Binary data has no lines, and a PEM block already ends with one.
+	if !cfg.age.enabled() && cfg.format != formatBinary && cfg.format != formatPEM {
+		data = append(data, '\n')
+	}
+	if _, err := stdout.Write(data); err != nil {
+		return fmt.Errorf("failed to write key to stdout: %w", err)
+	}
+
+	res := newGenerateResult(cfg, stdoutPath, kp)
+	slog.Info("BLS key pair generated", "path", "stdout", "g1_pub_key", res.G1PubKey, "operator_id", res.OperatorID)
+	if err := cfg.registry.report(res.id); err != nil {
+		return err
+	}
+	if err := cfg.audit.done("generate", res.g1, nil); err != nil {
+		return err
+	}
+	return cfg.ackBackup(res)
+}
+
+// marshalKey encodes kp as a key file in cfg's format, encrypted to the
+// --encrypt-to recipients if there are any.
+func marshalKey(cfg *config, kp *blskeys.KeyPair, password string, meta *blskeys.Metadata) ([]byte, error) {
+	var data []byte
+	var err error
+	switch cfg.format {
+	case formatEIP2335:
+		data, err = blskeys.MarshalEIP2335Context(cmdContext, kp, password, cfg.kdfParams, meta)
+	case formatBinary:
+		data, err = blskeys.MarshalBinaryContext(cmdContext, kp, password, cfg.kdfParams, meta)
+	case formatPEM:
+		data, err = blskeys.MarshalPEMContext(cmdContext, kp, password, cfg.kdfParams, meta)
+	default:
+		data, err = blskeys.MarshalContext(cmdContext, kp, password, cfg.kdfParams, meta)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt key: %w", err)
+	}
+	if !cfg.age.enabled() {
+		return data, nil
+	}
+	return cfg.age.seal(data)
+}
+
+// generateKey creates one key and writes it to keyPath. With a non-empty
+// ecdsaPath it first writes a companion ECDSA key there, under
+// ecdsaPassword, and records its address in the BLS key's metadata; the
+// ECDSA key is removed again if the BLS key cannot be written.
+func generateKey(cfg *config, keyPath, password string, extra []byte, ecdsaPath, ecdsaPassword string) (res *generateResult, err error) {
+	slog.Info("generating new BLS key pair", "path", keyPath)
+
+	kp, err := newKeyPair(cfg, extra)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	defer kp.PrivateKey.Zero()
+
+	meta := cfg.metadata()
+	if ecdsaPath != "" {
+		addr, err := generateECDSA(cfg, ecdsaPath, ecdsaPassword)
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			if err != nil {
+				os.Remove(ecdsaPath)
+			}
+		}()
+		meta.OperatorAddress = addr.Hex()
+	}
+
+	slog.Info("encrypting private key", "format", cfg.format)
+	data, err := marshalKey(cfg, kp, password, meta)
+	if err != nil {
+		return nil, err
+	}
+	replace := cfg.force || cfg.replacePlaceholder && isPlaceholderFile(keyPath)
+	if _, err := writeKeyFile(keyPath, data, replace); err != nil {
+		return nil, fmt.Errorf("failed to save key: %w", err)
+	}
+	if err := cfg.perms.check(keyPath); err != nil {
+		return nil, err
+	}
+
+	res = newGenerateResult(cfg, keyPath, kp)
+	if ecdsaPath != "" {
+		res.ECDSAPath, res.OperatorAddress = ecdsaPath, meta.OperatorAddress
+	}
+	slog.Info("BLS key pair generated",
+		"path", keyPath,
+		"g1_pub_key", res.G1PubKey,
+		"operator_id", res.OperatorID)
+	if cfg.testKey {
+		slog.Warn("key is marked test-only and will be refused on mainnet", "path", keyPath)
+	}
+	return res, nil
+}
+
+// dryRun is the --dry-run tail of runGenerate: the password has already
+// been checked, so it only confirms the keys could be written and shows the
+// public keys of throwaway keys.
+func dryRun(cfg *config, paths []string, extra []byte, stdout io.Writer) error {
+	if cfg.out != stdoutPath {
+		if err := dryRunPreflight(cfg, paths, stdout); err != nil {
+			return err
+		}
+	}
+
+	for _, keyPath := range paths {
+		kp, err := newKeyPair(cfg, extra)
+		if err != nil {
+			return fmt.Errorf("failed to generate key: %w", err)
+		}
+		res := newGenerateResult(cfg, keyPath, kp)
+		kp.PrivateKey.Zero()
+		if cfg.output == outputJSON {
+			if err := cfg.writeResult(stdout, res); err != nil {
+				return err
+			}
+			continue
+		}
+		fmt.Fprintf(stdout, "Dry run: would write %s key to %s\n", cfg.format, keyPath)
+		fmt.Fprintf(stdout, "G1 public key: %s\n", res.G1PubKey)
+		fmt.Fprintf(stdout, "G2 public key: %s\n", res.G2PubKey)
+		if cfg.ecdsa.enabled {
+			fmt.Fprintf(stdout, "Dry run: would write ECDSA keystore to %s\n", cfg.ecdsa.path(keyPath))
+		}
+	}
+	return nil
+}
+
+// dryRunPreflight runs the existing-key and writability checks of a dry
+// run that would write files.
+func dryRunPreflight(cfg *config, paths []string, stdout io.Writer) error {
+	for _, keyPath := range paths {
+		if info, err := os.Stat(keyPath); err == nil {
+			if info.IsDir() {
+				return keyPathIsDir(keyPath)
+			}
+			if !cfg.force {
+				return errKeyExists
+			}
+			if cfg.output == outputJSON {
+				slog.Info("dry run: would back up existing key", "path", keyPath)
+			} else {
+				fmt.Fprintf(stdout, "Would back up existing key %s\n", keyPath)
+			}
+		}
+	}
+	return checkWritable(filepath.Dir(paths[0]))
+}
+
+// checkWritable reports whether a file could be created in dir, which may
+// not exist yet. It probes the closest existing ancestor with a temporary
+// file that is removed again.
+func checkWritable(dir string) error {
+	probe := dir
+	for {
+		info, err := os.Stat(probe)
+		if err == nil {
+			if !info.IsDir() {
+				return fmt.Errorf("cannot create key directory %s: %s is not a directory", dir, probe)
+			}
+			break
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("cannot access key directory %s: %w", dir, err)
+		}
+		parent := filepath.Dir(probe)
+		if parent == probe {
+			return fmt.Errorf("cannot access key directory %s: %w", dir, err)
+		}
+		probe = parent
+	}
+
+	f, err := os.CreateTemp(probe, ".keygen-dry-run-*")
+	if err != nil {
+		return fmt.Errorf("key directory %s is not writable: %w", dir, err)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// confirmOverwrite asks on a terminal whether the key at path may be
+// overwritten, and fails unless the answer is yes.
+func confirmOverwrite(path string) error {
+	if !stdinTerminal.isTerminal() {
+		return nil
+	}
+	answer, err := stdinTerminal.askLine(fmt.Sprintf("Overwrite existing key %s? [y/N] ", path))
+	if err != nil {
+		return err
+	}
+	if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+		return errors.New("aborted, existing key left untouched")
+	}
+	return nil
+}
+
+// replaceLink removes the symlink at path so that a regular file can take
+// its place.
+func replaceLink(path string) error {
+	target, err := os.Readlink(path)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to replace symlink: %w", err)
+	}
+	slog.Warn("replacing symlinked key path with a regular file", "path", path, "old_target", target)
+	return nil
+}
+
+// linkBackup hard-links path to blskeys.BackupPath, adding a counter before
+// the .bak suffix when that name is taken so that backups made within the
+// same second never replace one another. The hard link fails rather than
+// overwrite an existing file.
+func linkBackup(path string, now time.Time) (string, error) {
+	base := strings.TrimSuffix(blskeys.BackupPath(path, now), ".bak")
+	backup := base + ".bak"
+	for i := 1; ; i++ {
+		err := os.Link(path, backup)
+		if err == nil {
+			break
+		}
+		if !os.IsExist(err) {
+			return "", err
+		}
+		backup = fmt.Sprintf("%s-%d.bak", base, i)
+	}
+	return backup, nil
+}
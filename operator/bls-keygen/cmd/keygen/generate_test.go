@@ -0,0 +1,825 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+func TestRunGenerate(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "keys", "bls_key.json")
+	t.Setenv("KEY_PASSWORD", testPassword)
+
+	if err := runGenerate([]string{"--out", out}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := blskeys.Load(out, testPassword); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunGenerateRecordsNetwork(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "bls_key.json")
+	t.Setenv("KEY_PASSWORD", testPassword)
+
+	if err := runGenerate([]string{"--out", out, "--network", "holesky"}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	meta, err := blskeys.LoadMetadata(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta == nil || meta.Network != "holesky" {
+		t.Fatalf("metadata = %+v, want network holesky", meta)
+	}
+	if _, err := blskeys.Load(out, testPassword); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunGenerateRecordsProvenance(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "bls_key.json")
+	t.Setenv("KEY_PASSWORD", testPassword)
+
+	start := time.Now().Truncate(time.Second)
+	if err := runGenerate([]string{"--out", out}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	meta, err := blskeys.LoadMetadata(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, err := meta.Created()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.Before(start) || created.After(time.Now()) {
+		t.Errorf("created_at %s is not the time of generation", meta.CreatedAt)
+	}
+	if want := "keygen " + version; meta.GeneratorVersion != want {
+		t.Errorf("generator_version %q, want %q", meta.GeneratorVersion, want)
+	}
+
+	// Re-encryption keeps both.
+	if err := blskeys.ChangePassword(out, testPassword, "a new password"); err != nil {
+		t.Fatal(err)
+	}
+	after, err := blskeys.LoadMetadata(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *after != *meta {
+		t.Errorf("metadata after passwd = %+v, was %+v", after, meta)
+	}
+}
+
+func TestRunGenerateJSONOutput(t *testing.T) {
+	logs := captureLogs(t)
+	out := filepath.Join(t.TempDir(), "bls_key.json")
+	t.Setenv("KEY_PASSWORD", testPassword)
+
+	var stdout bytes.Buffer
+	if err := runGenerate([]string{"--out", out, "--output", "json"}, &stdout); err != nil {
+		t.Fatal(err)
+	}
+	var res map[string]interface{}
+	if err := json.Unmarshal(stdout.Bytes(), &res); err != nil {
+		t.Fatalf("stdout is not a JSON object: %v\n%s", err, stdout.String())
+	}
+	kp, err := blskeys.Load(out, testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"path":        out,
+		"g1_pub_key":  fmt.Sprintf("0x%x", kp.G1PubKey.Bytes()),
+		"g2_pub_key":  fmt.Sprintf("0x%x", kp.G2PubKey.Bytes()),
+		"operator_id": fmt.Sprintf("0x%x", bls.OperatorID(kp.G1PubKey)),
+		"format":      formatBastion,
+		"version":     float64(blskeys.CurrentVersion),
+	}
+	if !reflect.DeepEqual(res, want) {
+		t.Fatalf("stdout = %v, want %v", res, want)
+	}
+	for _, msg := range []string{"BLS key pair generated", "back up this key securely"} {
+		if !strings.Contains(logs.String(), msg) {
+			t.Errorf("logs missing %q:\n%s", msg, logs.String())
+		}
+	}
+
+	if err := runGenerate([]string{"--out", out, "--output", "yaml"}, &bytes.Buffer{}); exitCode(err) != exitUsage {
+		t.Fatalf("unknown --output: got %v, want a usage error", err)
+	}
+}
+
+func TestRunGenerateToStdout(t *testing.T) {
+	logs := captureLogs(t)
+	t.Setenv("KEY_PASSWORD", testPassword)
+	// A file named "-" in the working directory must be ignored, not
+	// skipped over as an existing key.
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	if err := os.WriteFile("-", []byte("not a key"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout bytes.Buffer
+	if err := runGenerate([]string{"--out", "-"}, &stdout); err != nil {
+		t.Fatal(err)
+	}
+	var kf blskeys.KeyFile
+	if err := json.Unmarshal(stdout.Bytes(), &kf); err != nil {
+		t.Fatalf("stdout is not a key file: %v\n%s", err, stdout.String())
+	}
+	path := filepath.Join(dir, "from-stdout.json")
+	if err := os.WriteFile(path, stdout.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	kp, err := blskeys.Load(path, testPassword)
+	if err != nil {
+		t.Fatalf("key written to stdout does not load: %v", err)
+	}
+	if kf.G1PubKey != fmt.Sprintf("0x%x", kp.G1PubKey.Bytes()) {
+		t.Fatal("stdout key file has the wrong public key")
+	}
+	if !strings.Contains(logs.String(), "BLS key pair generated") {
+		t.Fatalf("human messages missing from the log:\n%s", logs.String())
+	}
+	if data, _ := os.ReadFile("-"); string(data) != "not a key" {
+		t.Fatal("file named - was touched")
+	}
+}
+
+// blockingReader never returns until release is closed.
+type blockingReader struct{ release chan struct{} }
+
+func (b blockingReader) Read(p []byte) (int, error) {
+	<-b.release
+	return 0, io.EOF
+}
+
+func TestRunGenerateSlowEntropy(t *testing.T) {
+	blocked := blockingReader{release: make(chan struct{})}
+	defer close(blocked.release)
+	origSource, origTimeout := entropySource, entropyTimeout
+	entropySource, entropyTimeout = blocked, 10*time.Millisecond
+	defer func() { entropySource, entropyTimeout = origSource, origTimeout }()
+	logs := captureLogs(t)
+	t.Setenv("KEY_PASSWORD", testPassword)
+
+	out := filepath.Join(t.TempDir(), "bls_key.json")
+	if err := runGenerate([]string{"--out", out}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(logs.String(), "entropy source is slow") {
+		t.Fatalf("no entropy warning logged:\n%s", logs.String())
+	}
+
+	strict := filepath.Join(t.TempDir(), "bls_key.json")
+	if err := runGenerate([]string{"--out", strict, "--strict-entropy"}, &bytes.Buffer{}); !errors.Is(err, blskeys.ErrEntropyTimeout) {
+		t.Fatalf("--strict-entropy: got %v, want ErrEntropyTimeout", err)
+	}
+	if _, err := os.Stat(strict); !os.IsNotExist(err) {
+		t.Fatal("key written despite --strict-entropy failure")
+	}
+}
+
+func TestRunGenerateEntropyFile(t *testing.T) {
+	dir := t.TempDir()
+	seed := filepath.Join(dir, "dice.txt")
+	if err := os.WriteFile(seed, []byte("6 2 5 1 3 3 4 6 1 2 5 5 6 4 2 1 3 6 5 4 1 2 3 4 5 6 1 2 3 4 5 6"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("KEY_PASSWORD", testPassword)
+
+	out := filepath.Join(dir, "bls_key.json")
+	if err := runGenerate([]string{"--out", out, "--entropy-file", seed}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := blskeys.Load(out, testPassword); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runGenerate([]string{"--out", filepath.Join(dir, "other.json"), "--entropy-file", filepath.Join(dir, "missing")}, &bytes.Buffer{}); err == nil {
+		t.Fatal("missing entropy file accepted")
+	}
+}
+
+func TestRunGenerateSkipsExistingKey(t *testing.T) {
+	kp, path := writeTestKey(t)
+	t.Setenv("KEY_PASSWORD", testPassword)
+
+	if err := runGenerate([]string{"--out", path}, &bytes.Buffer{}); !errors.Is(err, errKeyExists) {
+		t.Fatalf("got %v, want errKeyExists", err)
+	}
+	loaded, err := blskeys.Load(path, testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(loaded.PrivateKey.Bytes(), kp.PrivateKey.Bytes()) {
+		t.Fatal("existing key was modified")
+	}
+}
+
+func TestRunGenerateForceKeepsEveryBackup(t *testing.T) {
+	_, path := writeTestKey(t)
+	t.Setenv("KEY_PASSWORD", testPassword)
+	scriptedTerminal(t, false)
+
+	// Several forced runs land in the same second; none may clobber an
+	// earlier backup.
+	for i := 0; i < 3; i++ {
+		if err := runGenerate([]string{"--out", path, "--force"}, &bytes.Buffer{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if backups, _ := filepath.Glob(path + ".*.bak"); len(backups) != 3 {
+		t.Fatalf("expected three backups, got %v", backups)
+	}
+}
+
+func TestRunGenerateForce(t *testing.T) {
+	old, path := writeTestKey(t)
+	t.Setenv("KEY_PASSWORD", testPassword)
+	scriptedTerminal(t, false) // non-interactive: no confirmation
+
+	if err := runGenerate([]string{"--out", path, "--force"}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+
+	current, err := blskeys.Load(path, testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(current.PrivateKey.Bytes(), old.PrivateKey.Bytes()) {
+		t.Fatal("--force did not replace the key")
+	}
+
+	backups, _ := filepath.Glob(path + ".*.bak")
+	if len(backups) != 1 {
+		t.Fatalf("expected one backup, got %v", backups)
+	}
+	backedUp, err := blskeys.Load(backups[0], testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(backedUp.PrivateKey.Bytes(), old.PrivateKey.Bytes()) {
+		t.Fatal("backup does not hold the old key")
+	}
+}
+
+func TestRunGenerateForceDeclined(t *testing.T) {
+	old, path := writeTestKey(t)
+	t.Setenv("KEY_PASSWORD", testPassword)
+	scriptedTerminal(t, true, "n\n")
+
+	if err := runGenerate([]string{"--out", path, "--force"}, &bytes.Buffer{}); err == nil {
+		t.Fatal("expected declining the confirmation to abort")
+	}
+	current, err := blskeys.Load(path, testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(current.PrivateKey.Bytes(), old.PrivateKey.Bytes()) {
+		t.Fatal("declined --force still replaced the key")
+	}
+	if backups, _ := filepath.Glob(path + ".*.bak"); len(backups) != 0 {
+		t.Fatalf("unexpected backups %v", backups)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunGenerateDryRun(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "keys", "bls_key.json")
+	t.Setenv("KEY_PASSWORD", testPassword)
+
+	var stdout bytes.Buffer
+	if err := runGenerate([]string{"--out", out, "--dry-run"}, &stdout); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(stdout.Bytes(), []byte("G2 public key: 0x")) {
+		t.Fatalf("public keys not printed:\n%s", stdout.String())
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("dry run wrote to disk: %v", entries)
+	}
+}
+
+func TestRunGenerateDryRunPreflightFailures(t *testing.T) {
+	dir := t.TempDir()
+	notADir := filepath.Join(dir, "file")
+	if err := os.WriteFile(notADir, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("unwritable directory", func(t *testing.T) {
+		t.Setenv("KEY_PASSWORD", testPassword)
+		err := runGenerate([]string{"--out", filepath.Join(notADir, "bls_key.json"), "--dry-run"}, &bytes.Buffer{})
+		if err == nil {
+			t.Fatal("expected an error for a key directory that cannot be created")
+		}
+	})
+
+	t.Run("weak password", func(t *testing.T) {
+		t.Setenv("KEY_PASSWORD", "secure_password")
+		err := runGenerate([]string{"--out", filepath.Join(dir, "bls_key.json"), "--dry-run"}, &bytes.Buffer{})
+		if !errors.Is(err, blskeys.ErrWeakPassword) {
+			t.Fatalf("got %v, want ErrWeakPassword", err)
+		}
+	})
+
+	t.Run("missing password", func(t *testing.T) {
+		t.Setenv("KEY_PASSWORD", "")
+		scriptedTerminal(t, false)
+		if err := runGenerate([]string{"--out", filepath.Join(dir, "bls_key.json"), "--dry-run"}, &bytes.Buffer{}); err == nil {
+			t.Fatal("expected an error without a password")
+		}
+	})
+}
+
+func TestRunGeneratePermissionCheck(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Chmod(dir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("KEY_PASSWORD", testPassword)
+
+	err := runGenerate([]string{"--out", filepath.Join(dir, "a.json")}, &bytes.Buffer{})
+	if !errors.Is(err, blskeys.ErrUnsafePermissions) {
+		t.Fatalf("world-writable key directory: got %v, want ErrUnsafePermissions", err)
+	}
+	if err := runGenerate([]string{"--out", filepath.Join(dir, "b.json"), "--skip-perm-check"}, &bytes.Buffer{}); err != nil {
+		t.Fatalf("--skip-perm-check: %v", err)
+	}
+}
+
+func TestRunGenerateSharedModes(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "keys")
+	out := filepath.Join(dir, "bls_key.json")
+	t.Setenv("KEY_PASSWORD", testPassword)
+
+	if err := runGenerate([]string{"--out", out, "--keyfile-mode", "0640", "--keydir-mode", "0750"}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]os.FileMode{out: 0640, dir: 0750} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := info.Mode().Perm(); got != want {
+			t.Errorf("%s has mode %04o, want %04o", path, got, want)
+		}
+	}
+	if _, err := blskeys.Load(out, testPassword); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunGenerateRejectsWorldReadableMode(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "bls_key.json")
+	t.Setenv("KEY_PASSWORD", testPassword)
+
+	var usage usageError
+	err := runGenerate([]string{"--out", out, "--keyfile-mode", "0644"}, &bytes.Buffer{})
+	if !errors.As(err, &usage) {
+		t.Fatalf("got %v, want a usage error", err)
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Fatal("key file written with a rejected mode")
+	}
+
+	if err := runGenerate([]string{"--out", out, "--keyfile-mode", "0644", "--allow-unsafe-perms"}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := info.Mode().Perm(); got != 0644 {
+		t.Fatalf("mode %04o, want 0644", got)
+	}
+}
+
+func TestRunGenerateRefusesRoot(t *testing.T) {
+	defer func(orig func() int) { geteuid = orig }(geteuid)
+	geteuid = func() int { return 0 }
+	out := filepath.Join(t.TempDir(), "bls_key.json")
+	t.Setenv("KEY_PASSWORD", testPassword)
+
+	var usage usageError
+	err := runGenerate([]string{"--out", out}, &bytes.Buffer{})
+	if !errors.As(err, &usage) {
+		t.Fatalf("got %v, want a usage error", err)
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Fatal("key file written as root")
+	}
+
+	if err := runGenerate([]string{"--out", out, "--allow-root"}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without an effective UID, as on Windows, there is nothing to check.
+	geteuid = func() int { return -1 }
+	if err := runGenerate([]string{"--out", filepath.Join(t.TempDir(), "bls_key.json")}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunGenerateBinaryFormat(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "bls_key.bin")
+	t.Setenv("KEY_PASSWORD", testPassword)
+
+	if err := runGenerate([]string{"--out", out, "--format", formatBinary}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := blskeys.Load(out, testPassword); err != nil {
+		t.Fatalf("binary key file does not load: %v", err)
+	}
+
+	var stdout bytes.Buffer
+	if err := runGenerate([]string{"--out", "-", "--format", formatBinary}, &stdout); err != nil {
+		t.Fatal(err)
+	}
+	piped := filepath.Join(dir, "piped.bin")
+	if err := os.WriteFile(piped, stdout.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := blskeys.Load(piped, testPassword); err != nil {
+		t.Fatalf("binary key written to stdout does not load: %v", err)
+	}
+}
+
+func TestRunGeneratePEMFormat(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "bls_key.pem")
+	t.Setenv("KEY_PASSWORD", testPassword)
+
+	if err := runGenerate([]string{"--out", out, "--format", formatPEM}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "-----BEGIN BASTION BLS KEY-----\n") {
+		t.Fatalf("key file is not a PEM block:\n%s", data)
+	}
+	if _, err := blskeys.Load(out, testPassword); err != nil {
+		t.Fatalf("PEM key file does not load: %v", err)
+	}
+	info, err := blskeys.Inspect(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Format != blskeys.FormatPEM {
+		t.Errorf("Inspect format = %q, want %q", info.Format, blskeys.FormatPEM)
+	}
+}
+
+func TestRunGenerateKeyDirErrors(t *testing.T) {
+	t.Setenv("KEY_PASSWORD", testPassword)
+
+	t.Run("cannot create", func(t *testing.T) {
+		parent := filepath.Join(t.TempDir(), "file")
+		if err := os.WriteFile(parent, nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+		dir := filepath.Join(parent, "keys")
+		err := runGenerate([]string{"--keydir", dir}, &bytes.Buffer{})
+		var dirErr *keyDirError
+		if !errors.As(err, &dirErr) || dirErr.Exists || dirErr.Dir != dir {
+			t.Fatalf("got %v, want a creation keyDirError for %s", err, dir)
+		}
+	})
+
+	t.Run("file in the way", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "keys")
+		if err := os.WriteFile(dir, []byte("not a directory"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		err := runGenerate([]string{"--keydir", dir}, &bytes.Buffer{})
+		var dirErr *keyDirError
+		if !errors.As(err, &dirErr) || !errors.Is(err, errNotDir) || dirErr.Dir != dir {
+			t.Fatalf("got %v, want a not-a-directory keyDirError for %s", err, dir)
+		}
+		if want := fmt.Sprintf("keys path %q exists but is not a directory", dir); err.Error() != want {
+			t.Fatalf("message %q, want %q", err, want)
+		}
+	})
+
+	t.Run("read-only", func(t *testing.T) {
+		if os.Geteuid() == 0 {
+			t.Skip("root ignores directory permissions")
+		}
+		dir := t.TempDir()
+		if err := os.Chmod(dir, 0500); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { os.Chmod(dir, 0700) })
+		err := runGenerate([]string{"--keydir", dir}, &bytes.Buffer{})
+		var dirErr *keyDirError
+		if !errors.As(err, &dirErr) || !dirErr.Exists || !errors.Is(err, os.ErrPermission) {
+			t.Fatalf("got %v, want a not-writable keyDirError", err)
+		}
+		if !strings.Contains(err.Error(), "exists but is not writable") {
+			t.Fatalf("message %q does not say the directory is read-only", err)
+		}
+	})
+}
+
+func TestRunGenerateCancelled(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "bls_key.json")
+	t.Setenv("KEY_PASSWORD", testPassword)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cmdContext = ctx
+	t.Cleanup(func() { cmdContext = context.Background() })
+
+	if err := runGenerate([]string{"--out", out}, &bytes.Buffer{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Fatal("key file written after cancellation")
+	}
+}
+
+// symlinkedKey writes a test key and returns it with a current.json
+// symlink pointing at it.
+func symlinkedKey(t *testing.T) (old *blskeys.KeyPair, link, target string) {
+	t.Helper()
+	old, target = writeTestKey(t)
+	link = filepath.Join(filepath.Dir(target), "current.json")
+	if err := os.Symlink(filepath.Base(target), link); err != nil {
+		t.Fatal(err)
+	}
+	return old, link, target
+}
+
+func TestGenerateFollowsSymlink(t *testing.T) {
+	old, link, target := symlinkedKey(t)
+	t.Setenv("KEY_PASSWORD", testPassword)
+	scriptedTerminal(t, false)
+
+	if err := runGenerate([]string{"--out", link, "--force"}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	if !blskeys.IsSymlink(link) {
+		t.Fatal("the symlink was replaced")
+	}
+	kp, err := blskeys.Load(target, testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(kp.PrivateKey.Bytes(), old.PrivateKey.Bytes()) {
+		t.Fatal("the link's target still holds the old key")
+	}
+	backups, _ := filepath.Glob(target + ".*.bak")
+	if len(backups) != 1 || blskeys.IsSymlink(backups[0]) {
+		t.Fatalf("backups = %v, want one regular copy of the target", backups)
+	}
+}
+
+func TestGenerateNoFollowSymlinks(t *testing.T) {
+	old, link, target := symlinkedKey(t)
+	t.Setenv("KEY_PASSWORD", testPassword)
+
+	err := runGenerate([]string{"--out", link, "--force", "--no-follow-symlinks"}, &bytes.Buffer{})
+	if !errors.Is(err, blskeys.ErrSymlink) {
+		t.Fatalf("got %v, want ErrSymlink", err)
+	}
+	if err := runPasswd([]string{"--key", link, "--new-password", testPassword + "-new", "--no-follow-symlinks"}, &bytes.Buffer{}); !errors.Is(err, blskeys.ErrSymlink) {
+		t.Fatalf("passwd: got %v, want ErrSymlink", err)
+	}
+	kp, err := blskeys.Load(target, testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(kp.PrivateKey.Bytes(), old.PrivateKey.Bytes()) {
+		t.Fatal("key was touched despite --no-follow-symlinks")
+	}
+}
+
+func TestGenerateReplaceSymlink(t *testing.T) {
+	old, link, target := symlinkedKey(t)
+	t.Setenv("KEY_PASSWORD", testPassword)
+	scriptedTerminal(t, false)
+
+	if err := runGenerate([]string{"--out", link, "--force", "--replace-symlink"}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	if blskeys.IsSymlink(link) {
+		t.Fatal("the symlink was not replaced")
+	}
+	kp, err := blskeys.Load(target, testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(kp.PrivateKey.Bytes(), old.PrivateKey.Bytes()) {
+		t.Fatal("the link's old target was modified")
+	}
+	if _, err := blskeys.Load(link, testPassword); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := parseFlags([]string{"--replace-symlink", "--no-follow-symlinks"}); exitCode(err) != exitUsage {
+		t.Fatalf("got %v, want a usage error", err)
+	}
+}
+
+func TestRunGenerateWithECDSA(t *testing.T) {
+	t.Setenv("KEY_PASSWORD", testPassword)
+	dir := t.TempDir()
+	ecdsaPasswordFile := filepath.Join(dir, "ecdsa_password")
+	if err := os.WriteFile(ecdsaPasswordFile, []byte("a separate ecdsa password\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		args          []string
+		ecdsaPassword string
+	}{
+		{"shared password", nil, testPassword},
+		{"separate password", []string{"--ecdsa-password-file", ecdsaPasswordFile}, "a separate ecdsa password"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := filepath.Join(t.TempDir(), "bls_key.json")
+			var stdout bytes.Buffer
+			args := append([]string{"--out", out, "--with-ecdsa", "--output", "json"}, tt.args...)
+			if err := runGenerate(args, &stdout); err != nil {
+				t.Fatal(err)
+			}
+			var res generateResult
+			if err := json.Unmarshal(stdout.Bytes(), &res); err != nil {
+				t.Fatal(err)
+			}
+			ecdsaPath := filepath.Join(filepath.Dir(out), "bls_key.ecdsa.json")
+			if res.ECDSAPath != ecdsaPath {
+				t.Fatalf("ecdsa_path %s, want %s", res.ECDSAPath, ecdsaPath)
+			}
+
+			if _, err := blskeys.Load(out, testPassword); err != nil {
+				t.Fatal(err)
+			}
+			k, err := blskeys.LoadECDSA(ecdsaPath, tt.ecdsaPassword)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer k.Zero()
+			meta, err := blskeys.LoadMetadata(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if meta.OperatorAddress != k.Address.Hex() || res.OperatorAddress != k.Address.Hex() {
+				t.Fatalf("recorded address %s (result %s), ECDSA key has %s", meta.OperatorAddress, res.OperatorAddress, k.Address.Hex())
+			}
+			if err := blskeys.CheckPermissions(ecdsaPath); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestRunGenerateWithECDSAUsage(t *testing.T) {
+	t.Setenv("KEY_PASSWORD", testPassword)
+	out := filepath.Join(t.TempDir(), "bls_key.json")
+	for _, args := range [][]string{
+		{"--out", out, "--ecdsa-out", "x.json"},
+		{"--out", out, "--with-ecdsa", "--format", "eip2335"},
+		{"--out", "-", "--with-ecdsa"},
+		{"--keydir", t.TempDir(), "--with-ecdsa", "--ecdsa-out", "x.json", "--count", "2"},
+	} {
+		if _, err := parseFlags(args); exitCode(err) != exitUsage {
+			t.Errorf("%v: got %v, want a usage error", args, err)
+		}
+	}
+
+	// An existing ECDSA keystore is not overwritten without --force.
+	if err := os.WriteFile(filepath.Join(filepath.Dir(out), "bls_key.ecdsa.json"), []byte("{}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := runGenerate([]string{"--out", out, "--with-ecdsa"}, &bytes.Buffer{}); !errors.Is(err, errKeyExists) {
+		t.Fatalf("got %v, want errKeyExists", err)
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Fatal("BLS key written although the ECDSA keystore exists")
+	}
+}
+
+func TestRunGeneratePasswordConfirmFile(t *testing.T) {
+	dir := t.TempDir()
+	passwordFile := filepath.Join(dir, "password")
+	confirmFile := filepath.Join(dir, "password_confirm")
+	typoFile := filepath.Join(dir, "password_typo")
+	for path, content := range map[string]string{
+		passwordFile: testPassword + "\n",
+		confirmFile:  testPassword,
+		typoFile:     testPassword + "x\n",
+	} {
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out := filepath.Join(t.TempDir(), "bls_key.json")
+	err := runGenerate([]string{"--out", out, "--password-file", passwordFile, "--password-confirm-file", typoFile}, &bytes.Buffer{})
+	if !errors.Is(err, errPasswordMismatch) {
+		t.Fatalf("got %v, want errPasswordMismatch", err)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(out)); len(entries) != 0 {
+		t.Fatalf("mismatched confirmation left files behind: %v", entries)
+	}
+
+	t.Setenv("KEY_PASSWORD", testPassword)
+	if err := runGenerate([]string{"--out", out, "--password-confirm-file", confirmFile}, &bytes.Buffer{}); exitCode(err) != exitUsage {
+		t.Fatalf("without --password-file: got %v, want a usage error", err)
+	}
+
+	if err := runGenerate([]string{"--out", out, "--password-file", passwordFile, "--password-confirm-file", confirmFile}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := blskeys.Load(out, testPassword); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunGenerateKeyPathIsDir(t *testing.T) {
+	t.Setenv("KEY_PASSWORD", testPassword)
+	out := filepath.Join(t.TempDir(), "bls_key.json")
+	if err := os.Mkdir(out, 0700); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"--out", out},
+		{"--out", out, "--force"},
+		{"--out", out, "--dry-run"},
+	} {
+		if err := runGenerate(args, &bytes.Buffer{}); !errors.Is(err, errKeyPathIsDir) {
+			t.Fatalf("%v: got %v, want errKeyPathIsDir", args, err)
+		}
+	}
+	// --force did not move the directory aside as a key backup.
+	if info, err := os.Stat(out); err != nil || !info.IsDir() {
+		t.Fatalf("key path directory was touched: %v", err)
+	}
+	if matches, _ := filepath.Glob(out + ".bak*"); len(matches) != 0 {
+		t.Fatalf("directory backed up as a key: %v", matches)
+	}
+}
+
+func TestRunGenerateReplacePlaceholder(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "bls_key.json")
+	writePlaceholderKey(t, out)
+	t.Setenv("KEY_PASSWORD", testPassword)
+
+	if err := runGenerate([]string{"--out", out}, &bytes.Buffer{}); !errors.Is(err, errKeyExists) {
+		t.Fatalf("without --replace-placeholder: got %v, want errKeyExists", err)
+	}
+	if err := runGenerate([]string{"--out", out, "--replace-placeholder"}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := blskeys.Load(out, testPassword); err != nil {
+		t.Fatalf("replacement is not a BLS key: %v", err)
+	}
+	backups, _ := filepath.Glob(out + ".*.bak")
+	if len(backups) != 1 {
+		t.Fatalf("expected one backup of the placeholder, got %v", backups)
+	}
+	if data, _ := os.ReadFile(backups[0]); !blskeys.IsLegacyPlaceholder(data) {
+		t.Fatal("backup is not the placeholder")
+	}
+
+	// A real key is not replaced by --replace-placeholder.
+	if err := runGenerate([]string{"--out", out, "--replace-placeholder"}, &bytes.Buffer{}); !errors.Is(err, errKeyExists) {
+		t.Fatalf("real key: got %v, want errKeyExists", err)
+	}
+}
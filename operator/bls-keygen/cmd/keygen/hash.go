@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+)
+
+// runHash implements `keygen hash`: it prints the response hash the task
+// manager computes for the task response --data, so an operator can check
+// the bytes it submits against the contract's. With --task-index it also
+// prints the message hash respondToTask checks the response signature
+// against for that task, and its eth-signed form, the hash the operator's
+// ECDSA key actually signs.
+func runHash(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("hash", flag.ContinueOnError)
+	data := fs.String("data", "", "hex-encoded task response data, as passed to respondToTask")
+	taskIndex := fs.Int64("task-index", -1, "also print the message hash respondToTask checks for this task")
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if *data == "" {
+		return usageErrorf("--data is required")
+	}
+	if *taskIndex > math.MaxUint32 {
+		return usageErrorf("--task-index must be at most %d", uint32(math.MaxUint32))
+	}
+
+	response, err := decodeHex(*data)
+	if err != nil {
+		return fmt.Errorf("invalid --data: %w", err)
+	}
+	h := bls.HashTaskResponse(response)
+	fmt.Fprintf(stdout, "Response hash: 0x%x\n", h)
+	if *taskIndex >= 0 {
+		m := bls.TaskResponseMessageHash(uint32(*taskIndex), response)
+		fmt.Fprintf(stdout, "Message hash (task %d): 0x%x\n", *taskIndex, m)
+		fmt.Fprintf(stdout, "Eth signed message hash: 0x%x\n", bls.EthSignedMessageHash(m))
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestRunHash(t *testing.T) {
+	// The volatility calc case of pkg/bls/testdata/task_responses.json.
+	data := "0x00000000000000000000000000000000000000000000000000000000000011940000000000000000000000000000000000000000000000000000000068e77800"
+	var out bytes.Buffer
+	if err := runHash([]string{"--data", data, "--task-index", "42"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	want := "Response hash: 0x41044814232e367a59fb79b07539ad670fe057f37dd27ea396cb9b854c8f66a6\n" +
+		"Message hash (task 42): 0x8c695885d07a50315dc6bacc45d1f206fab6500373f404d5554e5728078755cf\n" +
+		"Eth signed message hash: 0x45bee761c1f574c9f4b6a3ccd9050e6479db2c893fe035007c60fed804ce6346\n"
+	if out.String() != want {
+		t.Fatalf("output:\n%s\nwant:\n%s", out.String(), want)
+	}
+
+	out.Reset()
+	if err := runHash([]string{"--data", data}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "Message hash") {
+		t.Fatalf("message hash printed without --task-index:\n%s", out.String())
+	}
+
+	for _, args := range [][]string{
+		{},
+		{"--data", data, "--task-index", "4294967296"},
+	} {
+		if err := runHash(args, io.Discard); exitCode(err) != exitUsage {
+			t.Errorf("%v: got %v, want a usage error", args, err)
+		}
+	}
+	if err := runHash([]string{"--data", "0xzz"}, io.Discard); err == nil {
+		t.Error("invalid hex accepted")
+	}
+}
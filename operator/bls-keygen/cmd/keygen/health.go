@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// errNotReady is what /readyz reports until a readiness check is set.
+var errNotReady = errors.New("key not loaded")
+
+// errShuttingDown is what /readyz reports while serve drains its requests.
+var errShuttingDown = errors.New("shutting down")
+
+// healthOptions holds the --health-addr flag of the long-running modes.
+type healthOptions struct {
+	addr string
+}
+
+func (o *healthOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.addr, "health-addr", "", "serve /healthz and /readyz probes on this address, e.g. :8081 (default: disabled)")
+}
+
+// healthServer answers liveness and readiness probes. /healthz is 200 as
+// long as the process serves HTTP; /readyz is 200 only while the check set
+// by setReady passes, and 503 before. Neither says more than "ok" or why
+// it is not ready, so probes learn nothing about the key.
+//
+// A probe holds mu for reading while its check runs, so setReady returns
+// only once no probe is still running the check it replaces: after
+// setReady(nil) no probe touches the key, and it can be wiped.
+type healthServer struct {
+	mu    sync.RWMutex
+	ready func() error
+	srv   *http.Server
+}
+
+// start serves the probes on o.addr until ctx is done or close is called,
+// and returns the address it listens on. It returns a nil server when the
+// flag is unset, whose methods are no-ops.
+func (o *healthOptions) start(ctx context.Context) (*healthServer, net.Addr, error) {
+	if o.addr == "" {
+		return nil, nil, nil
+	}
+	ln, err := net.Listen("tcp", o.addr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to listen on --health-addr: %w", err)
+	}
+	h := &healthServer{}
+	h.srv = &http.Server{Handler: h.handler(), ReadHeaderTimeout: 5 * time.Second}
+	context.AfterFunc(ctx, h.close)
+	go func() {
+		if err := h.srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("health server stopped", "err", err)
+		}
+	}()
+	slog.Info("serving health checks", "addr", ln.Addr().String())
+	return h, ln.Addr(), nil
+}
+
+// close stops serving the probes.
+func (h *healthServer) close() {
+	if h == nil {
+		return
+	}
+	h.srv.Close()
+}
+
+// setReady makes /readyz run check on every probe. It waits for probes
+// still running the previous check.
+func (h *healthServer) setReady(check func() error) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.ready = check
+}
+
+func (h *healthServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if err := h.check(); err != nil {
+			http.Error(w, "not ready: "+err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+	return mux
+}
+
+func (h *healthServer) check() error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.ready == nil {
+		return errNotReady
+	}
+	return h.ready()
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHealthSetReadyWaitsForProbes(t *testing.T) {
+	h := &healthServer{}
+	entered, release := make(chan struct{}), make(chan struct{})
+	h.setReady(func() error {
+		close(entered)
+		<-release
+		return nil
+	})
+	probed := make(chan error, 1)
+	go func() { probed <- h.check() }()
+	<-entered
+
+	cleared := make(chan struct{})
+	go func() {
+		h.setReady(nil)
+		close(cleared)
+	}()
+	select {
+	case <-cleared:
+		t.Fatal("setReady returned while a probe was still running the old check")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	<-cleared
+	if err := <-probed; err != nil {
+		t.Fatal(err)
+	}
+	if err := h.check(); err != errNotReady {
+		t.Fatalf("after setReady(nil): got %v, want errNotReady", err)
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+// runImport implements `keygen import`: it wraps an existing raw private
+// key scalar, given as hex, in an encrypted key file.
+func runImport(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	privateKey := fs.String("private-key", "", "hex private key scalar to import (visible in the process list; prefer --private-key-file)")
+	privateKeyFile := fs.String("private-key-file", "", "file holding the hex private key scalar to import")
+	out := fs.String("out", filepath.Join(defaultKeyDir, defaultKeyFile), "path of the key file to write")
+	var pwSource passwordSource
+	pwSource.register(fs)
+	force := fs.Bool("force", false, "replace an existing key, backing it up first")
+	var policy passwordPolicy
+	policy.register(fs)
+	var perms permCheck
+	perms.register(fs)
+	var kdf kdfOptions
+	kdf.register(fs)
+	var network networkOptions
+	network.register(fs)
+	var label labelOption
+	label.register(fs)
+	var logOpts logOptions
+	logOpts.register(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if err := perms.validate(); err != nil {
+		return err
+	}
+	if err := logOpts.apply(); err != nil {
+		return err
+	}
+	resolved, err := perms.keyPath(*out)
+	if err != nil {
+		return err
+	}
+	*out = resolved
+
+	if (*privateKey == "") == (*privateKeyFile == "") {
+		return usageErrorf("exactly one of --private-key and --private-key-file is required")
+	}
+	params, err := kdf.params()
+	if err != nil {
+		return err
+	}
+	if _, err := network.domain(); err != nil {
+		return err
+	}
+	if err := label.validate(); err != nil {
+		return err
+	}
+
+	kp, err := readImportKey(*privateKey, *privateKeyFile)
+	if err != nil {
+		return err
+	}
+	defer kp.PrivateKey.Zero()
+
+	if err := checkNewKeyPath(*out, *force); err != nil {
+		return err
+	}
+	password, err := pwSource.readNew()
+	if err != nil {
+		return err
+	}
+	if err := policy.check(password); err != nil {
+		return err
+	}
+	if err := perms.ensureDir(filepath.Dir(*out)); err != nil {
+		return err
+	}
+	meta := network.metadata()
+	meta.Label = label.text
+	data, err := blskeys.MarshalContext(cmdContext, kp, password, params, meta)
+	if err != nil {
+		return err
+	}
+	if _, err := writeKeyFile(*out, data, *force); err != nil {
+		return err
+	}
+	if err := perms.check(*out); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "Key imported and saved to %s\n", *out)
+	fmt.Fprintf(stdout, "G1 public key: 0x%x\n", kp.G1PubKey.Bytes())
+	fmt.Fprintf(stdout, "Operator ID: 0x%x\n", bls.OperatorID(kp.G1PubKey))
+	return nil
+}
+
+// readImportKey parses the scalar from the flag or the file. Scalars that are
+// zero or not below the group order are rejected rather than reduced, so the
+// imported key is exactly the one the operator had.
+func readImportKey(flagValue, path string) (*blskeys.KeyPair, error) {
+	s := flagValue
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read private key file: %w", err)
+		}
+		defer bls.SecretBytes(data).Zero()
+		s = string(data)
+	} else {
+		slog.Warn("--private-key is visible to other users in the process list; prefer --private-key-file")
+	}
+
+	b, err := decodeHex(strings.TrimSpace(s))
+	if err != nil {
+		return nil, usageErrorf("private key is not valid hex: %v", err)
+	}
+	defer bls.SecretBytes(b).Zero()
+	sk, err := bls.PrivateKeyFromBytes(b)
+	if err != nil {
+		return nil, err
+	}
+	return bls.NewKeyPair(sk), nil
+}
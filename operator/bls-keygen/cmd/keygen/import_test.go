@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+// importScalar is 42, whose operator ID is pinned below.
+const (
+	importScalar     = "0x000000000000000000000000000000000000000000000000000000000000002a"
+	importOperatorID = "0xcf78a5a5c1f1e2dacd974cc58f091165782a8d4b6f442e61528dbde9d037729c"
+)
+
+func TestRunImport(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "raw.hex")
+	if err := os.WriteFile(keyFile, []byte(importScalar+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "keys", "bls_key.json")
+	t.Setenv("KEY_PASSWORD", testPassword)
+
+	var stdout bytes.Buffer
+	if err := runImport([]string{"--private-key-file", keyFile, "--out", out}, &stdout); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout.String(), importOperatorID) {
+		t.Errorf("output missing operator ID %s:\n%s", importOperatorID, stdout.String())
+	}
+
+	kp, err := blskeys.Load(out, testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprintf("0x%x", bls.OperatorID(kp.G1PubKey)); got != importOperatorID {
+		t.Fatalf("operator ID %s, want %s", got, importOperatorID)
+	}
+}
+
+func TestRunImportRejectsBadScalars(t *testing.T) {
+	t.Setenv("KEY_PASSWORD", testPassword)
+	for name, scalar := range map[string]string{
+		"zero": "0x" + strings.Repeat("00", 32),
+		// The BLS12-381 group order itself.
+		"out of field": "0x73eda753299d7d483339d80809a1d80553bda402fffe5bfeffffffff00000001",
+		"short":        "0x2a",
+	} {
+		t.Run(name, func(t *testing.T) {
+			out := filepath.Join(t.TempDir(), "bls_key.json")
+			err := runImport([]string{"--private-key", scalar, "--out", out}, &bytes.Buffer{})
+			if !errors.Is(err, bls.ErrInvalidPrivateKey) {
+				t.Fatalf("got %v, want ErrInvalidPrivateKey", err)
+			}
+			if _, err := os.Stat(out); !os.IsNotExist(err) {
+				t.Fatal("key file written for an invalid scalar")
+			}
+		})
+	}
+}
+
+func TestRunImportRequiresOneSource(t *testing.T) {
+	var usage usageError
+	if err := runImport(nil, &bytes.Buffer{}); !errors.As(err, &usage) {
+		t.Fatalf("got %v, want a usage error", err)
+	}
+}
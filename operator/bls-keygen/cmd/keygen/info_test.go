@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+func TestRunInfoNeedsNoPassword(t *testing.T) {
+	t.Setenv("KEY_PASSWORD", "")
+	kp, err := blskeys.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "bls_key.json")
+	kf, err := blskeys.Encrypt(kp, testPassword, blskeys.DefaultScryptParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kf.Metadata = &blskeys.Metadata{Network: "holesky", TestOnly: true}
+	// An encrypted blob that cannot even be hex-decoded: info must not
+	// look at it.
+	kf.Crypto.Ciphertext = "not a ciphertext"
+	data, err := json.Marshal(kf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := runInfo([]string{"--key", path, "--json"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	var got infoOutput
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Format != blskeys.FormatBastion || got.Version != blskeys.CurrentVersion || !got.Encrypted {
+		t.Errorf("format %s version %d encrypted %v", got.Format, got.Version, got.Encrypted)
+	}
+	if got.KDF != "scrypt" || got.KDFParams == nil || got.KDFParams.N != blskeys.DefaultScryptParams.N {
+		t.Errorf("kdf %s %+v, want scrypt with n=%d", got.KDF, got.KDFParams, blskeys.DefaultScryptParams.N)
+	}
+	if got.Network != "holesky" || !got.TestOnly {
+		t.Errorf("metadata network %q test_only %v", got.Network, got.TestOnly)
+	}
+	if want := fmt.Sprintf("0x%x", kp.G1PubKey.Bytes()); got.G1PubKey != want {
+		t.Errorf("g1 pubkey %s, want %s", got.G1PubKey, want)
+	}
+	if want := fmt.Sprintf("0x%x", bls.OperatorID(kp.G1PubKey)); got.OperatorID != want {
+		t.Errorf("operator id %s, want %s", got.OperatorID, want)
+	}
+	if got.Modified.IsZero() {
+		t.Error("no modification time")
+	}
+	if after, err := os.ReadFile(path); err != nil || !bytes.Equal(after, data) {
+		t.Fatalf("info changed the key file (err %v)", err)
+	}
+	if strings.Contains(out.String(), kf.Crypto.KDFParams.Salt) {
+		t.Error("info printed the salt")
+	}
+}
+
+func TestRunInfoFormats(t *testing.T) {
+	kp, path := writeTestKey(t)
+	dir := t.TempDir()
+	keystore := filepath.Join(dir, "keystore.json")
+	if err := blskeys.SaveEIP2335(kp, keystore, testPassword); err != nil {
+		t.Fatal(err)
+	}
+	legacy := filepath.Join(dir, "legacy.json")
+	if err := os.WriteFile(legacy, []byte(`{"private_key": "0x01"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{path, []string{"format: bastion (version 1)", "kdf: scrypt (n=1024 r=8 p=1 dklen=32)", "cipher: aes-256-gcm", "g2 pubkey:"}},
+		{keystore, []string{"format: eip2335 (version 4)", "cipher: aes-128-ctr", "operator id:"}},
+		{legacy, []string{"format: legacy (version 0)", "plaintext"}},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		if err := runInfo([]string{"--key", tt.path}, &out); err != nil {
+			t.Fatalf("%s: %v", tt.path, err)
+		}
+		// Ignore the column alignment.
+		text := strings.Join(strings.Fields(out.String()), " ")
+		for _, want := range tt.want {
+			if !strings.Contains(text, want) {
+				t.Errorf("%s: output lacks %q:\n%s", filepath.Base(tt.path), want, out.String())
+			}
+		}
+	}
+}
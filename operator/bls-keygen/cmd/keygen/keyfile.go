@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+// checkNewKeyPath is the check writeKeyFile makes, for commands to run
+// before they read passwords or spend time deriving a key. A directory at
+// path is an error, and so is an existing key unless replace, the command's
+// --force, is set; on a terminal replacing it has to be confirmed.
+func checkNewKeyPath(path string, replace bool) error {
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return keyPathIsDir(path)
+	}
+	if !replace {
+		return errKeyExists
+	}
+	return confirmOverwrite(path)
+}
+
+// writeKeyFile writes data, an encoded key file, to path. Every command
+// that produces a key writes it here, so none can destroy a key by
+// accident. Without replace the file is created only if nothing is at
+// path, even a key another process wrote after checkNewKeyPath, and
+// errKeyExists is returned otherwise. With replace an existing key is
+// first hard-linked to a timestamped backup, whose path is returned; it
+// stays in place until data is renamed over it, and the backup is removed
+// again if that fails. A symlinked path is written through, and the backup
+// is made beside the link's target.
+func writeKeyFile(path string, data []byte, replace bool) (backup string, err error) {
+	path, err = blskeys.ResolvePath(path)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		if err := blskeys.WriteNewFile(path, data); err != nil {
+			if errors.Is(err, fs.ErrExist) {
+				return "", errKeyExists
+			}
+			return "", err
+		}
+		return "", nil
+	case err != nil:
+		return "", err
+	case info.IsDir():
+		return "", keyPathIsDir(path)
+	case !replace:
+		return "", errKeyExists
+	}
+
+	backup, err = linkBackup(path, time.Now())
+	if err != nil {
+		return "", fmt.Errorf("failed to back up existing key: %w", err)
+	}
+	if err := blskeys.WriteFile(path, data); err != nil {
+		os.Remove(backup)
+		return "", err
+	}
+	slog.Info("existing key backed up", "path", backup)
+	return backup, nil
+}
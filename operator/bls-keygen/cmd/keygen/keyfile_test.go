@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/age"
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+// TestKeyProducingCommandsRefuseOverwrite runs every command that writes a
+// new key file at a path that already holds a key: without --force each
+// must fail with errKeyExists and leave the key alone, and with --force
+// replace it only after backing it up.
+func TestKeyProducingCommandsRefuseOverwrite(t *testing.T) {
+	t.Setenv("KEY_PASSWORD", testPassword)
+	scriptedTerminal(t, false)
+	dir := t.TempDir()
+
+	scalarFile := filepath.Join(dir, "raw.hex")
+	if err := os.WriteFile(scalarFile, []byte(importScalar+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	mnemonicFile := filepath.Join(dir, "mnemonic")
+	if err := os.WriteFile(mnemonicFile, []byte(testMnemonic+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, source := writeTestKey(t)
+	var split bytes.Buffer
+	if err := runSplit([]string{"--key", source, "--shares", "3", "--threshold", "2", "--out-dir", filepath.Join(dir, "shares")}, &split); err != nil {
+		t.Fatal(err)
+	}
+	shares := strings.Fields(split.String())
+
+	id, err := age.GenerateIdentity(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	identityFile := filepath.Join(dir, "ops.agekey")
+	if err := os.WriteFile(identityFile, []byte(id.String()+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	sealed := filepath.Join(dir, "sealed", "bls_key.json")
+	if err := runGenerate([]string{"--out", sealed, "--encrypt-to", id.Recipient().String()}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each command writes to out, a fresh path in its own directory, and
+	// target is the file in that directory it must not silently replace.
+	keyFile := func(out string) string { return out }
+	commands := map[string]struct {
+		target func(out string) string
+		run    func(out string, extra ...string) error
+	}{
+		"generate": {keyFile, func(out string, extra ...string) error {
+			return runGenerate(append([]string{"--out", out}, extra...), &bytes.Buffer{})
+		}},
+		"generate --with-ecdsa": {
+			func(out string) string { return strings.TrimSuffix(out, ".json") + ".ecdsa.json" },
+			func(out string, extra ...string) error {
+				return runGenerate(append([]string{"--out", out, "--with-ecdsa"}, extra...), &bytes.Buffer{})
+			},
+		},
+		"import": {keyFile, func(out string, extra ...string) error {
+			return runImport(append([]string{"--private-key-file", scalarFile, "--out", out}, extra...), &bytes.Buffer{})
+		}},
+		"derive": {keyFile, func(out string, extra ...string) error {
+			return runDerive(append([]string{"--mnemonic-file", mnemonicFile, "--out", out}, extra...), &bytes.Buffer{})
+		}},
+		"combine": {keyFile, func(out string, extra ...string) error {
+			return runCombine(append([]string{"--out", out, "--share", shares[0], "--share", shares[2]}, extra...), &bytes.Buffer{})
+		}},
+		"decrypt-age": {keyFile, func(out string, extra ...string) error {
+			return runDecryptAge(append([]string{"--identity", identityFile, "--in", sealed + ageSuffix, "--out", out}, extra...), &bytes.Buffer{})
+		}},
+		"split": {
+			func(out string) string { return sharePath(filepath.Dir(out), source, 2, 3) },
+			func(out string, extra ...string) error {
+				args := []string{"--key", source, "--shares", "3", "--threshold", "2", "--out-dir", filepath.Dir(out)}
+				return runSplit(append(args, extra...), &bytes.Buffer{})
+			},
+		},
+	}
+	for name, c := range commands {
+		t.Run(name, func(t *testing.T) {
+			out := filepath.Join(t.TempDir(), "bls_key.json")
+			path := c.target(out)
+			_, existing := writeTestKey(t)
+			original, err := os.ReadFile(existing)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, original, 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := c.run(out); !errors.Is(err, errKeyExists) {
+				t.Fatalf("without --force: got %v, want errKeyExists", err)
+			}
+			if got, _ := os.ReadFile(path); !bytes.Equal(got, original) {
+				t.Fatal("existing key modified without --force")
+			}
+			if backups, _ := filepath.Glob(path + ".*.bak"); len(backups) != 0 {
+				t.Fatalf("backups made without --force: %v", backups)
+			}
+
+			if err := c.run(out, "--force"); err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := os.ReadFile(path); bytes.Equal(got, original) {
+				t.Fatal("--force did not replace the key")
+			}
+			backups, _ := filepath.Glob(path + ".*.bak")
+			if len(backups) != 1 {
+				t.Fatalf("expected one backup, got %v", backups)
+			}
+			if got, _ := os.ReadFile(backups[0]); !bytes.Equal(got, original) {
+				t.Fatal("backup does not hold the old key")
+			}
+		})
+	}
+}
+
+func TestWriteKeyFileWithoutReplace(t *testing.T) {
+	// Commands check early, but writeKeyFile does not rely on it: a key
+	// written by another process since then is still not replaced.
+	_, path := writeTestKey(t)
+	original, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := writeKeyFile(path, []byte("{}"), false); !errors.Is(err, errKeyExists) {
+		t.Fatalf("got %v, want errKeyExists", err)
+	}
+	if got, _ := os.ReadFile(path); !bytes.Equal(got, original) {
+		t.Fatal("existing key replaced")
+	}
+}
+
+func TestWriteKeyFileThroughSymlink(t *testing.T) {
+	_, target := writeTestKey(t)
+	link := filepath.Join(t.TempDir(), "bls_key.json")
+	if err := os.Symlink(target, link); err != nil {
+		t.Skip("symlinks unavailable:", err)
+	}
+	backup, err := writeKeyFile(link, []byte("{}"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Dir(backup) != filepath.Dir(target) {
+		t.Fatalf("backup %s not beside the link's target %s", backup, target)
+	}
+	if !blskeys.IsSymlink(link) {
+		t.Fatal("symlink replaced by a regular file")
+	}
+	if got, _ := os.ReadFile(target); string(got) != "{}" {
+		t.Fatalf("target holds %q", got)
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/zalando/go-keyring"
+)
+
+// Values of --password-source.
+const (
+	passwordSourceEnv     = "env"
+	passwordSourceFile    = "file"
+	passwordSourceKeyring = "keyring"
+)
+
+// Default keyring entry read by --password-source keyring.
+const (
+	defaultKeyringService = "bastion-keygen"
+	defaultKeyringAccount = "bls-key"
+)
+
+// errKeyringUnavailable is returned when there is no OS keyring to read
+// from. --password-source keyring never falls back to another source.
+var errKeyringUnavailable = errors.New("no OS keyring is available on this platform")
+
+// keyringGet reads an entry from the OS keyring through go-keyring: the
+// Secret Service over D-Bus on Linux and the BSDs, the login keychain on
+// macOS and the Credential Manager on Windows.
+func keyringGet(service, account string) (string, error) {
+	password, err := keyring.Get(service, account)
+	switch {
+	case errors.Is(err, keyring.ErrUnsupportedPlatform):
+		return "", errKeyringUnavailable
+	case errors.Is(err, keyring.ErrNotFound):
+		return "", errors.New("no such entry")
+	}
+	return password, err
+}
+
+// passwordSource holds the flags choosing where a key's password comes
+// from. With no --password-source the password is read from --password-file,
+// then KEY_PASSWORD_FILE or KEY_PASSWORD, then an interactive prompt, as readPassword describes.
+type passwordSource struct {
+	file        string
+	confirmFile string
+	source      string
+	service     string
+	account     string
+}
+
+func (p *passwordSource) register(fs *flag.FlagSet) {
+	fs.StringVar(&p.file, "password-file", "", "read the password from this file instead of KEY_PASSWORD_FILE or KEY_PASSWORD")
+	fs.StringVar(&p.source, "password-source", "", "where the password comes from: env, file or keyring (default: --password-file, then KEY_PASSWORD_FILE or KEY_PASSWORD, then a prompt)")
+	fs.StringVar(&p.service, "keyring-service", defaultKeyringService, "OS keyring service name (--password-source keyring)")
+	fs.StringVar(&p.account, "keyring-account", defaultKeyringAccount, "OS keyring account name (--password-source keyring)")
+}
+
+// registerConfirm adds --password-confirm-file, for commands that encrypt
+// a key with a password read from a file.
+func (p *passwordSource) registerConfirm(fs *flag.FlagSet) {
+	fs.StringVar(&p.confirmFile, "password-confirm-file", "", "file holding the password again; the key is only written if it matches --password-file")
+}
+
+// read returns the password of an existing key.
+func (p *passwordSource) read() (string, error) {
+	return p.resolve(false)
+}
+
+// readNew returns the password for a key about to be encrypted; a prompt
+// asks for it twice, and a password file is checked against
+// --password-confirm-file when one is given.
+func (p *passwordSource) readNew() (string, error) {
+	if p.confirmFile != "" && p.file == "" {
+		return "", usageErrorf("--password-confirm-file needs --password-file")
+	}
+	password, err := p.resolve(true)
+	if err != nil || p.confirmFile == "" {
+		return password, err
+	}
+	again, err := readPasswordFile(p.confirmFile)
+	if err != nil {
+		return "", err
+	}
+	if !passwordsEqual(again, password) {
+		return "", fmt.Errorf("%w: --password-file and --password-confirm-file differ", errPasswordMismatch)
+	}
+	return password, nil
+}
+
+func (p *passwordSource) resolve(confirm bool) (string, error) {
+	switch p.source {
+	case "":
+		return resolvePassword(p.file, confirm)
+	case passwordSourceFile:
+		if p.file == "" {
+			return "", usageErrorf("--password-source file needs --password-file")
+		}
+		return readPasswordFile(p.file)
+	case passwordSourceEnv, passwordSourceKeyring:
+		if p.file != "" {
+			return "", usageErrorf("--password-file cannot be combined with --password-source %s", p.source)
+		}
+		if p.source == passwordSourceKeyring {
+			return readKeyringPassword(p.service, p.account)
+		}
+		if password, err := envPassword(); password != "" || err != nil {
+			return password, err
+		}
+		return "", errNoEnvPassword
+	default:
+		return "", usageErrorf("unknown --password-source %q", p.source)
+	}
+}
+
+// readKeyringPassword reads the password stored under service and account
+// in the OS keyring.
+func readKeyringPassword(service, account string) (string, error) {
+	password, err := keyringGet(service, account)
+	if errors.Is(err, errKeyringUnavailable) {
+		return "", fmt.Errorf("--password-source keyring: %w", err)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read password from keyring (service %q, account %q): %w", service, account, err)
+	}
+	if password == "" {
+		return "", fmt.Errorf("keyring entry (service %q, account %q) is empty", service, account)
+	}
+	return password, nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+	"github.com/zalando/go-keyring"
+)
+
+// The tests below run keyringGet against go-keyring's in-memory provider,
+// which keyring.MockInit installs in place of the OS keyring.
+
+func TestPasswordSourceKeyring(t *testing.T) {
+	kp, path := writeTestKey(t)
+	keyring.MockInit()
+	if err := keyring.Set("bastion", "operator-1", testPassword); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("KEY_PASSWORD", "") // must not be consulted
+
+	var out bytes.Buffer
+	args := []string{"--key", path, "--password-source", "keyring", "--keyring-service", "bastion", "--keyring-account", "operator-1"}
+	if err := runOperatorID(args, &out); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.TrimSpace(out.String()), fmt.Sprintf("0x%x", bls.OperatorID(kp.G1PubKey)); got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+
+	// The default entry does not exist in this keyring.
+	err := runOperatorID([]string{"--key", path, "--password-source", "keyring"}, &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "no such entry") {
+		t.Fatalf("missing keyring entry: got %v", err)
+	}
+}
+
+func TestPasswordSourceKeyringUnavailable(t *testing.T) {
+	_, path := writeTestKey(t)
+	keyring.MockInitWithError(keyring.ErrUnsupportedPlatform)
+	t.Setenv("KEY_PASSWORD", testPassword) // must not be a fallback
+
+	err := runOperatorID([]string{"--key", path, "--password-source", "keyring"}, &bytes.Buffer{})
+	if !errors.Is(err, errKeyringUnavailable) {
+		t.Fatalf("got %v, want errKeyringUnavailable", err)
+	}
+}
+
+func TestPasswordSourceConflicts(t *testing.T) {
+	var usage usageError
+	for _, p := range []passwordSource{
+		{source: passwordSourceFile},
+		{source: passwordSourceEnv, file: "pw"},
+		{source: passwordSourceKeyring, file: "pw"},
+		{source: "vault"},
+	} {
+		if _, err := p.read(); !errors.As(err, &usage) {
+			t.Errorf("%+v: got %v, want a usage error", p, err)
+		}
+	}
+}
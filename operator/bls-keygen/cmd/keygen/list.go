@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+// runList implements `keygen list`: it prints the public key, operator ID
+// and label of every key file in a directory. Only cleartext public keys
+// and metadata are read, so no password is needed; files that are not keys
+// are skipped.
+func runList(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	keyDir := fs.String("keydir", defaultKeyDir, "directory to list")
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(*keyDir)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".json") {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Slice(names, func(i, j int) bool { return keyFileLess(names[i], names[j]) })
+
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "INDEX\tFILE\tG1 PUBKEY\tOPERATOR ID\tLABEL")
+	index := 0
+	for _, name := range names {
+		pk, err := blskeys.LoadPublicKey(filepath.Join(*keyDir, name))
+		if err != nil {
+			slog.Debug("skipping non-key file", "file", name, "reason", err)
+			continue
+		}
+		var label string
+		if meta, err := blskeys.LoadMetadata(filepath.Join(*keyDir, name)); err == nil && meta != nil {
+			label = meta.Label
+		}
+		id := bls.OperatorID(pk)
+		fmt.Fprintf(w, "%d\t%s\t0x%x\t0x%x\t%s\n", index, name, pk.Bytes(), id, label)
+		index++
+	}
+	return w.Flush()
+}
+
+// keyFileLess orders key-<i>.json files by index, before any other names.
+func keyFileLess(a, b string) bool {
+	ia, aok := keyFileIndex(a)
+	ib, bok := keyFileIndex(b)
+	switch {
+	case aok && bok:
+		return ia < ib
+	case aok != bok:
+		return aok
+	default:
+		return a < b
+	}
+}
+
+func keyFileIndex(name string) (int, bool) {
+	i, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, "key-"), ".json"))
+	return i, err == nil && indexedKeyFile(i) == name
+}
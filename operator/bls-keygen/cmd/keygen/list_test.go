@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+func TestRunList(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("KEY_PASSWORD", testPassword)
+	if err := runGenerate([]string{"--keydir", dir, "--count", "12"}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		"notes.json":  `{"hello":"world"}`,
+		"broken.json": `{`,
+		"README":      "not json",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub.json"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := runList([]string{"--keydir", dir}, &out); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 13 {
+		t.Fatalf("expected a header and 12 keys, got:\n%s", out.String())
+	}
+	for i, line := range lines[1:] {
+		path := filepath.Join(dir, indexedKeyFile(i))
+		kp, err := blskeys.Load(path, testPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		id := bls.OperatorID(kp.G1PubKey)
+		want := fmt.Sprintf("%d  %s  0x%x  0x%x", i, indexedKeyFile(i), kp.G1PubKey.Bytes(), id)
+		if strings.Join(strings.Fields(line), "  ") != want {
+			t.Errorf("line %d = %q, want %q", i, line, want)
+		}
+	}
+}
+
+func TestRunGenerateCount(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("KEY_PASSWORD", testPassword)
+	if err := runGenerate([]string{"--keydir", dir, "--count", "3"}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+
+	seen := map[string]bool{}
+	for i := 0; i < 3; i++ {
+		kp, err := blskeys.Load(filepath.Join(dir, indexedKeyFile(i)), testPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen[fmt.Sprintf("%x", kp.PrivateKey.Bytes())] = true
+	}
+	if len(seen) != 3 {
+		t.Fatal("generated keys are not distinct")
+	}
+	if _, err := os.Stat(filepath.Join(dir, defaultKeyFile)); !os.IsNotExist(err) {
+		t.Fatalf("--count also wrote %s", defaultKeyFile)
+	}
+
+	if err := runGenerate([]string{"--keydir", dir, "--count", "4"}, &bytes.Buffer{}); err != errKeyExists {
+		t.Fatalf("got %v, want errKeyExists when some keys already exist", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, indexedKeyFile(3))); !os.IsNotExist(err) {
+		t.Fatal("a partial run wrote a new key")
+	}
+}
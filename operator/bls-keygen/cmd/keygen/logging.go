@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// logOutput is where log records go; tests swap it for a buffer.
+var logOutput io.Writer = os.Stderr
+
+// logOptions holds the flags selecting the log level and format. Nothing
+// secret is ever passed to the logger, whatever the level.
+type logOptions struct {
+	level  string
+	format string
+}
+
+func (o *logOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.level, "log-level", "info", "log level: debug, info, warn or error")
+	fs.StringVar(&o.format, "log-format", "text", "log format: text or json")
+}
+
+// apply installs the logger described by o as the slog default.
+func (o *logOptions) apply() error {
+	logger, err := newLogger(logOutput, o.level, o.format)
+	if err != nil {
+		return usageError{err}
+	}
+	slog.SetDefault(logger)
+	return nil
+}
+
+func newLogger(w io.Writer, level, format string) (*slog.Logger, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("unknown log level %q", level)
+	}
+	opts := &slog.HandlerOptions{Level: l}
+
+	switch strings.ToLower(format) {
+	case "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
+	}
+}
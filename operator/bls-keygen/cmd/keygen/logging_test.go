@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+// captureLogs sends log records to the returned buffer for the rest of the test.
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	origOut, origLogger := logOutput, slog.Default()
+	logOutput = &buf
+	t.Cleanup(func() {
+		logOutput = origOut
+		slog.SetDefault(origLogger)
+	})
+	return &buf
+}
+
+func TestGenerateLogLevels(t *testing.T) {
+	tests := []struct {
+		level, format string
+		want, notWant []string
+	}{
+		{"debug", "text", []string{"level=DEBUG", "level=INFO", "BLS key pair generated"}, nil},
+		{"info", "json", []string{`"level":"INFO"`, `"operator_id":"0x`}, []string{"DEBUG"}},
+		{"warn", "text", []string{"level=WARN"}, []string{"level=INFO", "level=DEBUG"}},
+		{"error", "text", nil, []string{"level="}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.level+"/"+tt.format, func(t *testing.T) {
+			logs := captureLogs(t)
+			t.Setenv("KEY_PASSWORD", testPassword)
+			out := filepath.Join(t.TempDir(), "bls_key.json")
+
+			args := []string{"--out", out, "--log-level", tt.level, "--log-format", tt.format}
+			if err := runGenerate(args, &bytes.Buffer{}); err != nil {
+				t.Fatal(err)
+			}
+			kp, err := blskeys.Load(out, testPassword)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := logs.String()
+			for _, w := range tt.want {
+				if !strings.Contains(got, w) {
+					t.Errorf("logs missing %q:\n%s", w, got)
+				}
+			}
+			for _, w := range tt.notWant {
+				if strings.Contains(got, w) {
+					t.Errorf("logs unexpectedly contain %q:\n%s", w, got)
+				}
+			}
+			if strings.Contains(got, hex.EncodeToString(kp.PrivateKey.Bytes())) {
+				t.Fatal("private key appears in the logs")
+			}
+			if strings.Contains(got, testPassword) {
+				t.Fatal("password appears in the logs")
+			}
+		})
+	}
+}
+
+func TestNewLoggerRejectsUnknownOptions(t *testing.T) {
+	if _, err := newLogger(&bytes.Buffer{}, "verbose", "text"); err == nil {
+		t.Error("expected an error for an unknown level")
+	}
+	if _, err := newLogger(&bytes.Buffer{}, "info", "xml"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
@@ -1,75 +1,214 @@
 package main
 
 import (
-	"crypto/rand"
-	"encoding/json"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"os"
+	"os/signal"
 
-	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
 )
 
-type BLSKeyPair struct {
-	PrivateKey string `json:"private_key"`
-	PublicKey  string `json:"public_key"`
-	G1PubKey   string `json:"g1_pub_key"`
-	G2PubKey   string `json:"g2_pub_key"`
+// version is the keygen build, recorded in the metadata of the keys it
+// creates. Release builds set it with
+// -ldflags "-X main.version=v1.2.3".
+var version = "dev"
+
+func init() {
+	blskeys.GeneratorVersion = "keygen " + version
 }
 
-func main() {
-	log.Println("🔐 Bastion BLS Key Generator")
+const (
+	formatBastion = "bastion"
+	formatEIP2335 = "eip2335"
+	formatBinary  = "binary"
+	formatPEM     = "pem"
+)
 
-	keyPath := "/keys/bls_key.json"
-	password := os.Getenv("KEY_PASSWORD")
+// Exit codes. Scripts can rely on these; exitCode maps errors onto them.
+const (
+	exitOK    = 0
+	exitError = 1
+	// exitUsage is returned for bad flags or arguments.
+	exitUsage = 2
+	// exitKeyExists is returned when generation is skipped because a key
+	// already exists, so scripts can tell it apart from success and failure.
+	exitKeyExists = 3
+	// exitKeyNotFound, exitBadPassword, exitCorruptKeyfile and
+	// exitInvalidKey are returned when a key file cannot be loaded, one per
+	// blskeys sentinel error.
+	exitKeyNotFound    = 4
+	exitBadPassword    = 5
+	exitCorruptKeyfile = 6
+	exitInvalidKey     = 7
+	// exitInterrupted is returned when SIGINT cancels the command.
+	exitInterrupted = 130
+)
+
+// usageError marks an error caused by how the command was invoked.
+type usageError struct {
+	err error
+}
 
-	if password == "" {
-		log.Fatal("❌ KEY_PASSWORD environment variable not set")
+func (e usageError) Error() string { return e.err.Error() }
+func (e usageError) Unwrap() error { return e.err }
+
+// usageErrorf formats a usageError.
+func usageErrorf(format string, a ...interface{}) error {
+	return usageError{fmt.Errorf(format, a...)}
+}
+
+// parseArgs parses args into fs, reporting bad flags as usage errors, then
+// fills the flags not given from their BASTION_ environment variables. The
+// flag package has already printed the usage text for a bad flag.
+func parseArgs(fs *flag.FlagSet, args []string) error {
+	if err := parseCommandLine(fs, args); err != nil {
+		return err
 	}
+	for _, w := range unknownEnvWarnings() {
+		slog.Warn(w)
+	}
+	return applyEnv(fs)
+}
 
-	// Check if key already exists
-	if _, err := os.Stat(keyPath); err == nil {
-		log.Println("⚠️  BLS key already exists, skipping generation")
-		log.Println("   To regenerate, delete", keyPath)
-		return
+// parseCommandLine parses args into fs without consulting the environment.
+func parseCommandLine(fs *flag.FlagSet, args []string) error {
+	if flagProbe != nil {
+		flagProbe(fs)
+		return flag.ErrHelp
 	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return usageError{err}
+	}
+	return nil
+}
 
-	log.Println("📝 Generating new BLS key pair...")
+// cmdContext is cancelled by SIGINT; long-running steps such as key
+// generation and the KDF give up when it is done.
+var cmdContext = context.Background()
 
-	// Generate BLS key (simplified - in production use proper BLS library)
-	// For now, generate ECDSA key as placeholder
-	privateKey, err := crypto.GenerateKey()
-	if err != nil {
-		log.Fatal("❌ Failed to generate key:", err)
-	}
+// commands are the keygen subcommands. Running without one generates a key.
+var commands = map[string]func(args []string, stdout io.Writer) error{
+	"aggregate":        runAggregate,
+	"assert":           runAssert,
+	"audit-verify":     runAuditVerify,
+	"bench":            runBench,
+	"combine":          runCombine,
+	"decrypt-age":      runDecryptAge,
+	"derive":           runDerive,
+	"doctor":           runDoctor,
+	"export":           runExport,
+	"fingerprint":      runFingerprint,
+	"import":           runImport,
+	"info":             runInfo,
+	"generate":         runGenerate,
+	"hash":             runHash,
+	"list":             runList,
+	"migrate":          runMigrate,
+	"operator-id":      runOperatorID,
+	"passwd":           runPasswd,
+	"pop":              runPoP,
+	"pubkey":           runPubkey,
+	"register-payload": runRegisterPayload,
+	"rekey":            runRekey,
+	"relabel":          runRelabel,
+	"repair-pubkeys":   runRepairPubkeys,
+	"rotate":           runRotate,
+	"serve":            runServe,
+	"sign":             runSign,
+	"sign-batch":       runSignBatch,
+	"sign-typed":       runSignTyped,
+	"split":            runSplit,
+	"testvectors":      runTestVectors,
+	"verify":           runVerify,
+	"verify-mnemonic":  runVerifyMnemonic,
+	"watch":            runWatch,
+	"whois":            runWhois,
+}
 
-	privateKeyBytes := crypto.FromECDSA(privateKey)
-	publicKeyBytes := crypto.FromECDSAPub(&privateKey.PublicKey)
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	cmdContext = ctx
 
-	keyPair := BLSKeyPair{
-		PrivateKey: fmt.Sprintf("0x%x", privateKeyBytes),
-		PublicKey:  fmt.Sprintf("0x%x", publicKeyBytes),
-		G1PubKey:   fmt.Sprintf("0x%x", publicKeyBytes[:32]),  // Simplified
-		G2PubKey:   fmt.Sprintf("0x%x", publicKeyBytes[32:]),  // Simplified
+	err := run(os.Args[1:], os.Stdout)
+	switch code := exitCode(err); code {
+	case exitOK:
+	case exitKeyExists:
+		slog.Warn(err.Error())
+		os.Exit(code)
+	case exitInterrupted:
+		slog.Warn("interrupted, no key written")
+		os.Exit(code)
+	default:
+		if hint := errorHint(err, code); hint != "" {
+			slog.Error(err.Error(), "hint", hint)
+		} else {
+			slog.Error(err.Error())
+		}
+		os.Exit(code)
 	}
+}
 
-	// Save to file
-	jsonData, err := json.MarshalIndent(keyPair, "", "  ")
-	if err != nil {
-		log.Fatal("❌ Failed to marshal JSON:", err)
+// run dispatches args to a subcommand, or to generate when the first
+// argument names none.
+func run(args []string, stdout io.Writer) error {
+	if len(args) > 0 {
+		if cmd, ok := commands[args[0]]; ok {
+			return cmd(args[1:], stdout)
+		}
 	}
+	return runGenerate(args, stdout)
+}
 
-	// Create keys directory if it doesn't exist
-	os.MkdirAll("/keys", 0700)
-
-	if err := os.WriteFile(keyPath, jsonData, 0600); err != nil {
-		log.Fatal("❌ Failed to write key file:", err)
+// exitCode maps the error returned by run to the process exit status.
+func exitCode(err error) int {
+	var usage usageError
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.Is(err, errKeyExists):
+		return exitKeyExists
+	case errors.Is(err, context.Canceled):
+		return exitInterrupted
+	case errors.As(err, &usage):
+		return exitUsage
+	case errors.Is(err, blskeys.ErrKeyNotFound):
+		return exitKeyNotFound
+	case errors.Is(err, blskeys.ErrBadPassword):
+		return exitBadPassword
+	case errors.Is(err, blskeys.ErrCorruptKeyfile):
+		return exitCorruptKeyfile
+	case errors.Is(err, blskeys.ErrInvalidKey), errors.Is(err, blskeys.ErrNotInSubgroup):
+		return exitInvalidKey
+	default:
+		return exitError
 	}
+}
 
-	log.Println("✅ BLS key pair generated successfully!")
-	log.Println("📁 Key saved to:", keyPath)
-	log.Println("🔑 Public Key (G1):", keyPair.G1PubKey[:20]+"...")
-	log.Println("")
-	log.Println("⚠️  IMPORTANT: Backup this key securely!")
-	log.Println("   The private key is needed to sign AVS responses")
+// errorHint suggests what to do about a key file that failed to load with
+// err and the given exit code.
+func errorHint(err error, code int) string {
+	if errors.Is(err, blskeys.ErrLegacyPlaceholder) {
+		return "run keygen generate --replace-placeholder to replace it with a real BLS key, then register the new key"
+	}
+	switch code {
+	case exitKeyNotFound:
+		return "check --key, or run keygen to create a key"
+	case exitBadPassword:
+		return "check the password and how it is passed (KEY_PASSWORD, KEY_PASSWORD_FILE or --password-file)"
+	case exitCorruptKeyfile:
+		return "restore the key file from a backup"
+	case exitInvalidKey:
+		return "run keygen doctor, or repair-pubkeys if only the public keys are wrong"
+	default:
+		return ""
+	}
 }
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+const testPassword = "correct horse battery staple"
+
+func TestMain(m *testing.M) {
+	// Keep the KDF cheap so tests run quickly.
+	blskeys.DefaultScryptParams = blskeys.KDFParams{N: 1 << 10, R: 8, P: 1, DKLen: 32}
+	blskeys.DefaultPBKDF2Params.C = 1000
+	blskeys.MinScryptN, blskeys.MinPBKDF2Iterations = 1<<10, 1000
+	logOutput = io.Discard
+	// The key-writing commands refuse root, which test containers often
+	// run as.
+	geteuid = func() int { return 1000 }
+	// Tests set KEY_PASSWORD, which a secret file from the environment
+	// would override.
+	os.Unsetenv("KEY_PASSWORD_FILE")
+	os.Exit(m.Run())
+}
+
+// writeTestKey generates a key, saves it under a temp dir with testPassword
+// and returns it with its path.
+func writeTestKey(t *testing.T) (*blskeys.KeyPair, string) {
+	t.Helper()
+	kp, err := blskeys.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "bls_key.json")
+	if err := blskeys.Save(kp, path, testPassword); err != nil {
+		t.Fatal(err)
+	}
+	return kp, path
+}
+
+func TestRunExitCodes(t *testing.T) {
+	kp, existing := writeTestKey(t)
+	t.Setenv("KEY_PASSWORD", testPassword)
+	fresh := filepath.Join(t.TempDir(), "bls_key.json")
+	dir := t.TempDir()
+	otherPassword := filepath.Join(dir, "other_password.json")
+	if err := blskeys.Save(kp, otherPassword, "another password"); err != nil {
+		t.Fatal(err)
+	}
+	corrupt := filepath.Join(dir, "corrupt.json")
+	if err := os.WriteFile(corrupt, []byte("{not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	other, err := blskeys.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	kf, err := blskeys.Encrypt(kp, testPassword, blskeys.DefaultScryptParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kf.G2PubKey = fmt.Sprintf("0x%x", other.G2PubKey.Bytes())
+	kf.Checksum = ""
+	kf.Version = 0 // version 0 files may lack the checksum
+	invalid := filepath.Join(dir, "invalid.json")
+	data, err := json.Marshal(kf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(invalid, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"success", []string{"--out", fresh}, exitOK},
+		{"subcommand success", []string{"pubkey", "--key", existing}, exitOK},
+		{"help", []string{"sign", "-h"}, exitOK},
+		{"unknown flag", []string{"--no-such-flag"}, exitUsage},
+		{"unknown subcommand flag", []string{"sign", "--no-such-flag"}, exitUsage},
+		{"missing required flag", []string{"sign", "--key", existing}, exitUsage},
+		{"bad flag value", []string{"--format", "xml"}, exitUsage},
+		{"bad log level", []string{"--log-level", "loud", "--out", fresh}, exitUsage},
+		{"key exists", []string{"--out", existing}, exitKeyExists},
+		{"runtime error", []string{"pubkey", "--key", t.TempDir()}, exitError},
+		{"key not found", []string{"pubkey", "--key", filepath.Join(t.TempDir(), "missing.json")}, exitKeyNotFound},
+		{"bad password", []string{"operator-id", "--key", otherPassword}, exitBadPassword},
+		{"corrupt key file", []string{"operator-id", "--key", corrupt}, exitCorruptKeyfile},
+		{"invalid key", []string{"operator-id", "--key", invalid}, exitInvalidKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := run(tt.args, io.Discard)
+			if got := exitCode(err); got != tt.want {
+				t.Fatalf("exit code %d (err %v), want %d", got, err, tt.want)
+			}
+		})
+	}
+}
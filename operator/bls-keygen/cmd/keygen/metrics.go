@@ -0,0 +1,92 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// signMetrics are the Prometheus metrics of the sign and aggregate paths,
+// labelled by operation. A nil *signMetrics records nothing, which is the
+// state unless a long-running mode has started the metrics server.
+type signMetrics struct {
+	total    *prometheus.CounterVec
+	errors   *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+// activeMetrics is set once the metrics server is started.
+var activeMetrics *signMetrics
+
+func newSignMetrics(reg prometheus.Registerer) *signMetrics {
+	m := &signMetrics{
+		total: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "bastion_bls_sign_total",
+			Help: "BLS sign and aggregate operations attempted.",
+		}, []string{"operation"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "bastion_bls_sign_errors_total",
+			Help: "BLS sign and aggregate operations that failed.",
+		}, []string{"operation"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "bastion_bls_sign_duration_seconds",
+			Help:    "Duration of BLS sign and aggregate operations, including key decryption.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"operation"}),
+	}
+	reg.MustRegister(m.total, m.errors, m.duration)
+	return m
+}
+
+// observe records one operation that started at start and returned err.
+func (m *signMetrics) observe(operation string, start time.Time, err error) {
+	if m == nil {
+		return
+	}
+	m.total.WithLabelValues(operation).Inc()
+	if err != nil {
+		m.errors.WithLabelValues(operation).Inc()
+	}
+	m.duration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+// metricsOptions holds the --metrics-addr flag. Only long-running modes
+// register it: the one-shot sign and aggregate commands exit before a
+// scrape could reach the server.
+type metricsOptions struct {
+	addr string
+}
+
+func (o *metricsOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.addr, "metrics-addr", "", "serve Prometheus metrics on this address, e.g. :9100 (default: disabled)")
+}
+
+// start serves /metrics on o.addr for the rest of the process. It is a
+// no-op when the flag is unset, and only the first call starts a server.
+func (o *metricsOptions) start() error {
+	if o.addr == "" || activeMetrics != nil {
+		return nil
+	}
+	ln, err := net.Listen("tcp", o.addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on --metrics-addr: %w", err)
+	}
+
+	reg := prometheus.NewRegistry()
+	activeMetrics = newSignMetrics(reg)
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+	go func() {
+		if err := http.Serve(ln, mux); err != nil {
+			slog.Error("metrics server stopped", "err", err)
+		}
+	}()
+	slog.Info("serving metrics", "addr", ln.Addr().String())
+	return nil
+}
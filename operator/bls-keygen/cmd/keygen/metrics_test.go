@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// useTestMetrics installs fresh metrics for the test and returns a server
+// exposing them.
+func useTestMetrics(t *testing.T) *httptest.Server {
+	t.Helper()
+	reg := prometheus.NewRegistry()
+	activeMetrics = newSignMetrics(reg)
+	srv := httptest.NewServer(promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+	t.Cleanup(func() {
+		srv.Close()
+		activeMetrics = nil
+	})
+	return srv
+}
+
+func scrape(t *testing.T, srv *httptest.Server) string {
+	t.Helper()
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func TestSignMetrics(t *testing.T) {
+	srv := useTestMetrics(t)
+	_, path := writeTestKey(t)
+	t.Setenv("KEY_PASSWORD", testPassword)
+
+	if err := runSign([]string{"--key", path, "--message", "0xdeadbeef"}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := runSign([]string{"--key", path, "--message", "zz"}, &bytes.Buffer{}); err == nil {
+		t.Fatal("expected an error for a non-hex message")
+	}
+
+	body := scrape(t, srv)
+	for _, want := range []string{
+		`bastion_bls_sign_total{operation="sign"} 2`,
+		`bastion_bls_sign_errors_total{operation="sign"} 1`,
+		`bastion_bls_sign_duration_seconds_count{operation="sign"} 2`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics missing %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestMetricsDisabled(t *testing.T) {
+	// With no --metrics-addr nothing is recorded, and a nil receiver is safe.
+	if activeMetrics != nil {
+		t.Fatal("metrics enabled without --metrics-addr")
+	}
+	var opts metricsOptions
+	if err := opts.start(); err != nil || activeMetrics != nil {
+		t.Fatalf("start with no address: err=%v, metrics=%v", err, activeMetrics)
+	}
+	activeMetrics.observe("sign", time.Now(), nil)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+// runMigrate implements `keygen migrate`: it rewrites a legacy key file in
+// the current encrypted format.
+func runMigrate(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	keyPath := fs.String("key", filepath.Join(defaultKeyDir, defaultKeyFile), "key file to migrate")
+	var pwSource passwordSource
+	pwSource.register(fs)
+	var policy passwordPolicy
+	policy.register(fs)
+	var perms permCheck
+	perms.register(fs)
+	var logOpts logOptions
+	logOpts.register(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if err := perms.validate(); err != nil {
+		return err
+	}
+	if err := logOpts.apply(); err != nil {
+		return err
+	}
+	resolved, err := perms.keyPath(*keyPath)
+	if err != nil {
+		return err
+	}
+	*keyPath = resolved
+
+	password, err := pwSource.readNew()
+	if err != nil {
+		return err
+	}
+	if err := policy.check(password); err != nil {
+		return err
+	}
+
+	migrated, err := blskeys.Migrate(*keyPath, password)
+	if err != nil {
+		return err
+	}
+	if err := perms.check(*keyPath); err != nil {
+		return err
+	}
+	if !migrated {
+		fmt.Fprintf(stdout, "%s is already at version %d\n", *keyPath, blskeys.CurrentVersion)
+		return nil
+	}
+	fmt.Fprintf(stdout, "Migrated %s to version %d\n", *keyPath, blskeys.CurrentVersion)
+	return nil
+}
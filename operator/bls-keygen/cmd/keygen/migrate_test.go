@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+func TestRunMigrate(t *testing.T) {
+	kp, err := blskeys.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "bls_key.json")
+	legacy := fmt.Sprintf(`{"private_key": "0x%x"}`, kp.PrivateKey.Bytes())
+	if err := os.WriteFile(path, []byte(legacy), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("KEY_PASSWORD", testPassword)
+
+	if err := runMigrate([]string{"--key", path}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := blskeys.Load(path, testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(loaded.PrivateKey.Bytes(), kp.PrivateKey.Bytes()) {
+		t.Fatal("migrated key does not match")
+	}
+	if _, err := blskeys.Load(path, "wrong password"); err == nil {
+		t.Fatal("migrated key is not encrypted")
+	}
+}
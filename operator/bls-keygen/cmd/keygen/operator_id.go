@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+)
+
+// runOperatorID implements `keygen operator-id`: it prints the operator ID
+// of the stored key, as computed by bls.OperatorID.
+func runOperatorID(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("operator-id", flag.ContinueOnError)
+	keyPath := fs.String("key", filepath.Join(defaultKeyDir, defaultKeyFile), "key file to read")
+	var pwSource passwordSource
+	pwSource.register(fs)
+	var pin keyPin
+	pin.register(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if err := pin.validate(); err != nil {
+		return err
+	}
+
+	password, err := pwSource.read()
+	if err != nil {
+		return err
+	}
+	kp, err := loadKey(*keyPath, password)
+	if err != nil {
+		return fmt.Errorf("failed to load key %s: %w", *keyPath, err)
+	}
+	defer kp.PrivateKey.Zero()
+	if err := pin.check(*keyPath, kp.G1PubKey); err != nil {
+		return err
+	}
+
+	id := bls.OperatorID(kp.G1PubKey)
+	fmt.Fprintf(stdout, "0x%x\n", id)
+	return nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+func TestRunOperatorID(t *testing.T) {
+	kp, path := writeTestKey(t)
+	t.Setenv("KEY_PASSWORD", testPassword)
+
+	var out bytes.Buffer
+	if err := runOperatorID([]string{"--key", path}, &out); err != nil {
+		t.Fatal(err)
+	}
+	id := bls.OperatorID(kp.G1PubKey)
+	if got, want := strings.TrimSpace(out.String()), fmt.Sprintf("0x%x", id); got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+}
+
+func TestRunOperatorIDBadPassword(t *testing.T) {
+	_, path := writeTestKey(t)
+	t.Setenv("KEY_PASSWORD", "not the password")
+
+	if err := runOperatorID([]string{"--key", path}, &bytes.Buffer{}); !errors.Is(err, blskeys.ErrDecrypt) {
+		t.Fatalf("got %v, want ErrDecrypt", err)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+// runPasswd implements `keygen passwd`: it re-encrypts the key under a new
+// password, keeping the key and so the operator ID.
+func runPasswd(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("passwd", flag.ContinueOnError)
+	keyPath := fs.String("key", filepath.Join(defaultKeyDir, defaultKeyFile), "key file to re-encrypt")
+	var pwSource passwordSource
+	pwSource.register(fs)
+	oldPassword := fs.String("old-password", "", "current password of the key (default: the usual password)")
+	newPassword := fs.String("new-password", "", "password to re-encrypt the key with")
+	newPasswordFile := fs.String("new-password-file", "", "read the new password from this file")
+	var policy passwordPolicy
+	policy.register(fs)
+	var perms permCheck
+	perms.register(fs)
+	var logOpts logOptions
+	logOpts.register(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if err := perms.validate(); err != nil {
+		return err
+	}
+	if err := logOpts.apply(); err != nil {
+		return err
+	}
+	resolved, err := perms.keyPath(*keyPath)
+	if err != nil {
+		return err
+	}
+	*keyPath = resolved
+	if *newPassword != "" && *newPasswordFile != "" {
+		return usageErrorf("--new-password and --new-password-file are mutually exclusive")
+	}
+
+	if *oldPassword == "" {
+		password, err := pwSource.read()
+		if err != nil {
+			return err
+		}
+		*oldPassword = password
+	}
+	switch {
+	case *newPassword != "":
+	case *newPasswordFile != "":
+		password, err := readPasswordFile(*newPasswordFile)
+		if err != nil {
+			return err
+		}
+		*newPassword = password
+	case stdinTerminal.isTerminal():
+		password, err := stdinTerminal.prompt(true)
+		if err != nil {
+			return err
+		}
+		*newPassword = password
+	default:
+		return usageErrorf("--new-password or --new-password-file is required")
+	}
+	if *newPassword == *oldPassword {
+		return usageErrorf("the new password is the same as the current one")
+	}
+	if err := policy.check(*newPassword); err != nil {
+		return err
+	}
+
+	if err := blskeys.ChangePassword(*keyPath, *oldPassword, *newPassword); err != nil {
+		return fmt.Errorf("failed to change password of %s: %w", *keyPath, err)
+	}
+	if err := perms.check(*keyPath); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "Key %s re-encrypted with the new password\n", *keyPath)
+	if pk, err := blskeys.LoadPublicKey(*keyPath); err == nil {
+		fmt.Fprintf(stdout, "Operator ID: 0x%x\n", bls.OperatorID(pk))
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+func TestRunPasswd(t *testing.T) {
+	kp, path := writeTestKey(t)
+	t.Setenv("KEY_PASSWORD", testPassword)
+	const newPassword = "Another-Strong-Passphrase-99"
+
+	if err := runPasswd([]string{"--key", path, "--new-password", newPassword}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := blskeys.Load(path, newPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(loaded.G2PubKey.Bytes(), kp.G2PubKey.Bytes()) {
+		t.Fatal("public key changed")
+	}
+	if _, err := blskeys.Load(path, testPassword); !errors.Is(err, blskeys.ErrDecrypt) {
+		t.Fatalf("old password: got %v, want ErrDecrypt", err)
+	}
+}
+
+func TestRunPasswdRequiresNewPassword(t *testing.T) {
+	_, path := writeTestKey(t)
+	t.Setenv("KEY_PASSWORD", testPassword)
+
+	if err := runPasswd([]string{"--key", path}, &bytes.Buffer{}); exitCode(err) != exitUsage {
+		t.Fatalf("no new password: got %v, want a usage error", err)
+	}
+	if err := runPasswd([]string{"--key", path, "--new-password", testPassword}, &bytes.Buffer{}); exitCode(err) != exitUsage {
+		t.Fatalf("unchanged password: got %v, want a usage error", err)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+)
+
+// keyPin holds the --pin-file and --repin flags of the commands that load a
+// private key. The first load records the key's identity in the pin file
+// (trust on first use); later loads fail with errKeyMismatch if the key file
+// has since been swapped for a different key.
+type keyPin struct {
+	path  string
+	repin bool
+}
+
+// pinnedKey is the content of a pin file.
+type pinnedKey struct {
+	OperatorID  string `json:"operator_id"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+func (p *keyPin) register(fs *flag.FlagSet) {
+	fs.StringVar(&p.path, "pin-file", "", "record the key's identity here on first use and refuse a different key later")
+	fs.BoolVar(&p.repin, "repin", false, "replace the identity recorded in --pin-file with the loaded key's, after an intentional key change")
+}
+
+// validate rejects --repin without a pin file.
+func (p *keyPin) validate() error {
+	if p.repin && p.path == "" {
+		return usageErrorf("--repin needs --pin-file")
+	}
+	return nil
+}
+
+// check compares the key loaded from keyPath against the pin file, writing
+// the pin file if it does not exist yet or --repin is set. It is a no-op
+// without --pin-file.
+func (p *keyPin) check(keyPath string, g1 *bls.G1PubKey) error {
+	if p.path == "" {
+		return nil
+	}
+	id := bls.OperatorID(g1)
+	found := pinnedKey{OperatorID: fmt.Sprintf("0x%x", id), Fingerprint: bls.Fingerprint(g1)}
+
+	data, err := os.ReadFile(p.path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		if err := p.write(found); err != nil {
+			return err
+		}
+		slog.Info("pinned key identity on first use", "pin_file", p.path, "operator_id", found.OperatorID, "fingerprint", found.Fingerprint)
+		return nil
+	case err != nil:
+		return fmt.Errorf("failed to read pin file: %w", err)
+	}
+	var pinned pinnedKey
+	if err := json.Unmarshal(data, &pinned); err != nil || pinned.OperatorID == "" {
+		return fmt.Errorf("pin file %s is not valid, remove it or pass --repin", p.path)
+	}
+	if pinned.OperatorID == found.OperatorID {
+		return nil
+	}
+	if p.repin {
+		if err := p.write(found); err != nil {
+			return err
+		}
+		slog.Warn("repinned key identity", "pin_file", p.path, "old_operator_id", pinned.OperatorID, "operator_id", found.OperatorID)
+		return nil
+	}
+	return fmt.Errorf("%w: %s is not the key pinned in %s (pass --repin if the key was changed on purpose)\n  pinned operator ID %s (fingerprint %s)\n  found  operator ID %s (fingerprint %s)",
+		errKeyMismatch, keyPath, p.path, pinned.OperatorID, pinned.Fingerprint, found.OperatorID, found.Fingerprint)
+}
+
+func (p *keyPin) write(k pinnedKey) error {
+	data, err := json.MarshalIndent(k, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(p.path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write pin file: %w", err)
+	}
+	return nil
+}
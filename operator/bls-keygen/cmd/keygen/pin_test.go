@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+)
+
+func readPin(t *testing.T, path string) pinnedKey {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var p pinnedKey
+	if err := json.Unmarshal(data, &p); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestPinFirstUse(t *testing.T) {
+	kp, path := writeTestKey(t)
+	t.Setenv("KEY_PASSWORD", testPassword)
+	pinFile := filepath.Join(t.TempDir(), "key.pin")
+
+	for i := 0; i < 2; i++ {
+		if err := runSign([]string{"--key", path, "--message", "0x01", "--pin-file", pinFile}, &bytes.Buffer{}); err != nil {
+			t.Fatalf("run %d: %v", i, err)
+		}
+	}
+	pin := readPin(t, pinFile)
+	if want := fmt.Sprintf("0x%x", bls.OperatorID(kp.G1PubKey)); pin.OperatorID != want {
+		t.Fatalf("pinned operator ID %s, want %s", pin.OperatorID, want)
+	}
+	if pin.Fingerprint != bls.Fingerprint(kp.G1PubKey) {
+		t.Fatalf("pinned fingerprint %s, want %s", pin.Fingerprint, bls.Fingerprint(kp.G1PubKey))
+	}
+	// Other commands honor the same pin.
+	if err := runPoP([]string{"--key", path, "--pin-file", pinFile}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPinDetectsSwap(t *testing.T) {
+	_, path := writeTestKey(t)
+	_, other := writeTestKey(t)
+	t.Setenv("KEY_PASSWORD", testPassword)
+	pinFile := filepath.Join(t.TempDir(), "key.pin")
+
+	if err := runOperatorID([]string{"--key", path, "--pin-file", pinFile}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	before := readPin(t, pinFile)
+
+	// Swap the key file for another key.
+	data, err := os.ReadFile(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	for name, run := range map[string]func() error{
+		"sign": func() error {
+			return runSign([]string{"--key", path, "--message", "0x01", "--pin-file", pinFile}, &out)
+		},
+		"sign-batch": func() error {
+			return runSignBatch([]string{"--key", path, "--messages-file", writeMessages(t), "--pin-file", pinFile}, &out)
+		},
+		"pop": func() error { return runPoP([]string{"--key", path, "--pin-file", pinFile}, &out) },
+	} {
+		err := run()
+		if !errors.Is(err, errKeyMismatch) || !strings.Contains(err.Error(), before.OperatorID) {
+			t.Errorf("%s: got %v, want a mismatch naming the pinned operator ID", name, err)
+		}
+	}
+	if out.Len() != 0 {
+		t.Fatalf("a swapped key was used:\n%s", out.String())
+	}
+	if readPin(t, pinFile) != before {
+		t.Fatal("a mismatch changed the pin file")
+	}
+}
+
+func TestRepin(t *testing.T) {
+	_, path := writeTestKey(t)
+	newKP, other := writeTestKey(t)
+	t.Setenv("KEY_PASSWORD", testPassword)
+	pinFile := filepath.Join(t.TempDir(), "key.pin")
+
+	if err := runOperatorID([]string{"--key", path, "--pin-file", pinFile}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := runOperatorID([]string{"--key", other, "--pin-file", pinFile, "--repin"}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := readPin(t, pinFile).OperatorID, fmt.Sprintf("0x%x", bls.OperatorID(newKP.G1PubKey)); got != want {
+		t.Fatalf("repinned operator ID %s, want %s", got, want)
+	}
+	if err := runOperatorID([]string{"--key", other, "--pin-file", pinFile}, &bytes.Buffer{}); err != nil {
+		t.Fatalf("repinned key rejected: %v", err)
+	}
+	if err := runOperatorID([]string{"--key", path, "--pin-file", pinFile}, &bytes.Buffer{}); !errors.Is(err, errKeyMismatch) {
+		t.Fatalf("old key after repin: got %v, want errKeyMismatch", err)
+	}
+
+	if err := runOperatorID([]string{"--key", path, "--repin"}, &bytes.Buffer{}); exitCode(err) != exitUsage {
+		t.Fatalf("--repin without --pin-file: got %v, want a usage error", err)
+	}
+}
+
+func writeMessages(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "messages.jsonl")
+	if err := os.WriteFile(path, []byte("0x01\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
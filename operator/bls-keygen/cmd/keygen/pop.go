@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+)
+
+// runPoP implements `keygen pop`: it prints the proof of possession of the
+// stored key under the PoP tag of --network.
+func runPoP(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("pop", flag.ContinueOnError)
+	keyPath := fs.String("key", filepath.Join(defaultKeyDir, defaultKeyFile), "key file to read")
+	var pwSource passwordSource
+	pwSource.register(fs)
+	var pin keyPin
+	pin.register(fs)
+	var network networkOptions
+	network.register(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if err := pin.validate(); err != nil {
+		return err
+	}
+	domain, err := network.domain()
+	if err != nil {
+		return err
+	}
+	warnNetworkMismatch(*keyPath, network.name)
+
+	password, err := pwSource.read()
+	if err != nil {
+		return err
+	}
+	kp, err := loadKey(*keyPath, password)
+	if err != nil {
+		return fmt.Errorf("failed to load key %s: %w", *keyPath, err)
+	}
+	defer kp.PrivateKey.Zero()
+	if err := pin.check(*keyPath, kp.G1PubKey); err != nil {
+		return err
+	}
+
+	pop, err := bls.ProofOfPossessionDomain(kp, domain)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "0x%x\n", pop.Bytes())
+	return nil
+}
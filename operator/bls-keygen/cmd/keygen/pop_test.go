@@ -0,0 +1,31 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+)
+
+func TestRunPoP(t *testing.T) {
+	kp, path := writeTestKey(t)
+	t.Setenv("KEY_PASSWORD", testPassword)
+
+	var out bytes.Buffer
+	if err := runPoP([]string{"--key", path}, &out); err != nil {
+		t.Fatal(err)
+	}
+	b, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(out.String()), "0x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pop, err := bls.SignatureFromBytes(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bls.VerifyPoP(kp.G2PubKey, pop) {
+		t.Fatal("printed proof of possession does not verify")
+	}
+}
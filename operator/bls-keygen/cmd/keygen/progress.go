@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+// progressOut is where the key derivation indicator is drawn. It is nil
+// unless stderr is a terminal, so scripts and logs never see it.
+var progressOut = func() io.Writer {
+	if term.IsTerminal(int(os.Stderr.Fd())) {
+		return os.Stderr
+	}
+	return nil
+}
+
+// progressInterval is how often the indicator adds a dot.
+var progressInterval = 500 * time.Millisecond
+
+// loadKey decrypts the key file at path, showing "Deriving key..." while
+// the KDF runs in interactive sessions. It gives up when cmdContext is done.
+func loadKey(path, password string) (*blskeys.KeyPair, error) {
+	return blskeys.LoadContext(cmdContext, path, password, kdfProgress(progressOut()))
+}
+
+// kdfProgress draws a line of dots on w between KDFStarted and KDFFinished.
+// It returns nil, which LoadContext accepts, when w is nil.
+func kdfProgress(w io.Writer) blskeys.KDFProgress {
+	if w == nil {
+		return nil
+	}
+	var stop chan struct{}
+	var wg sync.WaitGroup
+	return func(e blskeys.KDFEvent) {
+		switch e {
+		case blskeys.KDFStarted:
+			fmt.Fprint(w, "Deriving key")
+			stop = make(chan struct{})
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				t := time.NewTicker(progressInterval)
+				defer t.Stop()
+				for {
+					select {
+					case <-t.C:
+						fmt.Fprint(w, ".")
+					case <-stop:
+						return
+					}
+				}
+			}()
+		case blskeys.KDFFinished:
+			close(stop)
+			wg.Wait()
+			fmt.Fprintln(w, " done")
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+func TestKDFProgress(t *testing.T) {
+	if kdfProgress(nil) != nil {
+		t.Fatal("progress drawn without a terminal")
+	}
+
+	old := progressInterval
+	progressInterval = time.Millisecond
+	t.Cleanup(func() { progressInterval = old })
+
+	var out bytes.Buffer
+	progress := kdfProgress(&out)
+	progress(blskeys.KDFStarted)
+	time.Sleep(20 * time.Millisecond)
+	progress(blskeys.KDFFinished)
+
+	got := out.String()
+	if !strings.HasPrefix(got, "Deriving key.") || !strings.HasSuffix(got, " done\n") {
+		t.Fatalf("progress output %q", got)
+	}
+}
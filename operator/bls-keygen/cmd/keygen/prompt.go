@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/term"
+)
+
+var errPasswordMismatch = errors.New("passwords do not match")
+
+// terminal is the interactive input used for password prompts. It is a
+// struct of funcs so tests can script the prompt flow.
+type terminal struct {
+	isTerminal   func() bool
+	readPassword func() ([]byte, error)
+	readLine     func() (string, error)
+	out          io.Writer
+}
+
+var stdinTerminal = &terminal{
+	isTerminal:   func() bool { return term.IsTerminal(int(os.Stdin.Fd())) },
+	readPassword: func() ([]byte, error) { return term.ReadPassword(int(os.Stdin.Fd())) },
+	readLine:     func() (string, error) { return bufio.NewReader(os.Stdin).ReadString('\n') },
+	out:          os.Stderr,
+}
+
+// prompt reads a password without echo, asking a second time when confirm is set.
+func (t *terminal) prompt(confirm bool) (string, error) {
+	password, err := t.ask("Key password: ")
+	if err != nil {
+		return "", err
+	}
+	if password == "" {
+		return "", errors.New("password must not be empty")
+	}
+	if confirm {
+		again, err := t.ask("Confirm password: ")
+		if err != nil {
+			return "", err
+		}
+		if !passwordsEqual(again, password) {
+			return "", errPasswordMismatch
+		}
+	}
+	return password, nil
+}
+
+// passwordsEqual compares a password with its confirmation in constant
+// time, so how long the check takes says nothing about where they differ.
+func passwordsEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+func (t *terminal) ask(prompt string) (string, error) {
+	fmt.Fprint(t.out, prompt)
+	b, err := t.readPassword()
+	fmt.Fprintln(t.out)
+	if err != nil {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+	return string(b), nil
+}
+
+// askLine prints prompt and reads an echoed line of input.
+func (t *terminal) askLine(prompt string) (string, error) {
+	fmt.Fprint(t.out, prompt)
+	line, err := t.readLine()
+	if err != nil && line == "" {
+		return "", fmt.Errorf("failed to read input: %w", err)
+	}
+	return line, nil
+}
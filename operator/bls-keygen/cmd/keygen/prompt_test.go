@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// scriptedTerminal installs a fake terminal that answers prompts with lines
+// in order, restoring the real one when the test ends.
+func scriptedTerminal(t *testing.T, tty bool, lines ...string) *bytes.Buffer {
+	t.Helper()
+	var out bytes.Buffer
+	next := func() (string, error) {
+		if len(lines) == 0 {
+			return "", errors.New("no more input")
+		}
+		line := lines[0]
+		lines = lines[1:]
+		return line, nil
+	}
+	orig := stdinTerminal
+	stdinTerminal = &terminal{
+		isTerminal: func() bool { return tty },
+		readPassword: func() ([]byte, error) {
+			line, err := next()
+			return []byte(line), err
+		},
+		readLine: next,
+		out:      &out,
+	}
+	t.Cleanup(func() { stdinTerminal = orig })
+	return &out
+}
+
+func TestPromptConfirm(t *testing.T) {
+	t.Setenv("KEY_PASSWORD", "")
+
+	out := scriptedTerminal(t, true, testPassword, testPassword)
+	got, err := readNewPassword("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != testPassword {
+		t.Fatalf("password = %q", got)
+	}
+	if !bytes.Contains(out.Bytes(), []byte("Confirm password")) {
+		t.Fatal("new password was not confirmed")
+	}
+
+	scriptedTerminal(t, true, testPassword, "typo")
+	if _, err := readNewPassword(""); !errors.Is(err, errPasswordMismatch) {
+		t.Fatalf("mismatch: got %v, want errPasswordMismatch", err)
+	}
+
+	out = scriptedTerminal(t, true, testPassword)
+	if _, err := readPassword(""); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(out.Bytes(), []byte("Confirm password")) {
+		t.Fatal("existing-key password should not be confirmed")
+	}
+}
+
+func TestPromptNotTerminal(t *testing.T) {
+	t.Setenv("KEY_PASSWORD", "")
+	scriptedTerminal(t, false, testPassword, testPassword)
+	if _, err := readNewPassword(""); err == nil {
+		t.Fatal("expected an error without a terminal")
+	}
+}
+
+func TestPromptEnvTakesPrecedence(t *testing.T) {
+	t.Setenv("KEY_PASSWORD", "from-env")
+	scriptedTerminal(t, true) // any prompt would fail with no input
+	got, err := readPassword("")
+	if err != nil || got != "from-env" {
+		t.Fatalf("got %q, %v", got, err)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+)
+
+// pubkeyOutput is the --json form of `keygen pubkey`. The coordinate
+// points use the same EIP-2537 encoding as register-payload.
+type pubkeyOutput struct {
+	G1PubKey string  `json:"g1_pub_key"`
+	G2PubKey string  `json:"g2_pub_key"`
+	G1Point  g1Point `json:"g1_point"`
+	G2Point  g2Point `json:"g2_point"`
+}
+
+// runPubkey implements `keygen pubkey`: it prints the public keys of the
+// stored key, compressed and as EIP-2537 coordinates.
+func runPubkey(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("pubkey", flag.ContinueOnError)
+	keyPath := fs.String("key", filepath.Join(defaultKeyDir, defaultKeyFile), "key file to read")
+	var pwSource passwordSource
+	pwSource.register(fs)
+	var pin keyPin
+	pin.register(fs)
+	asJSON := fs.Bool("json", false, "print machine-readable JSON")
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if err := pin.validate(); err != nil {
+		return err
+	}
+
+	password, err := pwSource.read()
+	if err != nil {
+		return err
+	}
+	kp, err := loadKey(*keyPath, password)
+	if err != nil {
+		return fmt.Errorf("failed to load key %s: %w", *keyPath, err)
+	}
+	defer kp.PrivateKey.Zero()
+	if err := pin.check(*keyPath, kp.G1PubKey); err != nil {
+		return err
+	}
+
+	out := pubkeyOutput{
+		G1PubKey: fmt.Sprintf("0x%x", kp.G1PubKey.Bytes()),
+		G2PubKey: fmt.Sprintf("0x%x", kp.G2PubKey.Bytes()),
+		G1Point:  g1PubKeyToG1Point(kp.G1PubKey),
+		G2Point:  g2PubKeyToG2Point(kp.G2PubKey),
+	}
+	if *asJSON {
+		data, err := json.MarshalIndent(out, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, string(data))
+		return nil
+	}
+
+	fmt.Fprintf(stdout, "G1PubKey: %s\n", out.G1PubKey)
+	fmt.Fprintf(stdout, "  X: %s\n", out.G1Point.X)
+	fmt.Fprintf(stdout, "  Y: %s\n", out.G1Point.Y)
+	fmt.Fprintf(stdout, "G2PubKey: %s\n", out.G2PubKey)
+	fmt.Fprintf(stdout, "  X: [%s, %s]\n", out.G2Point.X[0], out.G2Point.X[1])
+	fmt.Fprintf(stdout, "  Y: [%s, %s]\n", out.G2Point.Y[0], out.G2Point.Y[1])
+	return nil
+}
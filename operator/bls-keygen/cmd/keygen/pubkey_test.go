@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestRunPubkeyJSON(t *testing.T) {
+	kp, path := writeTestKey(t)
+	t.Setenv("KEY_PASSWORD", testPassword)
+
+	var out bytes.Buffer
+	if err := runPubkey([]string{"--key", path, "--json"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	var got pubkeyOutput
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out.String(), err)
+	}
+
+	if want := fmt.Sprintf("0x%x", kp.G1PubKey.Bytes()); got.G1PubKey != want {
+		t.Errorf("g1_pub_key = %s, want %s", got.G1PubKey, want)
+	}
+	if want := fmt.Sprintf("0x%x", kp.G2PubKey.Bytes()); got.G2PubKey != want {
+		t.Errorf("g2_pub_key = %s, want %s", got.G2PubKey, want)
+	}
+	word := func(v *big.Int) string { return fmt.Sprintf("0x%0128x", v) }
+	x, y := kp.G1PubKey.Coordinates()
+	if got.G1Point.X != word(x) || got.G1Point.Y != word(y) {
+		t.Errorf("g1_point = %+v, want X=%s Y=%s", got.G1Point, word(x), word(y))
+	}
+	x0, x1, y0, y1 := kp.G2PubKey.Coordinates()
+	if got.G2Point.X != [2]string{word(x0), word(x1)} || got.G2Point.Y != [2]string{word(y0), word(y1)} {
+		t.Errorf("g2_point = %+v does not match the stored key", got.G2Point)
+	}
+}
+
+func TestRunPubkeyText(t *testing.T) {
+	kp, path := writeTestKey(t)
+	t.Setenv("KEY_PASSWORD", testPassword)
+
+	var out bytes.Buffer
+	if err := runPubkey([]string{"--key", path}, &out); err != nil {
+		t.Fatal(err)
+	}
+	x, _ := kp.G1PubKey.Coordinates()
+	for _, want := range []string{
+		fmt.Sprintf("G1PubKey: 0x%x", kp.G1PubKey.Bytes()),
+		fmt.Sprintf("G2PubKey: 0x%x", kp.G2PubKey.Bytes()),
+		fmt.Sprintf("X: 0x%0128x", x),
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+}
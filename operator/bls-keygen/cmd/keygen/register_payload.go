@@ -0,0 +1,229 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+)
+
+// registrationPayload follows the field layout of the arguments of
+// RegistryCoordinator.registerOperator, but for BLS12-381 keys. EigenLayer's
+// deployed contracts are BN254: they map the message hash with
+// BN254.hashToG1 and take uint256 coordinates, so they reject this payload.
+// It is meant for a BLS12-381 registry built on the EIP-2537 precompiles,
+// hence coordinates are EIP-2537 field elements (see g1Point).
+//
+// With --quorums there is one payload per quorum. Only the quorum number
+// differs between them: the registration hash and signature cover the
+// operator, not the quorum, so the key, hash and signature are the same in
+// each.
+type registrationPayload struct {
+	Curve                         string             `json:"curve"`
+	Operator                      string             `json:"operator"`
+	QuorumNumber                  *uint8             `json:"quorumNumber,omitempty"`
+	QuorumNumbers                 string             `json:"quorumNumbers,omitempty"`
+	PubkeyRegistrationMessageHash string             `json:"pubkeyRegistrationMessageHash"`
+	PubkeyRegistrationParams      pubkeyRegistration `json:"pubkeyRegistrationParams"`
+	OperatorSignature             saltAndExpiry      `json:"operatorSignature"`
+}
+
+type pubkeyRegistration struct {
+	PubkeyRegistrationSignature g1Point `json:"pubkeyRegistrationSignature"`
+	PubkeyG1                    g1Point `json:"pubkeyG1"`
+	PubkeyG2                    g2Point `json:"pubkeyG2"`
+}
+
+// payloadCurve names the curve of every point in a payload.
+const payloadCurve = "BLS12-381"
+
+// g1Point holds affine coordinates as 0x-prefixed 64-byte big-endian hex,
+// the EIP-2537 encoding of a BLS12-381 base field element. They are 381-bit
+// values and do not fit a uint256.
+type g1Point struct {
+	X string `json:"X"`
+	Y string `json:"Y"`
+}
+
+// g2Point holds Fp2 coordinates in EIP-2537 order, c0 (real part) then c1.
+type g2Point struct {
+	X [2]string `json:"X"`
+	Y [2]string `json:"Y"`
+}
+
+type saltAndExpiry struct {
+	Salt   string `json:"salt"`
+	Expiry uint64 `json:"expiry"`
+}
+
+// runRegisterPayload implements `keygen register-payload`: it signs the
+// RegistryCoordinator pubkey registration hash with the BLS12-381 key and
+// prints the registration params, or with --quorums a JSON array of them,
+// one per quorum. The result is not accepted by EigenLayer's BN254
+// registries; see registrationPayload.
+func runRegisterPayload(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("register-payload", flag.ContinueOnError)
+	keyPath := fs.String("key", filepath.Join(defaultKeyDir, defaultKeyFile), "key file to register")
+	var pwSource passwordSource
+	pwSource.register(fs)
+	var pin keyPin
+	pin.register(fs)
+	operatorHex := fs.String("operator", "", "operator address")
+	coordinatorHex := fs.String("registry-coordinator", "", "RegistryCoordinator address (EIP-712 verifying contract)")
+	chainID := fs.Uint64("chain-id", 0, "chain id of the registry")
+	saltHex := fs.String("salt", "", "32-byte hex salt for the operator signature")
+	expiry := fs.Uint64("expiry", 0, "operator signature expiry (unix seconds)")
+	allowTestKey := fs.Bool("allow-test-key", false, "register a key made with generate --test even on mainnet (--chain-id 1)")
+	var quorums quorumList
+	fs.Var(&quorums, "quorums", "comma-separated quorum numbers, e.g. 0,1,2, to print a payload for each")
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if err := pin.validate(); err != nil {
+		return err
+	}
+	if *operatorHex == "" || *coordinatorHex == "" || *chainID == 0 || *saltHex == "" || *expiry == 0 {
+		return errors.New("--operator, --registry-coordinator, --chain-id, --salt and --expiry are required")
+	}
+
+	operator, err := parseAddress(*operatorHex)
+	if err != nil {
+		return fmt.Errorf("invalid --operator: %w", err)
+	}
+	coordinator, err := parseAddress(*coordinatorHex)
+	if err != nil {
+		return fmt.Errorf("invalid --registry-coordinator: %w", err)
+	}
+	salt, err := decodeHex(*saltHex)
+	if err != nil || len(salt) != 32 {
+		return usageErrorf("invalid --salt: must be 32 bytes of hex")
+	}
+
+	if err := checkTestKey(*keyPath, *chainID == mainnetChainID, *allowTestKey); err != nil {
+		return err
+	}
+
+	password, err := pwSource.read()
+	if err != nil {
+		return err
+	}
+	kp, err := loadKey(*keyPath, password)
+	if err != nil {
+		return fmt.Errorf("failed to load key %s: %w", *keyPath, err)
+	}
+	defer kp.PrivateKey.Zero()
+	if err := pin.check(*keyPath, kp.G1PubKey); err != nil {
+		return err
+	}
+
+	msgHash := bls.PubkeyRegistrationHash(operator, new(big.Int).SetUint64(*chainID), coordinator)
+	sig, err := kp.Sign(msgHash[:])
+	if err != nil {
+		return fmt.Errorf("failed to sign registration hash: %w", err)
+	}
+
+	slog.Warn("register-payload emits BLS12-381 points, which EigenLayer's BN254 BLSApkRegistry rejects; use it only with a BLS12-381 registry")
+	payload := registrationPayload{
+		Curve:                         payloadCurve,
+		Operator:                      fmt.Sprintf("0x%x", operator),
+		PubkeyRegistrationMessageHash: fmt.Sprintf("0x%x", msgHash),
+		PubkeyRegistrationParams: pubkeyRegistration{
+			PubkeyRegistrationSignature: signatureToG1Point(sig),
+			PubkeyG1:                    g1PubKeyToG1Point(kp.G1PubKey),
+			PubkeyG2:                    g2PubKeyToG2Point(kp.G2PubKey),
+		},
+		OperatorSignature: saltAndExpiry{Salt: fmt.Sprintf("0x%x", salt), Expiry: *expiry},
+	}
+	var v any = payload
+	if len(quorums) > 0 {
+		payloads := make([]registrationPayload, len(quorums))
+		for i, q := range quorums {
+			q := q
+			p := payload
+			p.QuorumNumber = &q
+			p.QuorumNumbers = fmt.Sprintf("0x%02x", q)
+			payloads[i] = p
+		}
+		v = payloads
+	}
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, string(out))
+	return nil
+}
+
+// quorumList is a flag.Value holding comma-separated quorum numbers, kept
+// in ascending order as the RegistryCoordinator expects them.
+type quorumList []uint8
+
+func (l *quorumList) String() string {
+	parts := make([]string, len(*l))
+	for i, q := range *l {
+		parts[i] = strconv.Itoa(int(q))
+	}
+	return strings.Join(parts, ",")
+}
+
+func (l *quorumList) Set(s string) error {
+	var quorums quorumList
+	for _, part := range strings.Split(s, ",") {
+		q, err := strconv.ParseUint(strings.TrimSpace(part), 10, 8)
+		if err != nil {
+			return fmt.Errorf("%q is not a quorum number from 0 to 255", part)
+		}
+		if slices.Contains(quorums, uint8(q)) {
+			return fmt.Errorf("quorum %d is listed twice", q)
+		}
+		quorums = append(quorums, uint8(q))
+	}
+	slices.Sort(quorums)
+	*l = quorums
+	return nil
+}
+
+func parseAddress(s string) ([20]byte, error) {
+	var addr [20]byte
+	b, err := decodeHex(s)
+	if err != nil {
+		return addr, err
+	}
+	if len(b) != len(addr) {
+		return addr, fmt.Errorf("address must be 20 bytes, got %d", len(b))
+	}
+	copy(addr[:], b)
+	return addr, nil
+}
+
+// wordHex formats an EIP-2537 field element.
+func wordHex(w [64]byte) string {
+	return fmt.Sprintf("0x%x", w)
+}
+
+func g1PubKeyToG1Point(pk *bls.G1PubKey) g1Point {
+	x, y := pk.EVMWords()
+	return g1Point{X: wordHex(x), Y: wordHex(y)}
+}
+
+func signatureToG1Point(sig *bls.Signature) g1Point {
+	x, y := sig.EVMWords()
+	return g1Point{X: wordHex(x), Y: wordHex(y)}
+}
+
+func g2PubKeyToG2Point(pk *bls.G2PubKey) g2Point {
+	x, y := pk.EVMWords()
+	return g2Point{
+		X: [2]string{wordHex(x[0]), wordHex(x[1])},
+		Y: [2]string{wordHex(y[0]), wordHex(y[1])},
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+)
+
+func TestRunRegisterPayload(t *testing.T) {
+	kp, path := writeTestKey(t)
+	t.Setenv("KEY_PASSWORD", testPassword)
+
+	const (
+		operator    = "0x1111111111111111111111111111111111111111"
+		coordinator = "0x2222222222222222222222222222222222222222"
+		salt        = "0x3333333333333333333333333333333333333333333333333333333333333333"
+	)
+	var out bytes.Buffer
+	err := runRegisterPayload([]string{
+		"--key", path, "--operator", operator, "--registry-coordinator", coordinator,
+		"--chain-id", "17000", "--salt", salt, "--expiry", "1800000000",
+	}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var payload registrationPayload
+	if err := json.Unmarshal(out.Bytes(), &payload); err != nil {
+		t.Fatalf("output is not valid JSON: %v", err)
+	}
+	if payload.Operator != operator || payload.OperatorSignature.Salt != salt || payload.OperatorSignature.Expiry != 1800000000 {
+		t.Fatalf("unexpected operator fields: %+v", payload)
+	}
+
+	op, _ := parseAddress(operator)
+	rc, _ := parseAddress(coordinator)
+	msgHash := bls.PubkeyRegistrationHash(op, big.NewInt(17000), rc)
+	if payload.PubkeyRegistrationMessageHash != fmt.Sprintf("0x%x", msgHash) {
+		t.Fatalf("message hash = %s", payload.PubkeyRegistrationMessageHash)
+	}
+
+	if payload.Curve != "BLS12-381" {
+		t.Fatalf("curve = %q, want BLS12-381", payload.Curve)
+	}
+
+	params := payload.PubkeyRegistrationParams
+	x, y := kp.G1PubKey.Coordinates()
+	if params.PubkeyG1.X != fmt.Sprintf("0x%0128x", x) || params.PubkeyG1.Y != fmt.Sprintf("0x%0128x", y) {
+		t.Fatalf("pubkeyG1 = %+v is not the EIP-2537 encoding of the key", params.PubkeyG1)
+	}
+	x0, x1, _, _ := kp.G2PubKey.Coordinates()
+	if params.PubkeyG2.X != [2]string{fmt.Sprintf("0x%0128x", x0), fmt.Sprintf("0x%0128x", x1)} {
+		t.Fatalf("pubkeyG2.X = %v is not c0, c1", params.PubkeyG2.X)
+	}
+	if params.PubkeyG1 != g1PubKeyToG1Point(kp.G1PubKey) {
+		t.Fatal("pubkeyG1 does not match the key")
+	}
+	if params.PubkeyG2 != g2PubKeyToG2Point(kp.G2PubKey) {
+		t.Fatal("pubkeyG2 does not match the key")
+	}
+
+	// BLS signatures are deterministic, so the expected signature can be
+	// recomputed and checked against the registration hash.
+	want, err := kp.Sign(msgHash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bls.Verify(kp.G2PubKey, msgHash[:], want) {
+		t.Fatal("registration signature does not verify")
+	}
+	if params.PubkeyRegistrationSignature != signatureToG1Point(want) {
+		t.Fatal("pubkeyRegistrationSignature is not the signature over the registration hash")
+	}
+}
+
+func TestRunRegisterPayloadRequiresFlags(t *testing.T) {
+	if err := runRegisterPayload([]string{"--operator", "0x1111111111111111111111111111111111111111"}, &bytes.Buffer{}); err == nil {
+		t.Fatal("expected an error for missing flags")
+	}
+}
+
+func TestRunRegisterPayloadQuorums(t *testing.T) {
+	_, path := writeTestKey(t)
+	t.Setenv("KEY_PASSWORD", testPassword)
+
+	var out bytes.Buffer
+	err := runRegisterPayload([]string{
+		"--key", path, "--operator", "0x1111111111111111111111111111111111111111",
+		"--registry-coordinator", "0x2222222222222222222222222222222222222222",
+		"--chain-id", "17000", "--salt", "0x3333333333333333333333333333333333333333333333333333333333333333",
+		"--expiry", "1800000000", "--quorums", "1,0",
+	}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var payloads []registrationPayload
+	if err := json.Unmarshal(out.Bytes(), &payloads); err != nil {
+		t.Fatalf("output is not a JSON array of payloads: %v", err)
+	}
+	if len(payloads) != 2 {
+		t.Fatalf("got %d payloads, want 2", len(payloads))
+	}
+	for i, p := range payloads {
+		// Quorums are printed in ascending order.
+		q := uint8(i)
+		if p.QuorumNumber == nil || *p.QuorumNumber != q {
+			t.Fatalf("payload %d is not labelled quorum %d", i, q)
+		}
+		if p.QuorumNumbers != fmt.Sprintf("0x%02x", q) {
+			t.Errorf("payload %d quorumNumbers = %s", i, p.QuorumNumbers)
+		}
+	}
+
+	// Apart from the quorum fields the payloads are identical.
+	first, second := payloads[0], payloads[1]
+	first.QuorumNumber, first.QuorumNumbers = nil, ""
+	second.QuorumNumber, second.QuorumNumbers = nil, ""
+	if first != second {
+		t.Fatalf("payloads differ outside the quorum fields:\n%+v\n%+v", first, second)
+	}
+}
+
+func TestQuorumList(t *testing.T) {
+	var l quorumList
+	if err := l.Set("2, 0,1"); err != nil {
+		t.Fatal(err)
+	}
+	if l.String() != "0,1,2" {
+		t.Fatalf("quorums = %s, want 0,1,2", l.String())
+	}
+	for _, bad := range []string{"", "0,0", "256", "-1", "a"} {
+		if err := l.Set(bad); err == nil {
+			t.Errorf("Set(%q) accepted", bad)
+		}
+	}
+}
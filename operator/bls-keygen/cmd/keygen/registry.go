@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+// pubkeyHashToOperatorSelector is the selector of
+// BLSApkRegistry.pubkeyHashToOperator(bytes32), which maps an operator ID to
+// the operator that registered it, or the zero address.
+// The registry queried must key operators by bls.OperatorID; EigenLayer's
+// BN254 BLSApkRegistry computes IDs on another curve and never matches.
+var pubkeyHashToOperatorSelector = keccak256([]byte("pubkeyHashToOperator(bytes32)"))[:4]
+
+// contractCaller performs a read-only eth_call.
+type contractCaller interface {
+	CallContract(ctx context.Context, to [20]byte, data []byte) ([]byte, error)
+}
+
+// newContractCaller connects to --rpc; tests replace it with a mock.
+var newContractCaller = func(url string) contractCaller {
+	return &rpcClient{url: url, http: http.DefaultClient}
+}
+
+// rpcBackoff is the wait before the first retry of a failed registration
+// lookup; it doubles after every further attempt.
+var rpcBackoff = 250 * time.Millisecond
+
+// registryCheck holds the flags of the optional on-chain registration
+// lookup done around key generation. With no --rpc nothing is queried.
+type registryCheck struct {
+	rpc      string
+	registry string
+	timeout  time.Duration
+	retries  int
+	require  bool
+
+	caller contractCaller
+	addr   [20]byte
+}
+
+func (c *registryCheck) register(fs *flag.FlagSet) {
+	fs.StringVar(&c.rpc, "rpc", "", "Ethereum JSON-RPC URL used to look up operator registration (default: no lookup)")
+	fs.StringVar(&c.registry, "avs-registry", "", "BLSApkRegistry address to look the operator ID up in")
+	fs.DurationVar(&c.timeout, "rpc-timeout", 5*time.Second, "timeout of each registration lookup attempt")
+	fs.IntVar(&c.retries, "rpc-retries", 2, "retries of a failed registration lookup, with exponential backoff")
+	fs.BoolVar(&c.require, "require-rpc", false, "fail instead of warning when the registration lookup keeps failing")
+}
+
+// init validates the flags and connects. It is a no-op without --rpc.
+func (c *registryCheck) init() error {
+	if c.retries < 0 {
+		return usageErrorf("--rpc-retries must not be negative, got %d", c.retries)
+	}
+	if c.rpc == "" {
+		if c.require {
+			return usageErrorf("--require-rpc needs --rpc")
+		}
+		return nil
+	}
+	if c.registry == "" {
+		return usageErrorf("--avs-registry is required with --rpc")
+	}
+	addr, err := parseAddress(c.registry)
+	if err != nil {
+		return fmt.Errorf("invalid --avs-registry: %w", err)
+	}
+	c.addr = addr
+	c.caller = newContractCaller(c.rpc)
+	return nil
+}
+
+// lookup returns the operator registered under id, or false if there is
+// none. A failed attempt is retried up to --rpc-retries times, waiting
+// rpcBackoff, then twice that, and so on in between.
+func (c *registryCheck) lookup(id [32]byte) ([20]byte, bool, error) {
+	for attempt := 0; ; attempt++ {
+		operator, registered, err := c.lookupOnce(id)
+		if err == nil || attempt >= c.retries {
+			return operator, registered, err
+		}
+		slog.Debug("registration lookup failed, retrying", "attempt", attempt+1, "reason", err)
+		select {
+		case <-time.After(rpcBackoff << attempt):
+		case <-cmdContext.Done():
+			return operator, false, cmdContext.Err()
+		}
+	}
+}
+
+// lookupOnce makes a single lookup attempt, bounded by --rpc-timeout.
+func (c *registryCheck) lookupOnce(id [32]byte) ([20]byte, bool, error) {
+	var operator [20]byte
+	ctx, cancel := context.WithTimeout(cmdContext, c.timeout)
+	defer cancel()
+
+	out, err := c.caller.CallContract(ctx, c.addr, append(append([]byte(nil), pubkeyHashToOperatorSelector...), id[:]...))
+	if err != nil {
+		return operator, false, err
+	}
+	if len(out) != 32 {
+		return operator, false, fmt.Errorf("unexpected pubkeyHashToOperator result of %d bytes", len(out))
+	}
+	copy(operator[:], out[12:])
+	return operator, operator != [20]byte{}, nil
+}
+
+// checkShadowing looks up the existing key at keyPath before it is
+// replaced. Replacing a key that is registered on-chain would leave the
+// registration pointing at a key nobody holds, so that is refused unless
+// force is set, in which case it is only logged. Lookup failures only
+// block generation under --require-rpc.
+func (c *registryCheck) checkShadowing(keyPath string, force bool) error {
+	if c.caller == nil {
+		return nil
+	}
+	pk, err := blskeys.LoadPublicKey(keyPath)
+	if err != nil {
+		slog.Warn("could not read the key being replaced, skipping registration lookup", "path", keyPath, "reason", err)
+		return nil
+	}
+	id := bls.OperatorID(pk)
+	operator, registered, err := c.lookup(id)
+	if err != nil {
+		return c.lookupFailed(err)
+	}
+	if !registered {
+		return nil
+	}
+	if !force {
+		return fmt.Errorf("key %s is registered on-chain to operator 0x%x: %w", keyPath, operator, errKeyExists)
+	}
+	slog.Warn("replacing a key that is registered on-chain; its registration will be shadowed until the new key is registered",
+		"path", keyPath, "operator_id", fmt.Sprintf("0x%x", id), "operator", fmt.Sprintf("0x%x", operator))
+	return nil
+}
+
+// report logs whether the newly written key with operator ID id is
+// registered. The key is already written when it fails under --require-rpc.
+func (c *registryCheck) report(id [32]byte) error {
+	if c.caller == nil {
+		return nil
+	}
+	operator, registered, err := c.lookup(id)
+	switch {
+	case err != nil:
+		return c.lookupFailed(err)
+	case registered:
+		slog.Info("operator ID is registered", "operator_id", fmt.Sprintf("0x%x", id), "operator", fmt.Sprintf("0x%x", operator))
+	default:
+		slog.Warn("operator ID is not registered; register it before the operator can sign tasks", "operator_id", fmt.Sprintf("0x%x", id))
+	}
+	return nil
+}
+
+// lookupFailed handles a lookup that failed on every attempt: an error
+// under --require-rpc, a warning otherwise.
+func (c *registryCheck) lookupFailed(err error) error {
+	if c.require {
+		return fmt.Errorf("registration lookup failed after %d attempts: %w", c.retries+1, err)
+	}
+	slog.Warn("registration lookup failed", "attempts", c.retries+1, "reason", err)
+	return nil
+}
+
+// rpcClient is a minimal Ethereum JSON-RPC client supporting eth_call.
+type rpcClient struct {
+	url  string
+	http *http.Client
+}
+
+func (c *rpcClient) CallContract(ctx context.Context, to [20]byte, data []byte) ([]byte, error) {
+	reqBody, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "eth_call",
+		"params": []interface{}{
+			map[string]string{"to": fmt.Sprintf("0x%x", to), "data": fmt.Sprintf("0x%x", data)},
+			"latest",
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("rpc returned %s", resp.Status)
+	}
+
+	var rpcResp struct {
+		Result string `json:"result"`
+		Error  *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+		return nil, fmt.Errorf("invalid rpc response: %w", err)
+	}
+	if rpcResp.Error != nil {
+		return nil, fmt.Errorf("rpc error %d: %s", rpcResp.Error.Code, rpcResp.Error.Message)
+	}
+	return decodeHex(rpcResp.Result)
+}
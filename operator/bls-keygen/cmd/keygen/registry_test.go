@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+const testRegistry = "0x00000000000000000000000000000000000000aa"
+
+// mockRegistry answers pubkeyHashToOperator from a map of operator IDs.
+type mockRegistry struct {
+	operators map[[32]byte][20]byte
+	calls     int
+}
+
+func (m *mockRegistry) CallContract(ctx context.Context, to [20]byte, data []byte) ([]byte, error) {
+	m.calls++
+	if fmt.Sprintf("0x%x", to) != testRegistry {
+		return nil, fmt.Errorf("unexpected contract 0x%x", to)
+	}
+	if !bytes.Equal(data[:4], pubkeyHashToOperatorSelector) || len(data) != 36 {
+		return nil, fmt.Errorf("unexpected call data 0x%x", data)
+	}
+	var id [32]byte
+	copy(id[:], data[4:])
+	out := make([]byte, 32)
+	op := m.operators[id]
+	copy(out[12:], op[:])
+	return out, nil
+}
+
+func useMockRegistry(t *testing.T, m contractCaller) {
+	t.Helper()
+	orig := newContractCaller
+	newContractCaller = func(string) contractCaller { return m }
+	t.Cleanup(func() { newContractCaller = orig })
+}
+
+func TestGenerateRegistryLookupUnregistered(t *testing.T) {
+	logs := captureLogs(t)
+	reg := &mockRegistry{}
+	useMockRegistry(t, reg)
+	t.Setenv("KEY_PASSWORD", testPassword)
+
+	out := filepath.Join(t.TempDir(), "bls_key.json")
+	if err := runGenerate([]string{"--out", out, "--rpc", "http://rpc.invalid", "--avs-registry", testRegistry}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	if reg.calls != 1 {
+		t.Fatalf("registry was queried %d times, want 1", reg.calls)
+	}
+	if !strings.Contains(logs.String(), "is not registered") {
+		t.Fatalf("missing unregistered warning:\n%s", logs.String())
+	}
+}
+
+func TestGenerateRegistryLookupShadowing(t *testing.T) {
+	logs := captureLogs(t)
+	old, path := writeTestKey(t)
+	operator := [20]byte{19: 0x42}
+	reg := &mockRegistry{operators: map[[32]byte][20]byte{bls.OperatorID(old.G1PubKey): operator}}
+	useMockRegistry(t, reg)
+	t.Setenv("KEY_PASSWORD", testPassword)
+	scriptedTerminal(t, false)
+
+	if err := runGenerate([]string{"--out", path, "--force", "--rpc", "http://rpc.invalid", "--avs-registry", testRegistry}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	got := logs.String()
+	if !strings.Contains(got, "registration will be shadowed") || !strings.Contains(got, "0x0000000000000000000000000000000000000042") {
+		t.Fatalf("missing shadowing warning:\n%s", got)
+	}
+}
+
+func TestGenerateRefusesToShadowWithoutForce(t *testing.T) {
+	old, path := writeTestKey(t)
+	reg := &mockRegistry{operators: map[[32]byte][20]byte{bls.OperatorID(old.G1PubKey): {19: 0x42}}}
+	useMockRegistry(t, reg)
+	t.Setenv("KEY_PASSWORD", testPassword)
+
+	err := runGenerate([]string{"--out", path, "--rpc", "http://rpc.invalid", "--avs-registry", testRegistry}, &bytes.Buffer{})
+	if !errors.Is(err, errKeyExists) || !strings.Contains(err.Error(), "registered on-chain") {
+		t.Fatalf("got %v, want a registered-key refusal", err)
+	}
+	loaded, err := blskeys.Load(path, testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(loaded.PrivateKey.Bytes(), old.PrivateKey.Bytes()) {
+		t.Fatal("registered key was replaced")
+	}
+}
+
+func TestGenerateRegistryLookupRequiresRegistry(t *testing.T) {
+	t.Setenv("KEY_PASSWORD", testPassword)
+	out := filepath.Join(t.TempDir(), "bls_key.json")
+	if err := runGenerate([]string{"--out", out, "--rpc", "http://rpc.invalid"}, &bytes.Buffer{}); err == nil {
+		t.Fatal("expected an error for --rpc without --avs-registry")
+	}
+}
+
+func TestRPCClientCallContract(t *testing.T) {
+	var got struct {
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+		fmt.Fprint(w, `{"jsonrpc":"2.0","id":1,"result":"0x00000000000000000000000000000000000000000000000000000000000000ff"}`)
+	}))
+	defer srv.Close()
+
+	c := &rpcClient{url: srv.URL, http: srv.Client()}
+	out, err := c.CallContract(context.Background(), [20]byte{19: 0xaa}, []byte{1, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 32 || out[31] != 0xff {
+		t.Fatalf("result = %x", out)
+	}
+	if got.Method != "eth_call" || !strings.Contains(string(got.Params[0]), `"data":"0x0102"`) {
+		t.Fatalf("unexpected request %+v", got)
+	}
+}
+
+func TestRegistryLookupTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	c := &registryCheck{rpc: srv.URL, registry: testRegistry, timeout: 50 * time.Millisecond}
+	if err := c.init(); err != nil {
+		t.Fatal(err)
+	}
+	kp, err := blskeys.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := c.lookup(bls.OperatorID(kp.G1PubKey)); err == nil {
+		t.Fatal("expected the lookup to time out")
+	}
+}
+
+// flakyRegistry fails the first failures calls, then answers like registry.
+// With failures < 0 it never answers.
+type flakyRegistry struct {
+	mockRegistry
+	failures int
+}
+
+func (f *flakyRegistry) CallContract(ctx context.Context, to [20]byte, data []byte) ([]byte, error) {
+	if f.failures < 0 || f.calls < f.failures {
+		f.calls++
+		return nil, errors.New("connection reset by peer")
+	}
+	return f.mockRegistry.CallContract(ctx, to, data)
+}
+
+func fastRPCBackoff(t *testing.T) {
+	t.Helper()
+	orig := rpcBackoff
+	rpcBackoff = time.Millisecond
+	t.Cleanup(func() { rpcBackoff = orig })
+}
+
+func TestRegistryLookupRetries(t *testing.T) {
+	logs := captureLogs(t)
+	fastRPCBackoff(t)
+	reg := &flakyRegistry{failures: 2}
+	useMockRegistry(t, reg)
+	t.Setenv("KEY_PASSWORD", testPassword)
+
+	out := filepath.Join(t.TempDir(), "bls_key.json")
+	args := []string{"--out", out, "--rpc", "http://rpc.invalid", "--avs-registry", testRegistry, "--require-rpc"}
+	if err := runGenerate(args, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	if reg.calls != 3 {
+		t.Fatalf("registry was queried %d times, want 3", reg.calls)
+	}
+	if !strings.Contains(logs.String(), "is not registered") {
+		t.Fatalf("lookup did not succeed after retrying:\n%s", logs)
+	}
+}
+
+func TestRegistryLookupAlwaysFails(t *testing.T) {
+	fastRPCBackoff(t)
+	t.Setenv("KEY_PASSWORD", testPassword)
+
+	t.Run("warns", func(t *testing.T) {
+		logs := captureLogs(t)
+		reg := &flakyRegistry{failures: -1}
+		useMockRegistry(t, reg)
+		out := filepath.Join(t.TempDir(), "bls_key.json")
+		args := []string{"--out", out, "--rpc", "http://rpc.invalid", "--avs-registry", testRegistry, "--rpc-retries", "3"}
+		if err := runGenerate(args, &bytes.Buffer{}); err != nil {
+			t.Fatalf("lookup failure should not be fatal: %v", err)
+		}
+		if reg.calls != 4 {
+			t.Fatalf("registry was queried %d times, want 4", reg.calls)
+		}
+		if !strings.Contains(logs.String(), "registration lookup failed") {
+			t.Fatalf("missing lookup warning:\n%s", logs)
+		}
+	})
+
+	t.Run("require-rpc", func(t *testing.T) {
+		useMockRegistry(t, &flakyRegistry{failures: -1})
+		out := filepath.Join(t.TempDir(), "bls_key.json")
+		args := []string{"--out", out, "--rpc", "http://rpc.invalid", "--avs-registry", testRegistry, "--require-rpc"}
+		err := runGenerate(args, &bytes.Buffer{})
+		if err == nil || !strings.Contains(err.Error(), "after 3 attempts") {
+			t.Fatalf("got %v, want a lookup failure after 3 attempts", err)
+		}
+	})
+
+	t.Run("require-rpc blocks replacing a key", func(t *testing.T) {
+		old, path := writeTestKey(t)
+		useMockRegistry(t, &flakyRegistry{failures: -1})
+		args := []string{"--out", path, "--force", "--rpc", "http://rpc.invalid", "--avs-registry", testRegistry, "--require-rpc"}
+		if err := runGenerate(args, &bytes.Buffer{}); err == nil {
+			t.Fatal("expected the failed lookup to block replacing the key")
+		}
+		loaded, err := blskeys.Load(path, testPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(loaded.PrivateKey.Bytes(), old.PrivateKey.Bytes()) {
+			t.Fatal("key was replaced although the registration lookup failed")
+		}
+	})
+}
+
+func TestRequireRPCNeedsRPC(t *testing.T) {
+	t.Setenv("KEY_PASSWORD", testPassword)
+	out := filepath.Join(t.TempDir(), "bls_key.json")
+	if err := runGenerate([]string{"--out", out, "--require-rpc"}, &bytes.Buffer{}); exitCode(err) != exitUsage {
+		t.Fatalf("got %v, want a usage error", err)
+	}
+}
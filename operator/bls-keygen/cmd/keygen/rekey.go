@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+// errRekeyReusedIndex is returned by rekey for a --to-index whose key is
+// the current one or one a rekey already replaced.
+var errRekeyReusedIndex = errors.New("refusing to rekey to an index already used")
+
+// runRekey implements `keygen rekey`: it replaces the key at --key, which
+// must be the mnemonic's key at account --from-index, with the one at
+// --to-index, the recovery path when a mnemonic-derived key is compromised
+// but the mnemonic is not. The old file is kept as a timestamped backup,
+// and the new one records both derivation paths in its metadata. A key
+// derived with --avs-id is rekeyed with the same --avs-id.
+func runRekey(args []string, stdout io.Writer) (err error) {
+	fs := flag.NewFlagSet("rekey", flag.ContinueOnError)
+	keyPath := fs.String("key", filepath.Join(defaultKeyDir, defaultKeyFile), "key file to replace")
+	mnemonicFile := fs.String("mnemonic-file", "", "file holding the BIP-39 mnemonic")
+	fromIndex := fs.Int64("from-index", 0, "EIP-2334 account index of the current key")
+	toIndex := fs.Int64("to-index", -1, "EIP-2334 account index of the new key")
+	var avs avsOption
+	avs.register(fs)
+	var pwSource passwordSource
+	pwSource.register(fs)
+	var policy passwordPolicy
+	policy.register(fs)
+	var perms permCheck
+	perms.register(fs)
+	var kdf kdfOptions
+	kdf.register(fs)
+	var logOpts logOptions
+	logOpts.register(fs)
+	var audit auditOptions
+	audit.register(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	var auditKey *bls.G1PubKey
+	defer func() { err = audit.done("rekey", auditKey, err) }()
+	if err := perms.validate(); err != nil {
+		return err
+	}
+	if err := logOpts.apply(); err != nil {
+		return err
+	}
+	if *mnemonicFile == "" {
+		return usageErrorf("--mnemonic-file is required")
+	}
+	if *toIndex < 0 {
+		return usageErrorf("--to-index is required")
+	}
+	if *fromIndex < 0 || *fromIndex > math.MaxUint32 || *toIndex > math.MaxUint32 {
+		return usageErrorf("account indices must be between 0 and %d", uint32(math.MaxUint32))
+	}
+	if err := avs.validate(); err != nil {
+		return err
+	}
+	fromPath := avs.path(blskeys.SigningKeyPath(uint32(*fromIndex)))
+	toPath := avs.path(blskeys.SigningKeyPath(uint32(*toIndex)))
+	if *fromIndex == *toIndex {
+		return fmt.Errorf("%w: --to-index %d is the current key's", errRekeyReusedIndex, *toIndex)
+	}
+	params, err := kdf.params()
+	if err != nil {
+		return err
+	}
+	resolved, err := perms.keyPath(*keyPath)
+	if err != nil {
+		return err
+	}
+	// Replace the target of a symlinked path, where the backup goes too.
+	if *keyPath, err = blskeys.ResolvePath(resolved); err != nil {
+		return err
+	}
+
+	current, err := blskeys.LoadPublicKey(*keyPath)
+	if err != nil {
+		return fmt.Errorf("failed to read key %s: %w", *keyPath, err)
+	}
+	meta := blskeys.NewMetadata("", time.Now())
+	if oldMeta, err := blskeys.LoadMetadata(*keyPath); err == nil && oldMeta != nil {
+		if oldMeta.DerivationPath != "" && oldMeta.AVSID != avs.id {
+			return usageErrorf("%s was derived for --avs-id %q, not %q", *keyPath, oldMeta.AVSID, avs.id)
+		}
+		if oldMeta.DerivationPath != "" && oldMeta.DerivationPath != fromPath {
+			return usageErrorf("%s was derived at %s, not at --from-index %d", *keyPath, oldMeta.DerivationPath, *fromIndex)
+		}
+		if oldMeta.PreviousDerivationPath == toPath {
+			return fmt.Errorf("%w: %s was replaced by the current key", errRekeyReusedIndex, toPath)
+		}
+		meta.Label, meta.Network, meta.TestOnly = oldMeta.Label, oldMeta.Network, oldMeta.TestOnly
+	}
+	meta.DerivationPath, meta.PreviousDerivationPath = toPath, fromPath
+	meta.AVSID = avs.id
+
+	mnemonic, err := os.ReadFile(*mnemonicFile)
+	if err != nil {
+		return fmt.Errorf("failed to read mnemonic file: %w", err)
+	}
+	defer bls.SecretBytes(mnemonic).Zero()
+	old, err := blskeys.DeriveFromMnemonic(string(mnemonic), fromPath)
+	if err != nil {
+		return err
+	}
+	old.PrivateKey.Zero()
+	// The stored public key identifies the current key without decrypting
+	// it, whose password may be lost along with the key.
+	if !bytes.Equal(old.G1PubKey.Bytes(), current.Bytes()) {
+		return fmt.Errorf("%s: %w %s", *keyPath, errMnemonicMismatch, fromPath)
+	}
+	auditKey = current
+
+	password, err := pwSource.readNew()
+	if err != nil {
+		return err
+	}
+	if err := policy.check(password); err != nil {
+		return err
+	}
+	kp, err := blskeys.DeriveFromMnemonic(string(mnemonic), toPath)
+	if err != nil {
+		return err
+	}
+	defer kp.PrivateKey.Zero()
+
+	data, err := blskeys.MarshalContext(cmdContext, kp, password, params, meta)
+	if err != nil {
+		return err
+	}
+	backup, err := writeKeyFile(*keyPath, data, true)
+	if err != nil {
+		return err
+	}
+	auditKey = kp.G1PubKey
+	if err := perms.check(*keyPath); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "Old key (%s) moved to %s\n", fromPath, backup)
+	fmt.Fprintf(stdout, "New key (%s) saved to %s\n", toPath, *keyPath)
+	fmt.Fprintf(stdout, "G1 public key: 0x%x\n", kp.G1PubKey.Bytes())
+	fmt.Fprintln(stdout, "Register the new key and deregister the old one.")
+	return nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+// writeDerivedKey derives the testMnemonic key at account 0, passing
+// derive the extra flags, and returns the paths of the mnemonic and key
+// files.
+func writeDerivedKey(t *testing.T, extra ...string) (mnemonicFile, out string) {
+	t.Helper()
+	dir := t.TempDir()
+	mnemonicFile = filepath.Join(dir, "mnemonic")
+	if err := os.WriteFile(mnemonicFile, []byte(testMnemonic+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	out = filepath.Join(dir, "keys", "bls_key.json")
+	t.Setenv("KEY_PASSWORD", testPassword)
+	if err := runDerive(append([]string{"--mnemonic-file", mnemonicFile, "--out", out}, extra...), &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	return mnemonicFile, out
+}
+
+func TestRunRekey(t *testing.T) {
+	mnemonicFile, out := writeDerivedKey(t)
+
+	var stdout bytes.Buffer
+	if err := runRekey([]string{"--key", out, "--mnemonic-file", mnemonicFile, "--from-index", "0", "--to-index", "1"}, &stdout); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := blskeys.Load(out, testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := blskeys.DeriveFromMnemonic(testMnemonic, "m/12381/3600/1/0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(loaded.PrivateKey.Bytes(), want.PrivateKey.Bytes()) {
+		t.Fatal("new key is not the EIP-2334 key at index 1")
+	}
+	old, err := blskeys.DeriveFromMnemonic(testMnemonic, blskeys.DefaultDerivationPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(loaded.PrivateKey.Bytes(), old.PrivateKey.Bytes()) {
+		t.Fatal("new key is the key at index 0")
+	}
+
+	meta, err := blskeys.LoadMetadata(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.DerivationPath != "m/12381/3600/1/0" || meta.PreviousDerivationPath != "m/12381/3600/0/0" {
+		t.Errorf("metadata records %q replacing %q", meta.DerivationPath, meta.PreviousDerivationPath)
+	}
+	backups, err := filepath.Glob(filepath.Join(filepath.Dir(out), "*.bak"))
+	if err != nil || len(backups) != 1 {
+		t.Fatalf("got backups %v, want one", backups)
+	}
+	backup, err := blskeys.Load(backups[0], testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(backup.PrivateKey.Bytes(), old.PrivateKey.Bytes()) {
+		t.Error("backup is not the old key")
+	}
+
+	// Going back to the replaced index is refused too.
+	err = runRekey([]string{"--key", out, "--mnemonic-file", mnemonicFile, "--from-index", "1", "--to-index", "0"}, &bytes.Buffer{})
+	if !errors.Is(err, errRekeyReusedIndex) {
+		t.Fatalf("rekey back to index 0: got %v, want errRekeyReusedIndex", err)
+	}
+}
+
+func TestRunRekeyRefusesCurrentIndex(t *testing.T) {
+	mnemonicFile, out := writeDerivedKey(t)
+	before, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = runRekey([]string{"--key", out, "--mnemonic-file", mnemonicFile, "--to-index", "0"}, &bytes.Buffer{})
+	if !errors.Is(err, errRekeyReusedIndex) {
+		t.Fatalf("got %v, want errRekeyReusedIndex", err)
+	}
+	after, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(before, after) {
+		t.Fatal("key file changed")
+	}
+}
+
+func TestRunRekeyWrongFromIndex(t *testing.T) {
+	mnemonicFile, out := writeDerivedKey(t)
+
+	// The recorded path contradicts --from-index.
+	err := runRekey([]string{"--key", out, "--mnemonic-file", mnemonicFile, "--from-index", "2", "--to-index", "3"}, &bytes.Buffer{})
+	if exitCode(err) != exitUsage {
+		t.Fatalf("got %v, want a usage error", err)
+	}
+
+	// A key without a recorded path is checked against the mnemonic.
+	_, random := writeTestKey(t)
+	err = runRekey([]string{"--key", random, "--mnemonic-file", mnemonicFile, "--to-index", "1"}, &bytes.Buffer{})
+	if !errors.Is(err, errMnemonicMismatch) {
+		t.Fatalf("got %v, want errMnemonicMismatch", err)
+	}
+}
+
+func TestRunRekeyAVSID(t *testing.T) {
+	mnemonicFile, out := writeDerivedKey(t, "--avs-id", "avs-a")
+
+	err := runRekey([]string{"--key", out, "--mnemonic-file", mnemonicFile, "--to-index", "1"}, &bytes.Buffer{})
+	if exitCode(err) != exitUsage {
+		t.Fatalf("without --avs-id: got %v, want a usage error", err)
+	}
+	if err := runRekey([]string{"--key", out, "--mnemonic-file", mnemonicFile, "--to-index", "1", "--avs-id", "avs-a"}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := blskeys.Load(out, testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := blskeys.DeriveFromMnemonic(testMnemonic, blskeys.AVSPath("m/12381/3600/1/0", "avs-a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(loaded.PrivateKey.Bytes(), want.PrivateKey.Bytes()) {
+		t.Fatal("new key is not avs-a's key at index 1")
+	}
+	meta, err := blskeys.LoadMetadata(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.AVSID != "avs-a" {
+		t.Errorf("avs_id = %q, want avs-a", meta.AVSID)
+	}
+}
+
+func TestRunRekeyUsage(t *testing.T) {
+	mnemonicFile, out := writeDerivedKey(t)
+	for _, args := range [][]string{
+		{"--key", out, "--to-index", "1"},
+		{"--key", out, "--mnemonic-file", mnemonicFile},
+		{"--key", out, "--mnemonic-file", mnemonicFile, "--from-index", "-1", "--to-index", "1"},
+	} {
+		if err := runRekey(args, &bytes.Buffer{}); exitCode(err) != exitUsage {
+			t.Errorf("%v: got %v, want a usage error", args, err)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+// runRelabel implements `keygen relabel`: it replaces the label in a key
+// file's metadata, or removes it with --label "". The label is cleartext,
+// so the key is not decrypted and no password is read.
+func runRelabel(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("relabel", flag.ContinueOnError)
+	keyPath := fs.String("key", filepath.Join(defaultKeyDir, defaultKeyFile), "key file to relabel")
+	var label labelOption
+	label.register(fs)
+	var perms permCheck
+	perms.register(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	labelSet := false
+	fs.Visit(func(f *flag.Flag) { labelSet = labelSet || f.Name == "label" })
+	if !labelSet {
+		return usageErrorf("--label is required; --label \"\" removes the label")
+	}
+	if err := label.validate(); err != nil {
+		return err
+	}
+	if err := perms.validate(); err != nil {
+		return err
+	}
+	resolved, err := perms.keyPath(*keyPath)
+	if err != nil {
+		return err
+	}
+	*keyPath = resolved
+
+	if err := blskeys.Relabel(*keyPath, label.text); err != nil {
+		return fmt.Errorf("failed to relabel %s: %w", *keyPath, err)
+	}
+	if err := perms.check(*keyPath); err != nil {
+		return err
+	}
+	if label.text == "" {
+		fmt.Fprintf(stdout, "Removed the label of %s\n", *keyPath)
+	} else {
+		fmt.Fprintf(stdout, "Labelled %s %q\n", *keyPath, label.text)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunRelabel(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bls_key.json")
+	t.Setenv("KEY_PASSWORD", testPassword)
+	if err := runGenerate([]string{"--out", path, "--label", "holesky-test-2"}, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	label := func() (info, list string) {
+		t.Helper()
+		var out bytes.Buffer
+		if err := runInfo([]string{"--key", path, "--json"}, &out); err != nil {
+			t.Fatal(err)
+		}
+		var parsed struct {
+			Label string `json:"label"`
+		}
+		if err := json.Unmarshal(out.Bytes(), &parsed); err != nil {
+			t.Fatal(err)
+		}
+		out.Reset()
+		if err := runList([]string{"--keydir", dir}, &out); err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		if len(lines) != 2 {
+			t.Fatalf("list output:\n%s", out.String())
+		}
+		fields := strings.Fields(lines[1])
+		return parsed.Label, strings.Join(fields[4:], " ")
+	}
+	if info, list := label(); info != "holesky-test-2" || list != "holesky-test-2" {
+		t.Fatalf("info shows %q and list %q, want holesky-test-2", info, list)
+	}
+
+	// Relabelling reads no password.
+	t.Setenv("KEY_PASSWORD", "")
+	if err := runRelabel([]string{"--key", path, "--label", "mainnet primary"}, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if info, list := label(); info != "mainnet primary" || list != "mainnet primary" {
+		t.Fatalf("after relabel info shows %q and list %q, want mainnet primary", info, list)
+	}
+	var out bytes.Buffer
+	if err := runInfo([]string{"--key", path}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "mainnet primary") {
+		t.Errorf("info text lacks the label:\n%s", out.String())
+	}
+
+	if err := runRelabel([]string{"--key", path, "--label", ""}, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if info, list := label(); info != "" || list != "" {
+		t.Fatalf("after removing the label info shows %q and list %q", info, list)
+	}
+
+	for _, args := range [][]string{
+		{"--key", path},
+		{"--key", path, "--label", "two\nlines"},
+	} {
+		if err := runRelabel(args, io.Discard); exitCode(err) != exitUsage {
+			t.Errorf("%q: got %v, want a usage error", args, err)
+		}
+	}
+	err := runGenerate([]string{"--out", filepath.Join(t.TempDir(), "k.json"), "--format", "eip2335", "--label", "x"}, io.Discard)
+	if exitCode(err) != exitUsage {
+		t.Errorf("--label with eip2335: got %v, want a usage error", err)
+	}
+}
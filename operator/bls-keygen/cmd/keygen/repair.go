@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+// runRepairPubkeys implements `keygen repair-pubkeys`: it re-derives the
+// stored public keys from the private key and rewrites those that drifted.
+func runRepairPubkeys(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("repair-pubkeys", flag.ContinueOnError)
+	keyPath := fs.String("key", filepath.Join(defaultKeyDir, defaultKeyFile), "key file to repair")
+	var pwSource passwordSource
+	pwSource.register(fs)
+	var perms permCheck
+	perms.register(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if err := perms.validate(); err != nil {
+		return err
+	}
+	resolved, err := perms.keyPath(*keyPath)
+	if err != nil {
+		return err
+	}
+	*keyPath = resolved
+
+	password, err := pwSource.read()
+	if err != nil {
+		return err
+	}
+	report, err := blskeys.RepairPublicKeys(*keyPath, password)
+	if err != nil {
+		return fmt.Errorf("failed to repair %s: %w", *keyPath, err)
+	}
+	if !report.Changed() {
+		fmt.Fprintf(stdout, "%s: stored public keys match the private key, nothing to repair\n", *keyPath)
+		return nil
+	}
+	for _, f := range []struct {
+		name  string
+		fixed bool
+	}{
+		{"G1 public key", report.G1Fixed},
+		{"G2 public key", report.G2Fixed},
+		{"checksum", report.ChecksumFixed},
+	} {
+		status := "ok"
+		if f.fixed {
+			status = "REPAIRED"
+		}
+		fmt.Fprintf(stdout, "%-14s %s\n", f.name+":", status)
+	}
+	if err := perms.check(*keyPath); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Rewrote %s\n", *keyPath)
+	return nil
+}
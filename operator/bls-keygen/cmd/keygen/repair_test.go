@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+func TestRunRepairPubkeys(t *testing.T) {
+	kp, path := writeTestKey(t)
+	other, err := blskeys.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("KEY_PASSWORD", testPassword)
+
+	// A buggy edit swapped in another key's G1 public key.
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kf blskeys.KeyFile
+	if err := json.Unmarshal(data, &kf); err != nil {
+		t.Fatal(err)
+	}
+	kf.G1PubKey = fmt.Sprintf("0x%x", other.G1PubKey.Bytes())
+	if data, err = json.Marshal(&kf); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := blskeys.Load(path, testPassword); err == nil {
+		t.Fatal("corrupted key file still loads")
+	}
+
+	var out bytes.Buffer
+	if err := runRepairPubkeys([]string{"--key", path}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "G1 public key: REPAIRED") || !strings.Contains(out.String(), "G2 public key: ok") {
+		t.Errorf("report does not show what was repaired:\n%s", out.String())
+	}
+	loaded, err := blskeys.Load(path, testPassword)
+	if err != nil {
+		t.Fatalf("repaired key file does not load: %v", err)
+	}
+	if !bytes.Equal(loaded.G1PubKey.Bytes(), kp.G1PubKey.Bytes()) {
+		t.Fatal("repaired G1 public key is not the key's own")
+	}
+
+	out.Reset()
+	if err := runRepairPubkeys([]string{"--key", path}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "nothing to repair") {
+		t.Errorf("second repair changed something:\n%s", out.String())
+	}
+}
+
+func TestRunRepairPubkeysWrongPassword(t *testing.T) {
+	_, path := writeTestKey(t)
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("KEY_PASSWORD", "not the password")
+
+	if err := runRepairPubkeys([]string{"--key", path}, &bytes.Buffer{}); !errors.Is(err, blskeys.ErrDecrypt) {
+		t.Fatalf("got %v, want ErrDecrypt", err)
+	}
+	if after, _ := os.ReadFile(path); !bytes.Equal(before, after) {
+		t.Fatal("key file rewritten without decrypting")
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"time"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+// runRotate implements `keygen rotate`: it replaces the key with a new one
+// and keeps the old file as a timestamped backup.
+func runRotate(args []string, stdout io.Writer) (err error) {
+	fs := flag.NewFlagSet("rotate", flag.ContinueOnError)
+	keyPath := fs.String("key", filepath.Join(defaultKeyDir, defaultKeyFile), "key file to rotate")
+	var pwSource passwordSource
+	pwSource.register(fs)
+	oldPassword := fs.String("old-password", "", "password of the existing key (default: the usual password)")
+	newPassword := fs.String("new-password", "", "password for the new key (default: the old password)")
+	var policy passwordPolicy
+	policy.register(fs)
+	var perms permCheck
+	perms.register(fs)
+	var logOpts logOptions
+	logOpts.register(fs)
+	var audit auditOptions
+	audit.register(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	var auditKey *bls.G1PubKey
+	defer func() { err = audit.done("rotate", auditKey, err) }()
+	if err := perms.validate(); err != nil {
+		return err
+	}
+	if err := logOpts.apply(); err != nil {
+		return err
+	}
+	resolved, err := perms.keyPath(*keyPath)
+	if err != nil {
+		return err
+	}
+	*keyPath = resolved
+
+	if *oldPassword == "" {
+		password, err := pwSource.read()
+		if err != nil {
+			return err
+		}
+		*oldPassword = password
+	}
+	if *newPassword == "" {
+		*newPassword = *oldPassword
+	}
+	if err := policy.check(*newPassword); err != nil {
+		return err
+	}
+
+	kp, backup, err := blskeys.Rotate(*keyPath, *oldPassword, *newPassword, time.Now())
+	if err != nil {
+		return err
+	}
+	defer kp.PrivateKey.Zero()
+	auditKey = kp.G1PubKey
+	if err := perms.check(*keyPath); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "Old key moved to %s\n", backup)
+	fmt.Fprintf(stdout, "New key saved to %s\n", *keyPath)
+	fmt.Fprintf(stdout, "G1 public key: 0x%x\n", kp.G1PubKey.Bytes())
+	return nil
+}
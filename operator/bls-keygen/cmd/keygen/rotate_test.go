@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+func TestRunRotate(t *testing.T) {
+	old, path := writeTestKey(t)
+	t.Setenv("KEY_PASSWORD", testPassword)
+
+	if err := runRotate([]string{"--key", path, "--new-password", "a brand new password"}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+
+	backups, err := filepath.Glob(path + ".*.bak")
+	if err != nil || len(backups) != 1 {
+		t.Fatalf("expected one backup, got %v (%v)", backups, err)
+	}
+	restored, err := blskeys.Load(backups[0], testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(restored.PrivateKey.Bytes(), old.PrivateKey.Bytes()) {
+		t.Fatal("backup does not hold the old key")
+	}
+
+	current, err := blskeys.Load(path, "a brand new password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(current.PrivateKey.Bytes(), old.PrivateKey.Bytes()) {
+		t.Fatal("primary file still holds the old key")
+	}
+}
+
+func TestRunRotateWrongPassword(t *testing.T) {
+	_, path := writeTestKey(t)
+	if err := runRotate([]string{"--key", path, "--old-password", "nope"}, &bytes.Buffer{}); err == nil {
+		t.Fatal("expected rotate to refuse an undecryptable key")
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+)
+
+// errGeneratedKeyBroken is returned when a freshly generated key fails
+// verifyGenerated. The key is discarded, never written.
+var errGeneratedKeyBroken = errors.New("generated key failed verification, nothing was written")
+
+// selfTestMessage is what selfTest signs.
+var selfTestMessage = []byte("bastion bls-keygen self-test")
+
+// selfTest exercises the curve code end to end with ephemeral keys: sign
+// and verify, reject a wrong message, and verify an aggregate of two
+// signers. A binary with broken curve code would otherwise still write
+// keys, just unusable ones.
+func selfTest() error {
+	var kps [2]*bls.KeyPair
+	var sigs [2]*bls.Signature
+	for i := range kps {
+		kp, err := bls.GenerateKeyPair(rand.Reader)
+		if err != nil {
+			return fmt.Errorf("self-test: key generation: %w", err)
+		}
+		defer kp.PrivateKey.Zero()
+		if !bls.PubKeysMatch(kp.G1PubKey, kp.G2PubKey) {
+			return fmt.Errorf("self-test: G1 and G2 public keys do not match")
+		}
+		sig, err := kp.Sign(selfTestMessage)
+		if err != nil {
+			return fmt.Errorf("self-test: signing: %w", err)
+		}
+		if err := bls.VerifyE(kp.G2PubKey, selfTestMessage, sig); err != nil {
+			return fmt.Errorf("self-test: verification: %w", err)
+		}
+		kps[i], sigs[i] = kp, sig
+	}
+	if bls.Verify(kps[0].G2PubKey, []byte("some other message"), sigs[0]) {
+		return fmt.Errorf("self-test: signature verified for the wrong message")
+	}
+
+	aggSig, err := bls.AggregateSignatures(sigs[:])
+	if err != nil {
+		return fmt.Errorf("self-test: aggregating signatures: %w", err)
+	}
+	aggPK, err := bls.AggregatePublicKeys([]*bls.G2PubKey{kps[0].G2PubKey, kps[1].G2PubKey})
+	if err != nil {
+		return fmt.Errorf("self-test: aggregating public keys: %w", err)
+	}
+	if err := bls.VerifyE(aggPK, selfTestMessage, aggSig); err != nil {
+		return fmt.Errorf("self-test: aggregate verification: %w", err)
+	}
+	return nil
+}
+
+// verifyGenerated proves a freshly generated key works before its file is
+// written: it signs a random challenge and verifies the signature against
+// the G2 key with the backend in use, then against the G1 and G2 keys
+// together with bls.VerifyWithG1, which runs on gnark-crypto and so also
+// catches a backend that accepts what it should not.
+func verifyGenerated(kp *bls.KeyPair) error {
+	challenge := make([]byte, 32)
+	if _, err := rand.Read(challenge); err != nil {
+		return fmt.Errorf("failed to draw a challenge: %w", err)
+	}
+	sig, err := kp.Sign(challenge)
+	if err != nil {
+		return fmt.Errorf("%w: signing: %v", errGeneratedKeyBroken, err)
+	}
+	if err := bls.VerifyE(kp.G2PubKey, challenge, sig); err != nil {
+		return fmt.Errorf("%w: G2 public key: %v", errGeneratedKeyBroken, err)
+	}
+	if err := bls.VerifyWithG1(kp.G1PubKey, kp.G2PubKey, challenge, sig); err != nil {
+		return fmt.Errorf("%w: G1 and G2 public keys: %v", errGeneratedKeyBroken, err)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+)
+
+func TestSelfTest(t *testing.T) {
+	if err := selfTest(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunGenerateSelfTest(t *testing.T) {
+	t.Setenv("KEY_PASSWORD", testPassword)
+	out := filepath.Join(t.TempDir(), "bls_key.json")
+	if err := runGenerate([]string{"--out", out, "--self-test"}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// faultyBackend makes valid keys but signs every message with the G1
+// generator in place of its hash, and accepts any signature: a broken
+// build that would verify its own output.
+type faultyBackend struct{}
+
+func (faultyBackend) GenerateKeyPair(r io.Reader) (*bls.KeyPair, error) {
+	var b [bls.PrivateKeySize]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return nil, err
+	}
+	sk, err := bls.PrivateKeyFromBytesReduced(b[:])
+	if err != nil {
+		return nil, err
+	}
+	return bls.NewKeyPair(sk), nil
+}
+
+func (faultyBackend) Sign(kp *bls.KeyPair, msg []byte, dst string) (*bls.Signature, error) {
+	one := make([]byte, bls.PrivateKeySize)
+	one[len(one)-1] = 1
+	sk, err := bls.PrivateKeyFromBytes(one)
+	if err != nil {
+		return nil, err
+	}
+	return kp.SignPoint(bls.NewKeyPair(sk).G1PubKey.Bytes())
+}
+
+func (faultyBackend) Verify(*bls.G2PubKey, []byte, *bls.Signature, string) error { return nil }
+
+func (faultyBackend) AggregateSignatures(sigs []*bls.Signature) (*bls.Signature, error) {
+	return sigs[0], nil
+}
+
+func init() {
+	bls.RegisterBackend("faulty", faultyBackend{})
+}
+
+func TestRunGenerateVerifiesKey(t *testing.T) {
+	t.Setenv("KEY_PASSWORD", testPassword)
+	t.Cleanup(func() { bls.UseBackend(bls.DefaultBackendName) })
+	out := filepath.Join(t.TempDir(), "bls_key.json")
+
+	err := runGenerate([]string{"--out", out, "--backend", "faulty"}, &bytes.Buffer{})
+	if !errors.Is(err, errGeneratedKeyBroken) {
+		t.Fatalf("got %v, want errGeneratedKeyBroken", err)
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Fatal("a key that failed verification was written")
+	}
+
+	if err := runGenerate([]string{"--out", out, "--backend", "faulty", "--no-verify-generated"}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(out); err != nil {
+		t.Fatal("--no-verify-generated did not write the key")
+	}
+}
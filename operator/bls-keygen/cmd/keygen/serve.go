@@ -0,0 +1,309 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+// The serve protocol frames every request and response as a 4-byte
+// big-endian length followed by that many bytes. A request is "sign "
+// followed by the 32-byte hash to sign. A response is a status byte,
+// serveOK followed by the 48-byte compressed signature or serveError
+// followed by an error message. A connection carries any number of
+// requests, each answered in order.
+const (
+	serveSignOp = "sign "
+
+	serveOK    byte = 0
+	serveError byte = 1
+
+	// serveMaxFrame bounds the frames serve reads, so a client cannot make
+	// it allocate more than a request needs.
+	serveMaxFrame = 1024
+)
+
+// runServe implements `keygen serve`: it decrypts the key once and signs
+// the hashes that local processes send to it over a Unix socket, so the
+// operator can delegate signing without the key or its password ever
+// being in its own memory. Signing happens in the context of --network and
+// --message-prefix, like sign. Signing is never offered over TCP: anyone
+// who can reach the socket can sign, so it is created with mode 0600. Only
+// the probes of --health-addr, which cannot sign, listen on TCP. It runs
+// until SIGTERM or SIGINT, then stops accepting connections and gives the
+// requests being answered up to --shutdown-timeout to finish before
+// wiping the key.
+func runServe(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	keyPath := fs.String("key", filepath.Join(defaultKeyDir, defaultKeyFile), "key file to sign with")
+	socket := fs.String("socket", "", "Unix socket to listen on, e.g. /run/bastion.sock")
+	shutdownTimeout := fs.Duration("shutdown-timeout", 10*time.Second, "on SIGTERM or SIGINT, how long to wait for requests being answered before closing their connections")
+	var pwSource passwordSource
+	pwSource.register(fs)
+	var pin keyPin
+	pin.register(fs)
+	var signing signingOptions
+	signing.register(fs)
+	var metrics metricsOptions
+	metrics.register(fs)
+	var healthOpts healthOptions
+	healthOpts.register(fs)
+	var logOpts logOptions
+	logOpts.register(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if err := logOpts.apply(); err != nil {
+		return err
+	}
+	if err := pin.validate(); err != nil {
+		return err
+	}
+	if *socket == "" {
+		return usageErrorf("--socket is required")
+	}
+	if strings.Contains(*socket, "://") {
+		return usageErrorf("--socket %q is not a path: serve only listens on a Unix socket, never over the network", *socket)
+	}
+	if *shutdownTimeout < 0 {
+		return usageErrorf("--shutdown-timeout must not be negative")
+	}
+	sc, err := signing.context()
+	if err != nil {
+		return err
+	}
+
+	// The probes start before the key is decrypted, which can wait on a
+	// prompt or take a while, so /readyz says 503 rather than nothing.
+	health, healthAddr, err := healthOpts.start(cmdContext)
+	if err != nil {
+		return err
+	}
+	defer health.close()
+	if healthAddr != nil {
+		fmt.Fprintf(stdout, "health checks on %s\n", healthAddr)
+	}
+
+	password, err := pwSource.read()
+	if err != nil {
+		return err
+	}
+	kp, err := loadKey(*keyPath, password)
+	if err != nil {
+		return fmt.Errorf("failed to load key %s: %w", *keyPath, err)
+	}
+	defer kp.PrivateKey.Zero()
+	if err := pin.check(*keyPath, kp.G1PubKey); err != nil {
+		return err
+	}
+	warnNetworkMismatch(*keyPath, signing.name)
+	if err := metrics.start(); err != nil {
+		return err
+	}
+
+	ln, err := listenUnix(*socket)
+	if err != nil {
+		return err
+	}
+	// SIGTERM is only caught from here on: before, it still ends serve
+	// at once, even while a password prompt waits.
+	ctx, stopSignals := signal.NotifyContext(cmdContext, syscall.SIGTERM)
+	defer stopSignals()
+	health.setReady(func() error { return serveSelfSign(sc, kp) })
+	// Runs before the key is wiped, and waits for probes still signing
+	// with it.
+	defer health.setReady(nil)
+	fp := bls.Fingerprint(kp.G1PubKey)
+	slog.Info("serving signatures", "socket", *socket, "fingerprint", fp)
+	fmt.Fprintf(stdout, "serving fingerprint %s on %s\n", fp, *socket)
+
+	var wg sync.WaitGroup
+	// Cancelled once the connections still active are to be closed.
+	closing, closeAll := context.WithCancel(context.Background())
+	defer closeAll()
+	context.AfterFunc(ctx, func() {
+		// While requests drain, /readyz fails so that no new traffic is
+		// sent; it does by the time new connections are refused.
+		health.setReady(func() error { return errShuttingDown })
+		ln.Close()
+	})
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() == nil {
+				wg.Wait()
+				return fmt.Errorf("failed to accept a connection: %w", err)
+			}
+			return drainServe(&wg, *shutdownTimeout, closeAll)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Shutting down makes the next read fail at once, so idle
+			// connections close while a request being answered still
+			// gets its response.
+			stopReads := context.AfterFunc(ctx, func() { conn.SetReadDeadline(time.Now()) })
+			defer stopReads()
+			stopConn := context.AfterFunc(closing, func() { conn.Close() })
+			defer stopConn()
+			serveConn(ctx, conn, sc, kp)
+		}()
+	}
+}
+
+// drainServe waits up to timeout for the connections of wg to finish their
+// requests, then calls closeAll to close the rest. It always waits for
+// them to return, since the key is wiped after it.
+func drainServe(wg *sync.WaitGroup, timeout time.Duration, closeAll func()) error {
+	slog.Info("shutting down, finishing active requests", "timeout", timeout)
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return nil
+	case <-timer.C:
+	}
+	closeAll()
+	<-done
+	return fmt.Errorf("requests still active after --shutdown-timeout %s; their connections were closed", timeout)
+}
+
+// listenUnix listens on the Unix socket at path with mode 0600. A socket
+// file left behind by a server that is no longer running is replaced;
+// anything else at path is an error.
+func listenUnix(path string) (*net.UnixListener, error) {
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("--socket %s exists and is not a socket", path)
+		}
+		if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("--socket %s is in use by another server", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket: %w", err)
+		}
+	}
+	ln, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on --socket: %w", err)
+	}
+	// The socket was created with the umask's mode, so tighten it before
+	// the first Accept.
+	if err := os.Chmod(path, 0600); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("failed to set socket mode: %w", err)
+	}
+	return ln, nil
+}
+
+// serveConn answers the requests on conn until the client closes it or
+// sends a frame that cannot be read. Reads failing once ctx is done are
+// the shutdown, not worth a warning.
+func serveConn(ctx context.Context, conn net.Conn, sc bls.SigningContext, kp *blskeys.KeyPair) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		req, err := readFrame(r)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && ctx.Err() == nil {
+				slog.Warn("dropping serve connection", "err", err)
+			}
+			return
+		}
+		resp := handleServeRequest(req, sc, kp)
+		if err := writeFrame(conn, resp); err != nil {
+			slog.Warn("dropping serve connection", "err", err)
+			return
+		}
+	}
+}
+
+// handleServeRequest returns the response frame for the request frame req.
+func handleServeRequest(req []byte, sc bls.SigningContext, kp *blskeys.KeyPair) []byte {
+	start := time.Now()
+	sig, err := serveSign(req, sc, kp)
+	activeMetrics.observe("serve", start, err)
+	if err != nil {
+		return append([]byte{serveError}, err.Error()...)
+	}
+	return append([]byte{serveOK}, sig.CompressedBytes()...)
+}
+
+func serveSign(req []byte, sc bls.SigningContext, kp *blskeys.KeyPair) (*bls.Signature, error) {
+	hash, ok := strings.CutPrefix(string(req), serveSignOp)
+	if !ok {
+		return nil, errors.New("unknown request, want \"sign <32-byte hash>\"")
+	}
+	if len(hash) != 32 {
+		return nil, fmt.Errorf("hash is %d bytes, want 32", len(hash))
+	}
+	return sc.Sign(kp, []byte(hash))
+}
+
+// serveReadyProbe is the hash /readyz signs.
+var serveReadyProbe = keccak256([]byte("bastion serve readiness probe"))
+
+// serveSelfSign signs serveReadyProbe and verifies the signature, so that
+// /readyz only passes while serve can actually sign. The reason it fails
+// for is logged; the probe only hears that it did.
+func serveSelfSign(sc bls.SigningContext, kp *blskeys.KeyPair) error {
+	sig, err := sc.Sign(kp, serveReadyProbe)
+	if err == nil {
+		err = sc.VerifyE(kp.G2PubKey, serveReadyProbe, sig)
+	}
+	if err != nil {
+		slog.Warn("readiness self-sign failed", "err", err)
+		return errors.New("self-sign failed")
+	}
+	return nil
+}
+
+// readFrame reads one length-prefixed frame. It returns io.EOF only when r
+// ends between frames.
+func readFrame(r io.Reader) ([]byte, error) {
+	var n [4]byte
+	if _, err := io.ReadFull(r, n[:]); err != nil {
+		return nil, err
+	}
+	size := binary.BigEndian.Uint32(n[:])
+	if size > serveMaxFrame {
+		return nil, fmt.Errorf("frame of %d bytes is larger than %d", size, serveMaxFrame)
+	}
+	frame := make([]byte, size)
+	if _, err := io.ReadFull(r, frame); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return frame, nil
+}
+
+func writeFrame(w io.Writer, frame []byte) error {
+	buf := make([]byte, 4, 4+len(frame))
+	binary.BigEndian.PutUint32(buf, uint32(len(frame)))
+	_, err := w.Write(append(buf, frame...))
+	return err
+}
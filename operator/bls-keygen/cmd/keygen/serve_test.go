@@ -0,0 +1,349 @@
+//go:build unix
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+)
+
+// startServe runs serve on a socket in a temp dir until the test ends and
+// returns the socket's path.
+func startServe(t *testing.T, args ...string) string {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	cmdContext = ctx
+	socket := filepath.Join(t.TempDir(), "bastion.sock")
+
+	var out syncBuffer
+	done := make(chan error, 1)
+	go func() { done <- runServe(append([]string{"--socket", socket}, args...), &out) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+		cmdContext = context.Background()
+	})
+	waitFor(t, &out, "serving fingerprint")
+	return socket
+}
+
+func serveRequest(t *testing.T, conn net.Conn, r *bufio.Reader, req []byte) []byte {
+	t.Helper()
+	if err := writeFrame(conn, req); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := readFrame(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestRunServeSigns(t *testing.T) {
+	kp, path := writeTestKey(t)
+	t.Setenv("KEY_PASSWORD", testPassword)
+	socket := startServe(t, "--key", path)
+
+	info, err := os.Stat(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("socket mode = %o, want 600", perm)
+	}
+
+	// The connection is left open: stopping the server must not wait for
+	// the client to hang up.
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := bufio.NewReader(conn)
+
+	// One connection serves several requests.
+	for _, msg := range []string{"task one", "task two"} {
+		hash := keccak256([]byte(msg))
+		resp := serveRequest(t, conn, r, append([]byte(serveSignOp), hash...))
+		if len(resp) != 1+48 || resp[0] != serveOK {
+			t.Fatalf("response = %x, want ok and a 48-byte signature", resp)
+		}
+		sig, err := bls.ParseSignatureCompressed(resp[1:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bls.Verify(kp.G2PubKey, hash, sig) {
+			t.Fatalf("signature over %q does not verify", msg)
+		}
+	}
+
+	for name, req := range map[string][]byte{
+		"short hash": []byte(serveSignOp + "abc"),
+		"unknown op": append([]byte("verify "), make([]byte, 32)...),
+	} {
+		if resp := serveRequest(t, conn, r, req); len(resp) == 0 || resp[0] != serveError {
+			t.Errorf("%s: response = %q, want an error", name, resp)
+		}
+	}
+}
+
+func TestRunServeRejectsTCP(t *testing.T) {
+	_, path := writeTestKey(t)
+	t.Setenv("KEY_PASSWORD", testPassword)
+
+	err := runServe([]string{"--key", path, "--socket", "tcp://127.0.0.1:9000"}, &syncBuffer{})
+	if exitCode(err) != exitUsage || !strings.Contains(err.Error(), "Unix socket") {
+		t.Fatalf("got %v, want a usage error", err)
+	}
+}
+
+func probe(t *testing.T, url string) (int, string) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(body)
+}
+
+func TestRunServeHealth(t *testing.T) {
+	_, path := writeTestKey(t)
+	dir := t.TempDir()
+	// Reading the password from a FIFO holds serve before the key is
+	// loaded until the test writes to it.
+	passwordFile := filepath.Join(dir, "password")
+	if err := syscall.Mkfifo(passwordFile, 0600); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cmdContext = ctx
+	args := []string{"--key", path, "--socket", filepath.Join(dir, "bastion.sock"), "--password-file", passwordFile, "--health-addr", "127.0.0.1:0"}
+
+	var out syncBuffer
+	done := make(chan error, 1)
+	go func() { done <- runServe(args, &out) }()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+		cmdContext = context.Background()
+	}()
+
+	waitFor(t, &out, "health checks on ")
+	addr := regexp.MustCompile(`health checks on (\S+)`).FindStringSubmatch(out.String())[1]
+	if code, _ := probe(t, "http://"+addr+"/healthz"); code != http.StatusOK {
+		t.Errorf("/healthz before the key is loaded = %d, want 200", code)
+	}
+	if code, body := probe(t, "http://"+addr+"/readyz"); code != http.StatusServiceUnavailable {
+		t.Errorf("/readyz before the key is loaded = %d %q, want 503", code, body)
+	}
+
+	if err := os.WriteFile(passwordFile, []byte(testPassword+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, &out, "serving fingerprint")
+	code, body := probe(t, "http://"+addr+"/readyz")
+	if code != http.StatusOK {
+		t.Fatalf("/readyz after the key is loaded = %d %q, want 200", code, body)
+	}
+	if strings.TrimSpace(body) != "ok" {
+		t.Errorf("/readyz body = %q, want ok", body)
+	}
+	if code, _ := probe(t, "http://"+addr+"/healthz"); code != http.StatusOK {
+		t.Errorf("/healthz = %d, want 200", code)
+	}
+}
+
+// gatedBackend signs like faultyBackend, but only once the test sends on
+// release, so a serve request can be held in flight.
+type gatedBackend struct {
+	faultyBackend
+	entered, release chan struct{}
+}
+
+func (b gatedBackend) Sign(kp *bls.KeyPair, msg []byte, dst string) (*bls.Signature, error) {
+	b.entered <- struct{}{}
+	<-b.release
+	return b.faultyBackend.Sign(kp, msg, dst)
+}
+
+var serveGate = gatedBackend{entered: make(chan struct{}), release: make(chan struct{})}
+
+func init() {
+	bls.RegisterBackend("gated", serveGate)
+}
+
+// serveGated runs serve with the gated backend until SIGTERM and returns
+// its socket and the channel its result arrives on.
+func serveGated(t *testing.T, args ...string) (string, <-chan error) {
+	t.Helper()
+	socket, done, _ := serveGatedOutput(t, args...)
+	return socket, done
+}
+
+// serveGatedOutput is serveGated also returning serve's output.
+func serveGatedOutput(t *testing.T, args ...string) (string, <-chan error, *syncBuffer) {
+	t.Helper()
+	t.Cleanup(func() { bls.UseBackend(bls.DefaultBackendName) })
+	_, path := writeTestKey(t)
+	t.Setenv("KEY_PASSWORD", testPassword)
+	socket := filepath.Join(t.TempDir(), "bastion.sock")
+	args = append([]string{"--key", path, "--socket", socket, "--backend", "gated"}, args...)
+
+	out := new(syncBuffer)
+	done := make(chan error, 1)
+	go func() { done <- runServe(args, out) }()
+	waitFor(t, out, "serving fingerprint")
+	return socket, done, out
+}
+
+// sigterm sends SIGTERM to the test process, which serve has caught.
+func sigterm(t *testing.T) {
+	t.Helper()
+	if err := syscall.Kill(os.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunServeShutdownDrains(t *testing.T) {
+	socket, done := serveGated(t)
+	busy, err := net.Dial("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+	idle, err := net.Dial("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idle.Close()
+
+	if err := writeFrame(busy, append([]byte(serveSignOp), make([]byte, 32)...)); err != nil {
+		t.Fatal(err)
+	}
+	<-serveGate.entered
+	sigterm(t)
+
+	// The idle connection is closed, and no new ones are accepted.
+	if _, err := readFrame(idle); err == nil {
+		t.Error("idle connection still open after SIGTERM")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		conn, err := net.Dial("unix", socket)
+		if err != nil {
+			break
+		}
+		conn.Close()
+		if time.Now().After(deadline) {
+			t.Fatal("still accepting connections after SIGTERM")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	select {
+	case err := <-done:
+		t.Fatalf("serve returned with a request in flight: %v", err)
+	default:
+	}
+
+	serveGate.release <- struct{}{}
+	resp, err := readFrame(busy)
+	if err != nil {
+		t.Fatalf("in-flight request: %v", err)
+	}
+	if len(resp) != 1+48 || resp[0] != serveOK {
+		t.Fatalf("response = %x, want ok and a 48-byte signature", resp)
+	}
+	if _, err := bls.ParseSignatureCompressed(resp[1:]); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunServeShutdownTimeout(t *testing.T) {
+	socket, done := serveGated(t, "--shutdown-timeout", "50ms")
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if err := writeFrame(conn, append([]byte(serveSignOp), make([]byte, 32)...)); err != nil {
+		t.Fatal(err)
+	}
+	<-serveGate.entered
+	sigterm(t)
+
+	// Past the timeout the connection is closed without a response. Serve
+	// still waits for the signing to return before wiping the key.
+	if _, err := readFrame(conn); err == nil {
+		t.Error("got a response past --shutdown-timeout")
+	}
+	serveGate.release <- struct{}{}
+	if err := <-done; err == nil || !strings.Contains(err.Error(), "shutdown-timeout") {
+		t.Fatalf("got %v, want a shutdown timeout error", err)
+	}
+}
+
+func TestRunServeShutdownHealth(t *testing.T) {
+	socket, done, out := serveGatedOutput(t, "--health-addr", "127.0.0.1:0")
+	addr := regexp.MustCompile(`health checks on (\S+)`).FindStringSubmatch(out.String())[1]
+	busy, err := net.Dial("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+	if err := writeFrame(busy, append([]byte(serveSignOp), make([]byte, 32)...)); err != nil {
+		t.Fatal(err)
+	}
+	<-serveGate.entered
+	sigterm(t)
+
+	// Once new connections are refused, /readyz turns away new traffic too
+	// while the request drains.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		c, err := net.Dial("unix", socket)
+		if err != nil {
+			break
+		}
+		c.Close()
+		if time.Now().After(deadline) {
+			t.Fatal("socket still accepting after SIGTERM")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if code, _ := probe(t, "http://"+addr+"/readyz"); code != http.StatusServiceUnavailable {
+		t.Fatalf("/readyz = %d while shutting down, want 503", code)
+	}
+	serveGate.release <- struct{}{}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	// Once serve returns, the probes are no longer served.
+	if resp, err := http.Get("http://" + addr + "/healthz"); err == nil {
+		resp.Body.Close()
+		t.Fatal("health server still up after serve returned")
+	}
+}
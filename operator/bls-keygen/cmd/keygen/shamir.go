@@ -0,0 +1,173 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+// stringList is a flag that may be given more than once.
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+// sharePath is where split writes share i of n for the key at keyPath.
+func sharePath(dir, keyPath string, i, n int) string {
+	base := strings.TrimSuffix(filepath.Base(keyPath), filepath.Ext(keyPath))
+	return filepath.Join(dir, fmt.Sprintf("%s.share-%d-of-%d.json", base, i, n))
+}
+
+// runSplit implements `keygen split`: it splits the stored private key into
+// --shares Shamir shares, any --threshold of which `keygen combine` turns
+// back into the key. Each share is written to its own file.
+func runSplit(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("split", flag.ContinueOnError)
+	keyPath := fs.String("key", filepath.Join(defaultKeyDir, defaultKeyFile), "key file to split")
+	var pwSource passwordSource
+	pwSource.register(fs)
+	var pin keyPin
+	pin.register(fs)
+	n := fs.Int("shares", 5, "number of shares to write")
+	threshold := fs.Int("threshold", 3, "number of shares needed to reconstruct the key")
+	outDir := fs.String("out-dir", "", "directory to write the shares to (default: the key's directory)")
+	force := fs.Bool("force", false, "replace existing share files, backing each up first")
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if err := pin.validate(); err != nil {
+		return err
+	}
+	if *threshold < 2 || *threshold > *n || *n > blskeys.MaxShares {
+		return usageErrorf("need 2 <= --threshold <= --shares <= %d, got %d of %d", blskeys.MaxShares, *threshold, *n)
+	}
+	dir := *outDir
+	if dir == "" {
+		dir = filepath.Dir(*keyPath)
+	}
+	paths := make([]string, *n)
+	for i := range paths {
+		paths[i] = sharePath(dir, *keyPath, i+1, *n)
+		if err := checkNewKeyPath(paths[i], *force); err != nil {
+			return fmt.Errorf("share file %s: %w", paths[i], err)
+		}
+	}
+
+	password, err := pwSource.read()
+	if err != nil {
+		return err
+	}
+	kp, err := loadKey(*keyPath, password)
+	if err != nil {
+		return fmt.Errorf("failed to load key %s: %w", *keyPath, err)
+	}
+	defer kp.PrivateKey.Zero()
+	if err := pin.check(*keyPath, kp.G1PubKey); err != nil {
+		return err
+	}
+
+	shares, err := blskeys.Split(kp, *n, *threshold)
+	if err != nil {
+		return err
+	}
+	if err := ensureKeyDir(dir, blskeys.KeyDirMode); err != nil {
+		return err
+	}
+	for i, s := range shares {
+		data, err := blskeys.MarshalShare(s)
+		if err != nil {
+			return err
+		}
+		if _, err := writeKeyFile(paths[i], data, *force); err != nil {
+			return fmt.Errorf("share file %s: %w", paths[i], err)
+		}
+		fmt.Fprintln(stdout, paths[i])
+	}
+	slog.Warn("each share is unencrypted key material; give every share to a different custodian and store it offline")
+	return nil
+}
+
+// runCombine implements `keygen combine`: it reconstructs a key from
+// --share files and writes it as an encrypted key file.
+func runCombine(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("combine", flag.ContinueOnError)
+	var sharePaths stringList
+	fs.Var(&sharePaths, "share", "share file written by split (repeat for each share)")
+	out := fs.String("out", filepath.Join(defaultKeyDir, defaultKeyFile), "path of the key file to write")
+	var pwSource passwordSource
+	pwSource.register(fs)
+	force := fs.Bool("force", false, "replace an existing key, backing it up first")
+	var policy passwordPolicy
+	policy.register(fs)
+	var perms permCheck
+	perms.register(fs)
+	var kdf kdfOptions
+	kdf.register(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if err := perms.validate(); err != nil {
+		return err
+	}
+	resolved, err := perms.keyPath(*out)
+	if err != nil {
+		return err
+	}
+	*out = resolved
+
+	if len(sharePaths) == 0 {
+		return usageErrorf("at least one --share is required")
+	}
+	params, err := kdf.params()
+	if err != nil {
+		return err
+	}
+
+	shares := make([]blskeys.Share, len(sharePaths))
+	for i, p := range sharePaths {
+		if shares[i], err = blskeys.LoadShare(p); err != nil {
+			return fmt.Errorf("failed to read share %s: %w", p, err)
+		}
+	}
+	kp, err := blskeys.Combine(shares)
+	if err != nil {
+		return err
+	}
+	defer kp.PrivateKey.Zero()
+
+	if err := checkNewKeyPath(*out, *force); err != nil {
+		return err
+	}
+	password, err := pwSource.readNew()
+	if err != nil {
+		return err
+	}
+	if err := policy.check(password); err != nil {
+		return err
+	}
+	if err := perms.ensureDir(filepath.Dir(*out)); err != nil {
+		return err
+	}
+	data, err := blskeys.MarshalContext(cmdContext, kp, password, params, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := writeKeyFile(*out, data, *force); err != nil {
+		return err
+	}
+	if err := perms.check(*out); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Key reconstructed from %d shares and saved to %s\n", len(shares), *out)
+	fmt.Fprintf(stdout, "G1 public key: 0x%x\n", kp.G1PubKey.Bytes())
+	return nil
+}
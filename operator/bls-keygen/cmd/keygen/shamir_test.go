@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+func TestRunSplitCombine(t *testing.T) {
+	kp, path := writeTestKey(t)
+	t.Setenv("KEY_PASSWORD", testPassword)
+	dir := t.TempDir()
+
+	var out bytes.Buffer
+	if err := runSplit([]string{"--key", path, "--shares", "5", "--threshold", "3", "--out-dir", dir}, &out); err != nil {
+		t.Fatal(err)
+	}
+	shares := strings.Fields(out.String())
+	if len(shares) != 5 {
+		t.Fatalf("split wrote %d shares, want 5: %v", len(shares), shares)
+	}
+
+	restored := filepath.Join(t.TempDir(), "restored.json")
+	args := []string{"--out", restored, "--share", shares[4], "--share", shares[0], "--share", shares[2]}
+	if err := runCombine(args, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := blskeys.Load(restored, testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(loaded.PrivateKey.Bytes(), kp.PrivateKey.Bytes()) {
+		t.Fatal("combined key does not match the original")
+	}
+
+	if err := runSplit([]string{"--key", path, "--out-dir", dir}, &bytes.Buffer{}); err == nil {
+		t.Fatal("split overwrote existing share files")
+	}
+}
+
+func TestRunCombineInsufficientShares(t *testing.T) {
+	_, path := writeTestKey(t)
+	t.Setenv("KEY_PASSWORD", testPassword)
+	dir := t.TempDir()
+
+	var out bytes.Buffer
+	if err := runSplit([]string{"--key", path, "--shares", "5", "--threshold", "3", "--out-dir", dir}, &out); err != nil {
+		t.Fatal(err)
+	}
+	shares := strings.Fields(out.String())
+
+	restored := filepath.Join(t.TempDir(), "restored.json")
+	err := runCombine([]string{"--out", restored, "--share", shares[0], "--share", shares[1]}, &bytes.Buffer{})
+	if !errors.Is(err, blskeys.ErrTooFewShares) {
+		t.Fatalf("got %v, want ErrTooFewShares", err)
+	}
+}
+
+func TestRunSplitBadThreshold(t *testing.T) {
+	_, path := writeTestKey(t)
+	if err := runSplit([]string{"--key", path, "--shares", "2", "--threshold", "3"}, &bytes.Buffer{}); exitCode(err) != exitUsage {
+		t.Fatalf("got %v, want a usage error", err)
+	}
+}
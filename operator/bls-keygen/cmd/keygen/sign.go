@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/sha3"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+const (
+	signerFile = "file"
+	signerKMS  = "kms"
+
+	encodingCompressed   = "compressed"
+	encodingUncompressed = "uncompressed"
+)
+
+// newKMSClient connects to the KMS for --signer kms. No KMS is wired into
+// this build; deployments that have one replace it.
+var newKMSClient = func() (blskeys.KMSClient, error) {
+	return nil, errors.New("no KMS client is available in this build")
+}
+
+// runSign implements `keygen sign`: it signs keccak256(message) with the
+// stored key, or a KMS-held one, and prints the G1 signature. File keys sign
+// under the tag of --network; KMS keys only under the mainnet one. Either
+// way --message-prefix is prepended to the digest.
+func runSign(args []string, stdout io.Writer) (err error) {
+	fs := flag.NewFlagSet("sign", flag.ContinueOnError)
+	keyPath := fs.String("key", filepath.Join(defaultKeyDir, defaultKeyFile), "key file to sign with")
+	message := fs.String("message", "", "hex-encoded message to sign")
+	var pwSource passwordSource
+	pwSource.register(fs)
+	var pin keyPin
+	pin.register(fs)
+	signerKind := fs.String("signer", signerFile, "where the key lives: file or kms")
+	kmsKeyID := fs.String("kms-key-id", "", "KMS key to sign with (--signer kms)")
+	encoding := fs.String("encoding", encodingCompressed, "signature encoding: compressed (48 bytes) or uncompressed (96 bytes)")
+	var signing signingOptions
+	signing.register(fs)
+	var audit auditOptions
+	audit.register(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	defer func(start time.Time) { activeMetrics.observe("sign", start, err) }(time.Now())
+	var auditKey *bls.G1PubKey
+	defer func() { err = audit.done("sign", auditKey, err) }()
+	if *message == "" {
+		return usageErrorf("--message is required")
+	}
+	if err := pin.validate(); err != nil {
+		return err
+	}
+	if *encoding != encodingCompressed && *encoding != encodingUncompressed {
+		return usageErrorf("unknown --encoding %q", *encoding)
+	}
+
+	sc, err := signing.context()
+	if err != nil {
+		return err
+	}
+
+	msg, err := decodeHex(*message)
+	if err != nil {
+		return fmt.Errorf("invalid --message: %w", err)
+	}
+	var signer blskeys.Signer
+	switch *signerKind {
+	case signerFile:
+		password, err := pwSource.read()
+		if err != nil {
+			return err
+		}
+		if signer, err = blskeys.NewFileSigner(*keyPath, password); err != nil {
+			return fmt.Errorf("failed to load key %s: %w", *keyPath, err)
+		}
+		warnNetworkMismatch(*keyPath, signing.name)
+	case signerKMS:
+		if *kmsKeyID == "" {
+			return usageErrorf("--kms-key-id is required with --signer kms")
+		}
+		if pin.path != "" {
+			return usageErrorf("--pin-file needs --signer file")
+		}
+		client, err := newKMSClient()
+		if err != nil {
+			return err
+		}
+		if signer, err = blskeys.NewKMSSigner(client, *kmsKeyID); err != nil {
+			return err
+		}
+	default:
+		return usageErrorf("unknown --signer %q", *signerKind)
+	}
+	if c, ok := signer.(io.Closer); ok {
+		defer c.Close()
+	}
+	if *signerKind == signerFile {
+		// The file signer checked the stored public keys against the
+		// decrypted key, so the stored G1 key identifies it.
+		g1, err := blskeys.LoadPublicKey(*keyPath)
+		if err != nil {
+			return err
+		}
+		auditKey = g1
+		if err := pin.check(*keyPath, g1); err != nil {
+			return err
+		}
+	}
+
+	var sig *bls.Signature
+	if ds, ok := signer.(blskeys.DomainSigner); ok {
+		sig, err = ds.SignDomain(sc.Message(keccak256(msg)), sc.Domain)
+	} else if sc.Domain != bls.DefaultDomain {
+		return usageErrorf("--signer %s can only sign for --network mainnet", *signerKind)
+	} else {
+		sig, err = signer.Sign(sc.Message(keccak256(msg)))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to sign: %w", err)
+	}
+	if *encoding == encodingUncompressed {
+		fmt.Fprintf(stdout, "0x%x\n", sig.UncompressedBytes())
+	} else {
+		fmt.Fprintf(stdout, "0x%x\n", sig.CompressedBytes())
+	}
+	return nil
+}
+
+// warnNetworkMismatch logs a warning when the key file at path was created
+// for a network other than the one the command runs under.
+func warnNetworkMismatch(path, network string) {
+	meta, err := blskeys.LoadMetadata(path)
+	if err != nil || meta == nil || meta.Network == "" || meta.Network == network {
+		return
+	}
+	slog.Warn("key file was created for a different network", "path", path, "key_network", meta.Network, "network", network)
+}
+
+// decodeHex decodes a hex string with an optional 0x prefix.
+func decodeHex(s string) ([]byte, error) {
+	return hex.DecodeString(strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X"))
+}
+
+func keccak256(data []byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	h.Write(data)
+	return h.Sum(nil)
+}
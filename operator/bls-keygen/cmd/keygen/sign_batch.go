@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+)
+
+// runSignBatch implements `keygen sign-batch`: it signs keccak256 of every
+// hex message in --messages-file, one per line, and prints a JSON array of
+// signatures in input order. The key is decrypted once for the whole batch,
+// and every message is signed in the context of the --network and
+// --message-prefix flags, like sign.
+func runSignBatch(args []string, stdout io.Writer) (err error) {
+	fs := flag.NewFlagSet("sign-batch", flag.ContinueOnError)
+	keyPath := fs.String("key", filepath.Join(defaultKeyDir, defaultKeyFile), "key file to sign with")
+	messagesFile := fs.String("messages-file", "", "file with one hex-encoded message per line")
+	var pwSource passwordSource
+	pwSource.register(fs)
+	var pin keyPin
+	pin.register(fs)
+	encoding := fs.String("encoding", encodingCompressed, "signature encoding: compressed (48 bytes) or uncompressed (96 bytes)")
+	var signing signingOptions
+	signing.register(fs)
+	var audit auditOptions
+	audit.register(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if err := pin.validate(); err != nil {
+		return err
+	}
+	defer func(start time.Time) { activeMetrics.observe("sign-batch", start, err) }(time.Now())
+	var auditKey *bls.G1PubKey
+	defer func() { err = audit.done("sign-batch", auditKey, err) }()
+	if *messagesFile == "" {
+		return usageErrorf("--messages-file is required")
+	}
+	if *encoding != encodingCompressed && *encoding != encodingUncompressed {
+		return usageErrorf("unknown --encoding %q", *encoding)
+	}
+
+	sc, err := signing.context()
+	if err != nil {
+		return err
+	}
+	messages, err := readBatchMessages(*messagesFile)
+	if err != nil {
+		return err
+	}
+
+	password, err := pwSource.read()
+	if err != nil {
+		return err
+	}
+	kp, err := loadKey(*keyPath, password)
+	if err != nil {
+		return fmt.Errorf("failed to load key %s: %w", *keyPath, err)
+	}
+	defer kp.PrivateKey.Zero()
+	auditKey = kp.G1PubKey
+	if err := pin.check(*keyPath, kp.G1PubKey); err != nil {
+		return err
+	}
+	warnNetworkMismatch(*keyPath, signing.name)
+
+	sigs := make([]string, len(messages))
+	for i, msg := range messages {
+		sig, err := sc.Sign(kp, keccak256(msg))
+		if err != nil {
+			return fmt.Errorf("failed to sign message %d: %w", i+1, err)
+		}
+		if *encoding == encodingUncompressed {
+			sigs[i] = fmt.Sprintf("0x%x", sig.UncompressedBytes())
+		} else {
+			sigs[i] = fmt.Sprintf("0x%x", sig.CompressedBytes())
+		}
+	}
+	return json.NewEncoder(stdout).Encode(sigs)
+}
+
+// readBatchMessages decodes the hex message on each line of path. Blank
+// lines are skipped; a bad line is reported by its line number.
+func readBatchMessages(path string) ([][]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open messages file: %w", err)
+	}
+	defer f.Close()
+
+	var messages [][]byte
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 1<<20)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" {
+			continue
+		}
+		msg, err := decodeHex(text)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid message: %w", path, line, err)
+		}
+		messages = append(messages, msg)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read messages file: %w", err)
+	}
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("%s: no messages to sign", path)
+	}
+	return messages, nil
+}
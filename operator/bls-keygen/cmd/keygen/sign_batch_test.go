@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+)
+
+func TestRunSignBatch(t *testing.T) {
+	kp, path := writeTestKey(t)
+	t.Setenv("KEY_PASSWORD", testPassword)
+
+	// Count decryptions through the KDF progress hook.
+	var progress bytes.Buffer
+	defer func(old func() io.Writer) { progressOut = old }(progressOut)
+	progressOut = func() io.Writer { return &progress }
+
+	messages := [][]byte{{0x01}, {0xde, 0xad, 0xbe, 0xef}, {}, []byte("task 3")}
+	var lines []string
+	for _, m := range messages {
+		lines = append(lines, fmt.Sprintf("0x%x", m))
+	}
+	file := filepath.Join(t.TempDir(), "messages.jsonl")
+	if err := os.WriteFile(file, []byte(strings.Join(lines, "\n")+"\n\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := runSignBatch([]string{"--key", path, "--messages-file", file}, &out); err != nil {
+		t.Fatal(err)
+	}
+	var sigs []string
+	if err := json.Unmarshal(out.Bytes(), &sigs); err != nil {
+		t.Fatalf("output is not a JSON array: %v\n%s", err, out.String())
+	}
+	if len(sigs) != len(messages) {
+		t.Fatalf("got %d signatures, want %d", len(sigs), len(messages))
+	}
+	for i, s := range sigs {
+		raw, err := decodeHex(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig, err := bls.SignatureFromBytes(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bls.Verify(kp.G2PubKey, keccak256(messages[i]), sig) {
+			t.Fatalf("signature %d does not verify over message %d", i, i)
+		}
+	}
+	if n := strings.Count(progress.String(), "Deriving key"); n != 1 {
+		t.Fatalf("key was decrypted %d times, want once", n)
+	}
+}
+
+func TestRunSignBatchBadInput(t *testing.T) {
+	_, path := writeTestKey(t)
+	t.Setenv("KEY_PASSWORD", testPassword)
+	dir := t.TempDir()
+
+	if err := runSignBatch([]string{"--key", path}, &bytes.Buffer{}); exitCode(err) != exitUsage {
+		t.Fatalf("missing --messages-file: got %v, want a usage error", err)
+	}
+
+	bad := filepath.Join(dir, "bad.jsonl")
+	if err := os.WriteFile(bad, []byte("0x01\nnot-hex\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := runSignBatch([]string{"--key", path, "--messages-file", bad}, &bytes.Buffer{}); err == nil || !strings.Contains(err.Error(), ":2:") {
+		t.Fatalf("bad line: got %v, want an error naming line 2", err)
+	}
+
+	empty := filepath.Join(dir, "empty.jsonl")
+	if err := os.WriteFile(empty, []byte("\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := runSignBatch([]string{"--key", path, "--messages-file", empty}, &bytes.Buffer{}); err == nil {
+		t.Fatal("expected an error for a file without messages")
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+func TestRunSign(t *testing.T) {
+	kp, path := writeTestKey(t)
+	t.Setenv("KEY_PASSWORD", testPassword)
+
+	var out bytes.Buffer
+	if err := runSign([]string{"--key", path, "--message", "0xdeadbeef"}, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := decodeHex(strings.TrimSpace(out.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := bls.SignatureFromBytes(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bls.Verify(kp.G2PubKey, keccak256([]byte{0xde, 0xad, 0xbe, 0xef}), sig) {
+		t.Fatal("signature does not verify over keccak256(message)")
+	}
+}
+
+func TestRunSignEncoding(t *testing.T) {
+	kp, path := writeTestKey(t)
+	t.Setenv("KEY_PASSWORD", testPassword)
+
+	for encoding, parse := range map[string]func([]byte) (*bls.Signature, error){
+		"compressed":   bls.ParseSignatureCompressed,
+		"uncompressed": bls.ParseSignatureUncompressed,
+	} {
+		var out bytes.Buffer
+		if err := runSign([]string{"--key", path, "--message", "0xdeadbeef", "--encoding", encoding}, &out); err != nil {
+			t.Fatal(err)
+		}
+		raw, err := decodeHex(strings.TrimSpace(out.String()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig, err := parse(raw)
+		if err != nil {
+			t.Fatalf("%s: %v", encoding, err)
+		}
+		if !bls.Verify(kp.G2PubKey, keccak256([]byte{0xde, 0xad, 0xbe, 0xef}), sig) {
+			t.Fatalf("%s signature does not verify", encoding)
+		}
+	}
+
+	if err := runSign([]string{"--key", path, "--message", "0xdeadbeef", "--encoding", "der"}, &bytes.Buffer{}); exitCode(err) != exitUsage {
+		t.Fatalf("unknown encoding: got %v, want a usage error", err)
+	}
+}
+
+func TestRunSignBadPassword(t *testing.T) {
+	_, path := writeTestKey(t)
+	t.Setenv("KEY_PASSWORD", "not the password")
+
+	err := runSign([]string{"--key", path, "--message", "deadbeef"}, &bytes.Buffer{})
+	if !errors.Is(err, blskeys.ErrDecrypt) {
+		t.Fatalf("got %v, want ErrDecrypt", err)
+	}
+}
+
+func TestRunSignBadMessage(t *testing.T) {
+	_, path := writeTestKey(t)
+	t.Setenv("KEY_PASSWORD", testPassword)
+
+	if err := runSign([]string{"--key", path, "--message", "zz"}, &bytes.Buffer{}); err == nil {
+		t.Fatal("expected an error for a non-hex message")
+	}
+	if err := runSign([]string{"--key", path}, &bytes.Buffer{}); err == nil {
+		t.Fatal("expected an error for a missing message")
+	}
+}
+
+// mockKMS is a KMSClient holding its key in memory.
+type mockKMS struct {
+	kp    *blskeys.KeyPair
+	calls int
+}
+
+func (m *mockKMS) Sign(keyID string, msg []byte) ([]byte, error) {
+	m.calls++
+	sig, err := m.kp.Sign(msg)
+	if err != nil {
+		return nil, err
+	}
+	return sig.Bytes(), nil
+}
+
+func (m *mockKMS) PublicKeyG2(keyID string) ([]byte, error) {
+	return m.kp.G2PubKey.Bytes(), nil
+}
+
+func TestRunSignKMS(t *testing.T) {
+	kp, err := blskeys.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	kms := &mockKMS{kp: kp}
+	orig := newKMSClient
+	newKMSClient = func() (blskeys.KMSClient, error) { return kms, nil }
+	t.Cleanup(func() { newKMSClient = orig })
+
+	var out bytes.Buffer
+	if err := runSign([]string{"--signer", "kms", "--kms-key-id", "op", "--message", "0xdeadbeef"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if kms.calls != 1 {
+		t.Fatalf("KMS was called %d times, want 1", kms.calls)
+	}
+	raw, err := decodeHex(strings.TrimSpace(out.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := bls.SignatureFromBytes(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bls.Verify(kp.G2PubKey, keccak256([]byte{0xde, 0xad, 0xbe, 0xef}), sig) {
+		t.Fatal("KMS signature does not verify")
+	}
+}
+
+func TestRunSignBadSigner(t *testing.T) {
+	for _, args := range [][]string{
+		{"--signer", "hsm", "--message", "0x00"},
+		{"--signer", "kms", "--message", "0x00"},
+		{"--signer", "kms", "--kms-key-id", "op", "--message", "0x00"}, // no client in this build
+	} {
+		if err := runSign(args, &bytes.Buffer{}); err == nil {
+			t.Errorf("runSign(%q) succeeded, want error", args)
+		}
+	}
+}
+
+func TestSignVerifySigningContext(t *testing.T) {
+	kp, path := writeTestKey(t)
+	t.Setenv("KEY_PASSWORD", testPassword)
+	pub := fmt.Sprintf("0x%x", kp.G2PubKey.Bytes())
+
+	sign := func(flags ...string) string {
+		t.Helper()
+		var out bytes.Buffer
+		if err := runSign(append([]string{"--key", path, "--message", "0x01"}, flags...), &out); err != nil {
+			t.Fatal(err)
+		}
+		return strings.TrimSpace(out.String())
+	}
+	contexts := map[string][]string{
+		"mainnet": nil,
+		"holesky": {"--network", "holesky"},
+		"prefix":  {"--message-prefix", "0x626173"},
+	}
+	for signedIn, flags := range contexts {
+		sig := sign(flags...)
+		for verifiedIn, vflags := range contexts {
+			err := runVerify(append([]string{"--pubkey", pub, "--message", "0x01", "--signature", sig}, vflags...), &bytes.Buffer{})
+			if valid := err == nil; valid != (signedIn == verifiedIn) {
+				t.Errorf("signed with %s, verified with %s: %v", signedIn, verifiedIn, err)
+			}
+		}
+	}
+
+	if err := runSign([]string{"--key", path, "--message", "0x01", "--message-prefix", "zz"}, &bytes.Buffer{}); exitCode(err) != exitUsage {
+		t.Fatalf("bad --message-prefix: got %v, want a usage error", err)
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/eip712"
+)
+
+// signTypedResult is what sign-typed prints: the EIP-712 digest, so it can
+// be compared with the one the verifying contract computes, and the
+// signature over it.
+type signTypedResult struct {
+	Digest    string `json:"digest"`
+	Signature string `json:"signature"`
+}
+
+// runSignTyped implements `keygen sign-typed`: it computes the EIP-712
+// digest of --typed-data and signs the digest itself, not its keccak256,
+// in the context of --network and --message-prefix like sign.
+func runSignTyped(args []string, stdout io.Writer) (err error) {
+	fs := flag.NewFlagSet("sign-typed", flag.ContinueOnError)
+	keyPath := fs.String("key", filepath.Join(defaultKeyDir, defaultKeyFile), "key file to sign with")
+	typedData := fs.String("typed-data", "", "EIP-712 typed data to sign: a JSON object, or a file containing one")
+	var pwSource passwordSource
+	pwSource.register(fs)
+	var pin keyPin
+	pin.register(fs)
+	encoding := fs.String("encoding", encodingCompressed, "signature encoding: compressed (48 bytes) or uncompressed (96 bytes)")
+	var signing signingOptions
+	signing.register(fs)
+	var audit auditOptions
+	audit.register(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if err := pin.validate(); err != nil {
+		return err
+	}
+	defer func(start time.Time) { activeMetrics.observe("sign-typed", start, err) }(time.Now())
+	var auditKey *bls.G1PubKey
+	defer func() { err = audit.done("sign-typed", auditKey, err) }()
+	if *typedData == "" {
+		return usageErrorf("--typed-data is required")
+	}
+	if *encoding != encodingCompressed && *encoding != encodingUncompressed {
+		return usageErrorf("unknown --encoding %q", *encoding)
+	}
+
+	sc, err := signing.context()
+	if err != nil {
+		return err
+	}
+	digest, err := typedDataDigest(*typedData)
+	if err != nil {
+		return err
+	}
+
+	password, err := pwSource.read()
+	if err != nil {
+		return err
+	}
+	kp, err := loadKey(*keyPath, password)
+	if err != nil {
+		return fmt.Errorf("failed to load key %s: %w", *keyPath, err)
+	}
+	defer kp.PrivateKey.Zero()
+	auditKey = kp.G1PubKey
+	if err := pin.check(*keyPath, kp.G1PubKey); err != nil {
+		return err
+	}
+	warnNetworkMismatch(*keyPath, signing.name)
+
+	sig, err := sc.Sign(kp, digest[:])
+	if err != nil {
+		return fmt.Errorf("failed to sign: %w", err)
+	}
+	res := signTypedResult{Digest: fmt.Sprintf("0x%x", digest)}
+	if *encoding == encodingUncompressed {
+		res.Signature = fmt.Sprintf("0x%x", sig.UncompressedBytes())
+	} else {
+		res.Signature = fmt.Sprintf("0x%x", sig.CompressedBytes())
+	}
+	return json.NewEncoder(stdout).Encode(res)
+}
+
+// typedDataDigest parses the --typed-data value, inline JSON when it
+// starts with '{' and otherwise the path of a file, and returns its digest.
+func typedDataDigest(value string) ([32]byte, error) {
+	data := []byte(value)
+	if !strings.HasPrefix(strings.TrimSpace(value), "{") {
+		var err error
+		if data, err = os.ReadFile(value); err != nil {
+			return [32]byte{}, fmt.Errorf("failed to read --typed-data: %w", err)
+		}
+	}
+	td, err := eip712.Parse(data)
+	if err != nil {
+		return [32]byte{}, fmt.Errorf("invalid --typed-data: %w", err)
+	}
+	digest, err := td.Digest()
+	if err != nil {
+		return [32]byte{}, fmt.Errorf("invalid --typed-data: %w", err)
+	}
+	return digest, nil
+}
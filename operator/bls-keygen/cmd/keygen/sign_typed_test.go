@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+)
+
+// mailTypedData is the example of the EIP-712 specification, whose digest
+// is mailDigest.
+const (
+	mailTypedData = "../../pkg/eip712/testdata/mail.json"
+	mailDigest    = "0xbe609aee343fb3c4b28e1df9e632fca64fcfaede20f02e86244efddf30957bd2"
+)
+
+func TestRunSignTyped(t *testing.T) {
+	kp, path := writeTestKey(t)
+	t.Setenv("KEY_PASSWORD", testPassword)
+	inline, err := os.ReadFile(mailTypedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The file and the same JSON given inline sign the same digest.
+	for _, typedData := range []string{mailTypedData, string(inline)} {
+		var out bytes.Buffer
+		if err := runSignTyped([]string{"--key", path, "--typed-data", typedData}, &out); err != nil {
+			t.Fatal(err)
+		}
+		var res signTypedResult
+		if err := json.Unmarshal(out.Bytes(), &res); err != nil {
+			t.Fatalf("output is not JSON: %v\n%s", err, out.String())
+		}
+		if res.Digest != mailDigest {
+			t.Fatalf("digest = %s, want %s", res.Digest, mailDigest)
+		}
+		digest, err := decodeHex(res.Digest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw, err := decodeHex(res.Signature)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig, err := bls.SignatureFromBytes(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bls.Verify(kp.G2PubKey, digest, sig) {
+			t.Fatal("signature does not verify over the digest")
+		}
+	}
+}
+
+func TestRunSignTypedInvalid(t *testing.T) {
+	_, path := writeTestKey(t)
+	t.Setenv("KEY_PASSWORD", testPassword)
+	inline, err := os.ReadFile(mailTypedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bad := strings.Replace(string(inline), `"Hello, Bob!"`, `42`, 1)
+
+	err = runSignTyped([]string{"--key", path, "--typed-data", bad}, &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "message.contents: string: want a string, got number 42") {
+		t.Fatalf("got %v, want an error naming message.contents", err)
+	}
+}
+
+func TestRunSignTypedUsage(t *testing.T) {
+	if err := runSignTyped(nil, &bytes.Buffer{}); exitCode(err) != exitUsage {
+		t.Fatalf("got %v, want a usage error", err)
+	}
+}
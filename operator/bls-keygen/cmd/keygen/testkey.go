@@ -0,0 +1,28 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+// mainnetChainID is the chain ID of Ethereum mainnet.
+const mainnetChainID = 1
+
+// errTestKey is returned when a key made with `generate --test` is used
+// against mainnet.
+var errTestKey = errors.New("key is marked test-only")
+
+// checkTestKey refuses the key file at path on mainnet if its metadata
+// marks it test-only, unless allow (--allow-test-key) is set.
+func checkTestKey(path string, mainnet, allow bool) error {
+	if !mainnet || allow {
+		return nil
+	}
+	meta, err := blskeys.LoadMetadata(path)
+	if err != nil || meta == nil || !meta.TestOnly {
+		return nil
+	}
+	return fmt.Errorf("%w: refusing to use %s on mainnet (pass --allow-test-key to override)", errTestKey, path)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+func writeTestOnlyKey(t *testing.T) (*blskeys.KeyPair, string) {
+	t.Helper()
+	t.Setenv("KEY_PASSWORD", testPassword)
+	path := filepath.Join(t.TempDir(), "bls_key.json")
+	if err := runGenerate([]string{"--out", path, "--test"}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	meta, err := blskeys.LoadMetadata(path)
+	if err != nil || meta == nil || !meta.TestOnly {
+		t.Fatalf("metadata = %+v, %v; want test_only", meta, err)
+	}
+	kp, err := blskeys.Load(path, testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return kp, path
+}
+
+func registerArgs(path string, chainID int) []string {
+	return []string{
+		"--key", path,
+		"--operator", "0x0000000000000000000000000000000000000001",
+		"--registry-coordinator", "0x0000000000000000000000000000000000000002",
+		"--chain-id", fmt.Sprint(chainID),
+		"--salt", "0x" + fmt.Sprintf("%064x", 7),
+		"--expiry", "1700000000",
+	}
+}
+
+func TestTestKeyRefusedForMainnetRegistration(t *testing.T) {
+	_, path := writeTestOnlyKey(t)
+
+	var out bytes.Buffer
+	if err := runRegisterPayload(registerArgs(path, mainnetChainID), &out); !errors.Is(err, errTestKey) {
+		t.Fatalf("mainnet: got %v, want errTestKey", err)
+	}
+	if out.Len() != 0 {
+		t.Fatal("a payload was printed for a test key on mainnet")
+	}
+	if err := runRegisterPayload(append(registerArgs(path, mainnetChainID), "--allow-test-key"), &bytes.Buffer{}); err != nil {
+		t.Fatalf("--allow-test-key: %v", err)
+	}
+	// Test keys are meant for testnets such as Holesky.
+	if err := runRegisterPayload(registerArgs(path, 17000), &bytes.Buffer{}); err != nil {
+		t.Fatalf("holesky: %v", err)
+	}
+
+	// Keys without the mark are unaffected.
+	_, prod := writeTestKey(t)
+	if err := runRegisterPayload(registerArgs(prod, mainnetChainID), &bytes.Buffer{}); err != nil {
+		t.Fatalf("production key: %v", err)
+	}
+}
+
+func TestTestKeyAssertNetwork(t *testing.T) {
+	kp, path := writeTestOnlyKey(t)
+	g1 := fmt.Sprintf("0x%x", kp.G1PubKey.Bytes())
+
+	if err := runAssert([]string{"--key", path, "--expect-g1", g1}, &bytes.Buffer{}); !errors.Is(err, errTestKey) {
+		t.Fatalf("mainnet: got %v, want errTestKey", err)
+	}
+	if err := runAssert([]string{"--key", path, "--expect-g1", g1, "--network", "holesky"}, &bytes.Buffer{}); err != nil {
+		t.Fatalf("holesky: %v", err)
+	}
+	if err := runAssert([]string{"--key", path, "--expect-g1", g1, "--allow-test-key"}, &bytes.Buffer{}); err != nil {
+		t.Fatalf("--allow-test-key: %v", err)
+	}
+}
+
+func TestGenerateTestKeyEIP2335(t *testing.T) {
+	if _, err := parseFlags([]string{"--test", "--format", "eip2335"}); exitCode(err) != exitUsage {
+		t.Fatalf("got %v, want a usage error", err)
+	}
+}
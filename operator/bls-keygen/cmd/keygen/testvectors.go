@@ -0,0 +1,121 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+)
+
+// testVectorSeed seeds the generator of the test vectors. Changing it, the
+// message lengths or the derivation changes every vector, so other
+// implementations' copies have to be regenerated.
+const testVectorSeed = "bastion-bls-testvectors-v1"
+
+// testVectorMessageLengths are the lengths of the vectors' messages, one
+// vector each: empty, short, a 32-byte digest and longer than a hash block.
+var testVectorMessageLengths = []int{0, 1, 32, 100}
+
+// testVectorFile is the document testvectors prints. Every value is
+// 0x-prefixed hex; points are in the compressed ZCash encoding, and the
+// signature also uncompressed, the form the EIP-2537 precompiles take.
+type testVectorFile struct {
+	Curve   string       `json:"curve"`
+	DST     string       `json:"dst"`
+	Seed    string       `json:"seed"`
+	Vectors []testVector `json:"vectors"`
+}
+
+type testVector struct {
+	PrivateKey            string `json:"private_key"`
+	G1PubKey              string `json:"g1_pub_key"`
+	G2PubKey              string `json:"g2_pub_key"`
+	Message               string `json:"message"`
+	Signature             string `json:"signature"`
+	SignatureUncompressed string `json:"signature_uncompressed"`
+}
+
+// seededReader is a deterministic byte stream for the test vectors: block i
+// is sha256(seed || uint64be(i)). It is easy to reproduce in other
+// languages and must never be used for real keys.
+type seededReader struct {
+	seed    []byte
+	counter uint64
+	buf     []byte
+}
+
+func (r *seededReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(r.buf) == 0 {
+			var ctr [8]byte
+			binary.BigEndian.PutUint64(ctr[:], r.counter)
+			sum := sha256.Sum256(append(append([]byte{}, r.seed...), ctr[:]...))
+			r.buf = sum[:]
+			r.counter++
+		}
+		c := copy(p[n:], r.buf)
+		r.buf = r.buf[c:]
+		n += c
+	}
+	return n, nil
+}
+
+// runTestVectors implements `keygen testvectors`: it prints a fixed set of
+// key, message and signature tuples so that other implementations of the
+// signer can check that they agree with it byte for byte. The keys are
+// drawn from a seeded generator and are public; nothing here is secret.
+func runTestVectors(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("testvectors", flag.ContinueOnError)
+	out := fs.String("out", "", "file to write the vectors to (default: stdout)")
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	file, err := generateTestVectors()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeExport(*out, append(data, '\n'), stdout)
+}
+
+// generateTestVectors derives the vectors from testVectorSeed. For each
+// message length in turn, the key is sampled from the stream as
+// bls.GenerateKeyPair does (48 bytes reduced modulo the group order) and
+// the message is the next bytes of the stream. Signatures are over the
+// message under bls.DST.
+func generateTestVectors() (*testVectorFile, error) {
+	rng := &seededReader{seed: []byte(testVectorSeed)}
+	file := &testVectorFile{Curve: payloadCurve, DST: bls.DST, Seed: testVectorSeed}
+	for _, n := range testVectorMessageLengths {
+		kp, err := bls.GenerateKeyPair(rng)
+		if err != nil {
+			return nil, err
+		}
+		msg := make([]byte, n)
+		if _, err := io.ReadFull(rng, msg); err != nil {
+			return nil, err
+		}
+		sig, err := kp.Sign(msg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign test vector: %w", err)
+		}
+		sk := kp.PrivateKey.Bytes()
+		file.Vectors = append(file.Vectors, testVector{
+			PrivateKey:            fmt.Sprintf("0x%x", []byte(sk)),
+			G1PubKey:              fmt.Sprintf("0x%x", kp.G1PubKey.Bytes()),
+			G2PubKey:              fmt.Sprintf("0x%x", kp.G2PubKey.Bytes()),
+			Message:               fmt.Sprintf("0x%x", msg),
+			Signature:             fmt.Sprintf("0x%x", sig.CompressedBytes()),
+			SignatureUncompressed: fmt.Sprintf("0x%x", sig.UncompressedBytes()),
+		})
+	}
+	return file, nil
+}
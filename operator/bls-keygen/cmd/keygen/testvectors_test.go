@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite golden files in testdata")
+
+const testVectorsGolden = "testdata/testvectors.json"
+
+func TestRunTestVectorsGolden(t *testing.T) {
+	var out bytes.Buffer
+	if err := runTestVectors(nil, &out); err != nil {
+		t.Fatal(err)
+	}
+	if *updateGolden {
+		if err := os.WriteFile(testVectorsGolden, out.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(testVectorsGolden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), want) {
+		t.Fatalf("test vectors changed; if that is intended, bump testVectorSeed and run go test -update\ngot:\n%s", out.Bytes())
+	}
+}
+
+func TestTestVectorsSelfVerify(t *testing.T) {
+	data, err := os.ReadFile(testVectorsGolden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var file testVectorFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatal(err)
+	}
+	if file.DST != bls.DST || len(file.Vectors) != len(testVectorMessageLengths) {
+		t.Fatalf("unexpected header: dst %q, %d vectors", file.DST, len(file.Vectors))
+	}
+	for i, v := range file.Vectors {
+		raw := func(s string) []byte {
+			b, err := decodeHex(s)
+			if err != nil {
+				t.Fatalf("vector %d: %v", i, err)
+			}
+			return b
+		}
+		sk, err := bls.PrivateKeyFromBytes(raw(v.PrivateKey))
+		if err != nil {
+			t.Fatalf("vector %d: %v", i, err)
+		}
+		kp := bls.NewKeyPair(sk)
+		if !bytes.Equal(kp.G1PubKey.Bytes(), raw(v.G1PubKey)) || !bytes.Equal(kp.G2PubKey.Bytes(), raw(v.G2PubKey)) {
+			t.Errorf("vector %d: public keys are not those of the private key", i)
+		}
+		msg := raw(v.Message)
+		if len(msg) != testVectorMessageLengths[i] {
+			t.Errorf("vector %d: message is %d bytes, want %d", i, len(msg), testVectorMessageLengths[i])
+		}
+		sig, err := bls.ParseSignatureCompressed(raw(v.Signature))
+		if err != nil {
+			t.Fatalf("vector %d: %v", i, err)
+		}
+		if !bytes.Equal(sig.UncompressedBytes(), raw(v.SignatureUncompressed)) {
+			t.Errorf("vector %d: uncompressed signature is not the compressed one", i)
+		}
+		if !bls.Verify(kp.G2PubKey, msg, sig) {
+			t.Errorf("vector %d: signature does not verify", i)
+		}
+	}
+}
+
+func TestRunTestVectorsOut(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "vectors.json")
+	if err := runTestVectors([]string{"--out", out}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile(testVectorsGolden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatal("--out wrote different vectors than stdout")
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+var errSignatureInvalid = errors.New("signature verification failed")
+
+// runVerify implements `keygen verify`: it checks a G1 signature over
+// keccak256(message) against a G2 public key, in the signing context of
+// --network and --message-prefix. With --keydir it instead tries every key
+// file in the directory, rotation backups included, and reports which one
+// made the signature. The key and the signature can also be given as the
+// decimal affine coordinates of a decoded transaction.
+func runVerify(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	pubKeyHex := fs.String("pubkey", "", "hex-encoded G2 public key")
+	keyDir := fs.String("keydir", "", "try every key in this directory, current and .bak, instead of --pubkey")
+	message := fs.String("message", "", "hex-encoded message that was signed")
+	sigHex := fs.String("signature", "", "hex-encoded G1 signature")
+	var coords coordinateFlags
+	coords.register(fs)
+	var signing signingOptions
+	signing.register(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	sc, err := signing.context()
+	if err != nil {
+		return err
+	}
+	pkCoords, sigCoords := coords.pubKeySet(), coords.signatureSet()
+	if countSet(*pubKeyHex != "", *keyDir != "", pkCoords) > 1 {
+		return usageErrorf("--pubkey, --keydir and --pubkey-x0/x1/y0/y1 are mutually exclusive")
+	}
+	if *sigHex != "" && sigCoords {
+		return usageErrorf("--signature and --sig-x/--sig-y are mutually exclusive")
+	}
+	if (*pubKeyHex == "" && *keyDir == "" && !pkCoords) || *message == "" || (*sigHex == "" && !sigCoords) {
+		return errors.New("--pubkey, --keydir or --pubkey-x0/x1/y0/y1, --message and --signature or --sig-x/--sig-y are required")
+	}
+
+	msg, err := decodeHex(*message)
+	if err != nil {
+		return fmt.Errorf("invalid --message: %w", err)
+	}
+	var sig *bls.Signature
+	if sigCoords {
+		sig, err = coords.signature()
+	} else if sig, err = bls.ParseSignature(*sigHex); err != nil {
+		err = fmt.Errorf("invalid --signature: %w", err)
+	}
+	if err != nil {
+		return err
+	}
+	if *keyDir != "" {
+		return verifyKeyDir(*keyDir, keccak256(msg), sig, sc, stdout)
+	}
+
+	var pk *bls.G2PubKey
+	if pkCoords {
+		if pk, err = coords.pubKey(); err != nil {
+			return err
+		}
+	} else {
+		pkBytes, err := decodeHex(*pubKeyHex)
+		if err != nil {
+			return fmt.Errorf("invalid --pubkey: %w", err)
+		}
+		if pk, err = bls.G2PubKeyFromBytes(pkBytes); err != nil {
+			return fmt.Errorf("invalid --pubkey: %w", err)
+		}
+	}
+	if !sc.Verify(pk, keccak256(msg), sig) {
+		fmt.Fprintln(stdout, "❌ Signature is INVALID for this public key and message")
+		return errSignatureInvalid
+	}
+	fmt.Fprintln(stdout, "✅ Signature is valid")
+	return nil
+}
+
+// verifyKeyDir checks sig against the stored public key of every key file
+// and rotation backup in dir, reporting the first that made it.
+func verifyKeyDir(dir string, digest []byte, sig *bls.Signature, sc bls.SigningContext, stdout io.Writer) error {
+	keys, err := keyDirPubKeys(dir)
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		if sc.Verify(k.g2, digest, sig) {
+			fmt.Fprintf(stdout, "✅ Signature is valid, made by %s (fingerprint %s)\n", k.name, bls.Fingerprint(k.g1))
+			return nil
+		}
+	}
+	fmt.Fprintf(stdout, "❌ Signature was not made by any of the %d keys in %s\n", len(keys), dir)
+	return errSignatureInvalid
+}
+
+// dirPubKey is the stored public keys of a key file in a directory.
+type dirPubKey struct {
+	name string
+	g1   *bls.G1PubKey
+	g2   *bls.G2PubKey
+}
+
+// keyDirPubKeys returns the stored public keys of every key file and
+// rotation backup in dir. Only cleartext public keys are read, so no
+// password is needed. EIP-2335 keystores store no G2 key and are skipped.
+func keyDirPubKeys(dir string) ([]dirPubKey, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var keys []dirPubKey
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !(strings.HasSuffix(name, ".json") || strings.HasSuffix(name, ".bak")) {
+			continue
+		}
+		g1, g2, err := blskeys.LoadPublicKeys(filepath.Join(dir, name))
+		if err != nil {
+			slog.Debug("skipping non-key file", "file", name, "reason", err)
+			continue
+		}
+		if g2 == nil {
+			slog.Debug("skipping key file without a G2 public key", "file", name)
+			continue
+		}
+		keys = append(keys, dirPubKey{name, g1, g2})
+	}
+	return keys, nil
+}
+
+// coordinateFlags hold a G2 public key and a G1 signature as decimal affine
+// coordinates, the form they take in a decoded verifySignature call, with
+// x = x0 + x1*u and y = y0 + y1*u for the key. BLS12-381 coordinates are
+// 381-bit, so they are parsed as integers of any size.
+type coordinateFlags struct {
+	pkX0, pkX1, pkY0, pkY1 string
+	sigX, sigY             string
+}
+
+func (c *coordinateFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&c.pkX0, "pubkey-x0", "", "G2 public key x coordinate, real part, in decimal")
+	fs.StringVar(&c.pkX1, "pubkey-x1", "", "G2 public key x coordinate, imaginary part, in decimal")
+	fs.StringVar(&c.pkY0, "pubkey-y0", "", "G2 public key y coordinate, real part, in decimal")
+	fs.StringVar(&c.pkY1, "pubkey-y1", "", "G2 public key y coordinate, imaginary part, in decimal")
+	fs.StringVar(&c.sigX, "sig-x", "", "G1 signature x coordinate in decimal")
+	fs.StringVar(&c.sigY, "sig-y", "", "G1 signature y coordinate in decimal")
+}
+
+// pubKeySet reports whether any of the public key coordinates is given.
+func (c *coordinateFlags) pubKeySet() bool {
+	return c.pkX0 != "" || c.pkX1 != "" || c.pkY0 != "" || c.pkY1 != ""
+}
+
+// signatureSet reports whether any of the signature coordinates is given.
+func (c *coordinateFlags) signatureSet() bool {
+	return c.sigX != "" || c.sigY != ""
+}
+
+func (c *coordinateFlags) pubKey() (*bls.G2PubKey, error) {
+	var v [4]*big.Int
+	for i, f := range []struct{ name, value string }{
+		{"pubkey-x0", c.pkX0}, {"pubkey-x1", c.pkX1}, {"pubkey-y0", c.pkY0}, {"pubkey-y1", c.pkY1},
+	} {
+		var err error
+		if v[i], err = parseDecimal(f.name, f.value); err != nil {
+			return nil, err
+		}
+	}
+	pk, err := bls.G2PubKeyFromCoordinates(v[0], v[1], v[2], v[3])
+	if err != nil {
+		return nil, fmt.Errorf("invalid --pubkey-x0/x1/y0/y1: %w", err)
+	}
+	return pk, nil
+}
+
+func (c *coordinateFlags) signature() (*bls.Signature, error) {
+	x, err := parseDecimal("sig-x", c.sigX)
+	if err != nil {
+		return nil, err
+	}
+	y, err := parseDecimal("sig-y", c.sigY)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := bls.SignatureFromCoordinates(x, y)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --sig-x/--sig-y: %w", err)
+	}
+	return sig, nil
+}
+
+// parseDecimal parses the non-negative decimal integer of flag --name.
+func parseDecimal(name, s string) (*big.Int, error) {
+	if s == "" {
+		return nil, usageErrorf("--%s is required with the other coordinates", name)
+	}
+	v, ok := new(big.Int).SetString(s, 10)
+	if !ok || v.Sign() < 0 {
+		return nil, usageErrorf("--%s %q is not a non-negative decimal integer", name, s)
+	}
+	return v, nil
+}
+
+// countSet returns how many of set are true.
+func countSet(set ...bool) int {
+	n := 0
+	for _, b := range set {
+		if b {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"crypto/subtle"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+var errMnemonicMismatch = errors.New("key file does not match the mnemonic at this derivation path")
+
+// runVerifyMnemonic implements `keygen verify-mnemonic`: it derives the key
+// of a mnemonic and path and checks that the key file holds that key, so
+// the mnemonic can be trusted as its backup. Neither the mnemonic nor
+// either private key is printed or logged.
+func runVerifyMnemonic(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("verify-mnemonic", flag.ContinueOnError)
+	keyPath := fs.String("key", filepath.Join(defaultKeyDir, defaultKeyFile), "key file to check")
+	mnemonicFile := fs.String("mnemonic-file", "", "file holding the BIP-39 mnemonic")
+	path := fs.String("path", blskeys.DefaultDerivationPath, "EIP-2334 derivation path")
+	var avs avsOption
+	avs.register(fs)
+	var pwSource passwordSource
+	pwSource.register(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if *mnemonicFile == "" {
+		return usageErrorf("--mnemonic-file is required")
+	}
+	if _, err := blskeys.ParseDerivationPath(*path); err != nil {
+		return usageErrorf("invalid --path: %v", err)
+	}
+	if err := avs.validate(); err != nil {
+		return err
+	}
+	*path = avs.path(*path)
+
+	mnemonic, err := os.ReadFile(*mnemonicFile)
+	if err != nil {
+		return fmt.Errorf("failed to read mnemonic file: %w", err)
+	}
+	derived, err := blskeys.DeriveFromMnemonic(string(mnemonic), *path)
+	bls.SecretBytes(mnemonic).Zero()
+	if err != nil {
+		return err
+	}
+	defer derived.PrivateKey.Zero()
+
+	password, err := pwSource.read()
+	if err != nil {
+		return err
+	}
+	kp, err := loadKey(*keyPath, password)
+	if err != nil {
+		return fmt.Errorf("failed to load key %s: %w", *keyPath, err)
+	}
+	defer kp.PrivateKey.Zero()
+
+	want, got := derived.PrivateKey.Bytes(), kp.PrivateKey.Bytes()
+	defer want.Zero()
+	defer got.Zero()
+	if subtle.ConstantTimeCompare(want, got) != 1 {
+		return fmt.Errorf("%s: %w %s", *keyPath, errMnemonicMismatch, *path)
+	}
+	fmt.Fprintf(stdout, "Key %s matches the mnemonic at %s (fingerprint %s)\n", *keyPath, *path, bls.Fingerprint(kp.G1PubKey))
+	return nil
+}
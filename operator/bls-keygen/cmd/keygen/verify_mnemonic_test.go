@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+func TestRunVerifyMnemonic(t *testing.T) {
+	dir := t.TempDir()
+	mnemonicFile := filepath.Join(dir, "mnemonic")
+	if err := os.WriteFile(mnemonicFile, []byte(testMnemonic+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	otherFile := filepath.Join(dir, "other")
+	other := "legal winner thank year wave sausage worth useful legal winner thank yellow"
+	if err := os.WriteFile(otherFile, []byte(other), 0600); err != nil {
+		t.Fatal(err)
+	}
+	const path = "m/12381/3600/4/0"
+	kp, err := blskeys.DeriveFromMnemonic(testMnemonic, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := filepath.Join(dir, "bls_key.json")
+	if err := blskeys.Save(kp, key, testPassword); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("KEY_PASSWORD", testPassword)
+
+	var out bytes.Buffer
+	if err := runVerifyMnemonic([]string{"--key", key, "--mnemonic-file", mnemonicFile, "--path", path}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "matches the mnemonic") {
+		t.Fatalf("output %q", out.String())
+	}
+
+	for name, args := range map[string][]string{
+		"other mnemonic": {"--mnemonic-file", otherFile, "--path", path},
+		"other path":     {"--mnemonic-file", mnemonicFile, "--path", "m/12381/3600/5/0"},
+	} {
+		out.Reset()
+		err := runVerifyMnemonic(append([]string{"--key", key}, args...), &out)
+		if !errors.Is(err, errMnemonicMismatch) {
+			t.Fatalf("%s: got %v, want errMnemonicMismatch", name, err)
+		}
+		if exitCode(err) == exitOK {
+			t.Fatalf("%s: mismatch exits 0", name)
+		}
+		for _, secret := range []string{"abandon", "legal"} {
+			if strings.Contains(err.Error(), secret) || strings.Contains(out.String(), secret) {
+				t.Fatalf("%s: mnemonic leaked: %v %q", name, err, out.String())
+			}
+		}
+	}
+
+	if err := runVerifyMnemonic([]string{"--key", key}, &out); exitCode(err) != exitUsage {
+		t.Fatalf("without --mnemonic-file: got %v, want a usage error", err)
+	}
+}
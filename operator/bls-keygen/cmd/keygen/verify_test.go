@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+// A known-good (pubkey, message, signature) triple for the private key
+// 0x000000001122334455667788000000000000000000000000000000000000002a.
+const (
+	knownG2PubKey  = "0x911665643d79848cb2d334efe8df2deb4e8726fbc3aeeda36399af55137851d58d19eb7b87a93c378ded7c056afb90460199a1e6747e6b4fa36c9ce4a99f99b732e91b5e673ca38bda1a3c1153d1f883ce28146faee01c411b83d1bf6f752567"
+	knownMessage   = "0xdeadbeef"
+	knownSignature = "0xa93ff3beabf6cd4e026006220eb9bf0493ad67d8ec62db172e6fb0a09c3515fb5d41ec5f52754aad67f32eb209c470dd"
+
+	// On-curve points outside the prime-order subgroups.
+	nonSubgroupG1 = "0x800000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000004"
+	nonSubgroupG2 = "0x800000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000002"
+)
+
+func TestRunVerify(t *testing.T) {
+	var out bytes.Buffer
+	err := runVerify([]string{"--pubkey", knownG2PubKey, "--message", knownMessage, "--signature", knownSignature}, &out)
+	if err != nil {
+		t.Fatalf("known-good triple failed: %v (%s)", err, out.String())
+	}
+}
+
+func TestRunVerifyTampered(t *testing.T) {
+	// Signature of the same key over a different message.
+	err := runVerify([]string{"--pubkey", knownG2PubKey, "--message", "0xdeadbeee", "--signature", knownSignature}, &bytes.Buffer{})
+	if !errors.Is(err, errSignatureInvalid) {
+		t.Fatalf("tampered message: got %v, want errSignatureInvalid", err)
+	}
+
+	// Flip the compressed y-sign bit so the signature is a different point.
+	sig, _ := decodeHex(knownSignature)
+	sig[0] ^= 0x20
+	err = runVerify([]string{"--pubkey", knownG2PubKey, "--message", knownMessage, "--signature", "0x" + hex.EncodeToString(sig)}, &bytes.Buffer{})
+	if !errors.Is(err, errSignatureInvalid) {
+		t.Fatalf("tampered signature: got %v, want errSignatureInvalid", err)
+	}
+}
+
+func TestRunVerifyRejectsNonSubgroupPoints(t *testing.T) {
+	err := runVerify([]string{"--pubkey", knownG2PubKey, "--message", knownMessage, "--signature", nonSubgroupG1}, &bytes.Buffer{})
+	if !errors.Is(err, bls.ErrNotInSubgroup) {
+		t.Fatalf("signature: got %v, want ErrNotInSubgroup", err)
+	}
+	err = runVerify([]string{"--pubkey", nonSubgroupG2, "--message", knownMessage, "--signature", knownSignature}, &bytes.Buffer{})
+	if !errors.Is(err, bls.ErrNotInSubgroup) {
+		t.Fatalf("pubkey: got %v, want ErrNotInSubgroup", err)
+	}
+}
+
+func TestRunSignVerifyNetworks(t *testing.T) {
+	kp, path := writeTestKey(t)
+	t.Setenv("KEY_PASSWORD", testPassword)
+	pub := fmt.Sprintf("0x%x", kp.G2PubKey.Bytes())
+	custom := []string{"--network", "custom", "--dst", hex.EncodeToString([]byte("MY-AVS-V01-with-" + bls.DST))}
+
+	networks := map[string][]string{
+		"mainnet": {"--network", "mainnet"},
+		"holesky": {"--network", "holesky"},
+		"sepolia": {"--network", "sepolia"},
+		"custom":  custom,
+	}
+	for signedUnder, signFlags := range networks {
+		var out bytes.Buffer
+		if err := runSign(append([]string{"--key", path, "--message", knownMessage}, signFlags...), &out); err != nil {
+			t.Fatalf("%s: %v", signedUnder, err)
+		}
+		sig := strings.TrimSpace(out.String())
+		for verifiedUnder, verifyFlags := range networks {
+			err := runVerify(append([]string{"--pubkey", pub, "--message", knownMessage, "--signature", sig}, verifyFlags...), &bytes.Buffer{})
+			if signedUnder == verifiedUnder && err != nil {
+				t.Errorf("%s signature rejected under its own network: %v", signedUnder, err)
+			}
+			if signedUnder != verifiedUnder && !errors.Is(err, errSignatureInvalid) {
+				t.Errorf("%s signature under %s: got %v, want errSignatureInvalid", signedUnder, verifiedUnder, err)
+			}
+		}
+	}
+}
+
+func TestNetworkFlagErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{"--network", "goerli"},
+		{"--network", "custom"},
+		{"--network", "custom", "--dst", "zz"},
+		{"--dst", "00"},
+	} {
+		err := runVerify(append([]string{"--pubkey", knownG2PubKey, "--message", knownMessage, "--signature", knownSignature}, args...), &bytes.Buffer{})
+		if exitCode(err) != exitUsage {
+			t.Errorf("%v: got %v, want a usage error", args, err)
+		}
+	}
+}
+
+func TestRunVerifyKeyDir(t *testing.T) {
+	dir := t.TempDir()
+	current, err := blskeys.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous, err := blskeys.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := blskeys.Save(current, filepath.Join(dir, "bls_key.json"), testPassword); err != nil {
+		t.Fatal(err)
+	}
+	backup := "bls_key.json.1700000000.bak"
+	if err := blskeys.Save(previous, filepath.Join(dir, backup), testPassword); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := []byte("signed before rotation")
+	sig, err := previous.Sign(keccak256(msg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := []string{"--keydir", dir, "--message", fmt.Sprintf("0x%x", msg), "--signature", fmt.Sprintf("0x%x", sig.Bytes())}
+
+	var out bytes.Buffer
+	if err := runVerify(args, &out); err != nil {
+		t.Fatalf("%v (%s)", err, out.String())
+	}
+	if !strings.Contains(out.String(), backup) || !strings.Contains(out.String(), bls.Fingerprint(previous.G1PubKey)) {
+		t.Errorf("output does not name the backup that signed:\n%s", out.String())
+	}
+
+	sig, err = current.Sign(keccak256([]byte("another message")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	args[len(args)-1] = fmt.Sprintf("0x%x", sig.Bytes())
+	if err := runVerify(args, &bytes.Buffer{}); !errors.Is(err, errSignatureInvalid) {
+		t.Fatalf("signature over another message: got %v, want errSignatureInvalid", err)
+	}
+}
+
+// knownCoordinateArgs returns the known-good key and signature as the
+// decimal coordinate flags of verify.
+func knownCoordinateArgs(t *testing.T) []string {
+	t.Helper()
+	pkBytes, _ := decodeHex(knownG2PubKey)
+	pk, err := bls.G2PubKeyFromBytes(pkBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := bls.ParseSignature(knownSignature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x0, x1, y0, y1 := pk.Coordinates()
+	sx, sy := sig.Coordinates()
+	return []string{
+		"--pubkey-x0", x0.String(), "--pubkey-x1", x1.String(),
+		"--pubkey-y0", y0.String(), "--pubkey-y1", y1.String(),
+		"--sig-x", sx.String(), "--sig-y", sy.String(),
+	}
+}
+
+func TestRunVerifyCoordinates(t *testing.T) {
+	args := append(knownCoordinateArgs(t), "--message", knownMessage)
+	var out bytes.Buffer
+	if err := runVerify(args, &out); err != nil {
+		t.Fatalf("known-good coordinates failed: %v (%s)", err, out.String())
+	}
+
+	// Coordinates for one point and hex for the other also work.
+	mixed := append(knownCoordinateArgs(t)[:8], "--signature", knownSignature, "--message", knownMessage)
+	if err := runVerify(mixed, &bytes.Buffer{}); err != nil {
+		t.Fatalf("coordinate key with hex signature: %v", err)
+	}
+}
+
+func TestRunVerifyCoordinatesInvalid(t *testing.T) {
+	set := func(name, value string) []string {
+		args := append(knownCoordinateArgs(t), "--message", knownMessage)
+		for i := range args {
+			if args[i] == name {
+				args[i+1] = value
+			}
+		}
+		return args
+	}
+	sigX := knownCoordinateArgs(t)[9]
+	x, _ := new(big.Int).SetString(sigX, 10)
+	offCurve := new(big.Int).Add(x, big.NewInt(1)).String()
+
+	for name, tc := range map[string]struct {
+		args []string
+		want string
+	}{
+		"signature off curve": {set("--sig-x", offCurve), "not on the curve"},
+		"key off curve":       {set("--pubkey-y1", "1"), "not on the curve"},
+		"not a field element": {set("--sig-y", strings.Repeat("9", 120)), "not a field element"},
+		"not decimal":         {set("--pubkey-x0", "0x1f"), "not a non-negative decimal integer"},
+		"missing coordinate":  {set("--sig-y", ""), "--sig-y is required"},
+	} {
+		err := runVerify(tc.args, &bytes.Buffer{})
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: got %v, want an error containing %q", name, err, tc.want)
+		}
+		if errors.Is(err, errSignatureInvalid) {
+			t.Errorf("%s: reported as a bad signature instead of a bad point", name)
+		}
+	}
+
+	both := append(knownCoordinateArgs(t), "--message", knownMessage, "--signature", knownSignature)
+	if err := runVerify(both, &bytes.Buffer{}); exitCode(err) != exitUsage {
+		t.Errorf("--signature with --sig-x: got %v, want a usage error", err)
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+// watchedKey is the key a watch process holds. reload swaps in the key file's
+// current contents only once they have decrypted and validated, so a bad
+// rotation leaves the previous key in place.
+type watchedKey struct {
+	path     string
+	password string
+
+	mu sync.RWMutex
+	kp *blskeys.KeyPair
+}
+
+// current returns the key being served.
+func (w *watchedKey) current() *blskeys.KeyPair {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.kp
+}
+
+// reload loads the key file again and, if it is valid, replaces the key
+// being served with it and wipes the old one.
+func (w *watchedKey) reload() (*blskeys.KeyPair, error) {
+	kp, err := loadKey(w.path, w.password)
+	if err != nil {
+		return nil, err
+	}
+	w.mu.Lock()
+	old := w.kp
+	w.kp = kp
+	w.mu.Unlock()
+	if old != nil && old != kp {
+		old.PrivateKey.Zero()
+	}
+	return kp, nil
+}
+
+// close wipes the key being served.
+func (w *watchedKey) close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.kp != nil {
+		w.kp.PrivateKey.Zero()
+		w.kp = nil
+	}
+}
+
+// runWatch implements `keygen watch`: it loads the key and keeps running,
+// loading the key file again on every SIGHUP so a sidecar can pick up an
+// external rotation without a restart. Each load prints the fingerprint of
+// the key now held. A reload that fails is logged and the old key kept. It
+// runs until interrupted.
+func runWatch(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	keyPath := fs.String("key", filepath.Join(defaultKeyDir, defaultKeyFile), "key file to hold and reload on SIGHUP")
+	var pwSource passwordSource
+	pwSource.register(fs)
+	var logOpts logOptions
+	logOpts.register(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if err := logOpts.apply(); err != nil {
+		return err
+	}
+
+	password, err := pwSource.read()
+	if err != nil {
+		return err
+	}
+	w := &watchedKey{path: *keyPath, password: password}
+	defer w.close()
+
+	// Listen before the first load, so a SIGHUP sent once the key is
+	// reported is never missed.
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	kp, err := w.reload()
+	if err != nil {
+		return fmt.Errorf("failed to load key %s: %w", *keyPath, err)
+	}
+	reportWatchedKey(stdout, "loaded", *keyPath, kp)
+
+	for {
+		select {
+		case <-cmdContext.Done():
+			return nil
+		case <-hup:
+			kp, err := w.reload()
+			if err != nil {
+				fp := bls.Fingerprint(w.current().G1PubKey)
+				slog.Error("failed to reload key, keeping the old one", "path", *keyPath, "err", err, "fingerprint", fp)
+				fmt.Fprintf(stdout, "reload failed, keeping fingerprint %s\n", fp)
+				continue
+			}
+			reportWatchedKey(stdout, "reloaded", *keyPath, kp)
+		}
+	}
+}
+
+func reportWatchedKey(stdout io.Writer, event, path string, kp *blskeys.KeyPair) {
+	fp := bls.Fingerprint(kp.G1PubKey)
+	slog.Info("key "+event, "path", path, "fingerprint", fp)
+	fmt.Fprintf(stdout, "%s fingerprint %s\n", event, fp)
+}
@@ -0,0 +1,93 @@
+//go:build unix
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+// syncBuffer is a bytes.Buffer that runWatch can write to while the test
+// reads it.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// waitFor polls out until it contains want.
+func waitFor(t *testing.T, out *syncBuffer, want string) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !strings.Contains(out.String(), want) {
+		if time.Now().After(deadline) {
+			t.Fatalf("output never contained %q:\n%s", want, out.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRunWatchReloadsOnSIGHUP(t *testing.T) {
+	kp, path := writeTestKey(t)
+	t.Setenv("KEY_PASSWORD", testPassword)
+	ctx, cancel := context.WithCancel(context.Background())
+	cmdContext = ctx
+	t.Cleanup(func() { cmdContext = context.Background() })
+
+	var out syncBuffer
+	done := make(chan error, 1)
+	go func() { done <- runWatch([]string{"--key", path}, &out) }()
+	oldFP := bls.Fingerprint(kp.G1PubKey)
+	waitFor(t, &out, "loaded fingerprint "+oldFP)
+
+	// A file that does not decrypt is rejected and the old key kept.
+	if err := os.WriteFile(path, []byte("{}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	syscall.Kill(os.Getpid(), syscall.SIGHUP)
+	waitFor(t, &out, "reload failed, keeping fingerprint "+oldFP)
+
+	rotated, err := blskeys.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := blskeys.Save(rotated, path, testPassword); err != nil {
+		t.Fatal(err)
+	}
+	syscall.Kill(os.Getpid(), syscall.SIGHUP)
+	newFP := bls.Fingerprint(rotated.G1PubKey)
+	if newFP == oldFP {
+		t.Fatal("rotated key has the old fingerprint")
+	}
+	waitFor(t, &out, "reloaded fingerprint "+newFP)
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("watch did not stop when cancelled")
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+)
+
+// runWhois implements `keygen whois`: given a message and a signature
+// whose key is unknown, it checks the signature against the stored public
+// key of every key file in --keydir and prints the fingerprint and file of
+// each that made it. A BLS public key cannot be recovered from a
+// signature, so it can only find the signer among candidates. Like
+// verify, it signs keccak256(message) in the context of --network and
+// --message-prefix, and reads no private keys.
+func runWhois(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("whois", flag.ContinueOnError)
+	message := fs.String("message", "", "hex-encoded message that was signed")
+	sigHex := fs.String("signature", "", "hex-encoded G1 signature")
+	keyDir := fs.String("keydir", "", "directory of candidate key files, current and .bak")
+	var signing signingOptions
+	signing.register(fs)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if *message == "" || *sigHex == "" || *keyDir == "" {
+		return usageErrorf("--message, --signature and --keydir are required")
+	}
+	sc, err := signing.context()
+	if err != nil {
+		return err
+	}
+	msg, err := decodeHex(*message)
+	if err != nil {
+		return fmt.Errorf("invalid --message: %w", err)
+	}
+	sig, err := bls.ParseSignature(*sigHex)
+	if err != nil {
+		return fmt.Errorf("invalid --signature: %w", err)
+	}
+
+	keys, err := keyDirPubKeys(*keyDir)
+	if err != nil {
+		return err
+	}
+	digest := keccak256(msg)
+	matched := 0
+	for _, k := range keys {
+		if sc.Verify(k.g2, digest, sig) {
+			fmt.Fprintf(stdout, "%s\t%s\n", bls.Fingerprint(k.g1), k.name)
+			matched++
+		}
+	}
+	if matched == 0 {
+		return fmt.Errorf("%w: made by none of the %d keys in %s", errSignatureInvalid, len(keys), *keyDir)
+	}
+	return nil
+}
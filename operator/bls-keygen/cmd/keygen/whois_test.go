@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/blskeys"
+)
+
+func TestRunWhois(t *testing.T) {
+	dir := t.TempDir()
+	var keys []*blskeys.KeyPair
+	for i := 0; i < 4; i++ {
+		kp, err := blskeys.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := blskeys.Save(kp, filepath.Join(dir, fmt.Sprintf("operator%d.json", i)), testPassword); err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, kp)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.json"), []byte(`{"not": "a key"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := []byte("task 17 response")
+	signer := keys[2]
+	sig, err := signer.Sign(keccak256(msg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := []string{"--keydir", dir, "--message", fmt.Sprintf("0x%x", msg), "--signature", fmt.Sprintf("0x%x", sig.Bytes())}
+
+	// No password is set: whois reads only public keys.
+	var out bytes.Buffer
+	if err := runWhois(args, &out); err != nil {
+		t.Fatal(err)
+	}
+	if want := bls.Fingerprint(signer.G1PubKey) + "\toperator2.json\n"; out.String() != want {
+		t.Fatalf("output = %q, want %q", out.String(), want)
+	}
+
+	// The signature does not verify in another signing context.
+	err = runWhois(append(args, "--network", "holesky"), io.Discard)
+	if !errors.Is(err, errSignatureInvalid) || !strings.Contains(err.Error(), "none of the 4 keys") {
+		t.Fatalf("another network: got %v, want errSignatureInvalid naming 4 keys", err)
+	}
+
+	if err := runWhois(args[:4], io.Discard); exitCode(err) != exitUsage {
+		t.Fatalf("without --signature: got %v, want a usage error", err)
+	}
+}
@@ -0,0 +1,491 @@
+// Package age implements the X25519 recipients of the age v1 file format
+// (https://age-encryption.org/v1), enough to encrypt a key file to an
+// operator's age public key and decrypt it again with the matching
+// identity. The tests pin a file it writes that the reference age
+// implementation decrypts, decrypt a file the age CLI wrote, and run the
+// C2SP age test vectors for X25519 identities.
+//
+// Whole files are processed in memory; key files are small.
+package age
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	intro         = "age-encryption.org/v1"
+	recipientHRP  = "age"
+	identityHRP   = "AGE-SECRET-KEY-"
+	x25519Label   = "age-encryption.org/v1/X25519"
+	stanzaX25519  = "X25519"
+	fileKeySize   = 16
+	nonceSize     = 16
+	chunkSize     = 64 * 1024
+	columnsPerRow = 64
+)
+
+var (
+	// ErrNoIdentityMatched is returned by Decrypt when none of the
+	// identities is a recipient of the file.
+	ErrNoIdentityMatched = errors.New("age: no identity matched any of the file's recipients")
+	// ErrCorrupt is returned for a file that is not well-formed age or
+	// whose header MAC or payload does not authenticate.
+	ErrCorrupt = errors.New("age: malformed or tampered file")
+)
+
+var b64 = base64.RawStdEncoding.Strict()
+
+// Recipient is an X25519 age public key, age1... in text.
+type Recipient struct {
+	pub [curve25519.PointSize]byte
+}
+
+// ParseRecipient parses an age1... public key.
+func ParseRecipient(s string) (*Recipient, error) {
+	hrp, data, err := bech32Decode(s)
+	if err != nil {
+		return nil, fmt.Errorf("age: invalid recipient %q: %v", s, err)
+	}
+	if hrp != recipientHRP || len(data) != curve25519.PointSize {
+		return nil, fmt.Errorf("age: %q is not an X25519 recipient", s)
+	}
+	r := &Recipient{}
+	copy(r.pub[:], data)
+	return r, nil
+}
+
+// String returns the age1... encoding of r.
+func (r *Recipient) String() string {
+	s, _ := bech32Encode(recipientHRP, r.pub[:])
+	return s
+}
+
+// Identity is an X25519 age private key, AGE-SECRET-KEY-1... in text.
+type Identity struct {
+	secret [curve25519.ScalarSize]byte
+	r      Recipient
+}
+
+// GenerateIdentity creates a new identity from rng, which production
+// callers pass as crypto/rand.Reader.
+func GenerateIdentity(rng io.Reader) (*Identity, error) {
+	var secret [curve25519.ScalarSize]byte
+	if _, err := io.ReadFull(rng, secret[:]); err != nil {
+		return nil, fmt.Errorf("age: failed to read entropy: %w", err)
+	}
+	return newIdentity(secret[:])
+}
+
+func newIdentity(secret []byte) (*Identity, error) {
+	id := &Identity{}
+	copy(id.secret[:], secret)
+	pub, err := curve25519.X25519(id.secret[:], curve25519.Basepoint)
+	if err != nil {
+		return nil, fmt.Errorf("age: %w", err)
+	}
+	copy(id.r.pub[:], pub)
+	return id, nil
+}
+
+// ParseIdentity parses an AGE-SECRET-KEY-1... private key.
+func ParseIdentity(s string) (*Identity, error) {
+	hrp, data, err := bech32Decode(s)
+	if err != nil {
+		return nil, fmt.Errorf("age: invalid identity: %v", err)
+	}
+	if hrp != strings.ToLower(identityHRP) || len(data) != curve25519.ScalarSize {
+		return nil, errors.New("age: not an X25519 identity")
+	}
+	return newIdentity(data)
+}
+
+// ParseIdentities reads an identity file as age-keygen writes them: one
+// identity per line, with blank lines and # comments ignored.
+func ParseIdentities(r io.Reader) ([]*Identity, error) {
+	var ids []*Identity
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		id, err := ParseIdentity(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		ids = append(ids, id)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("age: failed to read identities: %w", err)
+	}
+	if len(ids) == 0 {
+		return nil, errors.New("age: no identities found")
+	}
+	return ids, nil
+}
+
+// String returns the AGE-SECRET-KEY-1... encoding of id.
+func (id *Identity) String() string {
+	s, _ := bech32Encode(identityHRP, id.secret[:])
+	return strings.ToUpper(s)
+}
+
+// Recipient returns the public key files are encrypted to for id.
+func (id *Identity) Recipient() *Recipient {
+	r := id.r
+	return &r
+}
+
+// Encrypt encrypts plaintext to every recipient: any one of their
+// identities decrypts it.
+func Encrypt(plaintext []byte, recipients ...*Recipient) ([]byte, error) {
+	return encrypt(rand.Reader, plaintext, recipients)
+}
+
+// encrypt is Encrypt drawing the file key, ephemeral keys and nonce from
+// rng, which tests fix to get reproducible files.
+func encrypt(rng io.Reader, plaintext []byte, recipients []*Recipient) ([]byte, error) {
+	if len(recipients) == 0 {
+		return nil, errors.New("age: no recipients")
+	}
+	fileKey := make([]byte, fileKeySize)
+	if _, err := io.ReadFull(rng, fileKey); err != nil {
+		return nil, fmt.Errorf("age: failed to generate file key: %w", err)
+	}
+	defer wipe(fileKey)
+
+	var out bytes.Buffer
+	out.WriteString(intro + "\n")
+	for _, r := range recipients {
+		share, body, err := wrapX25519(rng, r, fileKey)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&out, "-> %s %s\n", stanzaX25519, b64.EncodeToString(share))
+		writeWrapped(&out, b64.EncodeToString(body))
+	}
+	out.WriteString("---")
+	fmt.Fprintf(&out, " %s\n", b64.EncodeToString(headerMAC(fileKey, out.Bytes())))
+
+	nonce := make([]byte, nonceSize)
+	if _, err := io.ReadFull(rng, nonce); err != nil {
+		return nil, fmt.Errorf("age: failed to generate nonce: %w", err)
+	}
+	out.Write(nonce)
+	payload, err := sealPayload(fileKey, nonce, plaintext)
+	if err != nil {
+		return nil, err
+	}
+	out.Write(payload)
+	return out.Bytes(), nil
+}
+
+// Decrypt decrypts an age file encrypted to one of identities.
+func Decrypt(file []byte, identities ...*Identity) ([]byte, error) {
+	hdr, err := parseHeader(file)
+	if err != nil {
+		return nil, err
+	}
+	var fileKey []byte
+	for _, s := range hdr.stanzas {
+		if s.typ != stanzaX25519 {
+			continue
+		}
+		if len(s.args) != 1 {
+			return nil, fmt.Errorf("%w: X25519 stanza with %d arguments", ErrCorrupt, len(s.args))
+		}
+		for _, id := range identities {
+			if fileKey, err = unwrapX25519(id, s.args[0], s.body); err != nil {
+				return nil, err
+			}
+			if fileKey != nil {
+				break
+			}
+		}
+		if fileKey != nil {
+			break
+		}
+	}
+	if fileKey == nil {
+		return nil, ErrNoIdentityMatched
+	}
+	defer wipe(fileKey)
+
+	if !hmac.Equal(headerMAC(fileKey, hdr.macInput), hdr.mac) {
+		return nil, fmt.Errorf("%w: header MAC does not match", ErrCorrupt)
+	}
+	payload := file[hdr.size:]
+	if len(payload) < nonceSize {
+		return nil, fmt.Errorf("%w: missing payload nonce", ErrCorrupt)
+	}
+	return openPayload(fileKey, payload[:nonceSize], payload[nonceSize:])
+}
+
+// wrapX25519 encrypts fileKey to r under a fresh ephemeral key from rng,
+// returning the ephemeral share and the wrapped key.
+func wrapX25519(rng io.Reader, r *Recipient, fileKey []byte) (share, body []byte, err error) {
+	ephemeral := make([]byte, curve25519.ScalarSize)
+	if _, err := io.ReadFull(rng, ephemeral); err != nil {
+		return nil, nil, fmt.Errorf("age: failed to generate ephemeral key: %w", err)
+	}
+	defer wipe(ephemeral)
+	share, err = curve25519.X25519(ephemeral, curve25519.Basepoint)
+	if err != nil {
+		return nil, nil, fmt.Errorf("age: %w", err)
+	}
+	shared, err := curve25519.X25519(ephemeral, r.pub[:])
+	if err != nil {
+		return nil, nil, fmt.Errorf("age: %w", err)
+	}
+	defer wipe(shared)
+	aead, err := x25519WrapKey(shared, share, r.pub[:])
+	if err != nil {
+		return nil, nil, err
+	}
+	return share, aead.Seal(nil, make([]byte, chacha20poly1305.NonceSize), fileKey, nil), nil
+}
+
+// unwrapX25519 returns the file key of an X25519 stanza if id is its
+// recipient, and nil if it is not.
+func unwrapX25519(id *Identity, shareArg string, body []byte) ([]byte, error) {
+	share, err := b64.DecodeString(shareArg)
+	if err != nil || len(share) != curve25519.PointSize {
+		return nil, fmt.Errorf("%w: invalid X25519 share", ErrCorrupt)
+	}
+	if len(body) != fileKeySize+chacha20poly1305.Overhead {
+		return nil, fmt.Errorf("%w: invalid X25519 stanza body", ErrCorrupt)
+	}
+	shared, err := curve25519.X25519(id.secret[:], share)
+	if err != nil {
+		// A low-order share yields the all-zero secret, which X25519
+		// rejects.
+		return nil, fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+	defer wipe(shared)
+	aead, err := x25519WrapKey(shared, share, id.r.pub[:])
+	if err != nil {
+		return nil, err
+	}
+	fileKey, err := aead.Open(nil, make([]byte, chacha20poly1305.NonceSize), body, nil)
+	if err != nil {
+		return nil, nil
+	}
+	return fileKey, nil
+}
+
+// x25519WrapKey is the AEAD that wraps the file key for an X25519
+// recipient, keyed by the shared secret and bound to both public keys.
+func x25519WrapKey(shared, share, recipient []byte) (cipher.AEAD, error) {
+	salt := append(append(make([]byte, 0, len(share)+len(recipient)), share...), recipient...)
+	key := hkdfKey(shared, salt, x25519Label)
+	defer wipe(key)
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		return nil, fmt.Errorf("age: %w", err)
+	}
+	return aead, nil
+}
+
+func hkdfKey(secret, salt []byte, info string) []byte {
+	key := make([]byte, chacha20poly1305.KeySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, salt, []byte(info)), key); err != nil {
+		panic("age: hkdf: " + err.Error())
+	}
+	return key
+}
+
+// headerMAC is the MAC of the header up to and including "---".
+func headerMAC(fileKey, header []byte) []byte {
+	key := hkdfKey(fileKey, nil, "header")
+	defer wipe(key)
+	h := hmac.New(sha256.New, key)
+	h.Write(header)
+	return h.Sum(nil)
+}
+
+// writeWrapped writes s in lines of 64 columns. The last line is always
+// shorter than 64, empty if need be, which is how readers find the end of
+// a stanza body.
+func writeWrapped(w *bytes.Buffer, s string) {
+	for len(s) >= columnsPerRow {
+		w.WriteString(s[:columnsPerRow] + "\n")
+		s = s[columnsPerRow:]
+	}
+	w.WriteString(s + "\n")
+}
+
+type stanza struct {
+	typ  string
+	args []string
+	body []byte
+}
+
+type header struct {
+	stanzas  []stanza
+	macInput []byte
+	mac      []byte
+	size     int
+}
+
+// parseHeader parses the header at the start of file.
+func parseHeader(file []byte) (*header, error) {
+	pos := 0
+	nextLine := func() (string, error) {
+		i := bytes.IndexByte(file[pos:], '\n')
+		if i < 0 {
+			return "", fmt.Errorf("%w: truncated header", ErrCorrupt)
+		}
+		line := string(file[pos : pos+i])
+		pos += i + 1
+		return line, nil
+	}
+
+	line, err := nextLine()
+	if err != nil {
+		return nil, err
+	}
+	if line != intro {
+		return nil, fmt.Errorf("%w: not an age v1 file", ErrCorrupt)
+	}
+	hdr := &header{}
+	for {
+		start := pos
+		line, err := nextLine()
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(line, "--- ") {
+			hdr.macInput = file[:start+len("---")]
+			if hdr.mac, err = b64.DecodeString(line[len("--- "):]); err != nil {
+				return nil, fmt.Errorf("%w: invalid header MAC", ErrCorrupt)
+			}
+			hdr.size = pos
+			return hdr, nil
+		}
+		fields := strings.Split(line, " ")
+		if len(fields) < 2 || fields[0] != "->" || !validArgs(fields[1:]) {
+			return nil, fmt.Errorf("%w: invalid stanza line %q", ErrCorrupt, line)
+		}
+		s := stanza{typ: fields[1], args: fields[2:]}
+		var body strings.Builder
+		for {
+			line, err := nextLine()
+			if err != nil {
+				return nil, err
+			}
+			if len(line) > columnsPerRow {
+				return nil, fmt.Errorf("%w: stanza body line too long", ErrCorrupt)
+			}
+			body.WriteString(line)
+			if len(line) < columnsPerRow {
+				break
+			}
+		}
+		if s.body, err = b64.DecodeString(body.String()); err != nil {
+			return nil, fmt.Errorf("%w: invalid stanza body", ErrCorrupt)
+		}
+		hdr.stanzas = append(hdr.stanzas, s)
+	}
+}
+
+// validArgs reports whether every stanza argument, the type included, is a
+// non-empty string of visible ASCII characters, as the format requires.
+func validArgs(args []string) bool {
+	for _, a := range args {
+		if a == "" {
+			return false
+		}
+		for i := 0; i < len(a); i++ {
+			if a[i] < 0x21 || a[i] > 0x7e {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// chunkNonce is the STREAM nonce of chunk counter: an 11-byte big-endian
+// counter followed by a byte that is 1 on the last chunk.
+func chunkNonce(counter uint64, last bool) []byte {
+	nonce := make([]byte, chacha20poly1305.NonceSize)
+	for i := 0; i < 8; i++ {
+		nonce[10-i] = byte(counter >> (8 * i))
+	}
+	if last {
+		nonce[11] = 1
+	}
+	return nonce
+}
+
+// sealPayload encrypts plaintext in 64 KiB STREAM chunks.
+func sealPayload(fileKey, nonce, plaintext []byte) ([]byte, error) {
+	key := hkdfKey(fileKey, nonce, "payload")
+	defer wipe(key)
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		return nil, fmt.Errorf("age: %w", err)
+	}
+	var out []byte
+	for counter := uint64(0); ; counter++ {
+		n := len(plaintext)
+		last := n <= chunkSize
+		if !last {
+			n = chunkSize
+		}
+		out = aead.Seal(out, chunkNonce(counter, last), plaintext[:n], nil)
+		plaintext = plaintext[n:]
+		if last {
+			return out, nil
+		}
+	}
+}
+
+// openPayload reverses sealPayload.
+func openPayload(fileKey, nonce, payload []byte) ([]byte, error) {
+	key := hkdfKey(fileKey, nonce, "payload")
+	defer wipe(key)
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		return nil, fmt.Errorf("age: %w", err)
+	}
+	var out []byte
+	for counter := uint64(0); ; counter++ {
+		n := len(payload)
+		last := n <= chunkSize+chacha20poly1305.Overhead
+		if !last {
+			n = chunkSize + chacha20poly1305.Overhead
+		}
+		chunk, err := aead.Open(nil, chunkNonce(counter, last), payload[:n], nil)
+		if err != nil {
+			return nil, fmt.Errorf("%w: payload does not authenticate", ErrCorrupt)
+		}
+		if last && len(chunk) == 0 && counter > 0 {
+			return nil, fmt.Errorf("%w: empty final chunk", ErrCorrupt)
+		}
+		out = append(out, chunk...)
+		payload = payload[n:]
+		if last {
+			return out, nil
+		}
+	}
+}
+
+func wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
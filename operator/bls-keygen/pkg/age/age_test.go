@@ -0,0 +1,119 @@
+package age
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestBech32Vectors(t *testing.T) {
+	// Valid strings from BIP-173.
+	for _, s := range []string{
+		"A12UEL5L",
+		"abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxw",
+		"split1checkupstagehandshakeupstreamerranterredcaperred2y9e3w",
+	} {
+		if _, _, err := bech32Decode(s); err != nil {
+			t.Errorf("%s: %v", s, err)
+		}
+	}
+	// Mixed case, a bad checksum, an empty human-readable part.
+	for _, s := range []string{"A12UEL5l", "a12uel5m", "1qzzfhee"} {
+		if _, _, err := bech32Decode(s); err == nil {
+			t.Errorf("%s: decoded an invalid string", s)
+		}
+	}
+}
+
+func TestIdentityRecipient(t *testing.T) {
+	// RFC 7748 section 6.1: Alice's key pair.
+	secret, _ := hex.DecodeString("77076d0a7318a57d3c16c17251b26645df4c2f87ebc0992ab177fba51db92c2a")
+	wantPub, _ := hex.DecodeString("8520f0098930a754748b7ddcb43ef75a0dbf3a0d26381af4eba4a98eaa9b4e6a")
+	id, err := newIdentity(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(id.r.pub[:], wantPub) {
+		t.Fatalf("public key %x, want %x", id.r.pub, wantPub)
+	}
+
+	s := id.String()
+	if !strings.HasPrefix(s, "AGE-SECRET-KEY-1") {
+		t.Fatalf("identity %s", s)
+	}
+	parsed, err := ParseIdentity(s)
+	if err != nil || parsed.secret != id.secret {
+		t.Fatalf("identity does not round-trip: %v", err)
+	}
+	r := id.Recipient().String()
+	if !strings.HasPrefix(r, "age1") || len(r) != 62 {
+		t.Fatalf("recipient %s", r)
+	}
+	pr, err := ParseRecipient(r)
+	if err != nil || pr.pub != id.r.pub {
+		t.Fatalf("recipient does not round-trip: %v", err)
+	}
+	if _, err := ParseRecipient(s); err == nil {
+		t.Fatal("parsed an identity as a recipient")
+	}
+
+	ids, err := ParseIdentities(strings.NewReader("# created: today\n# public key: " + r + "\n" + s + "\n\n"))
+	if err != nil || len(ids) != 1 || ids[0].secret != id.secret {
+		t.Fatalf("identity file: %v", err)
+	}
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	alice, err := GenerateIdentity(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob, err := GenerateIdentity(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eve, err := GenerateIdentity(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	big := make([]byte, 2*chunkSize+100)
+	rand.Read(big)
+	for name, plaintext := range map[string][]byte{
+		"empty":      {},
+		"keystore":   []byte(`{"version": 1}`),
+		"one chunk":  bytes.Repeat([]byte{7}, chunkSize),
+		"multichunk": big,
+	} {
+		file, err := Encrypt(plaintext, alice.Recipient(), bob.Recipient())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasPrefix(file, []byte("age-encryption.org/v1\n-> X25519 ")) {
+			t.Fatalf("%s: header %q", name, file[:40])
+		}
+		for _, id := range []*Identity{alice, bob} {
+			got, err := Decrypt(file, eve, id)
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			if !bytes.Equal(got, plaintext) {
+				t.Fatalf("%s: plaintext does not round-trip", name)
+			}
+		}
+		if _, err := Decrypt(file, eve); !errors.Is(err, ErrNoIdentityMatched) {
+			t.Fatalf("%s: wrong identity: got %v, want ErrNoIdentityMatched", name, err)
+		}
+		tampered := append([]byte(nil), file...)
+		tampered[len(tampered)-1] ^= 1
+		if _, err := Decrypt(tampered, alice); !errors.Is(err, ErrCorrupt) {
+			t.Fatalf("%s: tampered payload: got %v, want ErrCorrupt", name, err)
+		}
+		if _, err := Decrypt(file[:len(file)-17], alice); !errors.Is(err, ErrCorrupt) {
+			t.Fatalf("%s: truncated payload: got %v, want ErrCorrupt", name, err)
+		}
+	}
+}
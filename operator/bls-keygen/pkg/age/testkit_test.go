@@ -0,0 +1,190 @@
+package age
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/curve25519"
+)
+
+// testkitVector is one file of the C2SP age test vectors in
+// testdata/testkit: a header of "key: value" lines, a blank line and the
+// age file itself.
+type testkitVector struct {
+	expect     string
+	payload    string
+	identities []*Identity
+	file       []byte
+}
+
+func readTestkitVector(t *testing.T, path string) testkitVector {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	head, file, ok := bytes.Cut(data, []byte("\n\n"))
+	if !ok {
+		t.Fatalf("%s: no blank line after the header", path)
+	}
+	v := testkitVector{file: file}
+	for _, line := range strings.Split(string(head), "\n") {
+		key, value, _ := strings.Cut(line, ": ")
+		switch key {
+		case "expect":
+			v.expect = value
+		case "payload":
+			v.payload = value
+		case "identity":
+			id, err := ParseIdentity(value)
+			if err != nil {
+				t.Fatalf("%s: %v", path, err)
+			}
+			v.identities = append(v.identities, id)
+		}
+	}
+	return v
+}
+
+// TestTestkit decrypts the C2SP age test vectors (c2sp.org/CCTV/age) for
+// X25519 identities, the files the reference implementation and rage are
+// tested against. Armored and passphrase vectors are left out, since this
+// package implements neither.
+func TestTestkit(t *testing.T) {
+	paths, err := filepath.Glob("testdata/testkit/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("no test vectors found")
+	}
+	for _, path := range paths {
+		if filepath.Base(path) == "README.md" {
+			continue
+		}
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			v := readTestkitVector(t, path)
+			plaintext, err := Decrypt(v.file, v.identities...)
+			switch v.expect {
+			case "success":
+				if err != nil {
+					t.Fatalf("expected success, got %v", err)
+				}
+				if sum := sha256.Sum256(plaintext); hex.EncodeToString(sum[:]) != v.payload {
+					t.Fatalf("payload sha256 %x, want %s", sum, v.payload)
+				}
+			case "no match":
+				if !errors.Is(err, ErrNoIdentityMatched) {
+					t.Fatalf("expected ErrNoIdentityMatched, got %v", err)
+				}
+			case "header failure", "HMAC failure", "payload failure":
+				if err == nil {
+					t.Fatalf("expected a %s, decrypted %d bytes", v.expect, len(plaintext))
+				}
+				if errors.Is(err, ErrNoIdentityMatched) {
+					t.Fatalf("expected a %s, got %v", v.expect, err)
+				}
+			default:
+				t.Fatalf("unknown expectation %q", v.expect)
+			}
+		})
+	}
+}
+
+// interopRNG is the randomness testdata/interop.age was encrypted with:
+// the file key, the ephemeral X25519 key and the payload nonce, in the
+// order encrypt reads them.
+const interopRNG = "000102030405060708090a0b0c0d0e0f" +
+	"101112131415161718191a1b1c1d1e1f202122232425262728292a2b2c2d2e2f" +
+	"303132333435363738393a3b3c3d3e3f"
+
+// interopPlaintext spans two STREAM chunks.
+var interopPlaintext = bytes.Repeat([]byte("bastion age interop\n"), 3500)
+
+func readInteropIdentity(t *testing.T) *Identity {
+	t.Helper()
+	f, err := os.Open("testdata/interop_identity.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	ids, err := ParseIdentities(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ids[0]
+}
+
+// TestEncryptInterop pins this package's output: encrypting with the
+// randomness of interopRNG must reproduce testdata/interop.age byte for
+// byte, a file checked to decrypt with the reference implementation
+// (age v1.2.1: age -d -i testdata/interop_identity.txt).
+func TestEncryptInterop(t *testing.T) {
+	id := readInteropIdentity(t)
+	rng, _ := hex.DecodeString(interopRNG)
+	want, err := os.ReadFile("testdata/interop.age")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := encrypt(bytes.NewReader(rng), interopPlaintext, []*Recipient{id.Recipient()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatal("encrypt no longer reproduces testdata/interop.age")
+	}
+
+	// The header binds what the format says it must: the stanza carries
+	// the ephemeral public key, and the MAC is HMAC-SHA-256 under
+	// HKDF(file key, "header") over the header up to "---".
+	hdr, err := parseHeader(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hdr.stanzas) != 1 || hdr.stanzas[0].typ != stanzaX25519 {
+		t.Fatalf("stanzas %+v, want one X25519 stanza", hdr.stanzas)
+	}
+	share, err := curve25519.X25519(rng[16:48], curve25519.Basepoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hdr.stanzas[0].args[0] != b64.EncodeToString(share) {
+		t.Fatalf("stanza share %s, want %s", hdr.stanzas[0].args[0], b64.EncodeToString(share))
+	}
+	mac := hmac.New(sha256.New, hkdfKey(rng[:16], nil, "header"))
+	mac.Write(hdr.macInput)
+	if !hmac.Equal(mac.Sum(nil), hdr.mac) {
+		t.Fatal("header MAC is not HMAC-SHA-256 under the header key")
+	}
+
+	plaintext, err := Decrypt(want, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(plaintext, interopPlaintext) {
+		t.Fatal("interop.age does not decrypt to the plaintext")
+	}
+}
+
+// TestDecryptAgeCLIFile decrypts testdata/age_cli.age, which the age v1.2.1
+// CLI encrypted to the interop identity.
+func TestDecryptAgeCLIFile(t *testing.T) {
+	file, err := os.ReadFile("testdata/age_cli.age")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := Decrypt(file, readInteropIdentity(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "{\"version\":1,\"note\":\"encrypted by age v1.2.1\"}\n"; string(plaintext) != want {
+		t.Fatalf("decrypted %q, want %q", plaintext, want)
+	}
+}
@@ -0,0 +1,207 @@
+package bls
+
+import (
+	"errors"
+	"fmt"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+)
+
+// ErrEmptyAggregate is returned when asked to aggregate nothing.
+var ErrEmptyAggregate = errors.New("bls: cannot aggregate an empty set")
+
+// AggregateSignatures sums sigs into a single signature. All signers must
+// have signed the same message for the result to verify against the
+// aggregate public key.
+func AggregateSignatures(sigs []*Signature) (*Signature, error) {
+	return activeBackend().AggregateSignatures(sigs)
+}
+
+func aggregateSignatures(sigs []*Signature) (*Signature, error) {
+	if len(sigs) == 0 {
+		return nil, ErrEmptyAggregate
+	}
+	var acc bls12381.G1Jac
+	for i, sig := range sigs {
+		if sig == nil {
+			return nil, fmt.Errorf("bls: signature %d is nil", i)
+		}
+		acc.AddMixed(&sig.point)
+	}
+	var agg Signature
+	agg.point.FromJacobian(&acc)
+	return &agg, nil
+}
+
+// AggregatePublicKeys sums pks into a single G2 public key.
+func AggregatePublicKeys(pks []*G2PubKey) (*G2PubKey, error) {
+	if len(pks) == 0 {
+		return nil, ErrEmptyAggregate
+	}
+	var acc bls12381.G2Jac
+	for i, pk := range pks {
+		if pk == nil {
+			return nil, fmt.Errorf("bls: public key %d is nil", i)
+		}
+		acc.AddMixed(&pk.point)
+	}
+	var agg G2PubKey
+	agg.point.FromJacobian(&acc)
+	return &agg, nil
+}
+
+// AggregateG1PublicKeys sums pks into the aggregate G1 public key (apk) of
+// a quorum. Unlike the other aggregates, the empty set is allowed: a quorum
+// without operators has the identity as its apk.
+func AggregateG1PublicKeys(pks []*G1PubKey) (*G1PubKey, error) {
+	var acc bls12381.G1Jac
+	acc.X.SetOne()
+	acc.Y.SetOne()
+	for i, pk := range pks {
+		if pk == nil {
+			return nil, fmt.Errorf("bls: public key %d is nil", i)
+		}
+		acc.AddMixed(&pk.point)
+	}
+	var agg G1PubKey
+	agg.point.FromJacobian(&acc)
+	return &agg, nil
+}
+
+// APKHash returns the hash BLSApkRegistry keys apk history by: keccak256
+// over the apk's coordinates, laid out as OperatorID lays them out. The
+// registry stores the first 24 bytes of it as the bytes24 apkHash of each
+// ApkUpdate. As with OperatorID, the curve is BLS12-381, so the hash only
+// matches a BLS12-381 registry, not EigenLayer's BN254 one.
+func APKHash(apk *G1PubKey) [32]byte {
+	return hashG1Point(apk)
+}
+
+// ErrNoSigners is returned by VerifyQuorum when every operator of the
+// quorum is a non-signer, leaving nothing to verify.
+var ErrNoSigners = errors.New("bls: every operator of the quorum is a non-signer")
+
+// VerifyQuorum checks aggSig over msg the way BLSSignatureChecker does: the
+// signers' aggregate key is the quorum's totalApk minus the keys of its
+// nonSignerPks, and aggSig must verify against it. If apk is not nil it is
+// the signers' aggregate key the caller claims, and it must equal the
+// computed one.
+//
+// With no non-signers the signing key is totalApk itself. If the
+// non-signers sum to totalApk nobody signed, and VerifyQuorum returns
+// ErrNoSigners rather than checking a signature against the identity. A
+// pairing mismatch returns false and ErrSignatureMismatch, as VerifyE does.
+func VerifyQuorum(apk *G2PubKey, msg []byte, aggSig *Signature, nonSignerPks []*G2PubKey, totalApk *G2PubKey) (bool, error) {
+	if totalApk == nil {
+		return false, fmt.Errorf("%w: nil total apk", ErrInvalidPoint)
+	}
+	var acc bls12381.G2Jac
+	acc.FromAffine(&totalApk.point)
+	for i, pk := range nonSignerPks {
+		if pk == nil {
+			return false, fmt.Errorf("bls: non-signer public key %d is nil", i)
+		}
+		var neg bls12381.G2Affine
+		neg.Neg(&pk.point)
+		acc.AddMixed(&neg)
+	}
+	var signing G2PubKey
+	signing.point.FromJacobian(&acc)
+	if signing.point.IsInfinity() {
+		return false, ErrNoSigners
+	}
+	if apk != nil && !apk.point.Equal(&signing.point) {
+		return false, errors.New("bls: apk does not equal the total apk minus the non-signers")
+	}
+	if err := VerifyE(&signing, msg, aggSig); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// AggregateVerifyDistinct checks aggSig, the sum of signatures by pubkeys[i]
+// over messages[i] under DST, where operators may have signed different
+// messages. It tests
+//
+//	e(aggSig, -g2) · Π e(H(m), Σ pk_i over the i with messages[i] == m) == 1
+//
+// with one pairing per distinct message: the keys of operators who signed
+// the same message are summed first, and a sum that is the identity is
+// rejected like an identity key. A mismatch returns false and
+// ErrSignatureMismatch, invalid points the errors VerifyE returns for them.
+func AggregateVerifyDistinct(pubkeys []*G2PubKey, messages [][]byte, aggSig *Signature) (bool, error) {
+	if len(pubkeys) != len(messages) {
+		return false, fmt.Errorf("%w: %d public keys for %d messages", ErrBatchLength, len(pubkeys), len(messages))
+	}
+	if len(pubkeys) == 0 {
+		return false, ErrEmptyAggregate
+	}
+	if aggSig == nil {
+		return false, fmt.Errorf("%w: nil signature", ErrInvalidPoint)
+	}
+	if !aggSig.point.IsOnCurve() {
+		return false, fmt.Errorf("%w: signature is not on the curve", ErrInvalidPoint)
+	}
+	if !aggSig.point.IsInSubGroup() {
+		return false, fmt.Errorf("%w: signature", ErrNotInSubgroup)
+	}
+
+	// Sum the keys per distinct message, keeping first-seen order.
+	index := make(map[string]int)
+	var msgs [][]byte
+	var sums []bls12381.G2Jac
+	for i, pk := range pubkeys {
+		if pk == nil {
+			return false, fmt.Errorf("%w: public key %d is nil", ErrInvalidPoint, i)
+		}
+		switch {
+		case pk.point.IsInfinity():
+			return false, fmt.Errorf("%w: public key %d is the identity", ErrInvalidPoint, i)
+		case !pk.point.IsOnCurve():
+			return false, fmt.Errorf("%w: public key %d is not on the curve", ErrInvalidPoint, i)
+		case !pk.point.IsInSubGroup():
+			return false, fmt.Errorf("%w: public key %d", ErrNotInSubgroup, i)
+		}
+		j, ok := index[string(messages[i])]
+		if !ok {
+			j = len(msgs)
+			index[string(messages[i])] = j
+			msgs = append(msgs, messages[i])
+			sums = append(sums, bls12381.G2Jac{})
+			sums[j].FromAffine(&pk.point)
+			continue
+		}
+		sums[j].AddMixed(&pk.point)
+	}
+
+	_, _, _, g2 := bls12381.Generators()
+	g1s := make([]bls12381.G1Affine, 0, len(msgs)+1)
+	g2s := make([]bls12381.G2Affine, 0, len(msgs)+1)
+	var negG2 bls12381.G2Affine
+	negG2.Neg(&g2)
+	g1s = append(g1s, aggSig.point)
+	g2s = append(g2s, negG2)
+	for j, msg := range msgs {
+		h, err := bls12381.HashToG1(msg, []byte(DST))
+		if err != nil {
+			return false, fmt.Errorf("bls: hash to curve: %w", err)
+		}
+		var pk bls12381.G2Affine
+		pk.FromJacobian(&sums[j])
+		// Keys that cancel would drop the message from the check, so their
+		// holders would appear to have signed it without doing so.
+		if pk.IsInfinity() {
+			return false, fmt.Errorf("%w: the keys signing message %d sum to the identity", ErrInvalidPoint, j)
+		}
+		g1s = append(g1s, h)
+		g2s = append(g2s, pk)
+	}
+	ok, err := bls12381.PairingCheck(g1s, g2s)
+	if err != nil {
+		return false, fmt.Errorf("bls: pairing check: %w", err)
+	}
+	if !ok {
+		return false, ErrSignatureMismatch
+	}
+	return true, nil
+}
@@ -0,0 +1,237 @@
+package bls
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func TestAggregateVerify(t *testing.T) {
+	msg := []byte("task 42 response")
+
+	var sigs []*Signature
+	var pks []*G2PubKey
+	for i := 0; i < 3; i++ {
+		kp, err := GenerateKeyPair(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig, err := kp.Sign(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sigs = append(sigs, sig)
+		pks = append(pks, kp.G2PubKey)
+	}
+
+	aggSig, err := AggregateSignatures(sigs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aggPk, err := AggregatePublicKeys(pks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Verify(aggPk, msg, aggSig) {
+		t.Fatal("aggregate signature does not verify against aggregate public key")
+	}
+
+	partial, err := AggregatePublicKeys(pks[:2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if Verify(partial, msg, aggSig) {
+		t.Fatal("aggregate signature verified against a partial aggregate key")
+	}
+}
+
+func TestAggregateEmpty(t *testing.T) {
+	if _, err := AggregateSignatures(nil); !errors.Is(err, ErrEmptyAggregate) {
+		t.Fatalf("signatures: got %v, want ErrEmptyAggregate", err)
+	}
+	if _, err := AggregatePublicKeys([]*G2PubKey{}); !errors.Is(err, ErrEmptyAggregate) {
+		t.Fatalf("public keys: got %v, want ErrEmptyAggregate", err)
+	}
+	if _, err := AggregateSignatures([]*Signature{nil}); err == nil {
+		t.Fatal("expected an error for a nil signature")
+	}
+}
+
+func TestAggregateG1PublicKeysAPKHash(t *testing.T) {
+	// Operators with private keys 1, 2 and 3 have apk 6*G1.
+	var pks []*G1PubKey
+	for _, s := range []int64{1, 2, 3} {
+		var sk PrivateKey
+		sk.scalar.SetInt64(s)
+		pks = append(pks, NewKeyPair(&sk).G1PubKey)
+	}
+	apk, err := AggregateG1PublicKeys(pks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var six PrivateKey
+	six.scalar.SetInt64(6)
+	if !bytes.Equal(apk.Bytes(), NewKeyPair(&six).G1PubKey.Bytes()) {
+		t.Fatal("apk is not the sum of the operator keys")
+	}
+	const wantHash = "c11c40d50ad5bf6b409249fabce08656fb8704339ad6db806948d0a3d869fd0f"
+	if h := APKHash(apk); hex.EncodeToString(h[:]) != wantHash {
+		t.Fatalf("apk hash = %x, want %s", h, wantHash)
+	}
+
+	empty, err := AggregateG1PublicKeys(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !empty.point.IsInfinity() {
+		t.Fatal("empty set did not aggregate to the identity")
+	}
+	// keccak256 of 128 zero bytes: the identity is hashed as (0, 0).
+	const wantEmpty = "012893657d8eb2efad4de0a91bcd0e39ad9837745dec3ea923737ea803fc8e3d"
+	if h := APKHash(empty); hex.EncodeToString(h[:]) != wantEmpty {
+		t.Fatalf("empty apk hash = %x, want %s", h, wantEmpty)
+	}
+
+	if _, err := AggregateG1PublicKeys([]*G1PubKey{pks[0], nil}); err == nil {
+		t.Fatal("nil public key accepted")
+	}
+}
+
+func TestVerifyQuorum(t *testing.T) {
+	msg := []byte("task 7 response")
+
+	var kps []*KeyPair
+	var pks []*G2PubKey
+	for i := 0; i < 3; i++ {
+		kp, err := GenerateKeyPair(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		kps = append(kps, kp)
+		pks = append(pks, kp.G2PubKey)
+	}
+	totalApk, err := AggregatePublicKeys(pks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sign := func(signers []*KeyPair) *Signature {
+		t.Helper()
+		var sigs []*Signature
+		for _, kp := range signers {
+			sig, err := kp.Sign(msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sigs = append(sigs, sig)
+		}
+		agg, err := AggregateSignatures(sigs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return agg
+	}
+
+	// Operators 0 and 2 sign, operator 1 does not.
+	aggSig := sign([]*KeyPair{kps[0], kps[2]})
+	apk, err := AggregatePublicKeys([]*G2PubKey{pks[0], pks[2]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonSigners := []*G2PubKey{pks[1]}
+	if ok, err := VerifyQuorum(apk, msg, aggSig, nonSigners, totalApk); !ok || err != nil {
+		t.Fatalf("one non-signer: got %v, %v", ok, err)
+	}
+	if ok, err := VerifyQuorum(nil, msg, aggSig, nonSigners, totalApk); !ok || err != nil {
+		t.Fatalf("one non-signer without apk: got %v, %v", ok, err)
+	}
+	if ok, err := VerifyQuorum(totalApk, msg, aggSig, nonSigners, totalApk); ok || err == nil {
+		t.Fatal("accepted an apk that is not the total minus the non-signers")
+	}
+	if ok, err := VerifyQuorum(nil, msg, aggSig, nil, totalApk); ok || !errors.Is(err, ErrSignatureMismatch) {
+		t.Fatalf("non-signer left out: got %v, %v, want ErrSignatureMismatch", ok, err)
+	}
+	if ok, err := VerifyQuorum(nil, []byte("other"), aggSig, nonSigners, totalApk); ok || !errors.Is(err, ErrSignatureMismatch) {
+		t.Fatalf("wrong message: got %v, %v, want ErrSignatureMismatch", ok, err)
+	}
+
+	// Everyone signs.
+	if ok, err := VerifyQuorum(totalApk, msg, sign(kps), nil, totalApk); !ok || err != nil {
+		t.Fatalf("all signed: got %v, %v", ok, err)
+	}
+
+	// Nobody signs.
+	if ok, err := VerifyQuorum(nil, msg, aggSig, pks, totalApk); ok || !errors.Is(err, ErrNoSigners) {
+		t.Fatalf("none signed: got %v, %v, want ErrNoSigners", ok, err)
+	}
+}
+
+func TestAggregateVerifyDistinct(t *testing.T) {
+	messages := [][]byte{[]byte("stake 100"), []byte("stake 250"), []byte("stake 75")}
+	var pks []*G2PubKey
+	var sigs []*Signature
+	for _, msg := range messages {
+		kp, err := GenerateKeyPair(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig, err := kp.Sign(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pks = append(pks, kp.G2PubKey)
+		sigs = append(sigs, sig)
+	}
+	aggSig, err := AggregateSignatures(sigs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, err := AggregateVerifyDistinct(pks, messages, aggSig); !ok || err != nil {
+		t.Fatalf("got %v, %v", ok, err)
+	}
+
+	altered := [][]byte{messages[0], []byte("stake 251"), messages[2]}
+	if ok, err := AggregateVerifyDistinct(pks, altered, aggSig); ok || !errors.Is(err, ErrSignatureMismatch) {
+		t.Fatalf("altered message: got %v, %v, want ErrSignatureMismatch", ok, err)
+	}
+	swapped := []*G2PubKey{pks[1], pks[0], pks[2]}
+	if ok, err := AggregateVerifyDistinct(swapped, messages, aggSig); ok || !errors.Is(err, ErrSignatureMismatch) {
+		t.Fatalf("keys swapped: got %v, %v, want ErrSignatureMismatch", ok, err)
+	}
+	if ok, err := AggregateVerifyDistinct(pks, messages[:2], aggSig); ok || !errors.Is(err, ErrBatchLength) {
+		t.Fatalf("length mismatch: got %v, %v, want ErrBatchLength", ok, err)
+	}
+	if _, err := AggregateVerifyDistinct(nil, nil, aggSig); !errors.Is(err, ErrEmptyAggregate) {
+		t.Fatalf("empty: got %v, want ErrEmptyAggregate", err)
+	}
+
+	// Operators signing the same message share a pairing.
+	kp, err := GenerateKeyPair(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := kp.Sign(messages[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	aggSig, err = AggregateSignatures(append(sigs, sig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	repeated := append(append([][]byte(nil), messages...), messages[0])
+	if ok, err := AggregateVerifyDistinct(append(pks, kp.G2PubKey), repeated, aggSig); !ok || err != nil {
+		t.Fatalf("repeated message: got %v, %v", ok, err)
+	}
+
+	// A key and its negation cancel: without the identity check their
+	// message would drop out of the pairing and aggSig alone, over the other
+	// message, would pass for both of them.
+	var neg G2PubKey
+	neg.point.Neg(&kp.G2PubKey.point)
+	cancelling := []*G2PubKey{pks[1], kp.G2PubKey, &neg}
+	if ok, err := AggregateVerifyDistinct(cancelling, [][]byte{messages[1], messages[0], messages[0]}, sigs[1]); ok || !errors.Is(err, ErrInvalidPoint) {
+		t.Fatalf("cancelling keys: got %v, %v, want ErrInvalidPoint", ok, err)
+	}
+}
@@ -0,0 +1,125 @@
+package bls
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultBackendName is the backend in use until UseBackend selects
+// another: gnark-crypto, the curve implementation this package is built on.
+const DefaultBackendName = "gnark"
+
+// ErrUnknownBackend is returned by UseBackend for a name nothing registered.
+var ErrUnknownBackend = errors.New("bls: unknown backend")
+
+// Backend is a BLS12-381 implementation of the operations that dominate
+// signing and verification cost: key generation, signing and verifying
+// under a domain separation tag, and signature aggregation. GenerateKeyPair,
+// Sign, Verify, AggregateSignatures and everything built on them, such as
+// SigningContext, proofs of possession and VerifyQuorum, go through the
+// backend in use, so alternatives such as herumi or blst can be benchmarked
+// without forking the callers. BatchVerify, AggregateVerifyDistinct and
+// SignPoint stay on gnark-crypto. Points cross the interface as this
+// package's types; a backend with its own representation converts through
+// Bytes and the FromBytes parsers.
+//
+// Keys and signatures are the same whatever the backend, so key files
+// record none and a key generated under one backend signs under another.
+type Backend interface {
+	GenerateKeyPair(r io.Reader) (*KeyPair, error)
+	Sign(kp *KeyPair, msg []byte, dst string) (*Signature, error)
+	// Verify returns nil for a valid signature, and otherwise the same
+	// errors VerifyE does.
+	Verify(pk *G2PubKey, msg []byte, sig *Signature, dst string) error
+	AggregateSignatures(sigs []*Signature) (*Signature, error)
+}
+
+// gnarkBackend is the built-in gnark-crypto backend.
+type gnarkBackend struct{}
+
+func (gnarkBackend) GenerateKeyPair(r io.Reader) (*KeyPair, error) { return generateKeyPair(r) }
+
+func (gnarkBackend) Sign(kp *KeyPair, msg []byte, dst string) (*Signature, error) {
+	return gnarkSign(kp, msg, dst)
+}
+
+func (gnarkBackend) Verify(pk *G2PubKey, msg []byte, sig *Signature, dst string) error {
+	return gnarkVerify(pk, msg, sig, dst)
+}
+
+func (gnarkBackend) AggregateSignatures(sigs []*Signature) (*Signature, error) {
+	return aggregateSignatures(sigs)
+}
+
+type namedBackend struct {
+	name string
+	b    Backend
+}
+
+var (
+	backendsMu sync.Mutex
+	backends   = map[string]Backend{DefaultBackendName: gnarkBackend{}}
+	active     atomic.Pointer[namedBackend]
+)
+
+func init() {
+	active.Store(&namedBackend{DefaultBackendName, gnarkBackend{}})
+}
+
+func activeBackend() Backend {
+	return active.Load().b
+}
+
+// RegisterBackend makes b available to UseBackend under name. Like
+// database/sql.Register it is meant to be called from init, and panics if
+// b is nil or name is empty or already taken.
+func RegisterBackend(name string, b Backend) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+	if name == "" || b == nil {
+		panic("bls: RegisterBackend needs a name and a backend")
+	}
+	if _, dup := backends[name]; dup {
+		panic("bls: RegisterBackend called twice for backend " + name)
+	}
+	backends[name] = b
+}
+
+// UseBackend switches every operation in this package to the backend
+// registered under name. Select it once, before any key is used, so that a
+// run signs and verifies with one implementation throughout.
+func UseBackend(name string) error {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+	b, ok := backends[name]
+	if !ok {
+		return fmt.Errorf("%w %q (have %v)", ErrUnknownBackend, name, backendNames())
+	}
+	active.Store(&namedBackend{name, b})
+	return nil
+}
+
+// ActiveBackend returns the name of the backend in use.
+func ActiveBackend() string {
+	return active.Load().name
+}
+
+// Backends returns the names of the registered backends, sorted.
+func Backends() []string {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+	return backendNames()
+}
+
+func backendNames() []string {
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,124 @@
+package bls
+
+import (
+	"crypto/rand"
+	"errors"
+	"io"
+	"testing"
+)
+
+// countingBackend is a mock backend that records its calls and signs
+// through gnark-crypto.
+type countingBackend struct {
+	gnarkBackend
+	generated, signed, verified, aggregated int
+}
+
+func (b *countingBackend) GenerateKeyPair(r io.Reader) (*KeyPair, error) {
+	b.generated++
+	return b.gnarkBackend.GenerateKeyPair(r)
+}
+
+func (b *countingBackend) Sign(kp *KeyPair, msg []byte, dst string) (*Signature, error) {
+	b.signed++
+	return b.gnarkBackend.Sign(kp, msg, dst)
+}
+
+func (b *countingBackend) Verify(pk *G2PubKey, msg []byte, sig *Signature, dst string) error {
+	b.verified++
+	return b.gnarkBackend.Verify(pk, msg, sig, dst)
+}
+
+func (b *countingBackend) AggregateSignatures(sigs []*Signature) (*Signature, error) {
+	b.aggregated++
+	return b.gnarkBackend.AggregateSignatures(sigs)
+}
+
+var mockBackend = &countingBackend{}
+
+func init() {
+	RegisterBackend("mock", mockBackend)
+}
+
+// useBackend selects name for the rest of the test.
+func useBackend(t *testing.T, name string) {
+	t.Helper()
+	if err := UseBackend(name); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { UseBackend(DefaultBackendName) })
+}
+
+func TestBackendSignVerifyCycle(t *testing.T) {
+	useBackend(t, "mock")
+	*mockBackend = countingBackend{}
+	if got := ActiveBackend(); got != "mock" {
+		t.Fatalf("ActiveBackend() = %q", got)
+	}
+
+	msg := []byte("task 9 response")
+	var sigs []*Signature
+	var pks []*G2PubKey
+	for i := 0; i < 2; i++ {
+		kp, err := GenerateKeyPair(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig, err := kp.Sign(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := VerifyE(kp.G2PubKey, msg, sig); err != nil {
+			t.Fatal(err)
+		}
+		sigs = append(sigs, sig)
+		pks = append(pks, kp.G2PubKey)
+	}
+	agg, err := AggregateSignatures(sigs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	apk, err := AggregatePublicKeys(pks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Verify(apk, msg, agg) {
+		t.Fatal("aggregate signature does not verify")
+	}
+	if DefaultSigningContext.Verify(pks[0], msg, agg) {
+		t.Fatal("aggregate signature verified against one key")
+	}
+
+	want := countingBackend{generated: 2, signed: 2, verified: 4, aggregated: 1}
+	if *mockBackend != want {
+		t.Fatalf("backend calls %+v, want %+v", *mockBackend, want)
+	}
+
+	// Signatures do not depend on the backend that made them.
+	useBackend(t, DefaultBackendName)
+	if !Verify(apk, msg, agg) {
+		t.Fatal("signature from the mock backend does not verify under gnark")
+	}
+}
+
+func TestUseBackendUnknown(t *testing.T) {
+	if err := UseBackend("nope"); !errors.Is(err, ErrUnknownBackend) {
+		t.Fatalf("got %v, want ErrUnknownBackend", err)
+	}
+	if got := ActiveBackend(); got != DefaultBackendName {
+		t.Fatalf("failed UseBackend switched to %q", got)
+	}
+	names := Backends()
+	if len(names) != 2 || names[0] != DefaultBackendName || names[1] != "mock" {
+		t.Fatalf("Backends() = %v", names)
+	}
+}
+
+func TestRegisterBackendDuplicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("registering a name twice did not panic")
+		}
+	}()
+	RegisterBackend(DefaultBackendName, gnarkBackend{})
+}
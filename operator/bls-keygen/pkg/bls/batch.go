@@ -0,0 +1,104 @@
+package bls
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+)
+
+// ErrBatchLength is returned when the inputs of BatchVerify differ in length.
+var ErrBatchLength = errors.New("bls: public keys, messages and signatures differ in length")
+
+// batchScalarBits is the size of the random coefficients. A forged batch
+// passes with probability about 2^-128.
+const batchScalarBits = 128
+
+// BatchVerify checks many (pubkey, message, signature) triples at once using
+// a random linear combination: with fresh random r_i it tests
+//
+//	e(Σ r_i·sig_i, -g2) · Π e(r_i·H(m_i), pk_i) == 1
+//
+// which shares a single final exponentiation across the batch. Messages may
+// all differ. If the batch fails, every triple is verified on its own and
+// the indices of the bad ones are returned.
+func BatchVerify(pubkeys []*G2PubKey, messages [][]byte, sigs []*Signature) (bool, []int, error) {
+	if len(pubkeys) != len(messages) || len(pubkeys) != len(sigs) {
+		return false, nil, ErrBatchLength
+	}
+	if len(sigs) == 0 {
+		return false, nil, ErrEmptyAggregate
+	}
+	for i := range sigs {
+		if pubkeys[i] == nil || sigs[i] == nil {
+			return false, nil, fmt.Errorf("bls: batch entry %d is nil", i)
+		}
+	}
+
+	ok, err := batchCheck(rand.Reader, pubkeys, messages, sigs)
+	if err != nil {
+		return false, nil, err
+	}
+	if ok {
+		return true, nil, nil
+	}
+
+	var failed []int
+	for i := range sigs {
+		if !Verify(pubkeys[i], messages[i], sigs[i]) {
+			failed = append(failed, i)
+		}
+	}
+	return false, failed, nil
+}
+
+func batchCheck(r io.Reader, pubkeys []*G2PubKey, messages [][]byte, sigs []*Signature) (bool, error) {
+	n := len(sigs)
+	g1s := make([]bls12381.G1Affine, n+1)
+	g2s := make([]bls12381.G2Affine, n+1)
+
+	var sigAcc bls12381.G1Jac
+	for i := range sigs {
+		ri, err := randomCoefficient(r)
+		if err != nil {
+			return false, err
+		}
+		h, err := bls12381.HashToG1(messages[i], []byte(DST))
+		if err != nil {
+			return false, fmt.Errorf("bls: hash to curve: %w", err)
+		}
+		g1s[i].ScalarMultiplication(&h, ri)
+		g2s[i] = pubkeys[i].point
+
+		var s bls12381.G1Jac
+		s.FromAffine(&sigs[i].point)
+		s.ScalarMultiplication(&s, ri)
+		sigAcc.AddAssign(&s)
+	}
+
+	_, _, _, g2 := bls12381.Generators()
+	g1s[n].FromJacobian(&sigAcc)
+	g2s[n].Neg(&g2)
+
+	ok, err := bls12381.PairingCheck(g1s, g2s)
+	if err != nil {
+		return false, fmt.Errorf("bls: pairing check: %w", err)
+	}
+	return ok, nil
+}
+
+// randomCoefficient returns a nonzero batchScalarBits-bit scalar.
+func randomCoefficient(r io.Reader) (*big.Int, error) {
+	var buf [batchScalarBits / 8]byte
+	for {
+		if _, err := io.ReadFull(r, buf[:]); err != nil {
+			return nil, fmt.Errorf("bls: failed to read entropy: %w", err)
+		}
+		if k := new(big.Int).SetBytes(buf[:]); k.Sign() != 0 {
+			return k, nil
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package bls
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// batchFixture returns n key pairs, each signing its own message.
+func batchFixture(tb testing.TB, n int) ([]*G2PubKey, [][]byte, []*Signature) {
+	tb.Helper()
+	pks := make([]*G2PubKey, n)
+	msgs := make([][]byte, n)
+	sigs := make([]*Signature, n)
+	for i := range sigs {
+		kp, err := GenerateKeyPair(rand.Reader)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		msgs[i] = []byte(fmt.Sprintf("task %d response", i))
+		if sigs[i], err = kp.Sign(msgs[i]); err != nil {
+			tb.Fatal(err)
+		}
+		pks[i] = kp.G2PubKey
+	}
+	return pks, msgs, sigs
+}
+
+func TestBatchVerify(t *testing.T) {
+	pks, msgs, sigs := batchFixture(t, 8)
+
+	ok, failed, err := BatchVerify(pks, msgs, sigs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok || failed != nil {
+		t.Fatalf("valid batch: ok=%v failed=%v", ok, failed)
+	}
+}
+
+func TestBatchVerifyPinpointsBadSignature(t *testing.T) {
+	pks, msgs, sigs := batchFixture(t, 8)
+	// Signature 5 is still a valid signature, just not over this message.
+	msgs[5] = msgs[2]
+
+	ok, failed, err := BatchVerify(pks, msgs, sigs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Fatal("batch with a bad signature verified")
+	}
+	if !reflect.DeepEqual(failed, []int{5}) {
+		t.Fatalf("failed = %v, want [5]", failed)
+	}
+}
+
+func TestBatchVerifyRejectsBadInput(t *testing.T) {
+	pks, msgs, sigs := batchFixture(t, 2)
+	if _, _, err := BatchVerify(pks, msgs[:1], sigs); !errors.Is(err, ErrBatchLength) {
+		t.Fatalf("length mismatch: got %v, want ErrBatchLength", err)
+	}
+	if _, _, err := BatchVerify(nil, nil, nil); !errors.Is(err, ErrEmptyAggregate) {
+		t.Fatalf("empty batch: got %v, want ErrEmptyAggregate", err)
+	}
+	sigs[1] = nil
+	if _, _, err := BatchVerify(pks, msgs, sigs); err == nil {
+		t.Fatal("expected an error for a nil signature")
+	}
+}
+
+func BenchmarkVerify(b *testing.B) {
+	for _, n := range []int{16, 128} {
+		pks, msgs, sigs := batchFixture(b, n)
+		b.Run(fmt.Sprintf("sequential/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for j := range sigs {
+					if !Verify(pks[j], msgs[j], sigs[j]) {
+						b.Fatal("signature did not verify")
+					}
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("batch/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if ok, _, err := BatchVerify(pks, msgs, sigs); err != nil || !ok {
+					b.Fatal("batch did not verify")
+				}
+			}
+		})
+	}
+}
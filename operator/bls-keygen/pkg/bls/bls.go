@@ -0,0 +1,337 @@
+// Package bls implements BLS12-381 keys and signatures for Bastion AVS operators.
+//
+// Signatures live on G1 and are verified against the operator's G2 public key,
+// the same layout EigenLayer's BLSApkRegistry uses. Every key pair also carries
+// the matching G1 public key, which is what gets aggregated into quorum apks.
+package bls
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
+)
+
+// DST is the domain separation tag used when hashing messages to G1.
+const DST = "BLS_SIG_BLS12381G1_XMD:SHA-256_SSWU_RO_NUL_"
+
+// PrivateKeySize is the length of a big-endian encoded private key scalar.
+const PrivateKeySize = fr.Bytes
+
+var (
+	ErrInvalidPrivateKey = errors.New("bls: private key is zero or not in the scalar field")
+	ErrInvalidPoint      = errors.New("bls: invalid curve point")
+	ErrNotInSubgroup     = errors.New("bls: point is not in the prime-order subgroup")
+	// ErrSignatureMismatch is returned by VerifyE when the inputs are valid
+	// points but the pairing check fails: the signature is not over this
+	// message by this key.
+	ErrSignatureMismatch = errors.New("bls: signature does not match the public key and message")
+)
+
+// PrivateKey is a scalar in the BLS12-381 scalar field.
+type PrivateKey struct {
+	scalar fr.Element
+}
+
+// G1PubKey is a public key on G1.
+type G1PubKey struct {
+	point bls12381.G1Affine
+}
+
+// G2PubKey is a public key on G2.
+type G2PubKey struct {
+	point bls12381.G2Affine
+}
+
+// Signature is a signature on G1.
+type Signature struct {
+	point bls12381.G1Affine
+}
+
+// KeyPair holds a private key together with both of its public keys.
+type KeyPair struct {
+	PrivateKey *PrivateKey
+	G1PubKey   *G1PubKey
+	G2PubKey   *G2PubKey
+}
+
+// GenerateKeyPair samples a fresh private key from r and derives its public keys.
+func GenerateKeyPair(r io.Reader) (*KeyPair, error) {
+	return activeBackend().GenerateKeyPair(r)
+}
+
+func generateKeyPair(r io.Reader) (*KeyPair, error) {
+	// Draw 48 bytes so the reduction mod the group order has negligible bias.
+	var buf [48]byte
+	defer SecretBytes(buf[:]).Zero()
+	wide := new(big.Int)
+	defer zeroBigInt(wide)
+	for {
+		if _, err := io.ReadFull(r, buf[:]); err != nil {
+			return nil, fmt.Errorf("bls: failed to read entropy: %w", err)
+		}
+		var sk PrivateKey
+		sk.scalar.SetBigInt(wide.SetBytes(buf[:]))
+		if !sk.scalar.IsZero() {
+			return NewKeyPair(&sk), nil
+		}
+	}
+}
+
+// NewKeyPair derives the G1 and G2 public keys of sk.
+func NewKeyPair(sk *PrivateKey) *KeyPair {
+	_, _, g1, g2 := bls12381.Generators()
+
+	var pk1 G1PubKey
+	var pk2 G2PubKey
+	sk.withBigInt(func(s *big.Int) {
+		pk1.point.ScalarMultiplication(&g1, s)
+		pk2.point.ScalarMultiplication(&g2, s)
+	})
+
+	return &KeyPair{PrivateKey: sk, G1PubKey: &pk1, G2PubKey: &pk2}
+}
+
+// PrivateKeyFromBytes parses a 32-byte big-endian scalar.
+func PrivateKeyFromBytes(b []byte) (*PrivateKey, error) {
+	if len(b) != PrivateKeySize {
+		return nil, fmt.Errorf("%w: expected %d bytes, got %d", ErrInvalidPrivateKey, PrivateKeySize, len(b))
+	}
+	var sk PrivateKey
+	if err := sk.scalar.SetBytesCanonical(b); err != nil {
+		return nil, ErrInvalidPrivateKey
+	}
+	if sk.scalar.IsZero() {
+		return nil, ErrInvalidPrivateKey
+	}
+	return &sk, nil
+}
+
+// PrivateKeyFromBytesReduced parses a 32-byte big-endian integer and reduces
+// it modulo the scalar field order. It exists for importing keys that were
+// not generated as BLS12-381 scalars; new keys should use
+// PrivateKeyFromBytes, which rejects out-of-range input.
+func PrivateKeyFromBytesReduced(b []byte) (*PrivateKey, error) {
+	if len(b) != PrivateKeySize {
+		return nil, fmt.Errorf("%w: expected %d bytes, got %d", ErrInvalidPrivateKey, PrivateKeySize, len(b))
+	}
+	var sk PrivateKey
+	sk.scalar.SetBytes(b)
+	if sk.scalar.IsZero() {
+		return nil, ErrInvalidPrivateKey
+	}
+	return &sk, nil
+}
+
+// Bytes returns the big-endian encoding of the scalar. The caller owns the
+// copy and should Zero it when done.
+func (sk *PrivateKey) Bytes() SecretBytes {
+	b := sk.scalar.Bytes()
+	return b[:]
+}
+
+// withBigInt calls f with the scalar as a big.Int, which is what gnark's
+// scalar multiplication takes, and wipes that copy once f returns.
+func (sk *PrivateKey) withBigInt(f func(s *big.Int)) {
+	s := sk.scalar.BigInt(new(big.Int))
+	defer zeroBigInt(s)
+	f(s)
+}
+
+// Sign signs msg, hashing it to G1 under DST.
+func (kp *KeyPair) Sign(msg []byte) (*Signature, error) {
+	return kp.signWithDST(msg, DST)
+}
+
+func (kp *KeyPair) signWithDST(msg []byte, dst string) (*Signature, error) {
+	return activeBackend().Sign(kp, msg, dst)
+}
+
+func gnarkSign(kp *KeyPair, msg []byte, dst string) (*Signature, error) {
+	h, err := bls12381.HashToG1(msg, []byte(dst))
+	if err != nil {
+		return nil, fmt.Errorf("bls: hash to curve: %w", err)
+	}
+	var sig Signature
+	kp.PrivateKey.withBigInt(func(s *big.Int) {
+		sig.point.ScalarMultiplication(&h, s)
+	})
+	return &sig, nil
+}
+
+// SignPoint signs a message that has already been hashed to G1, given as a
+// compressed or uncompressed point.
+func (kp *KeyPair) SignPoint(b []byte) (*Signature, error) {
+	var h bls12381.G1Affine
+	if err := decodePoint(b, &h, bls12381.SizeOfG1AffineCompressed, bls12381.SizeOfG1AffineUncompressed); err != nil {
+		return nil, err
+	}
+	if !h.IsOnCurve() || h.IsInfinity() {
+		return nil, ErrInvalidPoint
+	}
+	if !h.IsInSubGroup() {
+		return nil, ErrNotInSubgroup
+	}
+	var sig Signature
+	kp.PrivateKey.withBigInt(func(s *big.Int) {
+		sig.point.ScalarMultiplication(&h, s)
+	})
+	return &sig, nil
+}
+
+// Verify checks sig over msg against pk, i.e. e(sig, g2) == e(H(msg), pk).
+// It is VerifyE without the reason.
+func Verify(pk *G2PubKey, msg []byte, sig *Signature) bool {
+	return VerifyE(pk, msg, sig) == nil
+}
+
+// VerifyE is Verify reporting why verification failed: ErrInvalidPoint
+// for a missing, off-curve or identity public key or an off-curve
+// signature, ErrNotInSubgroup for a point outside its subgroup, and
+// ErrSignatureMismatch when the pairing check fails.
+func VerifyE(pk *G2PubKey, msg []byte, sig *Signature) error {
+	return verifyWithDST(pk, msg, sig, DST)
+}
+
+func verifyWithDST(pk *G2PubKey, msg []byte, sig *Signature, dst string) error {
+	return activeBackend().Verify(pk, msg, sig, dst)
+}
+
+func gnarkVerify(pk *G2PubKey, msg []byte, sig *Signature, dst string) error {
+	if pk == nil || sig == nil {
+		return fmt.Errorf("%w: nil public key or signature", ErrInvalidPoint)
+	}
+	// The identity key would accept the identity signature on any message.
+	if pk.point.IsInfinity() {
+		return fmt.Errorf("%w: public key is the identity", ErrInvalidPoint)
+	}
+	if !pk.point.IsOnCurve() {
+		return fmt.Errorf("%w: public key is not on the curve", ErrInvalidPoint)
+	}
+	if !pk.point.IsInSubGroup() {
+		return fmt.Errorf("%w: public key", ErrNotInSubgroup)
+	}
+	if !sig.point.IsOnCurve() {
+		return fmt.Errorf("%w: signature is not on the curve", ErrInvalidPoint)
+	}
+	if !sig.point.IsInSubGroup() {
+		return fmt.Errorf("%w: signature", ErrNotInSubgroup)
+	}
+
+	h, err := bls12381.HashToG1(msg, []byte(dst))
+	if err != nil {
+		return fmt.Errorf("bls: hash to curve: %w", err)
+	}
+	_, _, _, g2 := bls12381.Generators()
+	var negG2 bls12381.G2Affine
+	negG2.Neg(&g2)
+
+	ok, err := bls12381.PairingCheck(
+		[]bls12381.G1Affine{sig.point, h},
+		[]bls12381.G2Affine{negG2, pk.point},
+	)
+	if err != nil {
+		return fmt.Errorf("bls: pairing check: %w", err)
+	}
+	if !ok {
+		return ErrSignatureMismatch
+	}
+	return nil
+}
+
+// PubKeysMatch reports whether g1 and g2 are the public keys of the same
+// private key, i.e. e(g1, G2) == e(G1, g2).
+func PubKeysMatch(g1 *G1PubKey, g2 *G2PubKey) bool {
+	_, _, genG1, genG2 := bls12381.Generators()
+	var negG1 bls12381.G1Affine
+	negG1.Neg(&genG1)
+
+	ok, err := bls12381.PairingCheck(
+		[]bls12381.G1Affine{g1.point, negG1},
+		[]bls12381.G2Affine{genG2, g2.point},
+	)
+	return err == nil && ok
+}
+
+// Bytes returns the 48-byte compressed encoding of the public key.
+func (pk *G1PubKey) Bytes() []byte {
+	b := pk.point.Bytes()
+	return b[:]
+}
+
+// Bytes returns the 96-byte compressed encoding of the public key.
+func (pk *G2PubKey) Bytes() []byte {
+	b := pk.point.Bytes()
+	return b[:]
+}
+
+// Bytes returns the 48-byte compressed encoding of the signature.
+func (sig *Signature) Bytes() []byte {
+	b := sig.point.Bytes()
+	return b[:]
+}
+
+// G1PubKeyFromBytes decodes a compressed or uncompressed G1 public key.
+func G1PubKeyFromBytes(b []byte) (*G1PubKey, error) {
+	var pk G1PubKey
+	if err := decodePoint(b, &pk.point, bls12381.SizeOfG1AffineCompressed, bls12381.SizeOfG1AffineUncompressed); err != nil {
+		return nil, err
+	}
+	if !pk.point.IsOnCurve() || pk.point.IsInfinity() {
+		return nil, ErrInvalidPoint
+	}
+	if !pk.point.IsInSubGroup() {
+		return nil, ErrNotInSubgroup
+	}
+	return &pk, nil
+}
+
+// G2PubKeyFromBytes decodes a compressed or uncompressed G2 public key.
+func G2PubKeyFromBytes(b []byte) (*G2PubKey, error) {
+	var pk G2PubKey
+	if err := decodePoint(b, &pk.point, bls12381.SizeOfG2AffineCompressed, bls12381.SizeOfG2AffineUncompressed); err != nil {
+		return nil, err
+	}
+	if !pk.point.IsOnCurve() || pk.point.IsInfinity() {
+		return nil, ErrInvalidPoint
+	}
+	if !pk.point.IsInSubGroup() {
+		return nil, ErrNotInSubgroup
+	}
+	return &pk, nil
+}
+
+// SignatureFromBytes decodes a compressed or uncompressed G1 signature.
+func SignatureFromBytes(b []byte) (*Signature, error) {
+	var sig Signature
+	if err := decodePoint(b, &sig.point, bls12381.SizeOfG1AffineCompressed, bls12381.SizeOfG1AffineUncompressed); err != nil {
+		return nil, err
+	}
+	if !sig.point.IsOnCurve() {
+		return nil, ErrInvalidPoint
+	}
+	if !sig.point.IsInSubGroup() {
+		return nil, ErrNotInSubgroup
+	}
+	return &sig, nil
+}
+
+// decodePoint decodes b into p without subgroup checks, so callers can report
+// off-curve and wrong-subgroup points separately.
+func decodePoint(b []byte, p interface{}, compressed, uncompressed int) error {
+	if len(b) != compressed && len(b) != uncompressed {
+		return fmt.Errorf("%w: unexpected length %d", ErrInvalidPoint, len(b))
+	}
+	dec := bls12381.NewDecoder(bytes.NewReader(b), bls12381.NoSubgroupChecks())
+	if err := dec.Decode(p); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPoint, err)
+	}
+	if int(dec.BytesRead()) != len(b) {
+		return fmt.Errorf("%w: trailing bytes", ErrInvalidPoint)
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package bls
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
+)
+
+func TestSignVerify(t *testing.T) {
+	kp, err := GenerateKeyPair(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := []byte("bastion task response")
+	sig, err := kp.Sign(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Verify(kp.G2PubKey, msg, sig) {
+		t.Fatal("valid signature did not verify")
+	}
+	if Verify(kp.G2PubKey, []byte("other message"), sig) {
+		t.Fatal("signature verified for a different message")
+	}
+
+	other, err := GenerateKeyPair(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if Verify(other.G2PubKey, msg, sig) {
+		t.Fatal("signature verified against a different key")
+	}
+}
+
+func TestPubKeysMatch(t *testing.T) {
+	kp, err := GenerateKeyPair(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !PubKeysMatch(kp.G1PubKey, kp.G2PubKey) {
+		t.Fatal("G1 and G2 public keys of the same key do not match")
+	}
+
+	other, err := GenerateKeyPair(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if PubKeysMatch(kp.G1PubKey, other.G2PubKey) {
+		t.Fatal("public keys of different keys matched")
+	}
+}
+
+func TestEncodingRoundTrip(t *testing.T) {
+	kp, err := GenerateKeyPair(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sk, err := PrivateKeyFromBytes(kp.PrivateKey.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(NewKeyPair(sk).G1PubKey.Bytes(), kp.G1PubKey.Bytes()) {
+		t.Fatal("private key did not round-trip")
+	}
+
+	g1, err := G1PubKeyFromBytes(kp.G1PubKey.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kp.G1PubKey.Bytes()) != 48 || !bytes.Equal(g1.Bytes(), kp.G1PubKey.Bytes()) {
+		t.Fatal("G1 public key did not round-trip")
+	}
+
+	g2, err := G2PubKeyFromBytes(kp.G2PubKey.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kp.G2PubKey.Bytes()) != 96 || !bytes.Equal(g2.Bytes(), kp.G2PubKey.Bytes()) {
+		t.Fatal("G2 public key did not round-trip")
+	}
+
+	sig, err := kp.Sign([]byte("msg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := SignatureFromBytes(sig.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Verify(g2, []byte("msg"), parsed) {
+		t.Fatal("decoded signature did not verify")
+	}
+}
+
+func TestPrivateKeyFromBytesRejectsOutOfRange(t *testing.T) {
+	if _, err := PrivateKeyFromBytes(make([]byte, PrivateKeySize)); !errors.Is(err, ErrInvalidPrivateKey) {
+		t.Fatalf("zero scalar: got %v, want ErrInvalidPrivateKey", err)
+	}
+
+	var order [PrivateKeySize]byte
+	fr.Modulus().FillBytes(order[:])
+	if _, err := PrivateKeyFromBytes(order[:]); !errors.Is(err, ErrInvalidPrivateKey) {
+		t.Fatalf("scalar equal to group order: got %v, want ErrInvalidPrivateKey", err)
+	}
+}
+
+func TestPrivateKeyFromBytesReduced(t *testing.T) {
+	var order [PrivateKeySize]byte
+	fr.Modulus().FillBytes(order[:])
+	if _, err := PrivateKeyFromBytesReduced(order[:]); !errors.Is(err, ErrInvalidPrivateKey) {
+		t.Fatalf("scalar equal to group order: got %v, want ErrInvalidPrivateKey", err)
+	}
+
+	order[PrivateKeySize-1]++
+	sk, err := PrivateKeyFromBytesReduced(order[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := sk.Bytes(); b[PrivateKeySize-1] != 1 || !bytes.Equal(b[:PrivateKeySize-1], make([]byte, PrivateKeySize-1)) {
+		t.Fatalf("order+1 reduced to %x, want 1", []byte(b))
+	}
+}
+
+func TestVerifyEFailureModes(t *testing.T) {
+	kp, err := GenerateKeyPair(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := []byte("bastion task response")
+	sig, err := kp.Sign(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyE(kp.G2PubKey, msg, sig); err != nil {
+		t.Fatalf("valid signature: %v", err)
+	}
+
+	// On-curve points outside the prime-order subgroups.
+	var badG1 Signature
+	if err := decodePoint(mustHex(t, "800000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000004"), &badG1.point, bls12381.SizeOfG1AffineCompressed, bls12381.SizeOfG1AffineUncompressed); err != nil {
+		t.Fatal(err)
+	}
+	var badG2 G2PubKey
+	if err := decodePoint(mustHex(t, "800000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000002"), &badG2.point, bls12381.SizeOfG2AffineCompressed, bls12381.SizeOfG2AffineUncompressed); err != nil {
+		t.Fatal(err)
+	}
+	var offCurveSig Signature
+	offCurveSig.point.X.SetOne()
+	offCurveSig.point.Y.SetOne()
+	var offCurvePK G2PubKey
+	offCurvePK.point.X.A0.SetOne()
+	offCurvePK.point.Y.A0.SetOne()
+
+	tests := []struct {
+		name string
+		pk   *G2PubKey
+		msg  []byte
+		sig  *Signature
+		want error
+	}{
+		{"nil public key", nil, msg, sig, ErrInvalidPoint},
+		{"nil signature", kp.G2PubKey, msg, nil, ErrInvalidPoint},
+		{"identity key and signature", &G2PubKey{}, msg, &Signature{}, ErrInvalidPoint},
+		{"off-curve public key", &offCurvePK, msg, sig, ErrInvalidPoint},
+		{"off-curve signature", kp.G2PubKey, msg, &offCurveSig, ErrInvalidPoint},
+		{"public key outside subgroup", &badG2, msg, sig, ErrNotInSubgroup},
+		{"signature outside subgroup", kp.G2PubKey, msg, &badG1, ErrNotInSubgroup},
+		{"wrong message", kp.G2PubKey, []byte("other"), sig, ErrSignatureMismatch},
+	}
+	for _, tt := range tests {
+		err := VerifyE(tt.pk, tt.msg, tt.sig)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.want)
+		}
+		if Verify(tt.pk, tt.msg, tt.sig) {
+			t.Errorf("%s: Verify returned true", tt.name)
+		}
+	}
+}
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
@@ -0,0 +1,118 @@
+package bls
+
+import (
+	"fmt"
+	"math/big"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/consensys/gnark-crypto/ecc/bls12-381/fp"
+)
+
+// Coordinates returns the affine coordinates of the public key.
+func (pk *G1PubKey) Coordinates() (x, y *big.Int) {
+	return pk.point.X.BigInt(new(big.Int)), pk.point.Y.BigInt(new(big.Int))
+}
+
+// Coordinates returns the affine coordinates of the public key, where
+// x = x0 + x1*u and y = y0 + y1*u.
+func (pk *G2PubKey) Coordinates() (x0, x1, y0, y1 *big.Int) {
+	p := &pk.point
+	return p.X.A0.BigInt(new(big.Int)), p.X.A1.BigInt(new(big.Int)),
+		p.Y.A0.BigInt(new(big.Int)), p.Y.A1.BigInt(new(big.Int))
+}
+
+// Coordinates returns the affine coordinates of the signature.
+func (sig *Signature) Coordinates() (x, y *big.Int) {
+	return sig.point.X.BigInt(new(big.Int)), sig.point.Y.BigInt(new(big.Int))
+}
+
+// G2PubKeyFromCoordinates builds a public key from its affine
+// coordinates, as returned by Coordinates. Each must be a reduced field
+// element, and the point must be on the curve, in the subgroup and not the
+// identity.
+func G2PubKeyFromCoordinates(x0, x1, y0, y1 *big.Int) (*G2PubKey, error) {
+	var pk G2PubKey
+	p := &pk.point
+	for _, c := range []struct {
+		e *fp.Element
+		v *big.Int
+	}{{&p.X.A0, x0}, {&p.X.A1, x1}, {&p.Y.A0, y0}, {&p.Y.A1, y1}} {
+		if err := setFieldElement(c.e, c.v); err != nil {
+			return nil, err
+		}
+	}
+	if !p.IsOnCurve() || p.IsInfinity() {
+		return nil, fmt.Errorf("%w: not on the curve", ErrInvalidPoint)
+	}
+	if !p.IsInSubGroup() {
+		return nil, ErrNotInSubgroup
+	}
+	return &pk, nil
+}
+
+// SignatureFromCoordinates builds a signature from its affine coordinates,
+// as returned by Coordinates, with the checks of SignatureFromBytes.
+func SignatureFromCoordinates(x, y *big.Int) (*Signature, error) {
+	var sig Signature
+	if err := setFieldElement(&sig.point.X, x); err != nil {
+		return nil, err
+	}
+	if err := setFieldElement(&sig.point.Y, y); err != nil {
+		return nil, err
+	}
+	if !sig.point.IsOnCurve() {
+		return nil, fmt.Errorf("%w: not on the curve", ErrInvalidPoint)
+	}
+	if !sig.point.IsInSubGroup() {
+		return nil, ErrNotInSubgroup
+	}
+	return &sig, nil
+}
+
+// setFieldElement sets e to v, which must be in [0, p).
+func setFieldElement(e *fp.Element, v *big.Int) error {
+	if v.Sign() < 0 || v.Cmp(fp.Modulus()) >= 0 {
+		return fmt.Errorf("%w: coordinate is not a field element", ErrInvalidPoint)
+	}
+	e.SetBigInt(v)
+	return nil
+}
+
+// EVMWords returns the public key's coordinates as EIP-2537 field elements:
+// 64-byte big-endian words, each a 48-byte coordinate left-padded with
+// zeros, in the order a BLS12-381 registry's G1Point struct declares them.
+// The encoding is fixed-width and independent of the host's byte order.
+// BLS12-381 coordinates are 381-bit, so there is no uint256 (BN254-style)
+// form of them.
+func (pk *G1PubKey) EVMWords() (x, y [evmWordSize]byte) {
+	return g1Words(&pk.point)
+}
+
+// EVMWords returns the public key's coordinates as EIP-2537 field elements,
+// x = x0 + x1*u and y = y0 + y1*u, in EIP-2537 order: the real part c0
+// before c1. Note this is the reverse of BN254's G2Point, which stores the
+// imaginary part first.
+func (pk *G2PubKey) EVMWords() (x, y [2][evmWordSize]byte) {
+	p := &pk.point
+	x[0], x[1] = fpWord(&p.X.A0), fpWord(&p.X.A1)
+	y[0], y[1] = fpWord(&p.Y.A0), fpWord(&p.Y.A1)
+	return x, y
+}
+
+// EVMWords returns the signature's coordinates as EIP-2537 field elements,
+// like G1PubKey.EVMWords.
+func (sig *Signature) EVMWords() (x, y [evmWordSize]byte) {
+	return g1Words(&sig.point)
+}
+
+func g1Words(p *bls12381.G1Affine) (x, y [evmWordSize]byte) {
+	return fpWord(&p.X), fpWord(&p.Y)
+}
+
+// fpWord left-pads the big-endian encoding of e to an EIP-2537 word.
+func fpWord(e *fp.Element) [evmWordSize]byte {
+	var w [evmWordSize]byte
+	b := e.Bytes()
+	copy(w[evmWordSize-fp.Bytes:], b[:])
+	return w
+}
@@ -0,0 +1,104 @@
+package bls
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bls12-381/fp"
+)
+
+// The BLS12-381 generators, from the curve specification
+// (draft-irtf-cfrg-pairing-friendly-curves, section 4.2.1).
+const (
+	g1GenX  = "17f1d3a73197d7942695638c4fa9ac0fc3688c4f9774b905a14e3a3f171bac586c55e83ff97a1aeffb3af00adb22c6bb"
+	g1GenY  = "08b3f481e3aaa0f1a09e30ed741d8ae4fcf5e095d5d00af600db18cb2c04b3edd03cc744a2888ae40caa232946c5e7e1"
+	g2GenX0 = "024aa2b2f08f0a91260805272dc51051c6e47ad4fa403b02b4510b647ae3d1770bac0326a805bbefd48056c8c121bdb8"
+	g2GenX1 = "13e02b6052719f607dacd3a088274f65596bd0d09920b61ab5da61bbdc7f5049334cf11213945d57e5ac7d055d042b7e"
+	g2GenY0 = "0ce5d527727d6e118cc9cdc6da2e351aadfd9baa8cbdd3a76d429a695160d12c923ac9cc3baca289e193548608b82801"
+	g2GenY1 = "0606c4a02ea734cc32acd2b02bc28b99cb3e287e85a763af267492ab572e99ab3f370d275cec1da1aaa9075ff05f79be"
+	wordPad = "00000000000000000000000000000000"
+)
+
+func TestEVMWords(t *testing.T) {
+	// sk = 1, so both public keys are the generators.
+	one := make([]byte, PrivateKeySize)
+	one[PrivateKeySize-1] = 1
+	sk, err := PrivateKeyFromBytes(one)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kp := NewKeyPair(sk)
+
+	check := func(name string, got [evmWordSize]byte, want string) {
+		t.Helper()
+		if g := hex.EncodeToString(got[:]); g != wordPad+want {
+			t.Errorf("%s = %s, want %s", name, g, wordPad+want)
+		}
+	}
+	x, y := kp.G1PubKey.EVMWords()
+	check("G1 x", x, g1GenX)
+	check("G1 y", y, g1GenY)
+
+	x2, y2 := kp.G2PubKey.EVMWords()
+	check("G2 x.c0", x2[0], g2GenX0)
+	check("G2 x.c1", x2[1], g2GenX1)
+	check("G2 y.c0", y2[0], g2GenY0)
+	check("G2 y.c1", y2[1], g2GenY1)
+}
+
+func TestSignatureEVMWordsMatchCoordinates(t *testing.T) {
+	kp, err := GenerateKeyPair(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := kp.Sign([]byte("words"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, y := sig.Coordinates()
+	wx, wy := sig.EVMWords()
+	if new(big.Int).SetBytes(wx[:]).Cmp(x) != 0 || new(big.Int).SetBytes(wy[:]).Cmp(y) != 0 {
+		t.Fatal("signature words do not encode its coordinates")
+	}
+}
+
+func TestPointsFromCoordinates(t *testing.T) {
+	kp, err := GenerateKeyPair(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := kp.Sign([]byte("coordinates"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pk, err := G2PubKeyFromCoordinates(kp.G2PubKey.Coordinates())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pk.point.Equal(&kp.G2PubKey.point) {
+		t.Fatal("G2 key rebuilt from its coordinates differs")
+	}
+	got, err := SignatureFromCoordinates(sig.Coordinates())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.point.Equal(&sig.point) {
+		t.Fatal("signature rebuilt from its coordinates differs")
+	}
+
+	x, y := sig.Coordinates()
+	if _, err := SignatureFromCoordinates(x, new(big.Int).Add(y, big.NewInt(1))); !errors.Is(err, ErrInvalidPoint) {
+		t.Errorf("off-curve signature: got %v, want ErrInvalidPoint", err)
+	}
+	if _, err := SignatureFromCoordinates(x, new(big.Int).Add(y, fp.Modulus())); !errors.Is(err, ErrInvalidPoint) {
+		t.Errorf("unreduced coordinate: got %v, want ErrInvalidPoint", err)
+	}
+	zero := new(big.Int)
+	if _, err := G2PubKeyFromCoordinates(zero, zero, zero, zero); !errors.Is(err, ErrInvalidPoint) {
+		t.Errorf("identity key: got %v, want ErrInvalidPoint", err)
+	}
+}
@@ -0,0 +1,71 @@
+package bls
+
+import (
+	"errors"
+	"fmt"
+)
+
+// maxDSTLen is the longest tag expand_message_xmd accepts.
+const maxDSTLen = 255
+
+// Domain holds the domain separation tags of one deployment: Sig for
+// signatures over messages and PoP for proofs of possession. Signatures made
+// under one Domain never verify under another.
+type Domain struct {
+	Sig string
+	PoP string
+}
+
+// DefaultDomain is DST and PopDST, the tags used by Sign, Verify,
+// ProofOfPossession and VerifyPoP. Mainnet deployments use it.
+var DefaultDomain = Domain{Sig: DST, PoP: PopDST}
+
+// Networks are the preset domains of the chains Bastion is deployed on.
+// Testnets prefix the ciphersuite tags with an application tag so their
+// signatures cannot be replayed on mainnet, or on each other.
+var Networks = map[string]Domain{
+	"mainnet": DefaultDomain,
+	"holesky": {Sig: "BASTION-HOLESKY-V01-CS01-with-" + DST, PoP: "BASTION-HOLESKY-V01-CS01-with-" + PopDST},
+	"sepolia": {Sig: "BASTION-SEPOLIA-V01-CS01-with-" + DST, PoP: "BASTION-SEPOLIA-V01-CS01-with-" + PopDST},
+}
+
+// CustomDomain returns the Domain of a deployment with its own signature
+// tag. Its PoP tag is dst with "_POP" appended, keeping the two distinct.
+func CustomDomain(dst []byte) (Domain, error) {
+	d := Domain{Sig: string(dst), PoP: string(dst) + "_POP"}
+	return d, d.Validate()
+}
+
+// Validate checks that both tags are usable and distinct.
+func (d Domain) Validate() error {
+	if d.Sig == "" || d.PoP == "" {
+		return errors.New("bls: empty domain separation tag")
+	}
+	if len(d.Sig) > maxDSTLen || len(d.PoP) > maxDSTLen {
+		return fmt.Errorf("bls: domain separation tag longer than %d bytes", maxDSTLen)
+	}
+	if d.Sig == d.PoP {
+		return errors.New("bls: signature and PoP domain separation tags must differ")
+	}
+	return nil
+}
+
+// SignDomain signs msg, hashing it to G1 under d.Sig.
+func (kp *KeyPair) SignDomain(msg []byte, d Domain) (*Signature, error) {
+	return kp.signWithDST(msg, d.Sig)
+}
+
+// VerifyDomain checks sig over msg against pk under d.Sig.
+func VerifyDomain(pk *G2PubKey, msg []byte, sig *Signature, d Domain) bool {
+	return verifyWithDST(pk, msg, sig, d.Sig) == nil
+}
+
+// ProofOfPossessionDomain is ProofOfPossession under d.PoP.
+func ProofOfPossessionDomain(kp *KeyPair, d Domain) (*Signature, error) {
+	return kp.signWithDST(kp.G2PubKey.Bytes(), d.PoP)
+}
+
+// VerifyPoPDomain is VerifyPoP under d.PoP.
+func VerifyPoPDomain(pk *G2PubKey, sig *Signature, d Domain) bool {
+	return verifyWithDST(pk, pk.Bytes(), sig, d.PoP) == nil
+}
@@ -0,0 +1,65 @@
+package bls
+
+import (
+	"crypto/rand"
+	"testing"
+)
+
+func TestNetworkDomainsDoNotCrossVerify(t *testing.T) {
+	kp, err := GenerateKeyPair(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	custom, err := CustomDomain([]byte("MY-AVS-V01-with-" + DST))
+	if err != nil {
+		t.Fatal(err)
+	}
+	domains := map[string]Domain{"custom": custom}
+	for name, d := range Networks {
+		if err := d.Validate(); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		domains[name] = d
+	}
+
+	msg := []byte("task response")
+	for signedUnder, d := range domains {
+		sig, err := kp.SignDomain(msg, d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pop, err := ProofOfPossessionDomain(kp, d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for verifiedUnder, v := range domains {
+			same := signedUnder == verifiedUnder
+			if got := VerifyDomain(kp.G2PubKey, msg, sig, v); got != same {
+				t.Errorf("signature from %s verified under %s: %v", signedUnder, verifiedUnder, got)
+			}
+			if got := VerifyPoPDomain(kp.G2PubKey, pop, v); got != same {
+				t.Errorf("PoP from %s verified under %s: %v", signedUnder, verifiedUnder, got)
+			}
+		}
+	}
+
+	sig, err := kp.SignDomain(msg, Networks["mainnet"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Verify(kp.G2PubKey, msg, sig) {
+		t.Fatal("mainnet signature rejected by Verify")
+	}
+}
+
+func TestDomainValidate(t *testing.T) {
+	if _, err := CustomDomain(nil); err == nil {
+		t.Error("empty custom DST accepted")
+	}
+	if _, err := CustomDomain(make([]byte, 254)); err == nil {
+		t.Error("custom DST whose PoP tag exceeds 255 bytes accepted")
+	}
+	if err := (Domain{Sig: DST, PoP: DST}).Validate(); err == nil {
+		t.Error("identical signature and PoP tags accepted")
+	}
+}
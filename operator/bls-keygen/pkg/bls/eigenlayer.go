@@ -0,0 +1,120 @@
+package bls
+
+import (
+	"fmt"
+	"math/big"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
+	"golang.org/x/crypto/sha3"
+)
+
+// EIP-712 domain of the EigenLayer RegistryCoordinator, which is what
+// BLSApkRegistry registrations are signed under.
+const (
+	registryCoordinatorName    = "AVSRegistryCoordinator"
+	registryCoordinatorVersion = "v0.0.1"
+)
+
+var (
+	eip712DomainTypehash           = keccak256([]byte("EIP712Domain(string name,string version,uint256 chainId,address verifyingContract)"))
+	pubkeyRegistrationTypehash     = keccak256([]byte("BN254PubkeyRegistration(address operator)"))
+	registryCoordinatorNameHash    = keccak256([]byte(registryCoordinatorName))
+	registryCoordinatorVersionHash = keccak256([]byte(registryCoordinatorVersion))
+)
+
+// PubkeyRegistrationHash returns the EIP-712 digest that an operator signs
+// with its BLS key to register it, matching the RegistryCoordinator's
+// pubkeyRegistrationMessageHash before it is mapped to the curve. The
+// contract maps it with BN254.hashToG1; signing it with KeyPair.Sign maps it
+// to BLS12-381 instead, which the deployed BN254 contracts cannot verify.
+func PubkeyRegistrationHash(operator [20]byte, chainID *big.Int, registryCoordinator [20]byte) [32]byte {
+	domainSeparator := keccak256(
+		eip712DomainTypehash,
+		registryCoordinatorNameHash,
+		registryCoordinatorVersionHash,
+		abiUint256(chainID),
+		abiAddress(registryCoordinator),
+	)
+	structHash := keccak256(pubkeyRegistrationTypehash, abiAddress(operator))
+
+	var digest [32]byte
+	copy(digest[:], keccak256([]byte("\x19\x01"), domainSeparator, structHash))
+	return digest
+}
+
+// VerifyWithG1 checks sig over msg against both public keys at once, the
+// way EigenLayer's BLSSignatureChecker does:
+//
+//	e(sig + γ·g1, −G2) · e(H(msg) + γ·G1, g2) == 1
+//
+// with γ = keccak256(H(msg), g1, g2, sig) mod r. It passes only if sig is
+// valid for g2 and g1 belongs to the same key, so it also proves the G1
+// key a registry stores. It always runs on gnark-crypto, whatever backend
+// is in use, and fails with ErrSignatureMismatch like VerifyE.
+func VerifyWithG1(g1 *G1PubKey, g2 *G2PubKey, msg []byte, sig *Signature) error {
+	if g1 == nil || g2 == nil || sig == nil {
+		return fmt.Errorf("%w: nil public key or signature", ErrInvalidPoint)
+	}
+	if g1.point.IsInfinity() || g2.point.IsInfinity() {
+		return fmt.Errorf("%w: public key is the identity", ErrInvalidPoint)
+	}
+	if !g1.point.IsOnCurve() || !g2.point.IsOnCurve() || !sig.point.IsOnCurve() {
+		return fmt.Errorf("%w: point is not on the curve", ErrInvalidPoint)
+	}
+	if !g1.point.IsInSubGroup() || !g2.point.IsInSubGroup() || !sig.point.IsInSubGroup() {
+		return ErrNotInSubgroup
+	}
+	h, err := bls12381.HashToG1(msg, []byte(DST))
+	if err != nil {
+		return fmt.Errorf("bls: hash to curve: %w", err)
+	}
+	hb := h.Bytes()
+	var gamma fr.Element
+	gamma.SetBytes(keccak256(hb[:], g1.Bytes(), g2.Bytes(), sig.Bytes()))
+	var gammaInt big.Int
+	gamma.BigInt(&gammaInt)
+
+	_, _, genG1, genG2 := bls12381.Generators()
+	var negG2 bls12381.G2Affine
+	negG2.Neg(&genG2)
+	var left, right, t bls12381.G1Affine
+	t.ScalarMultiplication(&g1.point, &gammaInt)
+	left.Add(&sig.point, &t)
+	t.ScalarMultiplication(&genG1, &gammaInt)
+	right.Add(&h, &t)
+
+	ok, err := bls12381.PairingCheck(
+		[]bls12381.G1Affine{left, right},
+		[]bls12381.G2Affine{negG2, g2.point},
+	)
+	if err != nil {
+		return fmt.Errorf("bls: pairing check: %w", err)
+	}
+	if !ok {
+		return ErrSignatureMismatch
+	}
+	return nil
+}
+
+func keccak256(data ...[]byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	for _, d := range data {
+		h.Write(d)
+	}
+	return h.Sum(nil)
+}
+
+// abiUint256 is the 32-byte big-endian ABI encoding of v.
+func abiUint256(v *big.Int) []byte {
+	b := make([]byte, 32)
+	v.FillBytes(b)
+	return b
+}
+
+// abiAddress is the left-padded 32-byte ABI encoding of an address.
+func abiAddress(a [20]byte) []byte {
+	b := make([]byte, 32)
+	copy(b[12:], a[:])
+	return b
+}
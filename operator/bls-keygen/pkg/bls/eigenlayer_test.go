@@ -0,0 +1,71 @@
+package bls
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestEIP712DomainTypehash(t *testing.T) {
+	const want = "8b73c3c69bb8fe3d512ecc4cf759cc79239f7b179b0ffacaa9a75d522b39400f"
+	if got := hex.EncodeToString(eip712DomainTypehash); got != want {
+		t.Fatalf("EIP712Domain typehash = %s, want %s", got, want)
+	}
+}
+
+func TestPubkeyRegistrationTypehash(t *testing.T) {
+	// PUBKEY_REGISTRATION_TYPEHASH as published in eigenlayer-middleware's
+	// RegistryCoordinator.
+	const want = "2bd82124057f0913bc3b772ce7b83e8057c1ad1f3510fc83778be20f10ec5de6"
+	if got := hex.EncodeToString(pubkeyRegistrationTypehash); got != want {
+		t.Fatalf("BN254PubkeyRegistration typehash = %s, want %s", got, want)
+	}
+}
+
+func TestPubkeyRegistrationHash(t *testing.T) {
+	var operator, other, coordinator [20]byte
+	operator[19] = 1
+	other[19] = 2
+	coordinator[0] = 0xaa
+
+	h := PubkeyRegistrationHash(operator, big.NewInt(17000), coordinator)
+	if h != PubkeyRegistrationHash(operator, big.NewInt(17000), coordinator) {
+		t.Fatal("registration hash is not deterministic")
+	}
+	if h == PubkeyRegistrationHash(other, big.NewInt(17000), coordinator) {
+		t.Fatal("registration hash does not depend on the operator")
+	}
+	if h == PubkeyRegistrationHash(operator, big.NewInt(1), coordinator) {
+		t.Fatal("registration hash does not depend on the chain id")
+	}
+}
+
+func TestVerifyWithG1(t *testing.T) {
+	kp, err := GenerateKeyPair(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := GenerateKeyPair(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := []byte("challenge")
+	sig, err := kp.Sign(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyWithG1(kp.G1PubKey, kp.G2PubKey, msg, sig); err != nil {
+		t.Fatal(err)
+	}
+	for name, err := range map[string]error{
+		"other message": VerifyWithG1(kp.G1PubKey, kp.G2PubKey, []byte("other"), sig),
+		"other G1 key":  VerifyWithG1(other.G1PubKey, kp.G2PubKey, msg, sig),
+		"other G2 key":  VerifyWithG1(kp.G1PubKey, other.G2PubKey, msg, sig),
+	} {
+		if !errors.Is(err, ErrSignatureMismatch) {
+			t.Errorf("%s: got %v, want ErrSignatureMismatch", name, err)
+		}
+	}
+}
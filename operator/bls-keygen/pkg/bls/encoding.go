@@ -0,0 +1,80 @@
+package bls
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+)
+
+// Signature encoding sizes, in the ZCash serialization gnark uses.
+const (
+	SignatureCompressedSize   = bls12381.SizeOfG1AffineCompressed
+	SignatureUncompressedSize = bls12381.SizeOfG1AffineUncompressed
+)
+
+// CompressedBytes returns the 48-byte compressed encoding of the signature,
+// the same as Bytes.
+func (sig *Signature) CompressedBytes() []byte {
+	return sig.Bytes()
+}
+
+// UncompressedBytes returns the 96-byte encoding of the signature's x and
+// y coordinates.
+func (sig *Signature) UncompressedBytes() []byte {
+	b := sig.point.RawBytes()
+	return b[:]
+}
+
+// ParseSignatureCompressed decodes a 48-byte compressed signature.
+func ParseSignatureCompressed(b []byte) (*Signature, error) {
+	if len(b) != SignatureCompressedSize {
+		return nil, fmt.Errorf("%w: compressed signature must be %d bytes, got %d", ErrInvalidPoint, SignatureCompressedSize, len(b))
+	}
+	return SignatureFromBytes(b)
+}
+
+// ParseSignatureUncompressed decodes a 96-byte uncompressed signature.
+func ParseSignatureUncompressed(b []byte) (*Signature, error) {
+	if len(b) != SignatureUncompressedSize {
+		return nil, fmt.Errorf("%w: uncompressed signature must be %d bytes, got %d", ErrInvalidPoint, SignatureUncompressedSize, len(b))
+	}
+	return SignatureFromBytes(b)
+}
+
+// ZCash serialization flags in the first byte of an encoded point.
+const (
+	flagCompressed = 0x80
+	flagSign       = 0x20
+)
+
+// ParseSignature decodes a hex signature, with or without 0x, in either
+// encoding: 48 bytes with the compression flag set, or 96 bytes without
+// it. The encoding is told apart by length and checked against the flag
+// bits, so a truncated or mislabelled value fails with a clear error rather
+// than decoding as something else. The result is the same Signature either
+// way; Bytes re-encodes it compressed.
+func ParseSignature(s string) (*Signature, error) {
+	b, err := hex.DecodeString(strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X"))
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature is not hex: %v", ErrInvalidPoint, err)
+	}
+	switch len(b) {
+	case SignatureCompressedSize:
+		if b[0]&flagCompressed == 0 {
+			return nil, fmt.Errorf("%w: %d-byte signature lacks the compression flag", ErrInvalidPoint, len(b))
+		}
+	case SignatureUncompressedSize:
+		if b[0]&flagCompressed != 0 {
+			return nil, fmt.Errorf("%w: %d-byte signature has the compression flag set", ErrInvalidPoint, len(b))
+		}
+		if b[0]&flagSign != 0 {
+			return nil, fmt.Errorf("%w: uncompressed signature has the sign flag set", ErrInvalidPoint)
+		}
+	default:
+		return nil, fmt.Errorf("%w: signature must be %d bytes compressed or %d uncompressed, got %d",
+			ErrInvalidPoint, SignatureCompressedSize, SignatureUncompressedSize, len(b))
+	}
+	return SignatureFromBytes(b)
+}
@@ -0,0 +1,114 @@
+package bls
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSignatureEncodings(t *testing.T) {
+	kp, err := GenerateKeyPair(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := kp.Sign([]byte("msg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var infinity Signature
+
+	for name, s := range map[string]*Signature{"signature": sig, "infinity": &infinity} {
+		t.Run(name, func(t *testing.T) {
+			c, u := s.CompressedBytes(), s.UncompressedBytes()
+			if len(c) != SignatureCompressedSize || len(u) != SignatureUncompressedSize {
+				t.Fatalf("lengths %d and %d", len(c), len(u))
+			}
+			fromC, err := ParseSignatureCompressed(c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fromU, err := ParseSignatureUncompressed(u)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, got := range []*Signature{fromC, fromU} {
+				if !bytes.Equal(got.CompressedBytes(), c) || !bytes.Equal(got.UncompressedBytes(), u) {
+					t.Fatal("encoding did not round-trip")
+				}
+			}
+		})
+	}
+
+	if !infinity.point.IsInfinity() {
+		t.Fatal("zero Signature is not the point at infinity")
+	}
+	if s, _ := ParseSignatureCompressed(infinity.CompressedBytes()); !s.point.IsInfinity() {
+		t.Fatal("infinity did not decode to infinity")
+	}
+}
+
+func TestParseSignatureWrongEncoding(t *testing.T) {
+	kp, err := GenerateKeyPair(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := kp.Sign([]byte("msg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseSignatureCompressed(sig.UncompressedBytes()); !errors.Is(err, ErrInvalidPoint) {
+		t.Fatalf("uncompressed as compressed: got %v, want ErrInvalidPoint", err)
+	}
+	if _, err := ParseSignatureUncompressed(sig.CompressedBytes()); !errors.Is(err, ErrInvalidPoint) {
+		t.Fatalf("compressed as uncompressed: got %v, want ErrInvalidPoint", err)
+	}
+}
+
+func TestParseSignature(t *testing.T) {
+	kp, err := GenerateKeyPair(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := kp.Sign([]byte("msg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, s := range map[string]string{
+		"compressed":   hex.EncodeToString(sig.CompressedBytes()),
+		"uncompressed": "0x" + hex.EncodeToString(sig.UncompressedBytes()),
+	} {
+		got, err := ParseSignature(s)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !bytes.Equal(got.Bytes(), sig.Bytes()) || !Verify(kp.G2PubKey, []byte("msg"), got) {
+			t.Fatalf("%s: parsed signature differs from the original", name)
+		}
+	}
+
+	// The compression flag must agree with the length.
+	compressed := sig.CompressedBytes()
+	compressed[0] &^= flagCompressed
+	uncompressed := sig.UncompressedBytes()
+	uncompressed[0] |= flagCompressed
+	for name, s := range map[string]string{
+		"short":                       "0x" + hex.EncodeToString(sig.Bytes()[:47]),
+		"G2 length":                   hex.EncodeToString(kp.G2PubKey.Bytes()),
+		"empty":                       "",
+		"not hex":                     "0xzz",
+		"48 bytes without the flag":   hex.EncodeToString(compressed),
+		"96 bytes with the flag":      hex.EncodeToString(uncompressed),
+		"uncompressed with sign flag": hex.EncodeToString(append([]byte{sig.UncompressedBytes()[0] | flagSign}, sig.UncompressedBytes()[1:]...)),
+	} {
+		if _, err := ParseSignature(s); !errors.Is(err, ErrInvalidPoint) {
+			t.Errorf("%s: got %v, want ErrInvalidPoint", name, err)
+		}
+	}
+	if _, err := ParseSignature(hex.EncodeToString(make([]byte, 50))); err == nil || !strings.Contains(err.Error(), "got 50") {
+		t.Fatalf("malformed length: got %v, want an error naming the length", err)
+	}
+}
@@ -0,0 +1,30 @@
+package bls
+
+import (
+	"crypto/sha256"
+	"encoding/base32"
+	"strings"
+)
+
+// fingerprintChars is how much of the base32 digest a fingerprint keeps:
+// 80 bits, enough to tell an operator's keys apart at a glance.
+const fingerprintChars = 16
+
+// Fingerprint returns a short identifier for pk, for telling key files apart
+// by eye: the first 16 base32 characters of sha256 over the compressed key,
+// in groups of four, e.g. "K7QF-2M4D-XJ3A-9ZRB". It depends only on the
+// public key, so it is stable across re-encryptions. It is not an operator
+// ID and is too short to be collision resistant against an attacker.
+func Fingerprint(pk *G1PubKey) string {
+	sum := sha256.Sum256(pk.Bytes())
+	enc := base32.StdEncoding.EncodeToString(sum[:])[:fingerprintChars]
+
+	var b strings.Builder
+	for i := 0; i < len(enc); i += 4 {
+		if i > 0 {
+			b.WriteByte('-')
+		}
+		b.WriteString(enc[i : i+4])
+	}
+	return b.String()
+}
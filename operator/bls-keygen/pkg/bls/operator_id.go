@@ -0,0 +1,24 @@
+package bls
+
+// evmWordSize is the width EIP-2537 pads a BLS12-381 base field element to.
+const evmWordSize = 64
+
+// OperatorID returns the operator ID of pk: the keccak256 of its 128-byte
+// EIP-2537 encoding, X then Y, each left-padded to 64 bytes. The layout
+// follows BN254.hashG1Point, but the curve does not: EigenLayer's current
+// registries are BN254 and their operator IDs are keccak256 over two 32-byte
+// BN254 coordinates, so an ID computed here never matches one they store.
+// It is only comparable with IDs computed the same way, e.g. by a
+// BLS12-381 registry built on the EIP-2537 precompiles.
+func OperatorID(pk *G1PubKey) [32]byte {
+	return hashG1Point(pk)
+}
+
+// hashG1Point is keccak256 over the EIP-2537 encoding of pk's coordinates.
+// The identity hashes as (0, 0), as it does in BN254.hashG1Point.
+func hashG1Point(pk *G1PubKey) [32]byte {
+	x, y := pk.EVMWords()
+	var h [32]byte
+	copy(h[:], keccak256(x[:], y[:]))
+	return h
+}
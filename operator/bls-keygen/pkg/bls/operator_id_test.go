@@ -0,0 +1,41 @@
+package bls
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/sha3"
+)
+
+func TestOperatorID(t *testing.T) {
+	// sk = 1, so the public key is the G1 generator, whose coordinates are
+	// fixed by the BLS12-381 specification.
+	one := make([]byte, PrivateKeySize)
+	one[PrivateKeySize-1] = 1
+	sk, err := PrivateKeyFromBytes(one)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk := NewKeyPair(sk).G1PubKey
+
+	const (
+		genX = "17f1d3a73197d7942695638c4fa9ac0fc3688c4f9774b905a14e3a3f171bac586c55e83ff97a1aeffb3af00adb22c6bb"
+		genY = "08b3f481e3aaa0f1a09e30ed741d8ae4fcf5e095d5d00af600db18cb2c04b3edd03cc744a2888ae40caa232946c5e7e1"
+	)
+	// EIP-2537 G1 encoding: each 48-byte coordinate left-padded with 16
+	// zero bytes.
+	pad := strings.Repeat("00", 16)
+	encoded, err := hex.DecodeString(pad + genX + pad + genY)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := sha3.NewLegacyKeccak256()
+	h.Write(encoded)
+	want := hex.EncodeToString(h.Sum(nil))
+
+	id := OperatorID(pk)
+	if got := hex.EncodeToString(id[:]); got != want {
+		t.Fatalf("OperatorID = %s, want keccak256 of the EIP-2537 encoding %s", got, want)
+	}
+}
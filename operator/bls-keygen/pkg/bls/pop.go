@@ -0,0 +1,16 @@
+package bls
+
+// PopDST is the domain separation tag of proofs of possession, distinct from
+// DST so that a PoP can never double as a signature over a message.
+const PopDST = "BLS_POP_BLS12381G1_XMD:SHA-256_SSWU_RO_POP_"
+
+// ProofOfPossession signs the compressed G2 public key of kp under PopDST.
+// Requiring it at registration stops rogue-key attacks on aggregates.
+func ProofOfPossession(kp *KeyPair) (*Signature, error) {
+	return kp.signWithDST(kp.G2PubKey.Bytes(), PopDST)
+}
+
+// VerifyPoP reports whether sig is a proof of possession for pk.
+func VerifyPoP(pk *G2PubKey, sig *Signature) bool {
+	return verifyWithDST(pk, pk.Bytes(), sig, PopDST) == nil
+}
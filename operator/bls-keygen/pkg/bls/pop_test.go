@@ -0,0 +1,42 @@
+package bls
+
+import (
+	"crypto/rand"
+	"testing"
+)
+
+func TestProofOfPossession(t *testing.T) {
+	kp, err := GenerateKeyPair(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := GenerateKeyPair(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pop, err := ProofOfPossession(kp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !VerifyPoP(kp.G2PubKey, pop) {
+		t.Fatal("valid proof of possession rejected")
+	}
+
+	otherPoP, err := ProofOfPossession(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if VerifyPoP(kp.G2PubKey, otherPoP) {
+		t.Fatal("proof of possession from a different key accepted")
+	}
+
+	// A plain signature over the public key is not a PoP.
+	sig, err := kp.Sign(kp.G2PubKey.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if VerifyPoP(kp.G2PubKey, sig) {
+		t.Fatal("signature under the message DST accepted as a PoP")
+	}
+}
@@ -0,0 +1,69 @@
+package bls
+
+import (
+	"encoding/binary"
+	"math/big"
+)
+
+// responseTag prefixes every response digest so that it can never equal a
+// bare task hash or any other keccak256 this module signs.
+var responseTag = keccak256([]byte("BastionTaskResponse(uint32 taskIndex,bytes32 responseHash)"))
+
+// HashTaskResponse returns the response hash BastionTaskManager computes
+// for a response when counting quorum: keccak256(responseData), over the
+// responseData bytes exactly as passed to respondToTask. Those bytes are
+// already the operator's abi.encode of its result, e.g. (bool isDepegged,
+// uint256 currentPrice, uint256 deviation) for a depeg check, and the
+// contract hashes them as they are; hashing abi.encode(responseData)
+// instead would add an offset and a length word and give another hash.
+func HashTaskResponse(response []byte) [32]byte {
+	var h [32]byte
+	copy(h[:], keccak256(response))
+	return h
+}
+
+// TaskResponseMessageHash returns the message hash respondToTask checks an
+// operator's signature against: keccak256(abi.encodePacked(uint32
+// referenceTaskIndex, responseData)), the index packed as four big-endian
+// bytes and the response bytes appended as they are.
+func TaskResponseMessageHash(taskIndex uint32, response []byte) [32]byte {
+	var index [4]byte
+	binary.BigEndian.PutUint32(index[:], taskIndex)
+	var h [32]byte
+	copy(h[:], keccak256(index[:], response))
+	return h
+}
+
+// EthSignedMessageHash returns keccak256("\x19Ethereum Signed Message:\n32"
+// || hash), the hash BastionTaskManager's _verifySignature recovers the
+// operator's ECDSA signer from. For a task response, hash is
+// TaskResponseMessageHash.
+func EthSignedMessageHash(hash [32]byte) [32]byte {
+	var h [32]byte
+	copy(h[:], keccak256([]byte("\x19Ethereum Signed Message:\n32"), hash[:]))
+	return h
+}
+
+// responseDigest is the message SignResponse signs:
+// keccak256(responseTag, uint256(taskIndex), responseHash). It is this
+// module's own convention for BLS response signatures; no contract in the
+// repo rebuilds it.
+func responseDigest(taskIndex uint32, responseHash [32]byte) [32]byte {
+	var digest [32]byte
+	copy(digest[:], keccak256(responseTag, abiUint256(new(big.Int).SetUint64(uint64(taskIndex))), responseHash[:]))
+	return digest
+}
+
+// SignResponse signs responseHash for task taskIndex. Binding the index
+// into the message means a signature over the same response hash for task
+// N does not verify for task M.
+func SignResponse(kp *KeyPair, taskIndex uint32, responseHash [32]byte) (*Signature, error) {
+	digest := responseDigest(taskIndex, responseHash)
+	return kp.Sign(digest[:])
+}
+
+// VerifyResponse checks a signature made by SignResponse.
+func VerifyResponse(pk *G2PubKey, taskIndex uint32, responseHash [32]byte, sig *Signature) bool {
+	digest := responseDigest(taskIndex, responseHash)
+	return Verify(pk, digest[:], sig)
+}
@@ -0,0 +1,89 @@
+package bls
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSignResponseBindsTaskIndex(t *testing.T) {
+	kp, err := GenerateKeyPair(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var responseHash [32]byte
+	copy(responseHash[:], keccak256([]byte("price 1.0001")))
+
+	sig, err := SignResponse(kp, 1, responseHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !VerifyResponse(kp.G2PubKey, 1, responseHash, sig) {
+		t.Fatal("valid response signature rejected")
+	}
+	if VerifyResponse(kp.G2PubKey, 2, responseHash, sig) {
+		t.Fatal("signature for task 1 accepted for task 2")
+	}
+
+	var other [32]byte
+	copy(other[:], keccak256([]byte("price 0.98")))
+	if VerifyResponse(kp.G2PubKey, 1, other, sig) {
+		t.Fatal("signature accepted for a different response")
+	}
+
+	// A bare signature over the response hash is not a response signature.
+	bare, err := kp.Sign(responseHash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if VerifyResponse(kp.G2PubKey, 1, responseHash, bare) {
+		t.Fatal("signature over the bare hash accepted")
+	}
+}
+
+// TestHashTaskResponseFixture checks HashTaskResponse,
+// TaskResponseMessageHash and EthSignedMessageHash against
+// testdata/task_responses.json. test/TaskResponseHash.t.sol feeds the same
+// file to BastionTaskManager itself: it signs eth_signed_message_hash,
+// which respondToTask must accept, and reads response_hash back from the
+// aggregated response.
+func TestHashTaskResponseFixture(t *testing.T) {
+	data, err := os.ReadFile("testdata/task_responses.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cases []struct {
+		Name          string `json:"name"`
+		TaskIndex     uint32 `json:"task_index"`
+		ResponseData  string `json:"response_data"`
+		ResponseHash  string `json:"response_hash"`
+		MessageHash   string `json:"message_hash"`
+		EthSignedHash string `json:"eth_signed_message_hash"`
+	}
+	if err := json.Unmarshal(data, &cases); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			response, err := hex.DecodeString(strings.TrimPrefix(c.ResponseData, "0x"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			h := HashTaskResponse(response)
+			if got := fmt.Sprintf("0x%x", h); got != c.ResponseHash {
+				t.Errorf("HashTaskResponse = %s, want %s", got, c.ResponseHash)
+			}
+			m := TaskResponseMessageHash(c.TaskIndex, response)
+			if got := fmt.Sprintf("0x%x", m); got != c.MessageHash {
+				t.Errorf("TaskResponseMessageHash = %s, want %s", got, c.MessageHash)
+			}
+			if got := fmt.Sprintf("0x%x", EthSignedMessageHash(m)); got != c.EthSignedHash {
+				t.Errorf("EthSignedMessageHash = %s, want %s", got, c.EthSignedHash)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package bls
+
+import "math/big"
+
+// SecretBytes holds secret key material. Call Zero once it is no longer
+// needed rather than leaving the bytes to the garbage collector.
+type SecretBytes []byte
+
+// Zero overwrites s with zeros.
+func (s SecretBytes) Zero() {
+	for i := range s {
+		s[i] = 0
+	}
+}
+
+// Zero overwrites the private key scalar. The key is unusable afterwards.
+func (sk *PrivateKey) Zero() {
+	sk.scalar.SetZero()
+}
+
+// zeroBigInt overwrites the words backing x, then sets it to zero.
+func zeroBigInt(x *big.Int) {
+	words := x.Bits()
+	for i := range words {
+		words[i] = 0
+	}
+	x.SetInt64(0)
+}
@@ -0,0 +1,48 @@
+package bls
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+)
+
+func TestSecretBytesZero(t *testing.T) {
+	kp, err := GenerateKeyPair(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := kp.PrivateKey.Bytes()
+	b.Zero()
+	for i, v := range b {
+		if v != 0 {
+			t.Fatalf("byte %d not cleared", i)
+		}
+	}
+	if len(b) != PrivateKeySize {
+		t.Fatalf("Zero changed the length to %d", len(b))
+	}
+
+	kp.PrivateKey.Zero()
+	if !kp.PrivateKey.scalar.IsZero() {
+		t.Fatal("private key scalar not cleared")
+	}
+}
+
+func TestWithBigIntWipesCopy(t *testing.T) {
+	kp, err := GenerateKeyPair(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var words []big.Word
+	kp.PrivateKey.withBigInt(func(s *big.Int) {
+		words = s.Bits()
+	})
+	for i, w := range words {
+		if w != 0 {
+			t.Fatalf("word %d of the big.Int copy not cleared", i)
+		}
+	}
+	if len(words) == 0 {
+		t.Fatal("scalar copy had no words")
+	}
+}
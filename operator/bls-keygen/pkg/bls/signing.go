@@ -0,0 +1,56 @@
+package bls
+
+import "fmt"
+
+// maxPrefixLen bounds SigningContext.Prefix; prefixes are short
+// application tags, not payloads.
+const maxPrefixLen = 255
+
+// SigningContext fixes how one deployment signs messages: the Domain whose
+// signature tag msg is hashed under, and a Prefix prepended to msg before
+// hashing. Signing and verifying through the same SigningContext cannot
+// disagree on either. BLS signing is deterministic, so there is no nonce to
+// manage either.
+type SigningContext struct {
+	Domain Domain
+	Prefix []byte
+}
+
+// DefaultSigningContext signs like Sign and verifies like Verify.
+var DefaultSigningContext = SigningContext{Domain: DefaultDomain}
+
+// NewSigningContext returns the SigningContext of d and prefix after
+// checking both.
+func NewSigningContext(d Domain, prefix []byte) (SigningContext, error) {
+	if err := d.Validate(); err != nil {
+		return SigningContext{}, err
+	}
+	if len(prefix) > maxPrefixLen {
+		return SigningContext{}, fmt.Errorf("bls: message prefix longer than %d bytes", maxPrefixLen)
+	}
+	return SigningContext{Domain: d, Prefix: append([]byte(nil), prefix...)}, nil
+}
+
+// Message returns the bytes actually signed for msg: Prefix followed by
+// msg. Callers handing msg to a signer that takes a Domain use it.
+func (c SigningContext) Message(msg []byte) []byte {
+	if len(c.Prefix) == 0 {
+		return msg
+	}
+	return append(append(make([]byte, 0, len(c.Prefix)+len(msg)), c.Prefix...), msg...)
+}
+
+// Sign signs msg with kp in this context.
+func (c SigningContext) Sign(kp *KeyPair, msg []byte) (*Signature, error) {
+	return kp.signWithDST(c.Message(msg), c.Domain.Sig)
+}
+
+// Verify checks sig over msg against pk in this context.
+func (c SigningContext) Verify(pk *G2PubKey, msg []byte, sig *Signature) bool {
+	return c.VerifyE(pk, msg, sig) == nil
+}
+
+// VerifyE is Verify, returning why verification failed.
+func (c SigningContext) VerifyE(pk *G2PubKey, msg []byte, sig *Signature) error {
+	return verifyWithDST(pk, c.Message(msg), sig, c.Domain.Sig)
+}
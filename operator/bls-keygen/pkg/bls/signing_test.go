@@ -0,0 +1,63 @@
+package bls
+
+import (
+	"crypto/rand"
+	"testing"
+)
+
+func TestSigningContextsDoNotCrossVerify(t *testing.T) {
+	kp, err := GenerateKeyPair(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mainnet, err := NewSigningContext(Networks["mainnet"], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	holesky, err := NewSigningContext(Networks["holesky"], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prefixed, err := NewSigningContext(Networks["mainnet"], []byte("bastion-task:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	contexts := map[string]SigningContext{"mainnet": mainnet, "holesky": holesky, "mainnet+prefix": prefixed}
+
+	msg := []byte("task response")
+	for signedIn, c := range contexts {
+		sig, err := c.Sign(kp, msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for verifiedIn, v := range contexts {
+			if got, want := v.Verify(kp.G2PubKey, msg, sig), signedIn == verifiedIn; got != want {
+				t.Errorf("signature from %s verified in %s: %v", signedIn, verifiedIn, got)
+			}
+		}
+	}
+
+	sig, err := DefaultSigningContext.Sign(kp, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Verify(kp.G2PubKey, msg, sig) || !mainnet.Verify(kp.G2PubKey, msg, sig) {
+		t.Fatal("DefaultSigningContext does not match Sign")
+	}
+	psig, err := prefixed.Sign(kp, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Verify(kp.G2PubKey, prefixed.Message(msg), psig) {
+		t.Fatal("prefixed signature is not a plain signature over Prefix || msg")
+	}
+}
+
+func TestNewSigningContextRejectsBadInput(t *testing.T) {
+	if _, err := NewSigningContext(Domain{Sig: DST, PoP: DST}, nil); err == nil {
+		t.Fatal("expected an error for equal signature and PoP tags")
+	}
+	if _, err := NewSigningContext(DefaultDomain, make([]byte, maxPrefixLen+1)); err == nil {
+		t.Fatal("expected an error for an oversized prefix")
+	}
+}
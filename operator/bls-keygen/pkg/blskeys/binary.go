@@ -0,0 +1,233 @@
+package blskeys
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// binaryMagic opens every binary key file. No JSON document starts with
+// it, which is how Load tells the two encodings apart.
+var binaryMagic = []byte("BSTK")
+
+// binaryVersion is the layout version following binaryMagic. Version 1
+// carries the fields of a CurrentVersion KeyFile.
+const binaryVersion = 1
+
+// The binary layout is the magic, the version byte, then the fields below in
+// order. Integers are big-endian uint32; everything else is a uint16
+// big-endian length followed by that many bytes.
+//
+//	kdf, n, r, p, c, dklen, prf, salt,
+//	cipher, nonce, ciphertext,
+//	g1 pubkey, g2 pubkey, metadata (JSON, empty when absent),
+//	checksum
+//
+// Every value of the JSON key file is there, so a binary file decrypts on
+// its own and its checksum is the one the JSON form would have.
+
+// MarshalBinaryKeyFile encodes kf in the binary layout. It is the compact
+// alternative to the JSON encoding for size-constrained secret stores.
+func MarshalBinaryKeyFile(kf *KeyFile) ([]byte, error) {
+	if kf.Version != CurrentVersion {
+		return nil, fmt.Errorf("binary key files hold version %d key files, not version %d", CurrentVersion, kf.Version)
+	}
+	if err := kf.verifyChecksum(); err != nil {
+		return nil, err
+	}
+	var w binaryWriter
+	w.buf.Write(binaryMagic)
+	w.buf.WriteByte(binaryVersion)
+
+	p := kf.Crypto.KDFParams
+	w.str(kf.Crypto.KDF)
+	for _, v := range []int{p.N, p.R, p.P, p.C, p.DKLen} {
+		w.uint(v)
+	}
+	w.str(p.PRF)
+	w.hex(p.Salt)
+	w.str(kf.Crypto.Cipher)
+	w.hex(kf.Crypto.Nonce)
+	w.hex(kf.Crypto.Ciphertext)
+	w.hex(kf.G1PubKey)
+	w.hex(kf.G2PubKey)
+	if kf.Metadata != nil {
+		meta, err := json.Marshal(kf.Metadata)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal metadata: %w", err)
+		}
+		w.bytes(meta)
+	} else {
+		w.bytes(nil)
+	}
+	w.hex(kf.Checksum)
+	if w.err != nil {
+		return nil, fmt.Errorf("failed to encode binary key file: %w", w.err)
+	}
+	// Decoding rebuilds the hex fields in the form Encrypt writes them. A
+	// hand-edited file may differ, and its checksum would then not survive
+	// the trip.
+	data := w.buf.Bytes()
+	decoded, err := UnmarshalBinaryKeyFile(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := decoded.verifyChecksum(); err != nil {
+		return nil, fmt.Errorf("key file fields are not in canonical form: %w", err)
+	}
+	return data, nil
+}
+
+// UnmarshalBinaryKeyFile decodes a key file written by
+// MarshalBinaryKeyFile. Like the JSON form, it is checked when decrypted.
+func UnmarshalBinaryKeyFile(data []byte) (*KeyFile, error) {
+	if !IsBinaryKeyFile(data) {
+		return nil, fmt.Errorf("%w: not a binary key file", ErrCorruptKeyfile)
+	}
+	r := binaryReader{data: data[len(binaryMagic):]}
+	if v := r.byte(); r.err == nil && v != binaryVersion {
+		return nil, fmt.Errorf("%w: unsupported binary key file version %d", ErrCorruptKeyfile, v)
+	}
+
+	kf := &KeyFile{Version: CurrentVersion}
+	c := &kf.Crypto
+	c.KDF = r.str()
+	for _, v := range []*int{&c.KDFParams.N, &c.KDFParams.R, &c.KDFParams.P, &c.KDFParams.C, &c.KDFParams.DKLen} {
+		*v = r.uint()
+	}
+	c.KDFParams.PRF = r.str()
+	c.KDFParams.Salt = hex.EncodeToString(r.bytes())
+	c.Cipher = r.str()
+	c.Nonce = hex.EncodeToString(r.bytes())
+	c.Ciphertext = hex.EncodeToString(r.bytes())
+	kf.G1PubKey = fmt.Sprintf("0x%x", r.bytes())
+	kf.G2PubKey = fmt.Sprintf("0x%x", r.bytes())
+	if meta := r.bytes(); len(meta) > 0 {
+		kf.Metadata = new(Metadata)
+		if err := json.Unmarshal(meta, kf.Metadata); err != nil {
+			return nil, fmt.Errorf("%w: metadata: %v", ErrCorruptKeyfile, err)
+		}
+	}
+	kf.Checksum = hex.EncodeToString(r.bytes())
+	if r.err == nil && len(r.data) > 0 {
+		r.err = errors.New("trailing bytes")
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorruptKeyfile, r.err)
+	}
+	return kf, nil
+}
+
+// IsBinaryKeyFile reports whether data is in the binary layout rather than
+// JSON.
+func IsBinaryKeyFile(data []byte) bool {
+	return bytes.HasPrefix(data, binaryMagic)
+}
+
+// SaveBinaryContext is SaveContext writing the binary layout.
+func SaveBinaryContext(ctx context.Context, kp *KeyPair, path, password string, params KDFParams, meta *Metadata) error {
+	data, err := MarshalBinaryContext(ctx, kp, password, params, meta)
+	if err != nil {
+		return err
+	}
+	return writeFile(path, data)
+}
+
+// MarshalBinaryContext is MarshalContext returning the binary layout.
+func MarshalBinaryContext(ctx context.Context, kp *KeyPair, password string, params KDFParams, meta *Metadata) ([]byte, error) {
+	kf, err := encryptContext(ctx, kp, password, params, meta)
+	if err != nil {
+		return nil, err
+	}
+	return MarshalBinaryKeyFile(kf)
+}
+
+// binaryWriter appends binary fields, keeping the first error.
+type binaryWriter struct {
+	buf bytes.Buffer
+	err error
+}
+
+func (w *binaryWriter) uint(v int) {
+	if v < 0 || v > math.MaxUint32 {
+		w.fail(fmt.Errorf("value %d does not fit 32 bits", v))
+		return
+	}
+	w.buf.Write(binary.BigEndian.AppendUint32(nil, uint32(v)))
+}
+
+func (w *binaryWriter) bytes(b []byte) {
+	if len(b) > math.MaxUint16 {
+		w.fail(fmt.Errorf("field of %d bytes is too long", len(b)))
+		return
+	}
+	w.buf.Write(binary.BigEndian.AppendUint16(nil, uint16(len(b))))
+	w.buf.Write(b)
+}
+
+func (w *binaryWriter) str(s string) { w.bytes([]byte(s)) }
+
+func (w *binaryWriter) hex(s string) {
+	b, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil {
+		w.fail(err)
+		return
+	}
+	w.bytes(b)
+}
+
+func (w *binaryWriter) fail(err error) {
+	if w.err == nil {
+		w.err = err
+	}
+}
+
+// binaryReader consumes binary fields, keeping the first error; after one,
+// every read returns a zero value.
+type binaryReader struct {
+	data []byte
+	err  error
+}
+
+func (r *binaryReader) take(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if len(r.data) < n {
+		r.err = errors.New("truncated")
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *binaryReader) byte() byte {
+	if b := r.take(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *binaryReader) uint() int {
+	if b := r.take(4); b != nil {
+		return int(binary.BigEndian.Uint32(b))
+	}
+	return 0
+}
+
+func (r *binaryReader) bytes() []byte {
+	n := r.take(2)
+	if n == nil {
+		return nil
+	}
+	return r.take(int(binary.BigEndian.Uint16(n)))
+}
+
+func (r *binaryReader) str() string { return string(r.bytes()) }
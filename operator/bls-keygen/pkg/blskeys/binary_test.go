@@ -0,0 +1,125 @@
+package blskeys
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBinaryKeyFileRoundTrip(t *testing.T) {
+	kp, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "bls_key.bin")
+	meta := &Metadata{Network: "holesky"}
+	if err := SaveBinaryContext(context.Background(), kp, path, "pw", testScrypt, meta); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, binaryMagic) {
+		t.Fatalf("file starts %x, want the binary magic", data[:4])
+	}
+	jsonData, err := MarshalContext(context.Background(), kp, "pw", testScrypt, meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) >= len(jsonData)/2 {
+		t.Errorf("binary file is %d bytes, JSON %d; want it well under half", len(data), len(jsonData))
+	}
+
+	loaded, err := Load(path, "pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(loaded.PrivateKey.Bytes(), kp.PrivateKey.Bytes()) {
+		t.Fatal("loaded key differs from the saved one")
+	}
+	if _, err := Load(path, "wrong"); !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("wrong password: got %v, want ErrDecrypt", err)
+	}
+
+	g1, g2, err := LoadPublicKeys(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(g1.Bytes(), kp.G1PubKey.Bytes()) || !bytes.Equal(g2.Bytes(), kp.G2PubKey.Bytes()) {
+		t.Fatal("public keys differ from the saved ones")
+	}
+	if got, err := LoadMetadata(path); err != nil || got == nil || got.Network != "holesky" {
+		t.Fatalf("metadata %+v, %v", got, err)
+	}
+
+	if err := ChangePassword(path, "pw", "new pw"); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); !IsBinaryKeyFile(data) {
+		t.Fatal("passwd rewrote a binary key file as JSON")
+	}
+	if _, err := Load(path, "new pw"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadDetectsEncoding(t *testing.T) {
+	kp, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	jsonPath, binPath, pemPath := filepath.Join(dir, "key.json"), filepath.Join(dir, "key.bin"), filepath.Join(dir, "key.pem")
+	if err := SaveContext(context.Background(), kp, jsonPath, "pw", testScrypt, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveBinaryContext(context.Background(), kp, binPath, "pw", testScrypt, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := SavePEMContext(context.Background(), kp, pemPath, "pw", testScrypt, nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{jsonPath, binPath, pemPath} {
+		loaded, err := Load(path, "pw")
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		if !bytes.Equal(loaded.G1PubKey.Bytes(), kp.G1PubKey.Bytes()) {
+			t.Fatalf("%s: wrong key", path)
+		}
+	}
+}
+
+func TestBinaryKeyFileCorrupt(t *testing.T) {
+	kp, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := MarshalBinaryContext(context.Background(), kp, "pw", testScrypt, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := UnmarshalBinaryKeyFile(data[:len(data)-1]); !errors.Is(err, ErrCorruptKeyfile) {
+		t.Errorf("truncated: got %v, want ErrCorruptKeyfile", err)
+	}
+	if _, err := UnmarshalBinaryKeyFile(append(data, 0)); !errors.Is(err, ErrCorruptKeyfile) {
+		t.Errorf("trailing byte: got %v, want ErrCorruptKeyfile", err)
+	}
+
+	// Flip a bit in the ciphertext region: the checksum catches it.
+	tampered := bytes.Clone(data)
+	tampered[len(tampered)-200] ^= 1
+	kf, err := UnmarshalBinaryKeyFile(tampered)
+	if err == nil {
+		_, err = Decrypt(kf, "pw")
+	}
+	if !errors.Is(err, ErrCorruptKeyfile) {
+		t.Errorf("tampered: got %v, want ErrCorruptKeyfile", err)
+	}
+}
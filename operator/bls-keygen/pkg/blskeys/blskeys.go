@@ -0,0 +1,761 @@
+// Package blskeys generates, stores and loads Bastion operator BLS keys,
+// and the companion ECDSA operator key in go-ethereum's keystore format.
+//
+// Key files keep the G1 and G2 public keys in cleartext and the private key
+// encrypted with AES-256-GCM under a key derived from the password with
+// scrypt or PBKDF2, whichever the file's "kdf" field records. The top-level
+// "version" field selects the layout:
+//
+//	0  legacy plaintext file with a hex "private_key" (read-only)
+//	1  encrypted layout described by KeyFile
+package blskeys
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+)
+
+const (
+	kdfScrypt       = "scrypt"
+	kdfPBKDF2       = "pbkdf2"
+	prfHMACSHA256   = "hmac-sha256"
+	cipherAES256GCM = "aes-256-gcm"
+)
+
+var (
+	// ErrKeyNotFound is returned when there is no key file at the given
+	// path. It wraps the underlying fs.ErrNotExist.
+	ErrKeyNotFound = errors.New("key file not found")
+	// ErrBadPassword is returned when the private key does not decrypt
+	// under the password. AES-GCM cannot tell a wrong password from a
+	// tampered ciphertext, but the checksum catches tampering first.
+	ErrBadPassword = errors.New("failed to decrypt private key: wrong password or corrupted key file")
+	// ErrDecrypt is ErrBadPassword by its original name.
+	ErrDecrypt = ErrBadPassword
+	// ErrNotInSubgroup is returned when a stored public key is on the curve
+	// but outside the prime-order subgroup.
+	ErrNotInSubgroup = bls.ErrNotInSubgroup
+	// ErrCorruptKeyfile is returned when a key file cannot be parsed, its
+	// checksum does not match its contents or it names an unsupported
+	// version, KDF or cipher.
+	ErrCorruptKeyfile = errors.New("corrupt key file")
+	// ErrInvalidKey is returned when a key file parses but holds a key that
+	// is not usable: a private key outside the scalar field, or a public key
+	// that is not a valid point or does not belong to the private key.
+	ErrInvalidKey = errors.New("invalid key")
+	// ErrPubPrivMismatch is returned when a stored public key is valid but
+	// does not belong to the decrypted private key. It matches
+	// ErrInvalidKey.
+	ErrPubPrivMismatch = fmt.Errorf("%w: stored public key does not match the private key", ErrInvalidKey)
+	// ErrLegacyPlaceholder is returned for a key file written by the first
+	// keygen build, which stored a secp256k1 key with its public key split
+	// into fake G1 and G2 fields. It was never a BLS key and cannot sign
+	// AVS responses; the key has to be generated again. It matches
+	// ErrInvalidKey.
+	ErrLegacyPlaceholder = fmt.Errorf("%w: legacy ECDSA placeholder, not a BLS key; it cannot sign AVS responses and must be regenerated", ErrInvalidKey)
+)
+
+// readKeyFile is os.ReadFile reporting a missing file as ErrKeyNotFound.
+func readKeyFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %w", ErrKeyNotFound, err)
+	}
+	return data, err
+}
+
+// unmarshalNonJSON decodes data if it is a binary or PEM key file and
+// returns its format, FormatBinary or FormatPEM. For anything else format
+// is empty, and the caller decodes the JSON.
+func unmarshalNonJSON(data []byte) (kf *KeyFile, format string, err error) {
+	switch {
+	case IsBinaryKeyFile(data):
+		kf, err = UnmarshalBinaryKeyFile(data)
+		return kf, FormatBinary, err
+	case IsPEMKeyFile(data):
+		kf, err = UnmarshalPEMKeyFile(data)
+		return kf, FormatPEM, err
+	}
+	return nil, "", nil
+}
+
+// marshalNonJSON encodes kf in format, as returned by unmarshalNonJSON.
+func marshalNonJSON(kf *KeyFile, format string) ([]byte, error) {
+	if format == FormatPEM {
+		return MarshalPEMKeyFile(kf)
+	}
+	return MarshalBinaryKeyFile(kf)
+}
+
+// KeyPair is a parsed BLS key pair.
+type KeyPair = bls.KeyPair
+
+// CurrentVersion is the key file version written by Save.
+const CurrentVersion = 1
+
+// KeyFile is the on-disk key file. Public keys are stored in cleartext,
+// the private key only inside Crypto. Checksum is the hex sha256 of the
+// file's canonical JSON encoding without the checksum itself. It is
+// required from version 1 on; only version 0 files may lack it.
+type KeyFile struct {
+	Version  int          `json:"version"`
+	G1PubKey string       `json:"g1_pub_key"`
+	G2PubKey string       `json:"g2_pub_key"`
+	Metadata *Metadata    `json:"metadata,omitempty"`
+	Crypto   CryptoParams `json:"crypto"`
+	Checksum string       `json:"checksum,omitempty"`
+}
+
+// Metadata is cleartext information about a key. It is covered by the
+// checksum but plays no part in decryption.
+type Metadata struct {
+	// Label is the operator's free-form name for the key, such as
+	// "mainnet-primary". See ValidateLabel.
+	Label string `json:"label,omitempty"`
+	// Network is the --network preset the key was created for. It is a
+	// hint for tooling; the signing domain is always chosen by the caller.
+	Network string `json:"network,omitempty"`
+	// TestOnly marks a throwaway key made for integration tests, which
+	// tooling refuses to register or deploy on mainnet.
+	TestOnly bool `json:"test_only,omitempty"`
+	// CreatedAt is when the key was generated, derived or imported, in
+	// RFC 3339 form and UTC.
+	CreatedAt string `json:"created_at,omitempty"`
+	// GeneratorVersion is the GeneratorVersion of the program that
+	// created the key.
+	GeneratorVersion string `json:"generator_version,omitempty"`
+	// OperatorAddress is the EIP-55 Ethereum address of the operator's
+	// ECDSA key, when one was generated alongside this key.
+	OperatorAddress string `json:"operator_address,omitempty"`
+	// DerivationPath is the EIP-2334 path of a key derived from a
+	// mnemonic.
+	DerivationPath string `json:"derivation_path,omitempty"`
+	// AVSID is the AVS identifier mixed into DerivationPath by AVSPath.
+	AVSID string `json:"avs_id,omitempty"`
+	// PreviousDerivationPath is the path of the key a rekey replaced,
+	// which must not be derived again.
+	PreviousDerivationPath string `json:"previous_derivation_path,omitempty"`
+}
+
+// GeneratorVersion is recorded in the metadata NewMetadata returns.
+// Programs set it to their build version.
+var GeneratorVersion string
+
+// NewMetadata returns the metadata of a key for network created at now.
+func NewMetadata(network string, now time.Time) *Metadata {
+	return &Metadata{
+		Network:          network,
+		CreatedAt:        now.UTC().Format(time.RFC3339),
+		GeneratorVersion: GeneratorVersion,
+	}
+}
+
+// Created parses CreatedAt. It returns the zero time if m records none.
+func (m *Metadata) Created() (time.Time, error) {
+	if m == nil || m.CreatedAt == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, m.CreatedAt)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid created_at: %w", err)
+	}
+	return t, nil
+}
+
+// LoadMetadata returns the metadata of the Bastion key file at path, or nil
+// if it has none. EIP-2335 keystores and legacy files have none.
+func LoadMetadata(path string) (*Metadata, error) {
+	data, err := readKeyFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if kf, format, err := unmarshalNonJSON(data); format != "" {
+		if err != nil {
+			return nil, err
+		}
+		return kf.Metadata, nil
+	}
+	var fields struct {
+		Metadata *Metadata `json:"metadata"`
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorruptKeyfile, err)
+	}
+	return fields.Metadata, nil
+}
+
+// CryptoParams describes how the private key was encrypted.
+type CryptoParams struct {
+	KDF        string    `json:"kdf"`
+	KDFParams  KDFParams `json:"kdf_params"`
+	Cipher     string    `json:"cipher"`
+	Nonce      string    `json:"nonce"`
+	Ciphertext string    `json:"ciphertext"`
+}
+
+// KDFParams are the cost parameters and salt used to derive the AES key
+// from the password. N, R and P are scrypt's; C and PRF are PBKDF2's, and a
+// nonzero C selects PBKDF2.
+type KDFParams struct {
+	N     int    `json:"n,omitempty"`
+	R     int    `json:"r,omitempty"`
+	P     int    `json:"p,omitempty"`
+	C     int    `json:"c,omitempty"`
+	PRF   string `json:"prf,omitempty"`
+	DKLen int    `json:"dklen"`
+	Salt  string `json:"salt"`
+}
+
+// DefaultScryptParams matches the "standard" scrypt cost used by geth
+// keystores and is what Save and SaveEIP2335 use.
+var DefaultScryptParams = KDFParams{N: 1 << 18, R: 8, P: 1, DKLen: 32}
+
+// DefaultPBKDF2Params follows the OWASP iteration count for
+// PBKDF2-HMAC-SHA256.
+var DefaultPBKDF2Params = KDFParams{C: 600000, PRF: prfHMACSHA256, DKLen: 32}
+
+// Generate creates a new random key pair.
+func Generate() (*KeyPair, error) {
+	return GenerateContext(context.Background())
+}
+
+// GenerateN creates n key pairs from rng, reading fresh entropy for each in
+// turn, so a deterministic rng gives the same keys in the same order. That
+// is for test harnesses; production callers pass crypto/rand.Reader.
+func GenerateN(rng io.Reader, n int) ([]*KeyPair, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("blskeys: cannot generate %d keys", n)
+	}
+	kps := make([]*KeyPair, 0, n)
+	for i := 0; i < n; i++ {
+		kp, err := generate(context.Background(), rng)
+		if err != nil {
+			for _, kp := range kps {
+				kp.PrivateKey.Zero()
+			}
+			return nil, fmt.Errorf("blskeys: key %d: %w", i, err)
+		}
+		kps = append(kps, kp)
+	}
+	return kps, nil
+}
+
+// Save encrypts kp with password and writes it to path.
+func Save(kp *KeyPair, path, password string) error {
+	kf, err := Encrypt(kp, password, DefaultScryptParams)
+	if err != nil {
+		return err
+	}
+	return writeJSON(path, kf)
+}
+
+// legacyKeyFile is the version 0 layout, which stored the key in plaintext.
+type legacyKeyFile struct {
+	PrivateKey string `json:"private_key"`
+	PublicKey  string `json:"public_key"`
+	G1PubKey   string `json:"g1_pub_key"`
+	G2PubKey   string `json:"g2_pub_key"`
+}
+
+// isPlaceholder reports whether f has the shape the ECDSA placeholder build
+// wrote: a 65-byte uncompressed secp256k1 public_key whose first 32 bytes
+// were stored as g1_pub_key and the other 33 as g2_pub_key.
+func (f *legacyKeyFile) isPlaceholder() bool {
+	pub, err := hex.DecodeString(strings.TrimPrefix(f.PublicKey, "0x"))
+	if err != nil || len(pub) != 65 || pub[0] != 0x04 {
+		return false
+	}
+	g1, err1 := hex.DecodeString(strings.TrimPrefix(f.G1PubKey, "0x"))
+	g2, err2 := hex.DecodeString(strings.TrimPrefix(f.G2PubKey, "0x"))
+	return err1 == nil && err2 == nil && len(g1) == 32 && len(g2) == 33
+}
+
+// IsLegacyPlaceholder reports whether data is a key file of the ECDSA
+// placeholder build; see ErrLegacyPlaceholder.
+func IsLegacyPlaceholder(data []byte) bool {
+	var f legacyKeyFile
+	if json.Unmarshal(data, &f) != nil {
+		return false
+	}
+	f.PrivateKey = ""
+	return f.isPlaceholder()
+}
+
+// Load reads the key file or EIP-2335 keystore at path, in JSON, the
+// binary layout or PEM, and decrypts it with password. Legacy version 0 files are plaintext and ignore password;
+// their secp256k1 private_key is reduced modulo the BLS12-381 scalar order,
+// except in files of the ECDSA placeholder build, which are rejected with
+// ErrLegacyPlaceholder.
+// The stored public keys must be subgroup points matching the private key;
+// see ErrNotInSubgroup and ErrPubPrivMismatch.
+func Load(path, password string) (*KeyPair, error) {
+	data, err := readKeyFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if kf, format, err := unmarshalNonJSON(data); format != "" {
+		if err != nil {
+			return nil, err
+		}
+		return Decrypt(kf, password)
+	}
+	var header struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorruptKeyfile, err)
+	}
+	if header.Version == eip2335Version {
+		var ks EIP2335Keystore
+		if err := json.Unmarshal(data, &ks); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrCorruptKeyfile, err)
+		}
+		return decryptEIP2335(&ks, password)
+	}
+	var kf KeyFile
+	if err := json.Unmarshal(data, &kf); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorruptKeyfile, err)
+	}
+
+	switch kf.Version {
+	case 0:
+		// Early encrypted files predate the version field.
+		if kf.Crypto.Ciphertext != "" {
+			return Decrypt(&kf, password)
+		}
+		return loadLegacy(data)
+	case CurrentVersion:
+		return Decrypt(&kf, password)
+	default:
+		return nil, fmt.Errorf("%w: unsupported key file version %d", ErrCorruptKeyfile, kf.Version)
+	}
+}
+
+// LoadPublicKey returns the cleartext G1 public key stored in a Bastion or
+// EIP-2335 key file without decrypting it. It is not checked against the
+// private key; use Load for that. Legacy version 0 files store none.
+func LoadPublicKey(path string) (*bls.G1PubKey, error) {
+	g1, _, err := loadStoredPubKeys(path)
+	return g1, err
+}
+
+// LoadPublicKeys is LoadPublicKey returning the stored G2 public key too,
+// which is what signatures verify against. The G2 key is nil for EIP-2335
+// keystores, which store only G1.
+func LoadPublicKeys(path string) (*bls.G1PubKey, *bls.G2PubKey, error) {
+	return loadStoredPubKeys(path)
+}
+
+// loadStoredPubKeys returns the cleartext public keys of the key file at
+// path. The G2 key is nil for EIP-2335 keystores, which store only G1.
+func loadStoredPubKeys(path string) (*bls.G1PubKey, *bls.G2PubKey, error) {
+	data, err := readKeyFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var fields struct {
+		G1PubKey string `json:"g1_pub_key"`
+		G2PubKey string `json:"g2_pub_key"`
+		PubKey   string `json:"pubkey"` // EIP-2335
+	}
+	if kf, format, err := unmarshalNonJSON(data); format != "" {
+		if err != nil {
+			return nil, nil, err
+		}
+		fields.G1PubKey, fields.G2PubKey = kf.G1PubKey, kf.G2PubKey
+	} else if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrCorruptKeyfile, err)
+	}
+	pub := fields.G1PubKey
+	if pub == "" {
+		pub = fields.PubKey
+	}
+	if pub == "" {
+		return nil, nil, fmt.Errorf("%w: key file has no public key", ErrCorruptKeyfile)
+	}
+	b, err := hex.DecodeString(strings.TrimPrefix(pub, "0x"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: G1 public key: %v", ErrCorruptKeyfile, err)
+	}
+	g1, err := bls.G1PubKeyFromBytes(b)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: G1 public key: %w", ErrInvalidKey, err)
+	}
+	if fields.G2PubKey == "" {
+		return g1, nil, nil
+	}
+	b, err = hex.DecodeString(strings.TrimPrefix(fields.G2PubKey, "0x"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: G2 public key: %v", ErrCorruptKeyfile, err)
+	}
+	g2, err := bls.G2PubKeyFromBytes(b)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: G2 public key: %w", ErrInvalidKey, err)
+	}
+	return g1, g2, nil
+}
+
+func loadLegacy(data []byte) (*KeyPair, error) {
+	var legacy legacyKeyFile
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorruptKeyfile, err)
+	}
+	if legacy.isPlaceholder() {
+		legacy.PrivateKey = ""
+		return nil, ErrLegacyPlaceholder
+	}
+	if legacy.PrivateKey == "" {
+		return nil, fmt.Errorf("%w: legacy key file has no private_key", ErrCorruptKeyfile)
+	}
+	b, err := hex.DecodeString(strings.TrimPrefix(legacy.PrivateKey, "0x"))
+	legacy.PrivateKey = ""
+	if err != nil {
+		return nil, fmt.Errorf("%w: legacy private_key: %v", ErrCorruptKeyfile, err)
+	}
+	defer bls.SecretBytes(b).Zero()
+	// Version 0 files other than placeholders may still hold secp256k1
+	// scalars, most of which are at or above the BLS12-381 order. Reduce
+	// the scalar so every such file maps to one fixed BLS key instead of
+	// failing to load.
+	sk, err := bls.PrivateKeyFromBytesReduced(b)
+	if err != nil {
+		return nil, fmt.Errorf("%w: legacy private_key: %w", ErrInvalidKey, err)
+	}
+	return bls.NewKeyPair(sk), nil
+}
+
+// Migrate upgrades the key file at path to CurrentVersion in place,
+// encrypting it with password. It reports whether anything was rewritten.
+func Migrate(path, password string) (bool, error) {
+	data, err := readKeyFile(path)
+	if err != nil {
+		return false, err
+	}
+	// Binary and PEM files only hold CurrentVersion key files.
+	if _, format, err := unmarshalNonJSON(data); format != "" {
+		return false, err
+	}
+	var header struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return false, fmt.Errorf("%w: %v", ErrCorruptKeyfile, err)
+	}
+	if header.Version == CurrentVersion {
+		return false, nil
+	}
+
+	kp, err := Load(path, password)
+	if err != nil {
+		return false, err
+	}
+	defer kp.PrivateKey.Zero()
+	if err := Save(kp, path, password); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Encrypt encrypts the private key of kp with password.
+func Encrypt(kp *KeyPair, password string, params KDFParams) (*KeyFile, error) {
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	params.Salt = hex.EncodeToString(salt)
+
+	gcm, err := newGCM(password, params.function(), params)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	plaintext := kp.PrivateKey.Bytes()
+	defer plaintext.Zero()
+	ciphertext := gcm.Seal(nil, nonce, plaintext, nil)
+
+	kf := &KeyFile{
+		Version:  CurrentVersion,
+		G1PubKey: fmt.Sprintf("0x%x", kp.G1PubKey.Bytes()),
+		G2PubKey: fmt.Sprintf("0x%x", kp.G2PubKey.Bytes()),
+		Crypto: CryptoParams{
+			KDF:        params.function(),
+			KDFParams:  params,
+			Cipher:     cipherAES256GCM,
+			Nonce:      hex.EncodeToString(nonce),
+			Ciphertext: hex.EncodeToString(ciphertext),
+		},
+	}
+	kf.Checksum, err = kf.computeChecksum()
+	if err != nil {
+		return nil, err
+	}
+	return kf, nil
+}
+
+// computeChecksum hashes every field of kf except Checksum, so it covers
+// the public keys, the KDF parameters and the encrypted key.
+func (kf *KeyFile) computeChecksum() (string, error) {
+	c := *kf
+	c.Checksum = ""
+	data, err := json.Marshal(&c)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal key file: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// verifyChecksum returns ErrCorruptKeyfile if kf's checksum is missing or
+// does not match its contents.
+func (kf *KeyFile) verifyChecksum() error {
+	if kf.Checksum == "" {
+		if kf.Version >= 1 {
+			return fmt.Errorf("%w: missing checksum", ErrCorruptKeyfile)
+		}
+		return nil
+	}
+	want, err := kf.computeChecksum()
+	if err != nil {
+		return err
+	}
+	if !checksumEqual([]byte(kf.Checksum), []byte(want)) {
+		return fmt.Errorf("%w: checksum mismatch", ErrCorruptKeyfile)
+	}
+	return nil
+}
+
+// checksumEqual compares a stored checksum with a computed one in constant
+// time. The EIP-2335 checksum is keyed by the password-derived key, so an
+// early-exit comparison would tell an attacker who can time Load how many
+// leading bytes a guess got right. Our own checksum is not secret, but it
+// sits on the same path and gets the same treatment so nobody has to
+// reason about which comparison is safe to make fast.
+func checksumEqual(got, want []byte) bool {
+	return subtle.ConstantTimeCompare(got, want) == 1
+}
+
+// Decrypt recovers the key pair stored in kf after verifying its checksum.
+func Decrypt(kf *KeyFile, password string) (*KeyPair, error) {
+	if err := kf.verifyChecksum(); err != nil {
+		return nil, err
+	}
+	kp, err := decryptKeyFile(kf, password)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkStoredPubKeys(kp, kf.G1PubKey, kf.G2PubKey); err != nil {
+		kp.PrivateKey.Zero()
+		return nil, err
+	}
+	return kp, nil
+}
+
+// decryptKeyFile is Decrypt without the checksum and stored public key
+// checks. AES-GCM still authenticates the ciphertext.
+func decryptKeyFile(kf *KeyFile, password string) (*KeyPair, error) {
+	if kf.Crypto.KDF != kdfScrypt && kf.Crypto.KDF != kdfPBKDF2 {
+		return nil, fmt.Errorf("%w: unsupported kdf %q", ErrCorruptKeyfile, kf.Crypto.KDF)
+	}
+	if kf.Crypto.Cipher != cipherAES256GCM {
+		return nil, fmt.Errorf("%w: unsupported cipher %q", ErrCorruptKeyfile, kf.Crypto.Cipher)
+	}
+
+	nonce, err := hex.DecodeString(kf.Crypto.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("%w: nonce: %v", ErrCorruptKeyfile, err)
+	}
+	ciphertext, err := hex.DecodeString(kf.Crypto.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("%w: ciphertext: %v", ErrCorruptKeyfile, err)
+	}
+
+	gcm, err := newGCM(password, kf.Crypto.KDF, kf.Crypto.KDFParams)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("%w: nonce length %d", ErrCorruptKeyfile, len(nonce))
+	}
+
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, ErrBadPassword
+	}
+	defer bls.SecretBytes(plaintext).Zero()
+
+	sk, err := bls.PrivateKeyFromBytes(plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidKey, err)
+	}
+	return bls.NewKeyPair(sk), nil
+}
+
+// checkStoredPubKeys verifies that the cleartext public keys of a key file
+// are valid subgroup points and belong to kp. Empty fields are skipped.
+func checkStoredPubKeys(kp *KeyPair, g1Hex, g2Hex string) error {
+	if g1Hex != "" {
+		b, err := hex.DecodeString(strings.TrimPrefix(g1Hex, "0x"))
+		if err != nil {
+			return fmt.Errorf("%w: G1 public key: %v", ErrCorruptKeyfile, err)
+		}
+		pk, err := bls.G1PubKeyFromBytes(b)
+		if err != nil {
+			return fmt.Errorf("%w: G1 public key: %w", ErrInvalidKey, err)
+		}
+		if !bytes.Equal(pk.Bytes(), kp.G1PubKey.Bytes()) {
+			return fmt.Errorf("G1: %w", ErrPubPrivMismatch)
+		}
+	}
+	if g2Hex != "" {
+		b, err := hex.DecodeString(strings.TrimPrefix(g2Hex, "0x"))
+		if err != nil {
+			return fmt.Errorf("%w: G2 public key: %v", ErrCorruptKeyfile, err)
+		}
+		pk, err := bls.G2PubKeyFromBytes(b)
+		if err != nil {
+			return fmt.Errorf("%w: G2 public key: %w", ErrInvalidKey, err)
+		}
+		if !bytes.Equal(pk.Bytes(), kp.G2PubKey.Bytes()) {
+			return fmt.Errorf("G2: %w", ErrPubPrivMismatch)
+		}
+	}
+	return nil
+}
+
+func newGCM(password, kdf string, params KDFParams) (cipher.AEAD, error) {
+	salt, err := hex.DecodeString(strings.TrimPrefix(params.Salt, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("%w: salt: %v", ErrCorruptKeyfile, err)
+	}
+	key, err := deriveKey([]byte(password), salt, kdf, params)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to derive key: %v", ErrCorruptKeyfile, err)
+	}
+	// The AES key schedule keeps its own copy.
+	defer bls.SecretBytes(key).Zero()
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// renameFile is os.Rename; tests replace it to simulate a crash before the
+// new file is moved into place.
+var renameFile = os.Rename
+
+// writeJSON writes v to path through a temporary file in the same
+// directory that is fsynced and then renamed over path. Rename is atomic on
+// POSIX, so an interrupted write leaves either the old file or the new one,
+// never a truncated key. The file is readable by its owner only.
+func writeJSON(path string, v interface{}) error {
+	data, err := marshalKeyFile(v)
+	if err != nil {
+		return err
+	}
+	return writeFile(path, data)
+}
+
+// marshalKeyFile is the on-disk encoding of key files and keystores.
+func marshalKeyFile(v interface{}) ([]byte, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal key file: %w", err)
+	}
+	return data, nil
+}
+
+// WriteFile writes data to path the way Save writes key files: atomically
+// and readable by the owner only. It is for encodings of a key that this
+// package does not produce itself, such as an age-encrypted key file.
+func WriteFile(path string, data []byte) error {
+	return writeFile(path, data)
+}
+
+// WriteNewFile is WriteFile for a key file that must not exist yet: if
+// anything is at path, even something that appeared while data was being
+// written, it is left alone and WriteNewFile fails with an error wrapping
+// fs.ErrExist.
+func WriteNewFile(path string, data []byte) error {
+	return placeFile(path, data, linkNew)
+}
+
+// linkNew puts tmp in place at path only if path is free. A hard link
+// cannot replace anything; on file systems without hard links it falls
+// back to checking first and renaming, which leaves a window for a racing
+// writer but still never replaces a file that was already there.
+func linkNew(tmp, path string) error {
+	err := os.Link(tmp, path)
+	if err == nil || errors.Is(err, fs.ErrExist) {
+		return err
+	}
+	if _, serr := os.Lstat(path); !errors.Is(serr, fs.ErrNotExist) {
+		return &fs.PathError{Op: "create", Path: path, Err: fs.ErrExist}
+	}
+	return renameFile(tmp, path)
+}
+
+// writeFile is the atomic write behind writeJSON. A symlinked path is
+// written through: the temporary file is renamed over the link's target,
+// not over the link.
+func writeFile(path string, data []byte) error {
+	return placeFile(path, data, renameFile)
+}
+
+// placeFile writes data to a temporary file beside path and has place
+// move it to path.
+func placeFile(path string, data []byte, place func(tmp, path string) error) error {
+	path, err := ResolvePath(path)
+	if err != nil {
+		return fmt.Errorf("failed to write key file: %w", err)
+	}
+	dir := filepath.Dir(path)
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write key file: %w", err)
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp)
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write key file: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to sync key file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write key file: %w", err)
+	}
+	if err := place(tmp, path); err != nil {
+		return fmt.Errorf("failed to write key file: %w", err)
+	}
+	// Persist the rename itself. Not every platform can sync a directory,
+	// and the file is already in place, so failure here is not reported.
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
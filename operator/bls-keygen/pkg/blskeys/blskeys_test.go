@@ -0,0 +1,489 @@
+package blskeys
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+	"golang.org/x/crypto/sha3"
+)
+
+// testScrypt keeps the KDF cheap so tests run quickly.
+var testScrypt = KDFParams{N: 1 << 10, R: 8, P: 1, DKLen: 32}
+
+func TestMain(m *testing.M) {
+	DefaultScryptParams = testScrypt
+	os.Exit(m.Run())
+}
+
+func TestGenerate(t *testing.T) {
+	kp, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bls.PubKeysMatch(kp.G1PubKey, kp.G2PubKey) {
+		t.Fatal("generated public keys do not match")
+	}
+
+	other, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(kp.PrivateKey.Bytes(), other.PrivateKey.Bytes()) {
+		t.Fatal("two generated keys are identical")
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	kp, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "bls_key.json")
+	if err := Save(kp, path, "correct horse battery staple"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte(hex.EncodeToString(kp.PrivateKey.Bytes()))) {
+		t.Fatal("key file contains the plaintext private key")
+	}
+
+	loaded, err := Load(path, "correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(loaded.PrivateKey.Bytes(), kp.PrivateKey.Bytes()) {
+		t.Fatal("loaded private key does not match")
+	}
+	if !bytes.Equal(loaded.G2PubKey.Bytes(), kp.G2PubKey.Bytes()) {
+		t.Fatal("loaded G2 public key does not match")
+	}
+
+	if _, err := Load(path, "wrong password"); !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("wrong password: got %v, want ErrDecrypt", err)
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.json"), "pw"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("got %v, want os.ErrNotExist", err)
+	}
+}
+
+// TestLoadErrorSentinels checks that every way a key file can fail to load
+// matches exactly one of the sentinel errors callers branch on.
+func TestLoadErrorSentinels(t *testing.T) {
+	sentinels := []error{ErrKeyNotFound, ErrBadPassword, ErrCorruptKeyfile, ErrInvalidKey}
+	kp, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	saveTampered := func(name string, tamper func(kf *KeyFile)) string {
+		kf, err := Encrypt(kp, "pw", testScrypt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tamper(kf)
+		if kf.Checksum, err = kf.computeChecksum(); err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, name)
+		if err := writeJSON(path, kf); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	jsonKey := filepath.Join(dir, "key.json")
+	if err := Save(kp, jsonKey, "pw"); err != nil {
+		t.Fatal(err)
+	}
+	binaryKey := filepath.Join(dir, "key.bin")
+	if err := SaveBinaryContext(context.Background(), kp, binaryKey, "pw", testScrypt, nil); err != nil {
+		t.Fatal(err)
+	}
+	keystore := filepath.Join(dir, "keystore.json")
+	if err := SaveEIP2335(kp, keystore, "pw"); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "missing.json")
+	garbage := write("garbage.json", []byte("{not json"))
+	future := write("future.json", []byte(`{"version": 99}`))
+	badChecksum := saveTampered("checksum.json", func(*KeyFile) {})
+	data, err := os.ReadFile(badChecksum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	badChecksum = write("checksum.json", bytes.Replace(data, []byte(`"checksum": "`), []byte(`"checksum": "00`), 1))
+	mismatch := saveTampered("mismatch.json", func(kf *KeyFile) { kf.G1PubKey = fmt.Sprintf("0x%x", other.G1PubKey.Bytes()) })
+	notOnCurve := saveTampered("point.json", func(kf *KeyFile) { kf.G2PubKey = "0x" + strings.Repeat("ff", 96) })
+
+	load := func(path string) func() error {
+		return func() error { _, err := Load(path, "pw"); return err }
+	}
+	tests := []struct {
+		name string
+		run  func() error
+		want error
+	}{
+		{"Load missing", load(missing), ErrKeyNotFound},
+		{"LoadPublicKey missing", func() error { _, err := LoadPublicKey(missing); return err }, ErrKeyNotFound},
+		{"LoadMetadata missing", func() error { _, err := LoadMetadata(missing); return err }, ErrKeyNotFound},
+		{"LoadEIP2335 missing", func() error { _, err := LoadEIP2335(missing, "pw"); return err }, ErrKeyNotFound},
+		{"Migrate missing", func() error { _, err := Migrate(missing, "pw"); return err }, ErrKeyNotFound},
+		{"ChangePassword missing", func() error { return ChangePassword(missing, "pw", "new") }, ErrKeyNotFound},
+		{"RepairPublicKeys missing", func() error { _, err := RepairPublicKeys(missing, "pw"); return err }, ErrKeyNotFound},
+		{"JSON wrong password", func() error { _, err := Load(jsonKey, "wrong"); return err }, ErrBadPassword},
+		{"binary wrong password", func() error { _, err := Load(binaryKey, "wrong"); return err }, ErrBadPassword},
+		{"EIP-2335 wrong password", func() error { _, err := Load(keystore, "wrong"); return err }, ErrBadPassword},
+		{"unparseable", load(garbage), ErrCorruptKeyfile},
+		{"unsupported version", load(future), ErrCorruptKeyfile},
+		{"checksum mismatch", load(badChecksum), ErrCorruptKeyfile},
+		{"LoadPublicKey unparseable", func() error { _, err := LoadPublicKey(garbage); return err }, ErrCorruptKeyfile},
+		{"public key of another key", load(mismatch), ErrInvalidKey},
+		{"public key not on the curve", load(notOnCurve), ErrInvalidKey},
+		{"LoadPublicKeys not on the curve", func() error { _, _, err := LoadPublicKeys(notOnCurve); return err }, ErrInvalidKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.run()
+			for _, s := range sentinels {
+				if got, want := errors.Is(err, s), s == tt.want; got != want {
+					t.Errorf("errors.Is(%v, %v) = %v, want %v", err, s, got, want)
+				}
+			}
+		})
+	}
+
+	if _, err := Load(missing, "pw"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("missing file: %v does not wrap os.ErrNotExist", err)
+	}
+	if !errors.Is(ErrChecksum, ErrBadPassword) || !errors.Is(ErrPubPrivMismatch, ErrInvalidKey) {
+		t.Error("ErrChecksum and ErrPubPrivMismatch must match their sentinel")
+	}
+}
+
+func TestLoadRejectsTamperedPubKeys(t *testing.T) {
+	// On-curve points outside the prime-order subgroups.
+	const (
+		nonSubgroupG1 = "0x800000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000004"
+		nonSubgroupG2 = "0x800000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000002"
+	)
+	other, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		tamper func(kf *KeyFile)
+		want   error
+	}{
+		{"G1 outside subgroup", func(kf *KeyFile) { kf.G1PubKey = nonSubgroupG1 }, ErrNotInSubgroup},
+		{"G2 outside subgroup", func(kf *KeyFile) { kf.G2PubKey = nonSubgroupG2 }, ErrNotInSubgroup},
+		{"G1 of another key", func(kf *KeyFile) { kf.G1PubKey = fmt.Sprintf("0x%x", other.G1PubKey.Bytes()) }, ErrPubPrivMismatch},
+		{"G2 of another key", func(kf *KeyFile) { kf.G2PubKey = fmt.Sprintf("0x%x", other.G2PubKey.Bytes()) }, ErrPubPrivMismatch},
+		{"G1 truncated", func(kf *KeyFile) { kf.G1PubKey = kf.G1PubKey[:len(kf.G1PubKey)-2] }, bls.ErrInvalidPoint},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kp, err := Generate()
+			if err != nil {
+				t.Fatal(err)
+			}
+			kf, err := Encrypt(kp, "pw", testScrypt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.tamper(kf)
+			// A deliberate tamperer can recompute the unkeyed checksum.
+			if kf.Checksum, err = kf.computeChecksum(); err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(t.TempDir(), "bls_key.json")
+			if err := writeJSON(path, kf); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := Load(path, "pw"); !errors.Is(err, tt.want) {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadPublicKey(t *testing.T) {
+	kp, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	bastion := filepath.Join(dir, "bastion.json")
+	if err := Save(kp, bastion, "pw"); err != nil {
+		t.Fatal(err)
+	}
+	keystore := filepath.Join(dir, "keystore.json")
+	if err := SaveEIP2335(kp, keystore, "pw"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{bastion, keystore} {
+		pk, err := LoadPublicKey(path)
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		if !bytes.Equal(pk.Bytes(), kp.G1PubKey.Bytes()) {
+			t.Fatalf("%s: public key does not match", path)
+		}
+	}
+
+	notKey := filepath.Join(dir, "notes.json")
+	if err := os.WriteFile(notKey, []byte(`{"hello":"world"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadPublicKey(notKey); err == nil {
+		t.Fatal("expected an error for a file without a public key")
+	}
+}
+
+func TestLoadDetectsCorruption(t *testing.T) {
+	kp, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "bls_key.json")
+	if err := Save(kp, path, "pw"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kf KeyFile
+	if err := json.Unmarshal(data, &kf); err != nil {
+		t.Fatal(err)
+	}
+	if kf.Checksum == "" {
+		t.Fatal("saved key file has no checksum")
+	}
+
+	for _, field := range []string{kf.Crypto.Ciphertext, kf.Crypto.KDFParams.Salt, kf.G2PubKey} {
+		i := bytes.Index(data, []byte(field)) + len(field) - 1
+		corrupted := append([]byte(nil), data...)
+		if corrupted[i] == '0' {
+			corrupted[i] = '1'
+		} else {
+			corrupted[i] = '0'
+		}
+		if err := os.WriteFile(path, corrupted, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load(path, "pw"); !errors.Is(err, ErrCorruptKeyfile) {
+			t.Fatalf("flipped byte in %q: got %v, want ErrCorruptKeyfile", field, err)
+		}
+	}
+
+	if err := os.WriteFile(path, data[:len(data)/2], 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path, "pw"); !errors.Is(err, ErrCorruptKeyfile) {
+		t.Fatalf("truncated file: got %v, want ErrCorruptKeyfile", err)
+	}
+
+	kf.Checksum = ""
+	stripped, err := json.Marshal(&kf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, stripped, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path, "pw"); !errors.Is(err, ErrCorruptKeyfile) {
+		t.Fatalf("missing checksum: got %v, want ErrCorruptKeyfile", err)
+	}
+}
+
+func TestChecksumEqual(t *testing.T) {
+	sum := sha256.Sum256([]byte("key file"))
+	flipped := sum
+	flipped[len(flipped)-1] ^= 1
+	tests := []struct {
+		got, want []byte
+		equal     bool
+	}{
+		{sum[:], sum[:], true},
+		{flipped[:], sum[:], false},
+		{sum[:16], sum[:], false},
+		{nil, sum[:], false},
+		{nil, nil, true},
+	}
+	for i, tt := range tests {
+		if got := checksumEqual(tt.got, tt.want); got != tt.equal {
+			t.Errorf("case %d: checksumEqual = %v, want %v", i, got, tt.equal)
+		}
+	}
+}
+
+func TestEIP2335ChecksumMismatch(t *testing.T) {
+	kp, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks, err := EncryptEIP2335(kp, "pw", testScrypt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := decryptEIP2335(ks, "pw"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := decryptEIP2335(ks, "wrong"); !errors.Is(err, ErrChecksum) {
+		t.Fatalf("wrong password: got %v, want ErrChecksum", err)
+	}
+	msg := []byte(ks.Crypto.Checksum.Message)
+	if msg[0] == '0' {
+		msg[0] = '1'
+	} else {
+		msg[0] = '0'
+	}
+	ks.Crypto.Checksum.Message = string(msg)
+	if _, err := decryptEIP2335(ks, "pw"); !errors.Is(err, ErrChecksum) {
+		t.Fatalf("tampered checksum: got %v, want ErrChecksum", err)
+	}
+}
+
+func TestWriteFailureBeforeRenameKeepsOriginal(t *testing.T) {
+	kp, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bls_key.json")
+	if err := Save(kp, path, "pw"); err != nil {
+		t.Fatal(err)
+	}
+	original, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	crash := errors.New("simulated crash")
+	renameFile = func(string, string) error { return crash }
+	defer func() { renameFile = os.Rename }()
+
+	other, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1700000000, 0)
+	writes := map[string]func() error{
+		"save":   func() error { return Save(other, path, "pw") },
+		"passwd": func() error { return ChangePassword(path, "pw", "new") },
+		"rotate": func() error { _, _, err := Rotate(path, "pw", "new", now); return err },
+	}
+	for name, write := range writes {
+		if err := write(); !errors.Is(err, crash) {
+			t.Fatalf("%s: got %v, want the simulated failure", name, err)
+		}
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, original) {
+			t.Fatalf("%s: original key file modified", name)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("leftover files after failed writes: %v", entries)
+	}
+
+	fresh := filepath.Join(dir, "new.json")
+	if err := Save(other, fresh, "pw"); !errors.Is(err, crash) {
+		t.Fatalf("got %v, want the simulated failure", err)
+	}
+	if _, err := os.Stat(fresh); !os.IsNotExist(err) {
+		t.Fatal("partial key file left at the destination")
+	}
+}
+
+func TestGenerateNDeterministic(t *testing.T) {
+	seeded := func() io.Reader {
+		h := sha3.NewShake256()
+		h.Write([]byte("bastion fake operators"))
+		return h
+	}
+	first, err := GenerateN(seeded(), 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := GenerateN(seeded(), 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seen := make(map[string]bool)
+	for i := range first {
+		a, b := first[i].PrivateKey.Bytes(), second[i].PrivateKey.Bytes()
+		if !bytes.Equal(a, b) {
+			t.Errorf("key %d differs between runs with the same seed", i)
+		}
+		if seen[string(a)] {
+			t.Errorf("key %d repeats an earlier key", i)
+		}
+		seen[string(a)] = true
+	}
+
+	if _, err := GenerateN(seeded(), 0); err == nil {
+		t.Error("GenerateN(0) succeeded")
+	}
+}
+
+func TestWriteNewFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bls_key.json")
+	if err := WriteNewFile(path, []byte("first")); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteNewFile(path, []byte("second")); !errors.Is(err, fs.ErrExist) {
+		t.Fatalf("existing file: got %v, want fs.ErrExist", err)
+	}
+	if got, _ := os.ReadFile(path); string(got) != "first" {
+		t.Fatalf("existing file replaced with %q", got)
+	}
+	if tmps, _ := filepath.Glob(filepath.Join(filepath.Dir(path), ".*.tmp-*")); len(tmps) != 0 {
+		t.Fatalf("temporary files left behind: %v", tmps)
+	}
+
+}
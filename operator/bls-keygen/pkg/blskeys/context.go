@@ -0,0 +1,165 @@
+package blskeys
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"io"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+)
+
+// GenerateContext is Generate bounded by ctx: it returns ctx.Err() if ctx
+// is done before or while entropy is collected.
+func GenerateContext(ctx context.Context) (*KeyPair, error) {
+	return generate(ctx, rand.Reader)
+}
+
+// generate samples a key pair from r, giving up once ctx is done.
+func generate(ctx context.Context, r io.Reader) (*KeyPair, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	kp, err := bls.GenerateKeyPair(ctxReader{ctx: ctx, r: r})
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, err
+	}
+	if !bls.PubKeysMatch(kp.G1PubKey, kp.G2PubKey) {
+		kp.PrivateKey.Zero()
+		return nil, errors.New("generated G1 and G2 public keys do not match")
+	}
+	return kp, nil
+}
+
+// SaveContext is Save with explicit scrypt parameters and optional
+// metadata, bounded by ctx. scrypt itself cannot be interrupted, so on
+// cancellation the derivation is abandoned in the background and nothing
+// is written.
+func SaveContext(ctx context.Context, kp *KeyPair, path, password string, params KDFParams, meta *Metadata) error {
+	data, err := MarshalContext(ctx, kp, password, params, meta)
+	if err != nil {
+		return err
+	}
+	return writeFile(path, data)
+}
+
+// MarshalContext is SaveContext returning the encoded key file instead of
+// writing it, for callers that store it somewhere other than a file.
+func MarshalContext(ctx context.Context, kp *KeyPair, password string, params KDFParams, meta *Metadata) ([]byte, error) {
+	kf, err := encryptContext(ctx, kp, password, params, meta)
+	if err != nil {
+		return nil, err
+	}
+	return marshalKeyFile(kf)
+}
+
+// encryptContext is Encrypt bounded by ctx, with meta attached.
+func encryptContext(ctx context.Context, kp *KeyPair, password string, params KDFParams, meta *Metadata) (*KeyFile, error) {
+	kf, err := withContext(ctx, func() (*KeyFile, error) {
+		return Encrypt(kp, password, params)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if meta != nil {
+		kf.Metadata = meta
+		if kf.Checksum, err = kf.computeChecksum(); err != nil {
+			return nil, err
+		}
+	}
+	return kf, nil
+}
+
+// SaveEIP2335Context is SaveEIP2335 with explicit scrypt parameters,
+// bounded by ctx like SaveContext. EIP-2335 has no metadata section, so
+// meta is recorded in the keystore description.
+func SaveEIP2335Context(ctx context.Context, kp *KeyPair, path, password string, params KDFParams, meta *Metadata) error {
+	data, err := MarshalEIP2335Context(ctx, kp, password, params, meta)
+	if err != nil {
+		return err
+	}
+	return writeFile(path, data)
+}
+
+// MarshalEIP2335Context is SaveEIP2335Context returning the encoded
+// keystore instead of writing it.
+func MarshalEIP2335Context(ctx context.Context, kp *KeyPair, password string, params KDFParams, meta *Metadata) ([]byte, error) {
+	ks, err := withContext(ctx, func() (*EIP2335Keystore, error) {
+		return EncryptEIP2335(kp, password, params)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if meta != nil && meta.Network != "" {
+		ks.Description = "network: " + meta.Network
+	}
+	return marshalKeyFile(ks)
+}
+
+// KDFEvent is what a KDFProgress callback is told.
+type KDFEvent int
+
+const (
+	// KDFStarted is reported before the password is stretched.
+	KDFStarted KDFEvent = iota
+	// KDFFinished is reported once decryption is over, whether or not it
+	// succeeded.
+	KDFFinished
+)
+
+// KDFProgress is called by LoadContext around key derivation, which takes
+// seconds with hardened parameters. The KDFs report nothing while they run,
+// so callers that want to show activity in between animate it themselves.
+type KDFProgress func(KDFEvent)
+
+// LoadContext is Load bounded by ctx. If progress is not nil it is called
+// with KDFStarted before decrypting and KDFFinished after.
+func LoadContext(ctx context.Context, path, password string, progress KDFProgress) (*KeyPair, error) {
+	if progress != nil {
+		progress(KDFStarted)
+		defer progress(KDFFinished)
+	}
+	return withContext(ctx, func() (*KeyPair, error) {
+		return Load(path, password)
+	})
+}
+
+// withContext runs f in its own goroutine and returns its result, or
+// ctx.Err() as soon as ctx is done.
+func withContext[T any](ctx context.Context, f func() (T, error)) (T, error) {
+	var zero T
+	if err := ctx.Err(); err != nil {
+		return zero, err
+	}
+	type result struct {
+		v   T
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		v, err := f()
+		done <- result{v, err}
+	}()
+	select {
+	case r := <-done:
+		return r.v, r.err
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}
+
+// ctxReader fails reads once ctx is done.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c ctxReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
@@ -0,0 +1,84 @@
+package blskeys
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGenerateContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := GenerateContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+}
+
+func TestSaveContextCancelled(t *testing.T) {
+	kp, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "bls_key.json")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, save := range []func(context.Context, *KeyPair, string, string, KDFParams, *Metadata) error{SaveContext, SaveEIP2335Context} {
+		if err := save(ctx, kp, path, "pw", DefaultScryptParams, nil); !errors.Is(err, context.Canceled) {
+			t.Fatalf("got %v, want context.Canceled", err)
+		}
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Fatal("key file written after cancellation")
+		}
+	}
+}
+
+func TestWithContextAbandonsWork(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	release := make(chan struct{})
+	defer close(release)
+
+	go cancel()
+	_, err := withContext(ctx, func() (int, error) {
+		<-release // a KDF that outlives the context
+		return 0, nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+}
+
+func TestLoadContextProgress(t *testing.T) {
+	kp, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "bls_key.json")
+	if err := SaveContext(context.Background(), kp, path, "pw", testScrypt, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var events []KDFEvent
+	if _, err := LoadContext(context.Background(), path, "pw", func(e KDFEvent) { events = append(events, e) }); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0] != KDFStarted || events[1] != KDFFinished {
+		t.Fatalf("events %v, want [KDFStarted KDFFinished]", events)
+	}
+
+	events = nil
+	if _, err := LoadContext(context.Background(), path, "wrong", func(e KDFEvent) { events = append(events, e) }); !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("got %v, want ErrDecrypt", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("events %v after a failed decrypt, want start and finish", events)
+	}
+
+	if _, err := LoadContext(context.Background(), path, "pw", nil); err != nil {
+		t.Fatalf("nil progress: %v", err)
+	}
+}
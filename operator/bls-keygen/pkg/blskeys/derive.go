@@ -0,0 +1,170 @@
+package blskeys
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
+	"github.com/tyler-smith/go-bip39"
+	"golang.org/x/crypto/hkdf"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+)
+
+// DefaultDerivationPath is the EIP-2334 signing key path for account 0.
+const DefaultDerivationPath = "m/12381/3600/0/0"
+
+// SigningKeyPath returns the EIP-2334 signing key path of account index,
+// m/12381/3600/<index>/0.
+func SigningKeyPath(index uint32) string {
+	return fmt.Sprintf("m/12381/3600/%d/0", index)
+}
+
+// avsIndexTag prefixes the AVS identifiers AVSIndex hashes.
+const avsIndexTag = "bastion/avs-id/"
+
+// AVSPath returns path with one more component, AVSIndex(avsID), so that
+// one mnemonic and path derive a different key for every AVS. EIP-2333
+// derivation is hardened: a child key reveals neither its parent nor its
+// siblings, so the key of one AVS exposes no other AVS's. An empty avsID
+// returns path unchanged.
+func AVSPath(path, avsID string) string {
+	if avsID == "" {
+		return path
+	}
+	return fmt.Sprintf("%s/%d", path, AVSIndex(avsID))
+}
+
+// AVSIndex maps an AVS identifier to an EIP-2333 child index: the first
+// four bytes, big-endian, of sha256("bastion/avs-id/" || avsID). Two
+// identifiers share an index, and so a key, with probability 2^-32.
+func AVSIndex(avsID string) uint32 {
+	h := sha256.Sum256([]byte(avsIndexTag + avsID))
+	return binary.BigEndian.Uint32(h[:4])
+}
+
+// ErrInvalidMnemonic is returned when a mnemonic fails BIP-39 validation.
+var ErrInvalidMnemonic = errors.New("invalid mnemonic: unknown word or bad checksum")
+
+// DeriveFromMnemonic derives the key at the EIP-2334 path from a BIP-39
+// mnemonic using the EIP-2333 tree. The mnemonic checksum is verified first
+// and no passphrase is used.
+func DeriveFromMnemonic(mnemonic string, path string) (*KeyPair, error) {
+	mnemonic = strings.Join(strings.Fields(mnemonic), " ")
+	seed, err := bip39.NewSeedWithErrorChecking(mnemonic, "")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMnemonic, err)
+	}
+	return DeriveFromSeed(seed, path)
+}
+
+// DeriveFromSeed derives the key at path from an EIP-2333 seed.
+func DeriveFromSeed(seed []byte, path string) (*KeyPair, error) {
+	indices, err := ParseDerivationPath(path)
+	if err != nil {
+		return nil, err
+	}
+	sk, err := deriveMasterSK(seed)
+	if err != nil {
+		return nil, err
+	}
+	for _, index := range indices {
+		sk = deriveChildSK(sk, index)
+	}
+
+	var b [bls.PrivateKeySize]byte
+	sk.FillBytes(b[:])
+	priv, err := bls.PrivateKeyFromBytes(b[:])
+	if err != nil {
+		return nil, err
+	}
+	return bls.NewKeyPair(priv), nil
+}
+
+// ParseDerivationPath parses an EIP-2334 path such as m/12381/3600/0/0.
+func ParseDerivationPath(path string) ([]uint32, error) {
+	parts := strings.Split(path, "/")
+	if parts[0] != "m" {
+		return nil, fmt.Errorf("invalid derivation path %q: must start with m", path)
+	}
+	indices := make([]uint32, 0, len(parts)-1)
+	for _, p := range parts[1:] {
+		i, err := strconv.ParseUint(p, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid derivation path %q: bad index %q", path, p)
+		}
+		indices = append(indices, uint32(i))
+	}
+	return indices, nil
+}
+
+func deriveMasterSK(seed []byte) (*big.Int, error) {
+	if len(seed) < 32 {
+		return nil, errors.New("seed must be at least 32 bytes")
+	}
+	return hkdfModR(seed, nil), nil
+}
+
+func deriveChildSK(parent *big.Int, index uint32) *big.Int {
+	return hkdfModR(parentSKToLamportPK(parent, index), nil)
+}
+
+// hkdfModR is HKDF_mod_r from EIP-2333.
+func hkdfModR(ikm, keyInfo []byte) *big.Int {
+	const l = 48
+	salt := []byte("BLS-SIG-KEYGEN-SALT-")
+	r := fr.Modulus()
+	sk := new(big.Int)
+	for sk.Sign() == 0 {
+		h := sha256.Sum256(salt)
+		salt = h[:]
+		prk := hkdf.Extract(sha256.New, append(append([]byte{}, ikm...), 0), salt)
+		info := append(append([]byte{}, keyInfo...), 0, l)
+		okm := make([]byte, l)
+		if _, err := io.ReadFull(hkdf.Expand(sha256.New, prk, info), okm); err != nil {
+			panic(err) // unreachable: l is far below the HKDF output limit
+		}
+		sk.SetBytes(okm).Mod(sk, r)
+	}
+	return sk
+}
+
+func parentSKToLamportPK(parent *big.Int, index uint32) []byte {
+	var salt [4]byte
+	binary.BigEndian.PutUint32(salt[:], index)
+	ikm := make([]byte, 32)
+	parent.FillBytes(ikm)
+	notIKM := make([]byte, 32)
+	for i := range ikm {
+		notIKM[i] = ^ikm[i]
+	}
+
+	lamportPK := make([]byte, 0, 2*255*32)
+	for _, k := range [][]byte{ikm, notIKM} {
+		for _, chunk := range ikmToLamportSK(k, salt[:]) {
+			h := sha256.Sum256(chunk)
+			lamportPK = append(lamportPK, h[:]...)
+		}
+	}
+	compressed := sha256.Sum256(lamportPK)
+	return compressed[:]
+}
+
+func ikmToLamportSK(ikm, salt []byte) [][]byte {
+	okm := make([]byte, 255*32)
+	prk := hkdf.Extract(sha256.New, ikm, salt)
+	if _, err := io.ReadFull(hkdf.Expand(sha256.New, prk, nil), okm); err != nil {
+		panic(err) // unreachable: 255*32 is exactly the HKDF-SHA256 limit
+	}
+	chunks := make([][]byte, 255)
+	for i := range chunks {
+		chunks[i] = okm[i*32 : (i+1)*32]
+	}
+	return chunks
+}
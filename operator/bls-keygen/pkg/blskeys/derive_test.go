@@ -0,0 +1,112 @@
+package blskeys
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+// EIP-2333 test case 0.
+const (
+	eip2333Seed    = "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04"
+	eip2333Master  = "6083874454709270928345386274498605044986640685124978867557563392430687146096"
+	eip2333ChildSK = "20397789859736650942317412262472558107875392172444076792671091975210932703118"
+)
+
+const testMnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+func TestEIP2333Vector(t *testing.T) {
+	seed, _ := hex.DecodeString(eip2333Seed)
+
+	master, err := deriveMasterSK(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if master.String() != eip2333Master {
+		t.Fatalf("master SK = %s, want %s", master, eip2333Master)
+	}
+
+	kp, err := DeriveFromSeed(seed, "m/0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := new(big.Int).SetString(eip2333ChildSK, 10)
+	if got := new(big.Int).SetBytes(kp.PrivateKey.Bytes()); got.Cmp(want) != 0 {
+		t.Fatalf("child SK = %s, want %s", got, want)
+	}
+}
+
+func TestDeriveFromMnemonicDeterministic(t *testing.T) {
+	a, err := DeriveFromMnemonic(testMnemonic, DefaultDerivationPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := DeriveFromMnemonic(testMnemonic, DefaultDerivationPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(a.PrivateKey.Bytes(), b.PrivateKey.Bytes()) {
+		t.Fatal("same mnemonic and path produced different keys")
+	}
+
+	c, err := DeriveFromMnemonic(testMnemonic, "m/12381/3600/1/0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(a.PrivateKey.Bytes(), c.PrivateKey.Bytes()) {
+		t.Fatal("different paths produced the same key")
+	}
+}
+
+func TestAVSPath(t *testing.T) {
+	// Keys already derived depend on this mapping; it must never change.
+	if got, want := AVSPath(DefaultDerivationPath, "eigenda"), "m/12381/3600/0/0/970959253"; got != want {
+		t.Fatalf("AVSPath = %s, want %s", got, want)
+	}
+	if got := AVSPath(DefaultDerivationPath, ""); got != DefaultDerivationPath {
+		t.Fatalf("AVSPath without an AVS = %s, want the path unchanged", got)
+	}
+
+	derive := func(avsID string) []byte {
+		t.Helper()
+		kp, err := DeriveFromMnemonic(testMnemonic, AVSPath(DefaultDerivationPath, avsID))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return kp.PrivateKey.Bytes()
+	}
+	a, b := derive("avs-a"), derive("avs-b")
+	if bytes.Equal(a, b) {
+		t.Fatal("two AVS ids produced the same key")
+	}
+	if bytes.Equal(a, derive("")) {
+		t.Fatal("an AVS id produced the key without one")
+	}
+	if !bytes.Equal(a, derive("avs-a")) {
+		t.Fatal("the same AVS id produced different keys")
+	}
+}
+
+func TestDeriveFromMnemonicRejectsBadChecksum(t *testing.T) {
+	bad := "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon"
+	if _, err := DeriveFromMnemonic(bad, DefaultDerivationPath); !errors.Is(err, ErrInvalidMnemonic) {
+		t.Fatalf("got %v, want ErrInvalidMnemonic", err)
+	}
+}
+
+func TestParseDerivationPath(t *testing.T) {
+	got, err := ParseDerivationPath("m/12381/3600/0/0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 4 || got[0] != 12381 || got[1] != 3600 {
+		t.Fatalf("got %v", got)
+	}
+	for _, bad := range []string{"", "12381/3600", "m/x", "m/12381/", "m/4294967296"} {
+		if _, err := ParseDerivationPath(bad); err == nil {
+			t.Errorf("ParseDerivationPath(%q) succeeded, want error", bad)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package blskeys
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+	"github.com/consensys/gnark-crypto/ecc/secp256k1"
+	"github.com/consensys/gnark-crypto/ecc/secp256k1/fr"
+	"golang.org/x/crypto/sha3"
+)
+
+// ecdsaKeystoreVersion is the "version" of go-ethereum's keystore format.
+const ecdsaKeystoreVersion = 3
+
+// ECDSAKey is a secp256k1 key pair: the ECDSA key that is an EigenLayer
+// operator's Ethereum identity, kept next to its BLS key.
+type ECDSAKey struct {
+	// D is the private scalar, 32 bytes big-endian.
+	D bls.SecretBytes
+	// Address is the Ethereum address of the public key.
+	Address ECDSAAddress
+}
+
+// ECDSAAddress is an Ethereum address: the last 20 bytes of the keccak256
+// hash of the uncompressed public key.
+type ECDSAAddress [20]byte
+
+// Hex returns a in its EIP-55 mixed-case checksum encoding.
+func (a ECDSAAddress) Hex() string {
+	lower := hex.EncodeToString(a[:])
+	h := sha3.NewLegacyKeccak256()
+	h.Write([]byte(lower))
+	sum := h.Sum(nil)
+	out := []byte(lower)
+	for i, c := range out {
+		if c >= 'a' && sum[i/2]>>(4*(1-uint(i%2)))&0xf >= 8 {
+			out[i] = c - 'a' + 'A'
+		}
+	}
+	return "0x" + string(out)
+}
+
+// Zero overwrites the private scalar.
+func (k *ECDSAKey) Zero() {
+	k.D.Zero()
+}
+
+// GenerateECDSA creates a new secp256k1 key from rng, which production
+// callers pass as crypto/rand.Reader.
+func GenerateECDSA(rng io.Reader) (*ECDSAKey, error) {
+	d := make(bls.SecretBytes, 32)
+	defer d.Zero()
+	// Rejection sampling: all but a 2^-128 fraction of 256-bit strings are
+	// valid scalars, so this almost never loops.
+	for {
+		if _, err := io.ReadFull(rng, d); err != nil {
+			return nil, fmt.Errorf("failed to read entropy: %w", err)
+		}
+		if k, err := ECDSAKeyFromBytes(d); err == nil {
+			return k, nil
+		}
+	}
+}
+
+// ECDSAKeyFromBytes returns the key with the 32-byte big-endian private
+// scalar d, which must be in [1, n). d is copied.
+func ECDSAKeyFromBytes(d []byte) (*ECDSAKey, error) {
+	if len(d) != 32 {
+		return nil, fmt.Errorf("%w: ECDSA private key is %d bytes, want 32", ErrInvalidKey, len(d))
+	}
+	s := new(big.Int).SetBytes(d)
+	if s.Sign() == 0 || s.Cmp(fr.Modulus()) >= 0 {
+		return nil, fmt.Errorf("%w: ECDSA private key is not a valid secp256k1 scalar", ErrInvalidKey)
+	}
+	var pub secp256k1.G1Affine
+	pub.ScalarMultiplicationBase(s)
+	s.SetInt64(0)
+	xy := pub.RawBytes()
+
+	k := &ECDSAKey{D: append(bls.SecretBytes(nil), d...)}
+	h := sha3.NewLegacyKeccak256()
+	h.Write(xy[:])
+	copy(k.Address[:], h.Sum(nil)[12:])
+	return k, nil
+}
+
+// ECDSAKeystore is a go-ethereum version 3 keystore, the format geth,
+// clef and the EigenLayer CLI read operator ECDSA keys from.
+type ECDSAKeystore struct {
+	Address string      `json:"address"`
+	Crypto  ECDSACrypto `json:"crypto"`
+	ID      string      `json:"id"`
+	Version int         `json:"version"`
+}
+
+// ECDSACrypto is the crypto section of an ECDSAKeystore. KDFParams are
+// kept raw because their shape depends on KDF.
+type ECDSACrypto struct {
+	Cipher       string            `json:"cipher"`
+	CipherText   string            `json:"ciphertext"`
+	CipherParams ECDSACipherParams `json:"cipherparams"`
+	KDF          string            `json:"kdf"`
+	KDFParams    json.RawMessage   `json:"kdfparams"`
+	MAC          string            `json:"mac"`
+}
+
+// ECDSACipherParams holds the AES-128-CTR initialization vector.
+type ECDSACipherParams struct {
+	IV string `json:"iv"`
+}
+
+// EncryptECDSA encrypts k with password into a version 3 keystore. The
+// KDF is scrypt or PBKDF2-HMAC-SHA256, as params select.
+func EncryptECDSA(k *ECDSAKey, password string, params KDFParams) (*ECDSAKeystore, error) {
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(iv); err != nil {
+		return nil, fmt.Errorf("failed to generate iv: %w", err)
+	}
+	id, err := newUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	// go-ethereum names the KDF parameters like EIP-2335 does.
+	var kdfParams interface{} = eip2335ScryptParams{DKLen: 32, N: params.N, R: params.R, P: params.P, Salt: hex.EncodeToString(salt)}
+	if params.function() == kdfPBKDF2 {
+		kdfParams = eip2335PBKDF2Params{DKLen: 32, C: params.C, PRF: params.PRF, Salt: hex.EncodeToString(salt)}
+	}
+	params.DKLen = 32
+	dk, err := deriveKey([]byte(password), salt, params.function(), params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	defer bls.SecretBytes(dk).Zero()
+
+	ciphertext, err := aes128CTR(dk[:16], iv, k.D)
+	if err != nil {
+		return nil, err
+	}
+	kdfJSON, err := json.Marshal(kdfParams)
+	if err != nil {
+		return nil, err
+	}
+	return &ECDSAKeystore{
+		Address: hex.EncodeToString(k.Address[:]),
+		Crypto: ECDSACrypto{
+			Cipher:       "aes-128-ctr",
+			CipherText:   hex.EncodeToString(ciphertext),
+			CipherParams: ECDSACipherParams{IV: hex.EncodeToString(iv)},
+			KDF:          params.function(),
+			KDFParams:    kdfJSON,
+			MAC:          hex.EncodeToString(ecdsaMAC(dk, ciphertext)),
+		},
+		ID:      id,
+		Version: ecdsaKeystoreVersion,
+	}, nil
+}
+
+// ecdsaMAC is the version 3 keystore MAC, keccak256 of the second half of
+// the derived key followed by the ciphertext.
+func ecdsaMAC(dk, ciphertext []byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	h.Write(dk[16:32])
+	h.Write(ciphertext)
+	return h.Sum(nil)
+}
+
+// DecryptECDSA recovers the key in ks. The MAC is checked before anything
+// is decrypted, and the decrypted key must have the keystore's address.
+func DecryptECDSA(ks *ECDSAKeystore, password string) (*ECDSAKey, error) {
+	if ks.Version != ecdsaKeystoreVersion {
+		return nil, fmt.Errorf("%w: unsupported keystore version %d", ErrCorruptKeyfile, ks.Version)
+	}
+	if ks.Crypto.Cipher != "aes-128-ctr" {
+		return nil, fmt.Errorf("%w: unsupported cipher %q", ErrCorruptKeyfile, ks.Crypto.Cipher)
+	}
+	ciphertext, err := hex.DecodeString(ks.Crypto.CipherText)
+	if err != nil {
+		return nil, fmt.Errorf("%w: ciphertext: %v", ErrCorruptKeyfile, err)
+	}
+	iv, err := hex.DecodeString(ks.Crypto.CipherParams.IV)
+	if err != nil {
+		return nil, fmt.Errorf("%w: iv: %v", ErrCorruptKeyfile, err)
+	}
+	mac, err := hex.DecodeString(ks.Crypto.MAC)
+	if err != nil {
+		return nil, fmt.Errorf("%w: mac: %v", ErrCorruptKeyfile, err)
+	}
+
+	dk, err := eip2335DecryptionKey(&EIP2335Module{Function: ks.Crypto.KDF, Params: ks.Crypto.KDFParams}, []byte(password))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorruptKeyfile, err)
+	}
+	defer bls.SecretBytes(dk).Zero()
+	if !checksumEqual(ecdsaMAC(dk, ciphertext), mac) {
+		return nil, ErrBadPassword
+	}
+
+	d, err := aes128CTR(dk[:16], iv, ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorruptKeyfile, err)
+	}
+	defer bls.SecretBytes(d).Zero()
+	k, err := ECDSAKeyFromBytes(d)
+	if err != nil {
+		return nil, err
+	}
+	if ks.Address != "" && !strings.EqualFold(strings.TrimPrefix(ks.Address, "0x"), hex.EncodeToString(k.Address[:])) {
+		k.Zero()
+		return nil, fmt.Errorf("%w: keystore address %s does not match its key", ErrInvalidKey, ks.Address)
+	}
+	return k, nil
+}
+
+// SaveECDSAContext encrypts k with password and params and writes it to
+// path as a version 3 keystore. Like SaveContext, it gives up when ctx is
+// done while the KDF runs.
+func SaveECDSAContext(ctx context.Context, k *ECDSAKey, path, password string, params KDFParams) error {
+	data, err := MarshalECDSAContext(ctx, k, password, params)
+	if err != nil {
+		return err
+	}
+	return writeFile(path, data)
+}
+
+// MarshalECDSAContext is SaveECDSAContext returning the encoded keystore
+// instead of writing it.
+func MarshalECDSAContext(ctx context.Context, k *ECDSAKey, password string, params KDFParams) ([]byte, error) {
+	ks, err := withContext(ctx, func() (*ECDSAKeystore, error) {
+		return EncryptECDSA(k, password, params)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return marshalKeyFile(ks)
+}
+
+// LoadECDSA reads and decrypts the version 3 keystore at path.
+func LoadECDSA(path, password string) (*ECDSAKey, error) {
+	data, err := readKeyFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var ks ECDSAKeystore
+	if err := json.Unmarshal(data, &ks); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorruptKeyfile, err)
+	}
+	return DecryptECDSA(&ks, password)
+}
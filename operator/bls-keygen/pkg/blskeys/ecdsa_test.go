@@ -0,0 +1,86 @@
+package blskeys
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestECDSAAddress(t *testing.T) {
+	// The key with scalar 1 is the generator, whose address is well known.
+	d := make([]byte, 32)
+	d[31] = 1
+	k, err := ECDSAKeyFromBytes(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := k.Address.Hex(), "0x7E5F4552091A69125d5DfCb7b8C2659029395Bdf"; got != want {
+		t.Fatalf("address %s, want %s", got, want)
+	}
+
+	for _, bad := range [][]byte{make([]byte, 32), bytes.Repeat([]byte{0xff}, 32), d[:31]} {
+		if _, err := ECDSAKeyFromBytes(bad); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("%x: got %v, want ErrInvalidKey", bad, err)
+		}
+	}
+}
+
+func TestECDSAKeystoreTestVector(t *testing.T) {
+	// The PBKDF2 test vector of the Web3 Secret Storage definition.
+	const keystore = `{"crypto":{"cipher":"aes-128-ctr","cipherparams":{"iv":"6087dab2f9fdbbfaddc31a909735c1e6"},"ciphertext":"5318b4d5bcd28de64ee5559e671353e16f075ecae9f99c7a79a38af5f869aa46","kdf":"pbkdf2","kdfparams":{"c":262144,"dklen":32,"prf":"hmac-sha256","salt":"ae3cd4e7013836a3df6bd7241b12db061dbe2c6785853cce422d148a624ce0bd"},"mac":"517ead924a9d0dc3124507e3393d175ce3ff7c1e96529c6c555ce9e51205e9b2"},"id":"3198bc9c-6672-5ab3-d995-4942343ae5b6","version":3}`
+	var ks ECDSAKeystore
+	if err := json.Unmarshal([]byte(keystore), &ks); err != nil {
+		t.Fatal(err)
+	}
+	k, err := DecryptECDSA(&ks, "testpassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := hex.EncodeToString(k.D), "7a28b5ba57c53603b0b07b56bba752f7784bf506fa95edc395f5cf6c7514fe9d"; got != want {
+		t.Fatalf("private key %s, want %s", got, want)
+	}
+	if _, err := DecryptECDSA(&ks, "wrong"); !errors.Is(err, ErrBadPassword) {
+		t.Fatalf("wrong password: got %v, want ErrBadPassword", err)
+	}
+}
+
+func TestSaveLoadECDSA(t *testing.T) {
+	k, err := GenerateECDSA(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, params := range []KDFParams{testScrypt, {C: 1000, PRF: prfHMACSHA256, DKLen: 32}} {
+		path := filepath.Join(t.TempDir(), "ecdsa.json")
+		if err := SaveECDSAContext(context.Background(), k, path, "pw", params); err != nil {
+			t.Fatal(err)
+		}
+		loaded, err := LoadECDSA(path, "pw")
+		if err != nil {
+			t.Fatalf("%s: %v", params.function(), err)
+		}
+		if !bytes.Equal(loaded.D, k.D) || loaded.Address != k.Address {
+			t.Fatalf("%s: loaded key differs from the saved one", params.function())
+		}
+		if _, err := LoadECDSA(path, "wrong"); !errors.Is(err, ErrBadPassword) {
+			t.Fatalf("%s: wrong password: got %v, want ErrBadPassword", params.function(), err)
+		}
+	}
+
+	other, err := GenerateECDSA(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks, err := EncryptECDSA(k, "pw", testScrypt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks.Address = hex.EncodeToString(other.Address[:])
+	if _, err := DecryptECDSA(ks, "pw"); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("wrong address: got %v, want ErrInvalidKey", err)
+	}
+}
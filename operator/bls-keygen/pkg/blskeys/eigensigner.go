@@ -0,0 +1,143 @@
+package blskeys
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+)
+
+// EigenSigner has the method set of eigensdk-go's signer/bls/types.Signer,
+// so a key file can be handed to code written against that interface.
+// Everything it returns is BLS12-381: compressed signatures and public keys
+// as 0x hex, and bls.OperatorID operator IDs. eigensdk-go's own signers work
+// on BN254, so the values are not interchangeable with theirs.
+type EigenSigner interface {
+	// Sign hashes msg to G1 under bls.DST and signs it.
+	Sign(ctx context.Context, msg []byte) ([]byte, error)
+	// SignG1 signs msg, a message already hashed to a G1 point.
+	SignG1(ctx context.Context, msg []byte) ([]byte, error)
+	GetOperatorId() (string, error)
+	GetPublicKeyG1() string
+	GetPublicKeyG2() string
+}
+
+// lazySigner is the EigenSigner of a key file. The private key is decrypted
+// on the first signature and cached; Close clears it. mu is held for reading
+// while a signature is made, so signatures run in parallel and Close waits
+// for those in flight before zeroing the key.
+type lazySigner struct {
+	path string
+	g1   *bls.G1PubKey
+	g2   *bls.G2PubKey
+
+	mu       sync.RWMutex
+	password string
+	kp       *KeyPair
+	closed   bool
+}
+
+// NewBlsSigner returns an EigenSigner for the key file at path. Only the
+// cleartext public keys are read up front; the key is decrypted with
+// password when first needed, so a wrong password surfaces from the first
+// Sign. Files without a cleartext G2 key are decrypted immediately.
+//
+// The signer is safe for concurrent use: any number of goroutines may sign
+// at once, and Close may be called while they do. Signatures that started
+// before Close complete; later ones fail with ErrSignerClosed.
+func NewBlsSigner(path, password string) (EigenSigner, error) {
+	g1, g2, err := loadStoredPubKeys(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &lazySigner{path: path, g1: g1, g2: g2, password: password}
+	if g2 == nil {
+		kp, err := s.load()
+		if err != nil {
+			return nil, err
+		}
+		s.g2 = kp.G2PubKey
+	}
+	return s, nil
+}
+
+// load decrypts the key pair unless it already has been.
+func (s *lazySigner) load() (*KeyPair, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, ErrSignerClosed
+	}
+	if s.kp != nil {
+		return s.kp, nil
+	}
+	kp, err := Load(s.path, s.password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load key %s: %w", s.path, err)
+	}
+	s.kp, s.password = kp, ""
+	return kp, nil
+}
+
+// withKey calls sign with the key pair, loading it first if needed, and
+// holds the read lock until sign returns.
+func (s *lazySigner) withKey(ctx context.Context, sign func(*KeyPair) (*bls.Signature, error)) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	if s.kp == nil && !s.closed {
+		s.mu.RUnlock()
+		if _, err := s.load(); err != nil {
+			return nil, err
+		}
+		s.mu.RLock()
+	}
+	defer s.mu.RUnlock()
+	if s.closed {
+		return nil, ErrSignerClosed
+	}
+	sig, err := sign(s.kp)
+	if err != nil {
+		return nil, err
+	}
+	return sig.Bytes(), nil
+}
+
+func (s *lazySigner) Sign(ctx context.Context, msg []byte) ([]byte, error) {
+	return s.withKey(ctx, func(kp *KeyPair) (*bls.Signature, error) {
+		return kp.Sign(msg)
+	})
+}
+
+func (s *lazySigner) SignG1(ctx context.Context, msg []byte) ([]byte, error) {
+	return s.withKey(ctx, func(kp *KeyPair) (*bls.Signature, error) {
+		return kp.SignPoint(msg)
+	})
+}
+
+func (s *lazySigner) GetOperatorId() (string, error) {
+	return fmt.Sprintf("0x%x", bls.OperatorID(s.g1)), nil
+}
+
+func (s *lazySigner) GetPublicKeyG1() string {
+	return fmt.Sprintf("0x%x", s.g1.Bytes())
+}
+
+func (s *lazySigner) GetPublicKeyG2() string {
+	return fmt.Sprintf("0x%x", s.g2.Bytes())
+}
+
+// Close zeroes the cached key once signatures in flight have finished.
+// Signing fails with ErrSignerClosed afterwards.
+func (s *lazySigner) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	s.password = ""
+	if s.kp != nil {
+		s.kp.PrivateKey.Zero()
+	}
+	return nil
+}
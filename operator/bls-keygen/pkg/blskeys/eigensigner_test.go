@@ -0,0 +1,216 @@
+package blskeys
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+)
+
+func decodeHexPoint(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestNewBlsSigner(t *testing.T) {
+	kp, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "bls_key.json")
+	if err := Save(kp, path, "pw"); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewBlsSigner(path, "pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk, err := bls.G2PubKeyFromBytes(decodeHexPoint(t, s.GetPublicKeyG2()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := []byte("task response")
+	b, err := s.Sign(context.Background(), msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := bls.SignatureFromBytes(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bls.Verify(pk, msg, sig) {
+		t.Fatal("signature does not verify against the reported G2 key")
+	}
+
+	g1, err := bls.G1PubKeyFromBytes(decodeHexPoint(t, s.GetPublicKeyG1()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bls.PubKeysMatch(g1, pk) {
+		t.Fatal("reported G1 and G2 keys do not match")
+	}
+	id, err := s.GetOperatorId()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := fmt.Sprintf("0x%x", bls.OperatorID(kp.G1PubKey)); id != want {
+		t.Fatalf("operator id = %s, want %s", id, want)
+	}
+
+	h, err := bls12381.HashToG1(msg, []byte(bls.DST))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hb := h.Bytes()
+	b, err = s.SignG1(context.Background(), hb[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sig, err = bls.SignatureFromBytes(b); err != nil || !bls.Verify(pk, msg, sig) {
+		t.Fatalf("SignG1 signature does not verify: %v", err)
+	}
+}
+
+func TestNewBlsSignerDecryptsLazily(t *testing.T) {
+	kp, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "bls_key.json")
+	if err := Save(kp, path, "pw"); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewBlsSigner(path, "wrong")
+	if err != nil {
+		t.Fatalf("constructor should not decrypt: %v", err)
+	}
+	if _, err := s.Sign(context.Background(), []byte("m")); !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("got %v, want ErrDecrypt", err)
+	}
+}
+
+func TestNewBlsSignerEIP2335(t *testing.T) {
+	kp, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "keystore.json")
+	if err := SaveEIP2335Context(context.Background(), kp, path, "pw", testScrypt, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewBlsSigner(path, "pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := s.GetPublicKeyG2(), fmt.Sprintf("0x%x", kp.G2PubKey.Bytes()); got != want {
+		t.Fatalf("G2 key = %s, want %s", got, want)
+	}
+	if _, err := s.Sign(context.Background(), []byte("m")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewBlsSignerConcurrentSign(t *testing.T) {
+	kp, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "bls_key.json")
+	if err := SaveContext(context.Background(), kp, path, "pw", testScrypt, nil); err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewBlsSigner(path, "pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first signatures race to decrypt the key.
+	const workers, perWorker = 16, 4
+	sigs := make([][]byte, workers*perWorker)
+	errs := make([]error, len(sigs))
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				n := w*perWorker + i
+				sigs[n], errs[n] = s.Sign(context.Background(), []byte(fmt.Sprintf("task %d", n)))
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	for n, b := range sigs {
+		if errs[n] != nil {
+			t.Fatalf("signature %d: %v", n, errs[n])
+		}
+		sig, err := bls.SignatureFromBytes(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bls.Verify(kp.G2PubKey, []byte(fmt.Sprintf("task %d", n)), sig) {
+			t.Fatalf("signature %d does not verify", n)
+		}
+	}
+}
+
+func TestNewBlsSignerCloseWhileSigning(t *testing.T) {
+	kp, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "bls_key.json")
+	if err := SaveContext(context.Background(), kp, path, "pw", testScrypt, nil); err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewBlsSigner(path, "pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := []byte("closing")
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 4; i++ {
+				b, err := s.Sign(context.Background(), msg)
+				if errors.Is(err, ErrSignerClosed) {
+					return
+				}
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				// A signature made while Close ran must not use a
+				// half-zeroed key.
+				sig, err := bls.SignatureFromBytes(b)
+				if err != nil || !bls.Verify(kp.G2PubKey, msg, sig) {
+					t.Error("signature made during Close does not verify")
+					return
+				}
+			}
+		}()
+	}
+	s.(interface{ Close() error }).Close()
+	wg.Wait()
+	if _, err := s.Sign(context.Background(), msg); !errors.Is(err, ErrSignerClosed) {
+		t.Fatalf("after Close: got %v, want ErrSignerClosed", err)
+	}
+}
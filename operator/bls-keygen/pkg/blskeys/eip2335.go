@@ -0,0 +1,320 @@
+package blskeys
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+)
+
+const eip2335Version = 4
+
+// ErrChecksum is returned when an EIP-2335 checksum does not match. The
+// checksum is keyed by the password, so it matches ErrBadPassword.
+var ErrChecksum = fmt.Errorf("%w: keystore checksum mismatch", ErrBadPassword)
+
+// EIP2335Keystore is a keystore in the format defined by EIP-2335.
+type EIP2335Keystore struct {
+	Crypto      EIP2335Crypto `json:"crypto"`
+	Description string        `json:"description,omitempty"`
+	PubKey      string        `json:"pubkey"`
+	Path        string        `json:"path"`
+	UUID        string        `json:"uuid"`
+	Version     int           `json:"version"`
+}
+
+// EIP2335Crypto holds the kdf, checksum and cipher modules of a keystore.
+type EIP2335Crypto struct {
+	KDF      EIP2335Module `json:"kdf"`
+	Checksum EIP2335Module `json:"checksum"`
+	Cipher   EIP2335Module `json:"cipher"`
+}
+
+// EIP2335Module is one crypto module. Params are kept raw because their
+// shape depends on Function.
+type EIP2335Module struct {
+	Function string          `json:"function"`
+	Params   json.RawMessage `json:"params"`
+	Message  string          `json:"message"`
+}
+
+type eip2335ScryptParams struct {
+	DKLen int    `json:"dklen"`
+	N     int    `json:"n"`
+	R     int    `json:"r"`
+	P     int    `json:"p"`
+	Salt  string `json:"salt"`
+}
+
+type eip2335PBKDF2Params struct {
+	DKLen int    `json:"dklen"`
+	C     int    `json:"c"`
+	PRF   string `json:"prf"`
+	Salt  string `json:"salt"`
+}
+
+type eip2335CipherParams struct {
+	IV string `json:"iv"`
+}
+
+// SaveEIP2335 writes kp to path as an EIP-2335 keystore.
+func SaveEIP2335(kp *KeyPair, path, password string) error {
+	ks, err := EncryptEIP2335(kp, password, DefaultScryptParams)
+	if err != nil {
+		return err
+	}
+	return writeJSON(path, ks)
+}
+
+// EncryptEIP2335 builds an aes-128-ctr keystore for kp, with a scrypt or
+// PBKDF2 KDF module as params select.
+func EncryptEIP2335(kp *KeyPair, password string, params KDFParams) (*EIP2335Keystore, error) {
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(iv); err != nil {
+		return nil, fmt.Errorf("failed to generate iv: %w", err)
+	}
+	id, err := newUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	var kdfParams interface{} = eip2335ScryptParams{
+		DKLen: 32,
+		N:     params.N,
+		R:     params.R,
+		P:     params.P,
+		Salt:  hex.EncodeToString(salt),
+	}
+	if params.function() == kdfPBKDF2 {
+		kdfParams = eip2335PBKDF2Params{
+			DKLen: 32,
+			C:     params.C,
+			PRF:   params.PRF,
+			Salt:  hex.EncodeToString(salt),
+		}
+	}
+	params.DKLen = 32
+	dk, err := deriveKey(eip2335Password(password), salt, params.function(), params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	defer bls.SecretBytes(dk).Zero()
+
+	secret := kp.PrivateKey.Bytes()
+	defer secret.Zero()
+	ciphertext, err := aes128CTR(dk[:16], iv, secret)
+	if err != nil {
+		return nil, err
+	}
+	checksum := sha256.Sum256(append(dk[16:32:32], ciphertext...))
+
+	kdfJSON, err := json.Marshal(kdfParams)
+	if err != nil {
+		return nil, err
+	}
+	cipherJSON, err := json.Marshal(eip2335CipherParams{IV: hex.EncodeToString(iv)})
+	if err != nil {
+		return nil, err
+	}
+
+	return &EIP2335Keystore{
+		Crypto: EIP2335Crypto{
+			KDF:      EIP2335Module{Function: params.function(), Params: kdfJSON, Message: ""},
+			Checksum: EIP2335Module{Function: "sha256", Params: json.RawMessage("{}"), Message: hex.EncodeToString(checksum[:])},
+			Cipher:   EIP2335Module{Function: "aes-128-ctr", Params: cipherJSON, Message: hex.EncodeToString(ciphertext)},
+		},
+		Description: "Bastion BLS operator key",
+		PubKey:      hex.EncodeToString(kp.G1PubKey.Bytes()),
+		Path:        "",
+		UUID:        id,
+		Version:     eip2335Version,
+	}, nil
+}
+
+// LoadEIP2335 reads and decrypts the EIP-2335 keystore at path. The checksum
+// is verified before anything is decrypted.
+func LoadEIP2335(path, password string) (*bls.KeyPair, error) {
+	data, err := readKeyFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var ks EIP2335Keystore
+	if err := json.Unmarshal(data, &ks); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorruptKeyfile, err)
+	}
+	return decryptEIP2335(&ks, password)
+}
+
+func decryptEIP2335(ks *EIP2335Keystore, password string) (*bls.KeyPair, error) {
+	kp, err := openEIP2335(ks, password)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkStoredPubKeys(kp, ks.PubKey, ""); err != nil {
+		kp.PrivateKey.Zero()
+		return nil, err
+	}
+	return kp, nil
+}
+
+// openEIP2335 is decryptEIP2335 without the stored public key check, which
+// the keystore checksum does not cover.
+func openEIP2335(ks *EIP2335Keystore, password string) (*bls.KeyPair, error) {
+	if ks.Version != eip2335Version {
+		return nil, fmt.Errorf("%w: unsupported keystore version %d", ErrCorruptKeyfile, ks.Version)
+	}
+
+	dk, err := eip2335DecryptionKey(&ks.Crypto.KDF, eip2335Password(password))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorruptKeyfile, err)
+	}
+	defer bls.SecretBytes(dk).Zero()
+
+	if ks.Crypto.Checksum.Function != "sha256" {
+		return nil, fmt.Errorf("%w: unsupported checksum function %q", ErrCorruptKeyfile, ks.Crypto.Checksum.Function)
+	}
+	ciphertext, err := hex.DecodeString(ks.Crypto.Cipher.Message)
+	if err != nil {
+		return nil, fmt.Errorf("%w: cipher message: %v", ErrCorruptKeyfile, err)
+	}
+	want, err := hex.DecodeString(ks.Crypto.Checksum.Message)
+	if err != nil {
+		return nil, fmt.Errorf("%w: checksum message: %v", ErrCorruptKeyfile, err)
+	}
+	got := sha256.Sum256(append(dk[16:32:32], ciphertext...))
+	if !checksumEqual(got[:], want) {
+		return nil, ErrChecksum
+	}
+
+	if ks.Crypto.Cipher.Function != "aes-128-ctr" {
+		return nil, fmt.Errorf("%w: unsupported cipher function %q", ErrCorruptKeyfile, ks.Crypto.Cipher.Function)
+	}
+	var cp eip2335CipherParams
+	if err := json.Unmarshal(ks.Crypto.Cipher.Params, &cp); err != nil {
+		return nil, fmt.Errorf("%w: cipher params: %v", ErrCorruptKeyfile, err)
+	}
+	iv, err := hex.DecodeString(cp.IV)
+	if err != nil {
+		return nil, fmt.Errorf("%w: iv: %v", ErrCorruptKeyfile, err)
+	}
+	secret, err := aes128CTR(dk[:16], iv, ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorruptKeyfile, err)
+	}
+	defer bls.SecretBytes(secret).Zero()
+
+	sk, err := bls.PrivateKeyFromBytes(secret)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidKey, err)
+	}
+	return bls.NewKeyPair(sk), nil
+}
+
+// eip2335KDFParams returns the cost parameters of a keystore's KDF module,
+// so it can be re-encrypted at the same cost.
+func eip2335KDFParams(kdf *EIP2335Module) (KDFParams, error) {
+	switch kdf.Function {
+	case "scrypt":
+		var p eip2335ScryptParams
+		if err := json.Unmarshal(kdf.Params, &p); err != nil {
+			return KDFParams{}, fmt.Errorf("invalid scrypt params: %w", err)
+		}
+		return KDFParams{N: p.N, R: p.R, P: p.P, DKLen: p.DKLen}, nil
+	case "pbkdf2":
+		var p eip2335PBKDF2Params
+		if err := json.Unmarshal(kdf.Params, &p); err != nil {
+			return KDFParams{}, fmt.Errorf("invalid pbkdf2 params: %w", err)
+		}
+		return KDFParams{C: p.C, PRF: p.PRF, DKLen: p.DKLen}, nil
+	default:
+		return KDFParams{}, fmt.Errorf("unsupported kdf function %q", kdf.Function)
+	}
+}
+
+func eip2335DecryptionKey(kdf *EIP2335Module, password []byte) ([]byte, error) {
+	switch kdf.Function {
+	case "scrypt":
+		var p eip2335ScryptParams
+		if err := json.Unmarshal(kdf.Params, &p); err != nil {
+			return nil, fmt.Errorf("invalid scrypt params: %w", err)
+		}
+		salt, err := hex.DecodeString(p.Salt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid salt: %w", err)
+		}
+		if p.DKLen < 32 {
+			return nil, fmt.Errorf("scrypt dklen %d is too short", p.DKLen)
+		}
+		return scrypt.Key(password, salt, p.N, p.R, p.P, p.DKLen)
+	case "pbkdf2":
+		var p eip2335PBKDF2Params
+		if err := json.Unmarshal(kdf.Params, &p); err != nil {
+			return nil, fmt.Errorf("invalid pbkdf2 params: %w", err)
+		}
+		if p.PRF != "hmac-sha256" {
+			return nil, fmt.Errorf("unsupported pbkdf2 prf %q", p.PRF)
+		}
+		salt, err := hex.DecodeString(p.Salt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid salt: %w", err)
+		}
+		if p.DKLen < 32 {
+			return nil, fmt.Errorf("pbkdf2 dklen %d is too short", p.DKLen)
+		}
+		return pbkdf2.Key(password, salt, p.C, p.DKLen, sha256.New), nil
+	default:
+		return nil, fmt.Errorf("unsupported kdf function %q", kdf.Function)
+	}
+}
+
+// eip2335Password applies the EIP-2335 password processing: NFKD
+// normalization followed by stripping C0, C1 and Delete control codes.
+func eip2335Password(password string) []byte {
+	normalized := norm.NFKD.String(password)
+	var b strings.Builder
+	for _, r := range normalized {
+		if r < 0x20 || (r >= 0x7f && r <= 0x9f) {
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return []byte(b.String())
+}
+
+func aes128CTR(key, iv, in []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != aes.BlockSize {
+		return nil, fmt.Errorf("invalid iv length %d", len(iv))
+	}
+	out := make([]byte, len(in))
+	cipher.NewCTR(block, iv).XORKeyStream(out, in)
+	return out, nil
+}
+
+// newUUID returns a random RFC 4122 version 4 UUID.
+func newUUID() (string, error) {
+	var u [16]byte
+	if _, err := rand.Read(u[:]); err != nil {
+		return "", fmt.Errorf("failed to generate uuid: %w", err)
+	}
+	u[6] = (u[6] & 0x0f) | 0x40
+	u[8] = (u[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16]), nil
+}
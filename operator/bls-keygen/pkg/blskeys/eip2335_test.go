@@ -0,0 +1,125 @@
+package blskeys
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// eip2335PBKDF2Vector is the PBKDF2 test vector from EIP-2335.
+const eip2335PBKDF2Vector = `{
+    "crypto": {
+        "kdf": {
+            "function": "pbkdf2",
+            "params": {
+                "dklen": 32,
+                "c": 262144,
+                "prf": "hmac-sha256",
+                "salt": "d4e56740f876aef8c010b86a40d5f56745a118d0906a34e69aec8c0db1cb8fa3"
+            },
+            "message": ""
+        },
+        "checksum": {
+            "function": "sha256",
+            "params": {},
+            "message": "8a9f5d9912ed7e75ea794bc5a89bca5f193721d30868ade6f73043c6ea6febf1"
+        },
+        "cipher": {
+            "function": "aes-128-ctr",
+            "params": {
+                "iv": "264daa3f303d7259501c93d997d84fe6"
+            },
+            "message": "cee03fde2af33149775b7223e7845e4fb2c8ae1792e5f99fe9ecf474cc8c16ad"
+        }
+    },
+    "description": "This is a test keystore that uses PBKDF2 to secure the secret.",
+    "pubkey": "9612d7a727c9d0a22e185a1c768478dfe919cada9266988cb32359c11f2b7b27f4ae4040902382ae2910c15e2b420d07",
+    "path": "m/12381/60/0/0",
+    "uuid": "64625def-3331-4eea-ab6f-782f3ed16a83",
+    "version": 4
+}`
+
+const (
+	eip2335VectorPassword = "𝔱𝔢𝔰𝔱𝔭𝔞𝔰𝔰𝔴𝔬𝔯𝔡🔑"
+	eip2335VectorSecret   = "000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f"
+)
+
+func TestLoadEIP2335Vector(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keystore.json")
+	if err := os.WriteFile(path, []byte(eip2335PBKDF2Vector), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	kp, err := LoadEIP2335(path, eip2335VectorPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := hex.EncodeToString(kp.PrivateKey.Bytes()); got != eip2335VectorSecret {
+		t.Fatalf("secret = %s, want %s", got, eip2335VectorSecret)
+	}
+
+	if _, err := LoadEIP2335(path, "testpassword"); !errors.Is(err, ErrChecksum) {
+		t.Fatalf("wrong password: got %v, want ErrChecksum", err)
+	}
+}
+
+func TestEIP2335RoundTrip(t *testing.T) {
+	kp, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks, err := EncryptEIP2335(kp, "correct horse battery staple", testScrypt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(ks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{"crypto", "pubkey", "uuid", "version", "path"} {
+		if _, ok := fields[f]; !ok {
+			t.Errorf("keystore is missing %q", f)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "keystore.json")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadEIP2335(path, "correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(loaded.PrivateKey.Bytes(), kp.PrivateKey.Bytes()) {
+		t.Fatal("loaded private key does not match")
+	}
+}
+
+func TestEIP2335PubKeyMismatch(t *testing.T) {
+	kp, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks, err := EncryptEIP2335(kp, "correct horse battery staple", testScrypt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks.PubKey = hex.EncodeToString(other.G1PubKey.Bytes())
+
+	if _, err := decryptEIP2335(ks, "correct horse battery staple"); !errors.Is(err, ErrPubPrivMismatch) {
+		t.Fatalf("got %v, want ErrPubPrivMismatch", err)
+	}
+}
@@ -0,0 +1,74 @@
+package blskeys
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"golang.org/x/crypto/sha3"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+)
+
+// ErrEntropyTimeout is returned by CheckEntropy when the source does not
+// deliver a sample in time, as happens when the kernel pool is not yet
+// initialized early in boot.
+var ErrEntropyTimeout = errors.New("entropy source did not respond in time")
+
+// entropyMixDST separates the entropy mixer's hash from any other use of
+// SHAKE256 in this module.
+const entropyMixDST = "BASTION-KEYGEN-ENTROPY-MIX-V1"
+
+// CheckEntropy reads a sample from r and returns ErrEntropyTimeout if that
+// takes longer than timeout. A read that never returns is abandoned.
+func CheckEntropy(r io.Reader, timeout time.Duration) error {
+	done := make(chan error, 1)
+	go func() {
+		var sample [32]byte
+		_, err := io.ReadFull(r, sample[:])
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("failed to read entropy: %w", err)
+		}
+		return nil
+	case <-time.After(timeout):
+		return fmt.Errorf("%w after %s", ErrEntropyTimeout, timeout)
+	}
+}
+
+// mixedReader hashes every block read from crypto/rand together with extra
+// seed material, so its output is unpredictable as long as either is.
+type mixedReader struct {
+	extra []byte
+	rand  io.Reader
+}
+
+func (m *mixedReader) Read(p []byte) (int, error) {
+	fresh := make(bls.SecretBytes, len(p))
+	defer fresh.Zero()
+	if _, err := io.ReadFull(m.rand, fresh); err != nil {
+		return 0, err
+	}
+	h := sha3.NewShake256()
+	h.Write([]byte(entropyMixDST))
+	var n [8]byte
+	binary.BigEndian.PutUint64(n[:], uint64(len(m.extra)))
+	h.Write(n[:])
+	h.Write(m.extra)
+	h.Write(fresh)
+	return h.Read(p)
+}
+
+// GenerateWithEntropy is GenerateContext with extra seed material, such as
+// dice rolls on an air-gapped machine, mixed into crypto/rand. The extra
+// material only ever adds to crypto/rand; it never replaces it.
+func GenerateWithEntropy(ctx context.Context, extra []byte) (*KeyPair, error) {
+	return generate(ctx, &mixedReader{extra: extra, rand: rand.Reader})
+}
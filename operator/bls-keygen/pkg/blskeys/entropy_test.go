@@ -0,0 +1,78 @@
+package blskeys
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+// blockingReader never returns until release is closed, like a
+// crypto/rand that is waiting for the kernel pool.
+type blockingReader struct{ release chan struct{} }
+
+func (b blockingReader) Read(p []byte) (int, error) {
+	<-b.release
+	return 0, io.EOF
+}
+
+func TestCheckEntropy(t *testing.T) {
+	if err := CheckEntropy(rand.Reader, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	blocked := blockingReader{release: make(chan struct{})}
+	defer close(blocked.release)
+	if err := CheckEntropy(blocked, 10*time.Millisecond); !errors.Is(err, ErrEntropyTimeout) {
+		t.Fatalf("got %v, want ErrEntropyTimeout", err)
+	}
+}
+
+func TestMixedReaderUsesBothSources(t *testing.T) {
+	read := func(extra, random []byte) []byte {
+		b := make([]byte, 48)
+		m := &mixedReader{extra: extra, rand: bytes.NewReader(random)}
+		if _, err := io.ReadFull(m, b); err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	random := bytes.Repeat([]byte{7}, 48)
+	otherRandom := bytes.Repeat([]byte{8}, 48)
+
+	base := read([]byte("dice: 3 6 1 4"), random)
+	if bytes.Equal(base, random) {
+		t.Fatal("crypto/rand output passed through unmixed")
+	}
+	if !bytes.Equal(base, read([]byte("dice: 3 6 1 4"), random)) {
+		t.Fatal("mixing is not a function of its inputs")
+	}
+	if bytes.Equal(base, read([]byte("dice: 3 6 1 5"), random)) {
+		t.Fatal("extra seed material ignored")
+	}
+	if bytes.Equal(base, read([]byte("dice: 3 6 1 4"), otherRandom)) {
+		t.Fatal("crypto/rand ignored")
+	}
+
+	// Running out of crypto/rand is an error, not a fallback to extra alone.
+	m := &mixedReader{extra: []byte("dice"), rand: bytes.NewReader(nil)}
+	if _, err := m.Read(make([]byte, 48)); err == nil {
+		t.Fatal("mixer produced output without crypto/rand")
+	}
+}
+
+func TestGenerateWithEntropy(t *testing.T) {
+	kp, err := GenerateWithEntropy(context.Background(), []byte("air-gapped dice rolls"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := GenerateWithEntropy(context.Background(), []byte("air-gapped dice rolls"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(kp.PrivateKey.Bytes(), other.PrivateKey.Bytes()) {
+		t.Fatal("same extra entropy produced the same key")
+	}
+}
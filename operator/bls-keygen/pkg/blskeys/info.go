@@ -0,0 +1,113 @@
+package blskeys
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Key file formats reported by Inspect.
+const (
+	FormatBastion = "bastion"
+	FormatBinary  = "binary"
+	FormatEIP2335 = "eip2335"
+	FormatLegacy  = "legacy"
+	FormatPEM     = "pem"
+)
+
+// Info is what a key file says about itself in cleartext. KDFParams holds
+// the cost parameters only; the salt is left out.
+type Info struct {
+	Format    string
+	Version   int
+	Encrypted bool
+	KDF       string
+	KDFParams KDFParams
+	Cipher    string
+	// G1PubKey and G2PubKey are the stored hex public keys, unchecked.
+	// EIP-2335 keystores store no G2 key and legacy files neither.
+	G1PubKey string
+	G2PubKey string
+	Metadata *Metadata
+}
+
+// Inspect reads the cleartext fields of the key file at path. It needs no
+// password and never decodes the encrypted private key, so it is safe to
+// run over any number of files; use Load to check that the key decrypts
+// and matches its public keys.
+func Inspect(path string) (*Info, error) {
+	data, err := readKeyFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if kf, format, err := unmarshalNonJSON(data); format != "" {
+		if err != nil {
+			return nil, err
+		}
+		info := keyFileInfo(kf)
+		info.Format = format
+		return info, nil
+	}
+	var header struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorruptKeyfile, err)
+	}
+	if header.Version == eip2335Version {
+		var ks EIP2335Keystore
+		if err := json.Unmarshal(data, &ks); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrCorruptKeyfile, err)
+		}
+		params, err := eip2335KDFParams(&ks.Crypto.KDF)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrCorruptKeyfile, err)
+		}
+		return &Info{
+			Format:    FormatEIP2335,
+			Version:   ks.Version,
+			Encrypted: true,
+			KDF:       ks.Crypto.KDF.Function,
+			KDFParams: params,
+			Cipher:    ks.Crypto.Cipher.Function,
+			G1PubKey:  hexPrefixed(ks.PubKey),
+		}, nil
+	}
+	var kf KeyFile
+	if err := json.Unmarshal(data, &kf); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorruptKeyfile, err)
+	}
+	switch {
+	case kf.Version == 0 && kf.Crypto.Ciphertext == "":
+		return &Info{Format: FormatLegacy}, nil
+	case kf.Version == 0, kf.Version == CurrentVersion:
+		info := keyFileInfo(&kf)
+		info.Format = FormatBastion
+		return info, nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported key file version %d", ErrCorruptKeyfile, kf.Version)
+	}
+}
+
+// hexPrefixed adds the 0x prefix EIP-2335 keystores leave off their pubkey.
+func hexPrefixed(s string) string {
+	if s == "" || strings.HasPrefix(s, "0x") {
+		return s
+	}
+	return "0x" + s
+}
+
+func keyFileInfo(kf *KeyFile) *Info {
+	params := kf.Crypto.KDFParams
+	params.Salt = ""
+	return &Info{
+		Version:   kf.Version,
+		Encrypted: true,
+		KDF:       kf.Crypto.KDF,
+		KDFParams: params,
+		Cipher:    kf.Crypto.Cipher,
+		G1PubKey:  kf.G1PubKey,
+		G2PubKey:  kf.G2PubKey,
+		Metadata:  kf.Metadata,
+	}
+}
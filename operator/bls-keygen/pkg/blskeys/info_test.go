@@ -0,0 +1,56 @@
+package blskeys
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func TestInspect(t *testing.T) {
+	kp, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	meta := &Metadata{Network: "mainnet"}
+	tests := []struct {
+		format string
+		save   func(path string) error
+	}{
+		{FormatBastion, func(path string) error {
+			return SaveContext(context.Background(), kp, path, "pw", DefaultPBKDF2Params, meta)
+		}},
+		{FormatBinary, func(path string) error {
+			return SaveBinaryContext(context.Background(), kp, path, "pw", testScrypt, meta)
+		}},
+		{FormatEIP2335, func(path string) error { return SaveEIP2335(kp, path, "pw") }},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(dir, tt.format)
+		if err := tt.save(path); err != nil {
+			t.Fatal(err)
+		}
+		info, err := Inspect(path)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.format, err)
+		}
+		if info.Format != tt.format || !info.Encrypted {
+			t.Errorf("%s: got format %s encrypted %v", tt.format, info.Format, info.Encrypted)
+		}
+		if want := fmt.Sprintf("0x%x", kp.G1PubKey.Bytes()); info.G1PubKey != want {
+			t.Errorf("%s: G1 %s, want %s", tt.format, info.G1PubKey, want)
+		}
+		if info.KDFParams.Salt != "" {
+			t.Errorf("%s: Info carries the salt", tt.format)
+		}
+		if tt.format != FormatEIP2335 && (info.Metadata == nil || info.Metadata.Network != "mainnet") {
+			t.Errorf("%s: metadata %+v", tt.format, info.Metadata)
+		}
+	}
+
+	if _, err := Inspect(filepath.Join(dir, "missing")); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("missing file: got %v, want ErrKeyNotFound", err)
+	}
+}
@@ -0,0 +1,66 @@
+package blskeys
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// ErrWeakKDF is returned by CheckStrength for KDF parameters too cheap to
+// slow down a password guesser.
+var ErrWeakKDF = errors.New("weak KDF parameters")
+
+// Floors below which CheckStrength rejects KDF parameters. Tests lower them
+// together with DefaultScryptParams.
+var (
+	MinScryptN          = 1 << 15
+	MinScryptR          = 8
+	MinPBKDF2Iterations = 100000
+)
+
+// function names the KDF params describe.
+func (p KDFParams) function() string {
+	if p.C != 0 {
+		return kdfPBKDF2
+	}
+	return kdfScrypt
+}
+
+// CheckStrength returns ErrWeakKDF if p is below the Min* floors.
+func (p KDFParams) CheckStrength() error {
+	switch p.function() {
+	case kdfPBKDF2:
+		if p.C < MinPBKDF2Iterations {
+			return fmt.Errorf("%w: pbkdf2 iterations %d, want at least %d", ErrWeakKDF, p.C, MinPBKDF2Iterations)
+		}
+	default:
+		if p.N < MinScryptN || p.R < MinScryptR {
+			return fmt.Errorf("%w: scrypt n=%d r=%d, want at least n=%d r=%d", ErrWeakKDF, p.N, p.R, MinScryptN, MinScryptR)
+		}
+	}
+	return nil
+}
+
+// deriveKey runs kdf over password and salt.
+func deriveKey(password, salt []byte, kdf string, p KDFParams) ([]byte, error) {
+	if p.DKLen < 32 {
+		return nil, fmt.Errorf("%s dklen %d is too short", kdf, p.DKLen)
+	}
+	switch kdf {
+	case kdfScrypt:
+		return scrypt.Key(password, salt, p.N, p.R, p.P, p.DKLen)
+	case kdfPBKDF2:
+		if p.PRF != prfHMACSHA256 {
+			return nil, fmt.Errorf("unsupported pbkdf2 prf %q", p.PRF)
+		}
+		if p.C < 1 {
+			return nil, fmt.Errorf("invalid pbkdf2 iteration count %d", p.C)
+		}
+		return pbkdf2.Key(password, salt, p.C, p.DKLen, sha256.New), nil
+	default:
+		return nil, fmt.Errorf("unsupported kdf %q", kdf)
+	}
+}
@@ -0,0 +1,100 @@
+package blskeys
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCustomKDFRoundTrip(t *testing.T) {
+	kp, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, params := range map[string]KDFParams{
+		"scrypt": {N: 1 << 11, R: 4, P: 2, DKLen: 32},
+		"pbkdf2": {C: 2000, PRF: prfHMACSHA256, DKLen: 32},
+	} {
+		t.Run(name, func(t *testing.T) {
+			kf, err := Encrypt(kp, "pw", params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if kf.Crypto.KDF != name {
+				t.Fatalf("kdf = %s, want %s", kf.Crypto.KDF, name)
+			}
+			path := filepath.Join(t.TempDir(), "bls_key.json")
+			if err := writeJSON(path, kf); err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var stored KeyFile
+			if err := json.Unmarshal(data, &stored); err != nil {
+				t.Fatal(err)
+			}
+			got := stored.Crypto.KDFParams
+			if got.N != params.N || got.R != params.R || got.P != params.P || got.C != params.C {
+				t.Fatalf("stored params %+v, want %+v", got, params)
+			}
+
+			loaded, err := Load(path, "pw")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(loaded.PrivateKey.Bytes(), kp.PrivateKey.Bytes()) {
+				t.Fatal("key encrypted with custom parameters did not load back")
+			}
+			if _, err := Load(path, "wrong"); !errors.Is(err, ErrDecrypt) {
+				t.Fatalf("wrong password: got %v, want ErrDecrypt", err)
+			}
+		})
+	}
+}
+
+func TestEIP2335PBKDF2RoundTrip(t *testing.T) {
+	kp, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks, err := EncryptEIP2335(kp, "pw", KDFParams{C: 2000, PRF: prfHMACSHA256, DKLen: 32})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ks.Crypto.KDF.Function != kdfPBKDF2 {
+		t.Fatalf("kdf function = %s", ks.Crypto.KDF.Function)
+	}
+	loaded, err := decryptEIP2335(ks, "pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(loaded.PrivateKey.Bytes(), kp.PrivateKey.Bytes()) {
+		t.Fatal("pbkdf2 keystore did not round-trip")
+	}
+}
+
+func TestCheckStrength(t *testing.T) {
+	for _, p := range []KDFParams{
+		{N: MinScryptN, R: MinScryptR, P: 1},
+		DefaultPBKDF2Params,
+	} {
+		if err := p.CheckStrength(); err != nil {
+			t.Errorf("%+v: %v", p, err)
+		}
+	}
+	for _, p := range []KDFParams{
+		{N: MinScryptN / 2, R: 8, P: 1},
+		{N: MinScryptN, R: 1, P: 1},
+		{C: MinPBKDF2Iterations - 1, PRF: prfHMACSHA256},
+	} {
+		if err := p.CheckStrength(); !errors.Is(err, ErrWeakKDF) {
+			t.Errorf("%+v: got %v, want ErrWeakKDF", p, err)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package blskeys
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxLabelLength is the longest Metadata.Label, in bytes.
+const MaxLabelLength = 128
+
+// ValidateLabel checks that label fits on one line of list and info
+// output: printable UTF-8 of at most MaxLabelLength bytes.
+func ValidateLabel(label string) error {
+	if len(label) > MaxLabelLength {
+		return fmt.Errorf("label is %d bytes, longer than %d", len(label), MaxLabelLength)
+	}
+	if !utf8.ValidString(label) {
+		return errors.New("label is not valid UTF-8")
+	}
+	for _, r := range label {
+		if !unicode.IsPrint(r) {
+			return fmt.Errorf("label contains the unprintable character %U", r)
+		}
+	}
+	return nil
+}
+
+// Relabel sets the label in the metadata of the key file at path, or
+// removes it if label is empty, and recomputes the checksum. Metadata is
+// cleartext, so no password is needed, but the file must pass its checksum
+// first: Relabel does not bless a corrupt file with a fresh one. The file
+// keeps its format. EIP-2335 keystores and version 0 files have no
+// metadata to hold a label.
+func Relabel(path, label string) error {
+	if err := ValidateLabel(label); err != nil {
+		return err
+	}
+	data, err := readKeyFile(path)
+	if err != nil {
+		return err
+	}
+	if kf, format, err := unmarshalNonJSON(data); format != "" {
+		if err != nil {
+			return err
+		}
+		if err := relabelKeyFile(kf, label); err != nil {
+			return err
+		}
+		b, err := marshalNonJSON(kf, format)
+		if err != nil {
+			return err
+		}
+		return writeFile(path, b)
+	}
+
+	var header struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return fmt.Errorf("%w: %v", ErrCorruptKeyfile, err)
+	}
+	switch header.Version {
+	case CurrentVersion:
+		var kf KeyFile
+		if err := json.Unmarshal(data, &kf); err != nil {
+			return fmt.Errorf("%w: %v", ErrCorruptKeyfile, err)
+		}
+		if err := relabelKeyFile(&kf, label); err != nil {
+			return err
+		}
+		return writeJSON(path, &kf)
+	case eip2335Version:
+		return errors.New("EIP-2335 keystores have no metadata to hold a label")
+	case 0:
+		return errors.New("version 0 key files have no metadata to hold a label; run migrate")
+	default:
+		return fmt.Errorf("%w: unsupported key file version %d", ErrCorruptKeyfile, header.Version)
+	}
+}
+
+// relabelKeyFile sets kf's label and checksum in place.
+func relabelKeyFile(kf *KeyFile, label string) error {
+	if err := kf.verifyChecksum(); err != nil {
+		return err
+	}
+	if kf.Metadata == nil {
+		kf.Metadata = new(Metadata)
+	}
+	kf.Metadata.Label = label
+	if *kf.Metadata == (Metadata{}) {
+		kf.Metadata = nil
+	}
+	var err error
+	kf.Checksum, err = kf.computeChecksum()
+	return err
+}
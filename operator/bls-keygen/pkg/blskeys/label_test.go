@@ -0,0 +1,142 @@
+package blskeys
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRelabel(t *testing.T) {
+	kp, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	meta := &Metadata{Label: "holesky-test-2", Network: "holesky"}
+	saves := map[string]func(context.Context, *KeyPair, string, string, KDFParams, *Metadata) error{
+		"json":   SaveContext,
+		"binary": SaveBinaryContext,
+		"pem":    SavePEMContext,
+	}
+	for name, save := range saves {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name)
+			if err := save(context.Background(), kp, path, "pw", testScrypt, meta); err != nil {
+				t.Fatal(err)
+			}
+			before, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := Relabel(path, "mainnet-primary"); err != nil {
+				t.Fatal(err)
+			}
+			got, err := LoadMetadata(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Label != "mainnet-primary" || got.Network != "holesky" {
+				t.Fatalf("metadata = %+v, want the new label and the rest kept", got)
+			}
+			after, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if IsBinaryKeyFile(before) != IsBinaryKeyFile(after) || IsPEMKeyFile(before) != IsPEMKeyFile(after) {
+				t.Fatal("relabel changed the file format")
+			}
+			loaded, err := Load(path, "pw")
+			if err != nil {
+				t.Fatalf("relabelled file does not load: %v", err)
+			}
+			if !bytes.Equal(loaded.PrivateKey.Bytes(), kp.PrivateKey.Bytes()) {
+				t.Fatal("relabel changed the key")
+			}
+
+			if err := Relabel(path, ""); err != nil {
+				t.Fatal(err)
+			}
+			if got, err := LoadMetadata(path); err != nil || got.Label != "" {
+				t.Fatalf("after removing the label: %+v, %v", got, err)
+			}
+		})
+	}
+}
+
+func TestRelabelRefuses(t *testing.T) {
+	kp, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+
+	// A corrupt file does not get a fresh checksum.
+	path := filepath.Join(dir, "bls_key.json")
+	if err := Save(kp, path, "pw"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := bytes.Replace(data, []byte(`"version": 1`), []byte(`"version": 1, "metadata": {"network": "x"}`), 1)
+	if bytes.Equal(tampered, data) {
+		t.Fatal("test setup: could not tamper with the key file")
+	}
+	if err := os.WriteFile(path, tampered, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := Relabel(path, "x"); !errors.Is(err, ErrCorruptKeyfile) {
+		t.Fatalf("corrupt file: got %v, want ErrCorruptKeyfile", err)
+	}
+
+	eip2335 := filepath.Join(dir, "keystore.json")
+	if err := SaveEIP2335(kp, eip2335, "pw"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Relabel(eip2335, "x"); err == nil {
+		t.Fatal("EIP-2335 keystore relabelled")
+	}
+
+	for _, label := range []string{"two\nlines", "tab\there", strings.Repeat("x", MaxLabelLength+1), "\xff"} {
+		if err := ValidateLabel(label); err == nil {
+			t.Errorf("ValidateLabel(%q) passed", label)
+		}
+	}
+	if err := ValidateLabel("mainnet primary (ü)"); err != nil {
+		t.Errorf("ValidateLabel rejected a printable label: %v", err)
+	}
+}
+
+func TestLabelSurvivesPasswordChangeAndRotation(t *testing.T) {
+	kp, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "bls_key.json")
+	if err := SaveContext(context.Background(), kp, path, "old", testScrypt, &Metadata{Label: "mainnet-primary"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ChangePassword(path, "old", "new"); err != nil {
+		t.Fatal(err)
+	}
+	if meta, err := LoadMetadata(path); err != nil || meta.Label != "mainnet-primary" {
+		t.Fatalf("after a password change: %+v, %v", meta, err)
+	}
+
+	_, backup, err := Rotate(path, "new", "newer", time.Unix(1700000000, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{path, backup} {
+		if meta, err := LoadMetadata(p); err != nil || meta.Label != "mainnet-primary" {
+			t.Errorf("%s after rotation: %+v, %v", filepath.Base(p), meta, err)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package blskeys
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// LockFileName is the file in a key directory that LockDir locks. It is
+// left in place after unlocking; removing it would let a process that
+// opened it before the removal lock a different file than a later one.
+const LockFileName = ".keygen.lock"
+
+// lockPollInterval is how often LockDir retries a held lock.
+var lockPollInterval = 50 * time.Millisecond
+
+// errLockHeld is returned by tryLock when another process holds the lock.
+var errLockHeld = errors.New("lock is held")
+
+// LockDir takes an exclusive advisory lock on dir, waiting while another
+// process holds it, and returns the function that releases it. Processes
+// that check whether a key exists and then write it hold the lock across
+// both steps, so two replicas starting together cannot both find the key
+// missing and overwrite each other's. It returns ctx.Err() if ctx is done
+// before the lock is free.
+func LockDir(ctx context.Context, dir string) (unlock func() error, err error) {
+	f, err := os.OpenFile(filepath.Join(dir, LockFileName), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+	release, err := LockFile(ctx, f)
+	if err != nil {
+		f.Close()
+		if ctxErr := ctx.Err(); ctxErr != nil && errors.Is(err, ctxErr) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to lock %s: %w", dir, err)
+	}
+	return func() error {
+		unlockErr := release()
+		if err := f.Close(); unlockErr == nil {
+			unlockErr = err
+		}
+		return unlockErr
+	}, nil
+}
+
+// LockFile takes an exclusive advisory lock on the open file f, waiting
+// like LockDir while another process holds it, and returns the function
+// that releases it. Closing f also releases the lock.
+func LockFile(ctx context.Context, f *os.File) (unlock func() error, err error) {
+	for {
+		err := tryLock(f)
+		if err == nil {
+			return func() error { return unlockFile(f) }, nil
+		}
+		if !errors.Is(err, errLockHeld) {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+// GenerateOrLoadContext returns the key at path, generating and saving it
+// first if there is none. The check and the write happen under LockDir of
+// path's directory, so when several processes race only one generates and
+// the others load the key it wrote. created reports whether this call
+// wrote the key.
+func GenerateOrLoadContext(ctx context.Context, path, password string, params KDFParams, meta *Metadata) (kp *KeyPair, created bool, err error) {
+	unlock, err := LockDir(ctx, filepath.Dir(path))
+	if err != nil {
+		return nil, false, err
+	}
+	defer unlock()
+
+	if _, err := os.Stat(path); err == nil {
+		kp, err := LoadContext(ctx, path, password, nil)
+		return kp, false, err
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, false, fmt.Errorf("failed to check for an existing key: %w", err)
+	}
+	kp, err = GenerateContext(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := SaveContext(ctx, kp, path, password, params, meta); err != nil {
+		kp.PrivateKey.Zero()
+		return nil, false, err
+	}
+	return kp, true, nil
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package blskeys
+
+import "os"
+
+// Without flock the lock is a no-op: the key image only targets Linux.
+func tryLock(f *os.File) error { return nil }
+
+func unlockFile(f *os.File) error { return nil }
@@ -0,0 +1,79 @@
+package blskeys
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGenerateOrLoadConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bls_key.json")
+
+	type result struct {
+		kp      *KeyPair
+		created bool
+		err     error
+	}
+	results := make([]result, 2)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			kp, created, err := GenerateOrLoadContext(context.Background(), path, "pw", testScrypt, nil)
+			results[i] = result{kp, created, err}
+		}(i)
+	}
+	wg.Wait()
+
+	created := 0
+	for i, r := range results {
+		if r.err != nil {
+			t.Fatalf("call %d: %v", i, r.err)
+		}
+		if r.created {
+			created++
+		}
+	}
+	if created != 1 {
+		t.Fatalf("%d calls generated a key, want exactly 1", created)
+	}
+	onDisk, err := Load(path, "pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, r := range results {
+		if !bytes.Equal(r.kp.G1PubKey.Bytes(), onDisk.G1PubKey.Bytes()) {
+			t.Fatalf("call %d returned a different key than the one on disk", i)
+		}
+	}
+}
+
+func TestLockDirWaitsForRelease(t *testing.T) {
+	dir := t.TempDir()
+	unlock, err := LockDir(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*lockPollInterval)
+	defer cancel()
+	if _, err := LockDir(ctx, dir); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("locked a held lock: got %v, want context.DeadlineExceeded", err)
+	}
+
+	if err := unlock(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	unlock, err = LockDir(ctx, dir)
+	if err != nil {
+		t.Fatalf("lock not released: %v", err)
+	}
+	unlock()
+}
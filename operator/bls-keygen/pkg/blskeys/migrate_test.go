@@ -0,0 +1,181 @@
+package blskeys
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
+)
+
+// writeLegacyKey writes kp as a version 0 plaintext key file.
+func writeLegacyKey(t *testing.T, kp *KeyPair) string {
+	t.Helper()
+	data := fmt.Sprintf(`{
+  "private_key": "0x%x",
+  "g1_pub_key": "0x%x",
+  "g2_pub_key": "0x%x"
+}`, kp.PrivateKey.Bytes(), kp.G1PubKey.Bytes(), kp.G2PubKey.Bytes())
+	path := filepath.Join(t.TempDir(), "bls_key.json")
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadBaselineKeyAboveOrder(t *testing.T) {
+	// The secp256k1 order minus one: a valid baseline key, well above the
+	// BLS12-381 scalar order.
+	const secp = "fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364140"
+	data := `{
+  "private_key": "0x` + secp + `",
+  "public_key": "0x04aa",
+  "g1_pub_key": "0xbb",
+  "g2_pub_key": "0xcc"
+}`
+	path := filepath.Join(t.TempDir(), "bls_key.json")
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := new(big.Int).SetString(secp, 16)
+	want.Mod(want, fr.Modulus())
+	if got := new(big.Int).SetBytes(loaded.PrivateKey.Bytes()); got.Cmp(want) != 0 {
+		t.Fatalf("loaded scalar %x, want %x", got, want)
+	}
+}
+
+func TestLoadVersions(t *testing.T) {
+	kp, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("version 0", func(t *testing.T) {
+		loaded, err := Load(writeLegacyKey(t, kp), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(loaded.PrivateKey.Bytes(), kp.PrivateKey.Bytes()) {
+			t.Fatal("legacy key did not load")
+		}
+	})
+
+	t.Run("version 1", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "bls_key.json")
+		if err := Save(kp, path, "pw"); err != nil {
+			t.Fatal(err)
+		}
+		var header struct{ Version int }
+		data, _ := os.ReadFile(path)
+		if err := json.Unmarshal(data, &header); err != nil || header.Version != CurrentVersion {
+			t.Fatalf("saved version = %d, want %d", header.Version, CurrentVersion)
+		}
+		loaded, err := Load(path, "pw")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(loaded.PrivateKey.Bytes(), kp.PrivateKey.Bytes()) {
+			t.Fatal("current key did not load")
+		}
+	})
+
+	t.Run("unknown version", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "bls_key.json")
+		if err := os.WriteFile(path, []byte(`{"version": 99}`), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load(path, "pw"); err == nil {
+			t.Fatal("expected an error for an unknown version")
+		}
+	})
+}
+
+func TestMigrate(t *testing.T) {
+	kp, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := writeLegacyKey(t, kp)
+
+	migrated, err := Migrate(path, "pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !migrated {
+		t.Fatal("legacy file was not migrated")
+	}
+
+	data, _ := os.ReadFile(path)
+	if bytes.Contains(data, []byte("private_key")) {
+		t.Fatal("migrated file still contains a plaintext private key")
+	}
+	loaded, err := Load(path, "pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(loaded.PrivateKey.Bytes(), kp.PrivateKey.Bytes()) {
+		t.Fatal("migrated file holds a different key")
+	}
+
+	migrated, err = Migrate(path, "pw")
+	if err != nil || migrated {
+		t.Fatalf("second migration: migrated=%v err=%v, want no-op", migrated, err)
+	}
+}
+
+// placeholderKeyFile is a key file as the ECDSA placeholder build wrote it:
+// an uncompressed secp256k1 public key split 32/33 into fake G1 and G2
+// fields.
+const placeholderKeyFile = `{
+  "private_key": "0x4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318",
+  "public_key": "0x04` + "8a7d5b2f4a6c1e0d9b3f7a2c6e1d0b9a8f7e6d5c4b3a29181716151413121110" + "0f0e0d0c0b0a09080706050403020100ffeeddccbbaa99887766554433221100" + `",
+  "g1_pub_key": "0x048a7d5b2f4a6c1e0d9b3f7a2c6e1d0b9a8f7e6d5c4b3a291817161514131211",
+  "g2_pub_key": "0x100f0e0d0c0b0a09080706050403020100ffeeddccbbaa99887766554433221100"
+}`
+
+func TestLoadLegacyPlaceholder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bls_key.json")
+	if err := os.WriteFile(path, []byte(placeholderKeyFile), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if !IsLegacyPlaceholder([]byte(placeholderKeyFile)) {
+		t.Fatal("placeholder file not recognized")
+	}
+
+	_, err := Load(path, "")
+	if !errors.Is(err, ErrLegacyPlaceholder) || !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("got %v, want ErrLegacyPlaceholder", err)
+	}
+	for _, want := range []string{"not a BLS key", "must be regenerated"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not say %q", err, want)
+		}
+	}
+	if _, err := Migrate(path, "pw"); !errors.Is(err, ErrLegacyPlaceholder) {
+		t.Fatalf("migrate: got %v, want ErrLegacyPlaceholder", err)
+	}
+
+	// Legacy files of real BLS keys are not placeholders.
+	kp, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(writeLegacyKey(t, kp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if IsLegacyPlaceholder(data) {
+		t.Fatal("legacy BLS key file taken for a placeholder")
+	}
+}
@@ -0,0 +1,87 @@
+package blskeys
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// ChangePassword re-encrypts the key file at path under newPassword
+// without changing the key. Public keys, metadata and the KDF with its cost
+// are kept; only the salt and nonce are fresh. EIP-2335 keystores also keep
+// their uuid, path and description, and legacy files are rewritten at
+// CurrentVersion. Binary and PEM key files keep their format. Nothing is written unless the key decrypts with
+// oldPassword, and the new file replaces the old one atomically.
+func ChangePassword(path, oldPassword, newPassword string) error {
+	data, err := readKeyFile(path)
+	if err != nil {
+		return err
+	}
+	if old, format, err := unmarshalNonJSON(data); format != "" {
+		if err != nil {
+			return err
+		}
+		kp, err := Decrypt(old, oldPassword)
+		if err != nil {
+			return err
+		}
+		defer kp.PrivateKey.Zero()
+		kf, err := encryptContext(context.Background(), kp, newPassword, old.Crypto.KDFParams, old.Metadata)
+		if err != nil {
+			return err
+		}
+		b, err := marshalNonJSON(kf, format)
+		if err != nil {
+			return err
+		}
+		return writeFile(path, b)
+	}
+	var header struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return fmt.Errorf("%w: %v", ErrCorruptKeyfile, err)
+	}
+	kp, err := Load(path, oldPassword)
+	if err != nil {
+		return err
+	}
+	defer kp.PrivateKey.Zero()
+
+	if header.Version == eip2335Version {
+		var old EIP2335Keystore
+		if err := json.Unmarshal(data, &old); err != nil {
+			return fmt.Errorf("%w: %v", ErrCorruptKeyfile, err)
+		}
+		params, err := eip2335KDFParams(&old.Crypto.KDF)
+		if err != nil {
+			return err
+		}
+		ks, err := EncryptEIP2335(kp, newPassword, params)
+		if err != nil {
+			return err
+		}
+		ks.Description, ks.Path, ks.UUID = old.Description, old.Path, old.UUID
+		return writeJSON(path, ks)
+	}
+
+	var old KeyFile
+	if err := json.Unmarshal(data, &old); err != nil {
+		return fmt.Errorf("%w: %v", ErrCorruptKeyfile, err)
+	}
+	params := old.Crypto.KDFParams
+	if old.Crypto.Ciphertext == "" {
+		params = DefaultScryptParams
+	}
+	kf, err := Encrypt(kp, newPassword, params)
+	if err != nil {
+		return err
+	}
+	if old.Metadata != nil {
+		kf.Metadata = old.Metadata
+		if kf.Checksum, err = kf.computeChecksum(); err != nil {
+			return err
+		}
+	}
+	return writeJSON(path, kf)
+}
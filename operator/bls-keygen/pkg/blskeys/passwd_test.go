@@ -0,0 +1,102 @@
+package blskeys
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestChangePassword(t *testing.T) {
+	kp, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	bastion := filepath.Join(dir, "bls_key.json")
+	if err := SaveContext(context.Background(), kp, bastion, "old", testScrypt, &Metadata{Network: "holesky"}); err != nil {
+		t.Fatal(err)
+	}
+	eip2335 := filepath.Join(dir, "keystore.json")
+	if err := SaveEIP2335Context(context.Background(), kp, eip2335, "old", testScrypt, nil); err != nil {
+		t.Fatal(err)
+	}
+	before, err := os.ReadFile(eip2335)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{bastion, eip2335} {
+		if err := ChangePassword(path, "wrong", "new"); err == nil {
+			t.Fatalf("%s: changed password without the current one", path)
+		}
+		if err := ChangePassword(path, "old", "new"); err != nil {
+			t.Fatal(err)
+		}
+		loaded, err := Load(path, "new")
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		if !bytes.Equal(loaded.PrivateKey.Bytes(), kp.PrivateKey.Bytes()) {
+			t.Fatalf("%s: key changed", path)
+		}
+		if !bytes.Equal(loaded.G1PubKey.Bytes(), kp.G1PubKey.Bytes()) || !bytes.Equal(loaded.G2PubKey.Bytes(), kp.G2PubKey.Bytes()) {
+			t.Fatalf("%s: public keys changed", path)
+		}
+		if _, err := Load(path, "old"); err == nil {
+			t.Fatalf("%s: still loads with the old password", path)
+		}
+	}
+
+	meta, err := LoadMetadata(bastion)
+	if err != nil || meta == nil || meta.Network != "holesky" {
+		t.Fatalf("metadata not preserved: %+v %v", meta, err)
+	}
+	after, err := os.ReadFile(eip2335)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var old, ks EIP2335Keystore
+	if err := json.Unmarshal(before, &old); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(after, &ks); err != nil {
+		t.Fatal(err)
+	}
+	if ks.UUID != old.UUID || ks.Path != old.Path {
+		t.Fatal("keystore uuid or path not preserved")
+	}
+
+	leftovers, err := filepath.Glob(filepath.Join(dir, ".*.tmp-*"))
+	if err != nil || len(leftovers) != 0 {
+		t.Fatalf("temporary files left behind: %v %v", leftovers, err)
+	}
+}
+
+func TestChangePasswordKeepsFileOnFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bls_key.json")
+	kp, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Save(kp, path, "old"); err != nil {
+		t.Fatal(err)
+	}
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ChangePassword(path, "wrong", "new"); !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("got %v, want ErrDecrypt", err)
+	}
+	after, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(before, after) {
+		t.Fatal("key file modified by a failed password change")
+	}
+}
@@ -0,0 +1,82 @@
+package blskeys
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultMinPasswordLength is the minimum password length ValidatePassword enforces.
+const DefaultMinPasswordLength = 12
+
+// ErrWeakPassword is returned for passwords that fail the strength policy.
+var ErrWeakPassword = errors.New("weak password")
+
+// commonPasswords are well-known or published placeholder passwords,
+// including the old docker-compose default and the OPERATOR_SETUP.md
+// example.
+var commonPasswords = map[string]bool{
+	"secure_password":           true,
+	"your_secure_password_here": true,
+	"password":                  true,
+	"password123":               true,
+	"password1234":              true,
+	"passwordpassword":          true,
+	"changeme":                  true,
+	"changemechangeme":          true,
+	"123456789012":              true,
+	"qwertyuiopas":              true,
+	"letmeinletmein":            true,
+	"administrator":             true,
+}
+
+// PasswordPolicy describes what makes a password acceptable.
+type PasswordPolicy struct {
+	MinLength int
+}
+
+// ValidatePassword checks pw against the default policy.
+func ValidatePassword(pw string) error {
+	return PasswordPolicy{MinLength: DefaultMinPasswordLength}.Validate(pw)
+}
+
+// Validate returns an ErrWeakPassword-wrapped error describing why pw is weak.
+func (p PasswordPolicy) Validate(pw string) error {
+	if n := utf8.RuneCountInString(pw); n < p.MinLength {
+		return fmt.Errorf("%w: must be at least %d characters, got %d", ErrWeakPassword, p.MinLength, n)
+	}
+	if commonPasswords[strings.ToLower(pw)] {
+		return fmt.Errorf("%w: commonly used or default password", ErrWeakPassword)
+	}
+	if isRepeated(pw) {
+		return fmt.Errorf("%w: a single repeated character", ErrWeakPassword)
+	}
+	if isSequential(pw) {
+		return fmt.Errorf("%w: a simple character sequence", ErrWeakPassword)
+	}
+	return nil
+}
+
+func isRepeated(pw string) bool {
+	first, _ := utf8.DecodeRuneInString(pw)
+	return strings.Trim(pw, string(first)) == ""
+}
+
+// isSequential reports whether pw is a run like "abcdef..." or "987654...".
+func isSequential(pw string) bool {
+	runes := []rune(pw)
+	if len(runes) < 2 {
+		return false
+	}
+	step := runes[1] - runes[0]
+	if step != 1 && step != -1 {
+		return false
+	}
+	for i := 2; i < len(runes); i++ {
+		if runes[i]-runes[i-1] != step {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,41 @@
+package blskeys
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidatePassword(t *testing.T) {
+	for _, pw := range []string{
+		"",
+		"short",
+		"secure_password",
+		"Password1234",
+		"aaaaaaaaaaaaaaaa",
+		"abcdefghijklmnop",
+		"zyxwvutsrqponm",
+	} {
+		if err := ValidatePassword(pw); !errors.Is(err, ErrWeakPassword) {
+			t.Errorf("ValidatePassword(%q) = %v, want ErrWeakPassword", pw, err)
+		}
+	}
+
+	for _, pw := range []string{
+		"correct horse battery staple",
+		"Tr0ub4dor&3-but-longer",
+	} {
+		if err := ValidatePassword(pw); err != nil {
+			t.Errorf("ValidatePassword(%q) = %v, want nil", pw, err)
+		}
+	}
+}
+
+func TestPasswordPolicyMinLength(t *testing.T) {
+	p := PasswordPolicy{MinLength: 20}
+	if err := p.Validate("correct horse"); !errors.Is(err, ErrWeakPassword) {
+		t.Fatalf("got %v, want ErrWeakPassword", err)
+	}
+	if err := p.Validate("correct horse battery staple"); err != nil {
+		t.Fatal(err)
+	}
+}
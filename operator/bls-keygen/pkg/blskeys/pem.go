@@ -0,0 +1,204 @@
+package blskeys
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// pemType is the type of the PEM block of a PEM key file.
+const pemType = "BASTION BLS KEY"
+
+// The PEM layout is one pemType block whose body is the raw ciphertext and
+// whose headers carry every other field of a CurrentVersion KeyFile, as
+// they appear in its JSON form:
+//
+//	Version, KDF, KDF-N, KDF-R, KDF-P, KDF-C, KDF-DKLen, KDF-PRF, KDF-Salt,
+//	Cipher, Cipher-Nonce, G1-Pub-Key, G2-Pub-Key, Metadata (JSON), Checksum
+//
+// KDF-N to KDF-PRF are left out when zero or empty, and Metadata when
+// absent. As with the binary layout, the file decrypts on its own and its
+// checksum is the one the JSON form would have.
+
+// MarshalPEMKeyFile encodes kf as a PEM block, for secret stores that only
+// take PEM.
+func MarshalPEMKeyFile(kf *KeyFile) ([]byte, error) {
+	if kf.Version != CurrentVersion {
+		return nil, fmt.Errorf("PEM key files hold version %d key files, not version %d", CurrentVersion, kf.Version)
+	}
+	if err := kf.verifyChecksum(); err != nil {
+		return nil, err
+	}
+	ciphertext, err := hex.DecodeString(kf.Crypto.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode PEM key file: ciphertext: %w", err)
+	}
+	p := kf.Crypto.KDFParams
+	headers := map[string]string{
+		"Version":      strconv.Itoa(kf.Version),
+		"KDF":          kf.Crypto.KDF,
+		"KDF-DKLen":    strconv.Itoa(p.DKLen),
+		"KDF-Salt":     p.Salt,
+		"Cipher":       kf.Crypto.Cipher,
+		"Cipher-Nonce": kf.Crypto.Nonce,
+		"G1-Pub-Key":   kf.G1PubKey,
+		"G2-Pub-Key":   kf.G2PubKey,
+		"Checksum":     kf.Checksum,
+	}
+	for name, v := range map[string]int{"KDF-N": p.N, "KDF-R": p.R, "KDF-P": p.P, "KDF-C": p.C} {
+		if v != 0 {
+			headers[name] = strconv.Itoa(v)
+		}
+	}
+	if p.PRF != "" {
+		headers["KDF-PRF"] = p.PRF
+	}
+	if kf.Metadata != nil {
+		meta, err := json.Marshal(kf.Metadata)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal metadata: %w", err)
+		}
+		headers["Metadata"] = string(meta)
+	}
+	for name, v := range headers {
+		if strings.ContainsAny(v, "\r\n") {
+			return nil, fmt.Errorf("failed to encode PEM key file: %s contains a line break", name)
+		}
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: pemType, Headers: headers, Bytes: ciphertext})
+	// Decoding re-encodes the ciphertext in the form Encrypt writes it. A
+	// hand-edited file may differ, and its checksum would then not survive
+	// the trip.
+	decoded, err := UnmarshalPEMKeyFile(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := decoded.verifyChecksum(); err != nil {
+		return nil, fmt.Errorf("key file fields are not in canonical form: %w", err)
+	}
+	return data, nil
+}
+
+// UnmarshalPEMKeyFile decodes a key file written by MarshalPEMKeyFile.
+// Like the JSON form, it is checked when decrypted.
+func UnmarshalPEMKeyFile(data []byte) (*KeyFile, error) {
+	block, rest := pem.Decode(data)
+	if block == nil || block.Type != pemType {
+		return nil, fmt.Errorf("%w: not a PEM key file", ErrCorruptKeyfile)
+	}
+	if len(bytes.TrimSpace(rest)) > 0 {
+		return nil, fmt.Errorf("%w: trailing data after the PEM block", ErrCorruptKeyfile)
+	}
+	h := pemHeaders{headers: block.Headers, seen: map[string]bool{}}
+
+	kf := &KeyFile{Version: h.int("Version")}
+	c := &kf.Crypto
+	c.KDF = h.str("KDF")
+	c.KDFParams.N = h.optionalInt("KDF-N")
+	c.KDFParams.R = h.optionalInt("KDF-R")
+	c.KDFParams.P = h.optionalInt("KDF-P")
+	c.KDFParams.C = h.optionalInt("KDF-C")
+	c.KDFParams.DKLen = h.int("KDF-DKLen")
+	c.KDFParams.PRF = h.headers["KDF-PRF"]
+	h.seen["KDF-PRF"] = true
+	c.KDFParams.Salt = h.str("KDF-Salt")
+	c.Cipher = h.str("Cipher")
+	c.Nonce = h.str("Cipher-Nonce")
+	c.Ciphertext = hex.EncodeToString(block.Bytes)
+	kf.G1PubKey = h.str("G1-Pub-Key")
+	kf.G2PubKey = h.str("G2-Pub-Key")
+	if meta, ok := block.Headers["Metadata"]; ok {
+		h.seen["Metadata"] = true
+		kf.Metadata = new(Metadata)
+		if err := json.Unmarshal([]byte(meta), kf.Metadata); err != nil {
+			return nil, fmt.Errorf("%w: Metadata header: %v", ErrCorruptKeyfile, err)
+		}
+	}
+	kf.Checksum = h.str("Checksum")
+	if h.err == nil {
+		for name := range block.Headers {
+			if !h.seen[name] {
+				h.err = fmt.Errorf("unknown header %q", name)
+				break
+			}
+		}
+	}
+	if h.err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorruptKeyfile, h.err)
+	}
+	if kf.Version != CurrentVersion {
+		return nil, fmt.Errorf("%w: unsupported key file version %d", ErrCorruptKeyfile, kf.Version)
+	}
+	return kf, nil
+}
+
+// IsPEMKeyFile reports whether data is a PEM key file rather than JSON or
+// the binary layout.
+func IsPEMKeyFile(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte("-----BEGIN "+pemType+"-----"))
+}
+
+// SavePEMContext is SaveContext writing a PEM key file.
+func SavePEMContext(ctx context.Context, kp *KeyPair, path, password string, params KDFParams, meta *Metadata) error {
+	data, err := MarshalPEMContext(ctx, kp, password, params, meta)
+	if err != nil {
+		return err
+	}
+	return writeFile(path, data)
+}
+
+// MarshalPEMContext is MarshalContext returning a PEM key file.
+func MarshalPEMContext(ctx context.Context, kp *KeyPair, password string, params KDFParams, meta *Metadata) ([]byte, error) {
+	kf, err := encryptContext(ctx, kp, password, params, meta)
+	if err != nil {
+		return nil, err
+	}
+	return MarshalPEMKeyFile(kf)
+}
+
+// pemHeaders reads required headers, keeping the first error and which
+// headers were read.
+type pemHeaders struct {
+	headers map[string]string
+	seen    map[string]bool
+	err     error
+}
+
+func (h *pemHeaders) str(name string) string {
+	h.seen[name] = true
+	v, ok := h.headers[name]
+	if !ok && h.err == nil {
+		h.err = fmt.Errorf("missing header %q", name)
+	}
+	return v
+}
+
+func (h *pemHeaders) int(name string) int {
+	return h.parseInt(name, h.str(name))
+}
+
+func (h *pemHeaders) optionalInt(name string) int {
+	h.seen[name] = true
+	v, ok := h.headers[name]
+	if !ok {
+		return 0
+	}
+	return h.parseInt(name, v)
+}
+
+func (h *pemHeaders) parseInt(name, v string) int {
+	if h.err != nil {
+		return 0
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		h.err = fmt.Errorf("header %q is not a count: %q", name, v)
+		return 0
+	}
+	return n
+}
@@ -0,0 +1,127 @@
+package blskeys
+
+import (
+	"bytes"
+	"context"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPEMKeyFileRoundTrip(t *testing.T) {
+	kp, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "bls_key.pem")
+	meta := &Metadata{Network: "holesky", DerivationPath: DefaultDerivationPath}
+	if err := SavePEMContext(context.Background(), kp, path, "pw", testScrypt, meta); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, rest := pem.Decode(data)
+	if block == nil || block.Type != "BASTION BLS KEY" || len(rest) != 0 {
+		t.Fatalf("file is not a single BASTION BLS KEY block:\n%s", data)
+	}
+	for _, name := range []string{"KDF", "KDF-Salt", "Cipher-Nonce", "G1-Pub-Key", "G2-Pub-Key", "Metadata", "Checksum"} {
+		if block.Headers[name] == "" {
+			t.Errorf("header %s is missing", name)
+		}
+	}
+	if !strings.Contains(block.Headers["Metadata"], `"network":"holesky"`) {
+		t.Errorf("Metadata header = %q", block.Headers["Metadata"])
+	}
+
+	// The block carries exactly the fields of the JSON form.
+	kf, err := UnmarshalPEMKeyFile(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := MarshalPEMKeyFile(kf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(again, data) {
+		t.Error("re-encoding the decoded key file changed it")
+	}
+
+	loaded, err := Load(path, "pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(loaded.PrivateKey.Bytes(), kp.PrivateKey.Bytes()) {
+		t.Fatal("loaded key differs from the saved one")
+	}
+	if _, err := Load(path, "wrong"); !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("wrong password: got %v, want ErrDecrypt", err)
+	}
+	g1, g2, err := LoadPublicKeys(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(g1.Bytes(), kp.G1PubKey.Bytes()) || !bytes.Equal(g2.Bytes(), kp.G2PubKey.Bytes()) {
+		t.Fatal("public keys differ from the saved ones")
+	}
+	if got, err := LoadMetadata(path); err != nil || got == nil || *got != *meta {
+		t.Fatalf("metadata %+v, %v", got, err)
+	}
+
+	if err := ChangePassword(path, "pw", "new pw"); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); !IsPEMKeyFile(data) {
+		t.Fatal("passwd rewrote a PEM key file in another format")
+	}
+	if _, err := Load(path, "new pw"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPEMKeyFileCorrupt(t *testing.T) {
+	kp, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := MarshalPEMContext(context.Background(), kp, "pw", testScrypt, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ := pem.Decode(data)
+
+	for name, edit := range map[string]func(b *pem.Block){
+		"missing header": func(b *pem.Block) { delete(b.Headers, "Cipher-Nonce") },
+		"unknown header": func(b *pem.Block) { b.Headers["Comment"] = "x" },
+		"bad count":      func(b *pem.Block) { b.Headers["KDF-N"] = "many" },
+		"wrong type":     func(b *pem.Block) { b.Type = "PRIVATE KEY" },
+	} {
+		b := &pem.Block{Type: block.Type, Headers: map[string]string{}, Bytes: block.Bytes}
+		for k, v := range block.Headers {
+			b.Headers[k] = v
+		}
+		edit(b)
+		if _, err := UnmarshalPEMKeyFile(pem.EncodeToMemory(b)); !errors.Is(err, ErrCorruptKeyfile) {
+			t.Errorf("%s: got %v, want ErrCorruptKeyfile", name, err)
+		}
+	}
+	if _, err := UnmarshalPEMKeyFile(append(bytes.Clone(data), "junk"...)); !errors.Is(err, ErrCorruptKeyfile) {
+		t.Errorf("trailing data: got %v, want ErrCorruptKeyfile", err)
+	}
+
+	// Flip a bit of the ciphertext: the checksum catches it.
+	tampered := &pem.Block{Type: block.Type, Headers: block.Headers, Bytes: bytes.Clone(block.Bytes)}
+	tampered.Bytes[0] ^= 1
+	kf, err := UnmarshalPEMKeyFile(pem.EncodeToMemory(tampered))
+	if err == nil {
+		_, err = Decrypt(kf, "pw")
+	}
+	if !errors.Is(err, ErrCorruptKeyfile) {
+		t.Errorf("tampered: got %v, want ErrCorruptKeyfile", err)
+	}
+}
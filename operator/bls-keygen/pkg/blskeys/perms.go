@@ -0,0 +1,51 @@
+package blskeys
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// KeyFileMode is the permission set key files are written with, and the
+// only one CheckPermissions accepts.
+const KeyFileMode os.FileMode = 0600
+
+// KeyDirMode is the permission set key directories are created with.
+const KeyDirMode os.FileMode = 0700
+
+// ErrUnsafePermissions is returned by CheckPermissions when the key file or
+// its directory can be read or modified by other users.
+var ErrUnsafePermissions = errors.New("unsafe key file permissions")
+
+// CheckPermissions verifies that the key file at path is owner-only (0600)
+// and that its directory is not group- or world-writable. Key files are
+// written 0600, but a file copied or restored into place may not be, so
+// this is checked after the fact rather than assumed.
+func CheckPermissions(path string) error {
+	return CheckPermissionsMode(path, KeyFileMode, KeyDirMode)
+}
+
+// CheckPermissionsMode is CheckPermissions for deployments that deliberately
+// share the key, e.g. with a sidecar in the same group: the key file must
+// have exactly fileMode, and its directory may be writable by group or world
+// only where dirMode is.
+func CheckPermissionsMode(path string, fileMode, dirMode os.FileMode) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if mode := info.Mode().Perm(); mode != fileMode {
+		return fmt.Errorf("%w: %s has mode %04o, want %04o (run: chmod %o %s)", ErrUnsafePermissions, path, mode, fileMode, fileMode, path)
+	}
+
+	dir := filepath.Dir(path)
+	info, err = os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if mode := info.Mode().Perm(); mode&0022&^dirMode != 0 {
+		return fmt.Errorf("%w: directory %s has mode %04o and is writable by other users (run: chmod go-w %s)", ErrUnsafePermissions, dir, mode, dir)
+	}
+	return nil
+}
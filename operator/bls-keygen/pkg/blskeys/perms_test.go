@@ -0,0 +1,74 @@
+package blskeys
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckPermissions(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Chmod(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "bls_key.json")
+	kp, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Save(kp, path, "pw"); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckPermissions(path); err != nil {
+		t.Fatalf("freshly saved key: %v", err)
+	}
+
+	if err := os.Chmod(path, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckPermissions(path); !errors.Is(err, ErrUnsafePermissions) {
+		t.Fatalf("world-readable key: got %v, want ErrUnsafePermissions", err)
+	}
+
+	if err := os.Chmod(path, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(dir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckPermissions(path); !errors.Is(err, ErrUnsafePermissions) {
+		t.Fatalf("world-writable directory: got %v, want ErrUnsafePermissions", err)
+	}
+
+	if err := os.Chmod(dir, 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckPermissions(path); err != nil {
+		t.Fatalf("group-readable directory: %v", err)
+	}
+}
+
+func TestCheckPermissionsMode(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Chmod(dir, 0770); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "bls_key.json")
+	if err := os.WriteFile(path, []byte("{}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := CheckPermissionsMode(path, 0640, 0770); err != nil {
+		t.Fatalf("configured shared modes: %v", err)
+	}
+	if err := CheckPermissions(path); !errors.Is(err, ErrUnsafePermissions) {
+		t.Fatalf("default modes: got %v, want ErrUnsafePermissions", err)
+	}
+	if err := CheckPermissionsMode(path, 0640, 0750); !errors.Is(err, ErrUnsafePermissions) {
+		t.Fatalf("group-writable directory not configured: got %v, want ErrUnsafePermissions", err)
+	}
+}
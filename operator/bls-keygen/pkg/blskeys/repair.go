@@ -0,0 +1,116 @@
+package blskeys
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// RepairReport says what RepairPublicKeys changed.
+type RepairReport struct {
+	// G1Fixed and G2Fixed are set when the stored public key was missing,
+	// malformed or not the private key's.
+	G1Fixed bool
+	G2Fixed bool
+	// ChecksumFixed is set when the stored checksum did not match the file.
+	// A repaired Bastion key file always gets a fresh checksum.
+	ChecksumFixed bool
+}
+
+// Changed reports whether the file needed repairing.
+func (r RepairReport) Changed() bool {
+	return r.G1Fixed || r.G2Fixed || r.ChecksumFixed
+}
+
+// RepairPublicKeys re-derives the public keys of the key file at path from
+// its private key and rewrites the stored ones that differ, recomputing the
+// checksum. The checksum and stored public keys are not trusted for this,
+// but the private key must still decrypt with password (AES-GCM and the
+// EIP-2335 checksum authenticate it) and be a valid scalar; otherwise
+// nothing is written. Files that need no repair are left untouched. Legacy
+// plaintext files have no public keys to repair; migrate them instead.
+func RepairPublicKeys(path, password string) (RepairReport, error) {
+	data, err := readKeyFile(path)
+	if err != nil {
+		return RepairReport{}, err
+	}
+	if kf, format, err := unmarshalNonJSON(data); format != "" {
+		if err != nil {
+			return RepairReport{}, err
+		}
+		report, err := repairKeyFile(kf, password)
+		if err != nil || !report.Changed() {
+			return report, err
+		}
+		b, err := marshalNonJSON(kf, format)
+		if err != nil {
+			return RepairReport{}, err
+		}
+		return report, writeFile(path, b)
+	}
+
+	var header struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return RepairReport{}, fmt.Errorf("%w: %v", ErrCorruptKeyfile, err)
+	}
+	switch header.Version {
+	case eip2335Version:
+		var ks EIP2335Keystore
+		if err := json.Unmarshal(data, &ks); err != nil {
+			return RepairReport{}, fmt.Errorf("%w: %v", ErrCorruptKeyfile, err)
+		}
+		kp, err := openEIP2335(&ks, password)
+		if err != nil {
+			return RepairReport{}, err
+		}
+		defer kp.PrivateKey.Zero()
+		if ks.PubKey != "" && checkStoredPubKeys(kp, ks.PubKey, "") == nil {
+			return RepairReport{}, nil
+		}
+		ks.PubKey = hex.EncodeToString(kp.G1PubKey.Bytes())
+		return RepairReport{G1Fixed: true}, writeJSON(path, &ks)
+	case CurrentVersion:
+		var kf KeyFile
+		if err := json.Unmarshal(data, &kf); err != nil {
+			return RepairReport{}, fmt.Errorf("%w: %v", ErrCorruptKeyfile, err)
+		}
+		report, err := repairKeyFile(&kf, password)
+		if err != nil || !report.Changed() {
+			return report, err
+		}
+		return report, writeJSON(path, &kf)
+	case 0:
+		return RepairReport{}, errors.New("version 0 key files store no public keys to repair; run migrate")
+	default:
+		return RepairReport{}, fmt.Errorf("%w: unsupported key file version %d", ErrCorruptKeyfile, header.Version)
+	}
+}
+
+// repairKeyFile fixes kf's public keys and checksum in place.
+func repairKeyFile(kf *KeyFile, password string) (RepairReport, error) {
+	kp, err := decryptKeyFile(kf, password)
+	if err != nil {
+		return RepairReport{}, err
+	}
+	defer kp.PrivateKey.Zero()
+
+	var report RepairReport
+	report.ChecksumFixed = kf.verifyChecksum() != nil
+	if kf.G1PubKey == "" || checkStoredPubKeys(kp, kf.G1PubKey, "") != nil {
+		kf.G1PubKey = fmt.Sprintf("0x%x", kp.G1PubKey.Bytes())
+		report.G1Fixed = true
+	}
+	if kf.G2PubKey == "" || checkStoredPubKeys(kp, "", kf.G2PubKey) != nil {
+		kf.G2PubKey = fmt.Sprintf("0x%x", kp.G2PubKey.Bytes())
+		report.G2Fixed = true
+	}
+	if report.Changed() {
+		if kf.Checksum, err = kf.computeChecksum(); err != nil {
+			return RepairReport{}, err
+		}
+	}
+	return report, nil
+}
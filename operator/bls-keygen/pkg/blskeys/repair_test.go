@@ -0,0 +1,65 @@
+package blskeys
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRepairPublicKeysEIP2335(t *testing.T) {
+	kp, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "keystore.json")
+	if err := SaveEIP2335Context(context.Background(), kp, path, "pw", testScrypt, nil); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored := []byte(`"pubkey": "` + hex.EncodeToString(kp.G1PubKey.Bytes()) + `"`)
+	if !bytes.Contains(data, stored) {
+		t.Fatalf("keystore does not contain %s", stored)
+	}
+	if err := os.WriteFile(path, bytes.Replace(data, stored, []byte(`"pubkey": ""`), 1), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := RepairPublicKeys(path, "pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.G1Fixed || report.G2Fixed {
+		t.Fatalf("report %+v, want only G1 fixed", report)
+	}
+	g1, err := LoadPublicKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(g1.Bytes(), kp.G1PubKey.Bytes()) {
+		t.Fatal("repaired pubkey is not the key's own")
+	}
+}
+
+func TestRepairPublicKeysIntactBinary(t *testing.T) {
+	kp, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "bls_key.bin")
+	if err := SaveBinaryContext(context.Background(), kp, path, "pw", testScrypt, nil); err != nil {
+		t.Fatal(err)
+	}
+	report, err := RepairPublicKeys(path, "pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Changed() {
+		t.Fatalf("intact file reported as repaired: %+v", report)
+	}
+}
@@ -0,0 +1,58 @@
+package blskeys
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+// BackupPath returns where Rotate moves the key at path at time now.
+func BackupPath(path string, now time.Time) string {
+	return fmt.Sprintf("%s.%d.bak", path, now.Unix())
+}
+
+// Rotate replaces the key at path with a freshly generated one encrypted
+// under newPassword. The old file is kept, still encrypted under
+// oldPassword, at BackupPath(path, now). Nothing is touched unless the
+// existing key decrypts with oldPassword, and the old key stays at path
+// until the new one atomically replaces it. A symlinked path is rotated at
+// its target, where the backup goes too. The new key keeps the old one's
+// label, network and test-only metadata, with a fresh creation time and
+// GeneratorVersion.
+func Rotate(path, oldPassword, newPassword string, now time.Time) (kp *KeyPair, backup string, err error) {
+	// Back up the target of a symlinked path, not the link, which would
+	// end up pointing at the new key.
+	if path, err = ResolvePath(path); err != nil {
+		return nil, "", err
+	}
+	old, err := Load(path, oldPassword)
+	if err != nil {
+		return nil, "", fmt.Errorf("refusing to rotate, existing key could not be decrypted: %w", err)
+	}
+	old.PrivateKey.Zero()
+	meta := NewMetadata("", now)
+	if oldMeta, err := LoadMetadata(path); err == nil && oldMeta != nil {
+		meta.Label, meta.Network, meta.TestOnly = oldMeta.Label, oldMeta.Network, oldMeta.TestOnly
+	}
+
+	kp, err = Generate()
+	if err != nil {
+		return nil, "", err
+	}
+
+	backup = BackupPath(path, now)
+	if err := os.Link(path, backup); err != nil {
+		if os.IsExist(err) {
+			return nil, "", fmt.Errorf("backup %s already exists", backup)
+		}
+		return nil, "", fmt.Errorf("failed to back up existing key: %w", err)
+	}
+	if err := SaveContext(context.Background(), kp, path, newPassword, DefaultScryptParams, meta); err != nil {
+		// The old key is still at path; drop the now redundant backup.
+		os.Remove(backup)
+		kp.PrivateKey.Zero()
+		return nil, "", err
+	}
+	return kp, backup, nil
+}
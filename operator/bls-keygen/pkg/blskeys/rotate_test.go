@@ -0,0 +1,100 @@
+package blskeys
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRotate(t *testing.T) {
+	old, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "bls_key.json")
+	if err := Save(old, path, "old-password"); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Unix(1700000000, 0)
+	kp, backup, err := Rotate(path, "old-password", "new-password", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if backup != path+".1700000000.bak" {
+		t.Fatalf("backup = %s", backup)
+	}
+
+	restored, err := Load(backup, "old-password")
+	if err != nil {
+		t.Fatalf("backup not loadable: %v", err)
+	}
+	if !bytes.Equal(restored.PrivateKey.Bytes(), old.PrivateKey.Bytes()) {
+		t.Fatal("backup does not hold the old key")
+	}
+
+	current, err := Load(path, "new-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(current.PrivateKey.Bytes(), kp.PrivateKey.Bytes()) {
+		t.Fatal("primary file does not hold the new key")
+	}
+	if bytes.Equal(current.PrivateKey.Bytes(), old.PrivateKey.Bytes()) {
+		t.Fatal("rotation kept the same key")
+	}
+}
+
+func TestRotateKeepsMetadata(t *testing.T) {
+	old, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "bls_key.json")
+	created := NewMetadata("holesky", time.Unix(1600000000, 0))
+	created.TestOnly = true
+	if err := SaveContext(context.Background(), old, path, "pw", testScrypt, created); err != nil {
+		t.Fatal(err)
+	}
+	defer func(v string) { GeneratorVersion = v }(GeneratorVersion)
+	GeneratorVersion = "rotator 2.0"
+
+	now := time.Unix(1700000000, 0)
+	if _, _, err := Rotate(path, "pw", "pw", now); err != nil {
+		t.Fatal(err)
+	}
+	meta, err := LoadMetadata(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Metadata{Network: "holesky", TestOnly: true, CreatedAt: "2023-11-14T22:13:20Z", GeneratorVersion: "rotator 2.0"}
+	if meta == nil || *meta != want {
+		t.Fatalf("metadata after rotation = %+v, want %+v", meta, want)
+	}
+}
+
+func TestRotateRefusesUndecryptableKey(t *testing.T) {
+	old, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "bls_key.json")
+	if err := Save(old, path, "old-password"); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Unix(1700000000, 0)
+	if _, _, err := Rotate(path, "wrong", "new-password", now); !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("got %v, want ErrDecrypt", err)
+	}
+	if _, err := os.Stat(BackupPath(path, now)); !os.IsNotExist(err) {
+		t.Fatal("a backup was written despite the failed decryption")
+	}
+	if _, err := Load(path, "old-password"); err != nil {
+		t.Fatalf("original key is no longer loadable: %v", err)
+	}
+}
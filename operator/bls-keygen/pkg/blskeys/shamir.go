@@ -0,0 +1,210 @@
+package blskeys
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+)
+
+// shareFileVersion is the version of the share file layout.
+const shareFileVersion = 1
+
+// MaxShares is the most shares Split produces; share indices are 1..255.
+const MaxShares = 255
+
+var (
+	// ErrTooFewShares is returned by Combine when given fewer shares than
+	// the threshold they were split with.
+	ErrTooFewShares = errors.New("not enough shares to reconstruct the key")
+	// ErrShareMismatch is returned when shares come from different splits
+	// or reconstruct a key other than the one they were split from.
+	ErrShareMismatch = errors.New("shares do not belong to the same key")
+)
+
+// Share is one Shamir share of a private key: the point (Index, Value) on
+// a random polynomial over the scalar field whose constant term is the key.
+// Value is as secret as the key itself. Threshold and G1PubKey let Combine
+// reject short or mixed share sets and check what it reconstructed.
+type Share struct {
+	Version   int    `json:"version"`
+	Index     int    `json:"index"`
+	Threshold int    `json:"threshold"`
+	Shares    int    `json:"shares"`
+	G1PubKey  string `json:"g1_pub_key"`
+	Value     string `json:"value"`
+}
+
+// Split splits the private key of kp into n shares, any threshold of which
+// reconstruct it.
+func Split(kp *KeyPair, n, threshold int) ([]Share, error) {
+	if threshold < 2 || threshold > n || n > MaxShares {
+		return nil, fmt.Errorf("invalid split: need 2 <= threshold <= shares <= %d, got %d of %d", MaxShares, threshold, n)
+	}
+	secret := kp.PrivateKey.Bytes()
+	defer secret.Zero()
+	coeffs := make([]fr.Element, threshold)
+	defer zeroElements(coeffs)
+	coeffs[0].SetBytes(secret)
+	for i := 1; i < threshold; i++ {
+		if _, err := coeffs[i].SetRandom(); err != nil {
+			return nil, fmt.Errorf("failed to sample polynomial: %w", err)
+		}
+	}
+
+	pub := fmt.Sprintf("0x%x", kp.G1PubKey.Bytes())
+	shares := make([]Share, n)
+	for i := range shares {
+		var x, y fr.Element
+		x.SetUint64(uint64(i + 1))
+		// Horner's rule from the highest coefficient down.
+		for j := threshold - 1; j >= 0; j-- {
+			y.Mul(&y, &x).Add(&y, &coeffs[j])
+		}
+		b := y.Bytes()
+		shares[i] = Share{
+			Version:   shareFileVersion,
+			Index:     i + 1,
+			Threshold: threshold,
+			Shares:    n,
+			G1PubKey:  pub,
+			Value:     hex.EncodeToString(b[:]),
+		}
+		y.SetZero()
+	}
+	return shares, nil
+}
+
+// Combine reconstructs the key pair from at least Threshold shares of one
+// split by Lagrange interpolation at zero. The result must match the public
+// key recorded in the shares.
+func Combine(shares []Share) (*KeyPair, error) {
+	if len(shares) == 0 {
+		return nil, ErrTooFewShares
+	}
+	first := shares[0]
+	pub, err := sharePubKey(first)
+	if err != nil {
+		return nil, err
+	}
+	xs := make([]fr.Element, len(shares))
+	ys := make([]fr.Element, len(shares))
+	defer zeroElements(ys)
+	seen := make(map[int]bool)
+	for i, s := range shares {
+		if s.Version != shareFileVersion {
+			return nil, fmt.Errorf("share %d: unsupported share version %d", s.Index, s.Version)
+		}
+		if s.Threshold != first.Threshold || s.Shares != first.Shares {
+			return nil, fmt.Errorf("%w: share %d is %d of %d, share %d is %d of %d",
+				ErrShareMismatch, s.Index, s.Threshold, s.Shares, first.Index, first.Threshold, first.Shares)
+		}
+		if p, err := sharePubKey(s); err != nil {
+			return nil, err
+		} else if !bytes.Equal(p.Bytes(), pub.Bytes()) {
+			return nil, fmt.Errorf("%w: share %d is of key %s, share %d of %s",
+				ErrShareMismatch, s.Index, bls.Fingerprint(p), first.Index, bls.Fingerprint(pub))
+		}
+		if s.Index < 1 || s.Index > s.Shares || seen[s.Index] {
+			return nil, fmt.Errorf("invalid or duplicate share index %d", s.Index)
+		}
+		seen[s.Index] = true
+		b, err := hex.DecodeString(strings.TrimPrefix(s.Value, "0x"))
+		if err != nil || len(b) != fr.Bytes {
+			return nil, fmt.Errorf("share %d: invalid value", s.Index)
+		}
+		err = ys[i].SetBytesCanonical(b)
+		bls.SecretBytes(b).Zero()
+		if err != nil {
+			return nil, fmt.Errorf("share %d: invalid value: %w", s.Index, err)
+		}
+		xs[i].SetUint64(uint64(s.Index))
+	}
+	if len(shares) < first.Threshold {
+		return nil, fmt.Errorf("%w: have %d, need %d", ErrTooFewShares, len(shares), first.Threshold)
+	}
+
+	// secret = sum_i y_i * prod_{j != i} x_j / (x_j - x_i)
+	var secret fr.Element
+	defer secret.SetZero()
+	for i := range xs {
+		num, den := fr.One(), fr.One()
+		for j := range xs {
+			if i == j {
+				continue
+			}
+			var d fr.Element
+			d.Sub(&xs[j], &xs[i])
+			num.Mul(&num, &xs[j])
+			den.Mul(&den, &d)
+		}
+		var term fr.Element
+		term.Div(&num, &den).Mul(&term, &ys[i])
+		secret.Add(&secret, &term)
+		term.SetZero()
+	}
+
+	b := secret.Bytes()
+	defer bls.SecretBytes(b[:]).Zero()
+	sk, err := bls.PrivateKeyFromBytes(b[:])
+	if err != nil {
+		return nil, ErrShareMismatch
+	}
+	kp := bls.NewKeyPair(sk)
+	if !bytes.Equal(kp.G1PubKey.Bytes(), pub.Bytes()) {
+		kp.PrivateKey.Zero()
+		return nil, ErrShareMismatch
+	}
+	return kp, nil
+}
+
+// SaveShare writes s to path, readable by its owner only.
+func SaveShare(s Share, path string) error {
+	return writeJSON(path, s)
+}
+
+// MarshalShare is SaveShare returning the encoded share instead of writing
+// it.
+func MarshalShare(s Share) ([]byte, error) {
+	return marshalKeyFile(s)
+}
+
+// LoadShare reads a share written by SaveShare.
+func LoadShare(path string) (Share, error) {
+	var s Share
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return s, fmt.Errorf("failed to parse share file: %w", err)
+	}
+	return s, nil
+}
+
+// sharePubKey parses the public key s records, so that shares are compared
+// by key rather than by how its hex is written.
+func sharePubKey(s Share) (*bls.G1PubKey, error) {
+	b, err := hex.DecodeString(strings.TrimPrefix(s.G1PubKey, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("share %d: invalid g1_pub_key: %w", s.Index, err)
+	}
+	pk, err := bls.G1PubKeyFromBytes(b)
+	if err != nil {
+		return nil, fmt.Errorf("share %d: invalid g1_pub_key: %w", s.Index, err)
+	}
+	return pk, nil
+}
+
+func zeroElements(es []fr.Element) {
+	for i := range es {
+		es[i].SetZero()
+	}
+}
@@ -0,0 +1,154 @@
+package blskeys
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSplitCombine(t *testing.T) {
+	kp, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	shares, err := Split(kp, 5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every 3-subset reconstructs the key.
+	for a := 0; a < 5; a++ {
+		for b := a + 1; b < 5; b++ {
+			for c := b + 1; c < 5; c++ {
+				got, err := Combine([]Share{shares[c], shares[a], shares[b]})
+				if err != nil {
+					t.Fatalf("shares %d,%d,%d: %v", a, b, c, err)
+				}
+				if !bytes.Equal(got.PrivateKey.Bytes(), kp.PrivateKey.Bytes()) {
+					t.Fatalf("shares %d,%d,%d reconstructed the wrong key", a, b, c)
+				}
+			}
+		}
+	}
+	if _, err := Combine(shares); err != nil {
+		t.Fatalf("all shares: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "share-1.json")
+	if err := SaveShare(shares[0], path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadShare(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded != shares[0] {
+		t.Fatal("share did not round-trip")
+	}
+}
+
+func TestCombineInsufficientShares(t *testing.T) {
+	kp, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	shares, err := Split(kp, 5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Combine(shares[:2]); !errors.Is(err, ErrTooFewShares) {
+		t.Fatalf("two shares: got %v, want ErrTooFewShares", err)
+	}
+
+	// Lowering the recorded threshold does not help: the interpolated key
+	// does not match the recorded public key.
+	forged := []Share{shares[0], shares[1]}
+	for i := range forged {
+		forged[i].Threshold = 2
+	}
+	if _, err := Combine(forged); !errors.Is(err, ErrShareMismatch) {
+		t.Fatalf("forged threshold: got %v, want ErrShareMismatch", err)
+	}
+
+	if _, err := Combine([]Share{shares[0], shares[0], shares[1]}); err == nil {
+		t.Fatal("duplicate share accepted")
+	}
+
+	other, err := Split(kp, 5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Combine([]Share{shares[0], shares[1], other[2]}); !errors.Is(err, ErrShareMismatch) {
+		t.Fatalf("mixed splits: got %v, want ErrShareMismatch", err)
+	}
+}
+
+func TestCombineChecksEveryShare(t *testing.T) {
+	kp, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	shares, err := Split(kp, 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherShares, err := Split(other, 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each case alters only the second share, so a check of the first alone
+	// lets it through.
+	cases := map[string]struct {
+		alter func(s *Share)
+		want  error
+	}{
+		"version":   {func(s *Share) { s.Version = shareFileVersion + 1 }, nil},
+		"threshold": {func(s *Share) { s.Threshold = 3 }, ErrShareMismatch},
+		"key":       {func(s *Share) { s.G1PubKey = otherShares[1].G1PubKey }, ErrShareMismatch},
+		"bad key":   {func(s *Share) { s.G1PubKey = "0x00" }, nil},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			second := shares[1]
+			c.alter(&second)
+			_, err := Combine([]Share{shares[0], second})
+			if err == nil {
+				t.Fatal("altered share accepted")
+			}
+			if c.want != nil && !errors.Is(err, c.want) {
+				t.Fatalf("got %v, want %v", err, c.want)
+			}
+		})
+	}
+
+	// The same key written in upper-case hex is still the same key.
+	second := shares[1]
+	second.G1PubKey = "0x" + strings.ToUpper(strings.TrimPrefix(second.G1PubKey, "0x"))
+	got, err := Combine([]Share{shares[0], second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer got.PrivateKey.Zero()
+	if !bytes.Equal(got.G1PubKey.Bytes(), kp.G1PubKey.Bytes()) {
+		t.Fatal("combined a different key")
+	}
+}
+
+func TestSplitRejectsBadParameters(t *testing.T) {
+	kp, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct{ n, threshold int }{{5, 1}, {3, 4}, {256, 3}} {
+		if _, err := Split(kp, c.n, c.threshold); err == nil {
+			t.Errorf("Split(%d, %d) accepted", c.n, c.threshold)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package blskeys
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+)
+
+// ErrSignerClosed is returned by Sign on a file signer after Close.
+var ErrSignerClosed = errors.New("signer is closed")
+
+// Signer produces BLS signatures without exposing where the private key
+// lives: in a local key file or behind a KMS.
+type Signer interface {
+	// Sign signs msg, hashing it to G1 under bls.DST.
+	Sign(msg []byte) (*bls.Signature, error)
+	// PublicKeyG2 returns the public key signatures verify against.
+	PublicKeyG2() *bls.G2PubKey
+}
+
+// DomainSigner is a Signer that can also sign under a bls.Domain other than
+// bls.DefaultDomain. File signers are DomainSigners; KMS signers are not,
+// since the KMS fixes its own tag.
+type DomainSigner interface {
+	Signer
+	SignDomain(msg []byte, d bls.Domain) (*bls.Signature, error)
+}
+
+// fileSigner signs with a key decrypted from a key file. Signing holds mu
+// for reading, so Close cannot zero the key under a signature in progress.
+type fileSigner struct {
+	mu     sync.RWMutex
+	kp     *KeyPair
+	closed bool
+}
+
+// NewFileSigner loads the key file at path with password and returns a
+// Signer for it. The Signer is also an io.Closer; Close clears the key
+// from memory. It is safe for concurrent use, Close included.
+func NewFileSigner(path, password string) (Signer, error) {
+	kp, err := Load(path, password)
+	if err != nil {
+		return nil, err
+	}
+	return &fileSigner{kp: kp}, nil
+}
+
+func (s *fileSigner) Sign(msg []byte) (*bls.Signature, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return nil, ErrSignerClosed
+	}
+	return s.kp.Sign(msg)
+}
+
+func (s *fileSigner) SignDomain(msg []byte, d bls.Domain) (*bls.Signature, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return nil, ErrSignerClosed
+	}
+	return s.kp.SignDomain(msg, d)
+}
+
+func (s *fileSigner) PublicKeyG2() *bls.G2PubKey {
+	return s.kp.G2PubKey
+}
+
+// Close zeroes the private key once signatures in flight have finished.
+// Sign returns ErrSignerClosed afterwards.
+func (s *fileSigner) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	s.kp.PrivateKey.Zero()
+	return nil
+}
+
+// KMSClient is the seam for KMS-held keys: the KMS signs and reports the
+// public key, and the private key never leaves it. Encodings are the
+// compressed ones used throughout this module.
+type KMSClient interface {
+	Sign(keyID string, msg []byte) ([]byte, error)
+	PublicKeyG2(keyID string) ([]byte, error)
+}
+
+// kmsSigner signs through a KMSClient.
+type kmsSigner struct {
+	client KMSClient
+	keyID  string
+	pk     *bls.G2PubKey
+}
+
+// NewKMSSigner returns a Signer for the key keyID held by client. The
+// public key is fetched once and checked up front.
+func NewKMSSigner(client KMSClient, keyID string) (Signer, error) {
+	if client == nil {
+		return nil, errors.New("no KMS client configured")
+	}
+	raw, err := client.PublicKeyG2(keyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch KMS public key: %w", err)
+	}
+	pk, err := bls.G2PubKeyFromBytes(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid KMS public key: %w", err)
+	}
+	return &kmsSigner{client: client, keyID: keyID, pk: pk}, nil
+}
+
+// Sign asks the KMS for a signature and rejects any that does not verify,
+// so a misbehaving KMS cannot hand out bad signatures.
+func (s *kmsSigner) Sign(msg []byte) (*bls.Signature, error) {
+	raw, err := s.client.Sign(s.keyID, msg)
+	if err != nil {
+		return nil, fmt.Errorf("KMS sign failed: %w", err)
+	}
+	sig, err := bls.SignatureFromBytes(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid KMS signature: %w", err)
+	}
+	if !bls.Verify(s.pk, msg, sig) {
+		return nil, errors.New("KMS signature does not verify against its public key")
+	}
+	return sig, nil
+}
+
+func (s *kmsSigner) PublicKeyG2() *bls.G2PubKey {
+	return s.pk
+}
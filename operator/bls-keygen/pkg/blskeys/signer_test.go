@@ -0,0 +1,153 @@
+package blskeys
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/big14way/bastion/operator/bls-keygen/pkg/bls"
+)
+
+var (
+	_ DomainSigner = (*fileSigner)(nil)
+	_ Signer       = (*kmsSigner)(nil)
+)
+
+// fakeKMS holds a key in memory, standing in for a real KMS.
+type fakeKMS struct {
+	kp      *KeyPair
+	corrupt bool
+	signed  int
+}
+
+func (k *fakeKMS) Sign(keyID string, msg []byte) ([]byte, error) {
+	if keyID != "bastion-operator" {
+		return nil, errors.New("unknown key")
+	}
+	k.signed++
+	if k.corrupt {
+		msg = append([]byte("x"), msg...)
+	}
+	sig, err := k.kp.Sign(msg)
+	if err != nil {
+		return nil, err
+	}
+	return sig.Bytes(), nil
+}
+
+func (k *fakeKMS) PublicKeyG2(keyID string) ([]byte, error) {
+	if keyID != "bastion-operator" {
+		return nil, errors.New("unknown key")
+	}
+	return k.kp.G2PubKey.Bytes(), nil
+}
+
+func TestFileSigner(t *testing.T) {
+	kp, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "bls_key.json")
+	if err := Save(kp, path, "pw"); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewFileSigner(path, "pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(s.PublicKeyG2().Bytes(), kp.G2PubKey.Bytes()) {
+		t.Fatal("file signer reports the wrong public key")
+	}
+	sig, err := s.Sign([]byte("msg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bls.Verify(s.PublicKeyG2(), []byte("msg"), sig) {
+		t.Fatal("file signer signature does not verify")
+	}
+
+	if err := s.(io.Closer).Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Sign([]byte("msg")); !errors.Is(err, ErrSignerClosed) {
+		t.Fatalf("sign after Close: got %v, want ErrSignerClosed", err)
+	}
+
+	if _, err := NewFileSigner(path, "wrong"); !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("wrong password: got %v, want ErrDecrypt", err)
+	}
+}
+
+func TestKMSSigner(t *testing.T) {
+	kp, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	kms := &fakeKMS{kp: kp}
+
+	s, err := NewKMSSigner(kms, "bastion-operator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := s.Sign([]byte("msg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kms.signed != 1 || !bls.Verify(kp.G2PubKey, []byte("msg"), sig) {
+		t.Fatal("KMS signer did not sign through the client")
+	}
+
+	kms.corrupt = true
+	if _, err := s.Sign([]byte("msg")); err == nil {
+		t.Fatal("KMS signer accepted a signature that does not verify")
+	}
+
+	if _, err := NewKMSSigner(kms, "other"); err == nil {
+		t.Fatal("expected an error for an unknown key")
+	}
+	if _, err := NewKMSSigner(nil, "bastion-operator"); err == nil {
+		t.Fatal("expected an error without a client")
+	}
+}
+
+func TestFileSignerConcurrentSign(t *testing.T) {
+	kp, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "bls_key.json")
+	if err := SaveContext(context.Background(), kp, path, "pw", testScrypt, nil); err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewFileSigner(path, "pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < 16; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			msg := []byte(fmt.Sprintf("worker %d", w))
+			for i := 0; i < 4; i++ {
+				sig, err := s.Sign(msg)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if !bls.Verify(kp.G2PubKey, msg, sig) {
+					t.Errorf("worker %d: signature does not verify", w)
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,49 @@
+package blskeys
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ErrSymlink is returned for a key path that is a symbolic link where
+// links must not be followed.
+var ErrSymlink = errors.New("key path is a symbolic link")
+
+// maxSymlinkHops bounds ResolvePath, like the kernel's ELOOP limit.
+const maxSymlinkHops = 40
+
+// ResolvePath returns the file path refers to once every symbolic link at
+// its final element is followed. Unlike filepath.EvalSymlinks it accepts a
+// link whose target does not exist yet, so a key can be written through a
+// freshly mounted link. A path that is not a link is returned unchanged.
+//
+// Key files are written through links: writing to a symlinked path
+// replaces the target atomically and leaves the link in place.
+func ResolvePath(path string) (string, error) {
+	for hops := 0; ; hops++ {
+		fi, err := os.Lstat(path)
+		if err != nil || fi.Mode()&os.ModeSymlink == 0 {
+			// A missing path is where a new file goes.
+			return path, nil
+		}
+		if hops == maxSymlinkHops {
+			return "", fmt.Errorf("%s: too many levels of symbolic links", path)
+		}
+		target, err := os.Readlink(path)
+		if err != nil {
+			return "", err
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(path), target)
+		}
+		path = target
+	}
+}
+
+// IsSymlink reports whether path itself is a symbolic link.
+func IsSymlink(path string) bool {
+	fi, err := os.Lstat(path)
+	return err == nil && fi.Mode()&os.ModeSymlink != 0
+}
@@ -0,0 +1,117 @@
+package blskeys
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResolvePath(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "real", "bls_key.json")
+	if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+		t.Fatal(err)
+	}
+	// link -> hop (relative) -> real/bls_key.json, which does not exist yet.
+	hop := filepath.Join(dir, "hop")
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(filepath.Join("real", "bls_key.json"), hop); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(hop, link); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ResolvePath(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != target {
+		t.Fatalf("ResolvePath = %s, want %s", got, target)
+	}
+	if got, err := ResolvePath(target); err != nil || got != target {
+		t.Fatalf("ResolvePath of a plain path = %s, %v", got, err)
+	}
+
+	loop := filepath.Join(dir, "loop")
+	if err := os.Symlink(loop, loop); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ResolvePath(loop); err == nil {
+		t.Fatal("expected an error for a symlink loop")
+	}
+}
+
+func TestSaveWritesThroughSymlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "v1.json")
+	link := filepath.Join(dir, "bls_key.json")
+	if err := os.Symlink("v1.json", link); err != nil {
+		t.Fatal(err)
+	}
+	kp, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The target does not exist yet: the first write creates it.
+	if err := SaveContext(context.Background(), kp, link, "pw", testScrypt, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !IsSymlink(link) {
+		t.Fatal("Save replaced the symlink")
+	}
+	if _, err := os.Stat(target); err != nil {
+		t.Fatalf("target was not written: %v", err)
+	}
+
+	if err := ChangePassword(link, "pw", "new-pw"); err != nil {
+		t.Fatal(err)
+	}
+	if !IsSymlink(link) {
+		t.Fatal("ChangePassword replaced the symlink")
+	}
+	if _, err := Load(target, "new-pw"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRotateThroughSymlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "v1.json")
+	link := filepath.Join(dir, "bls_key.json")
+	old, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveContext(context.Background(), old, target, "pw", testScrypt, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("v1.json", link); err != nil {
+		t.Fatal(err)
+	}
+
+	kp, backup, err := Rotate(link, "pw", "pw", time.Unix(1700000000, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if IsSymlink(backup) || !strings.HasPrefix(filepath.Base(backup), "v1.json.") {
+		t.Fatalf("backup %s is not a copy of the link's target", backup)
+	}
+	loaded, err := Load(backup, "pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(loaded.PrivateKey.Bytes(), old.PrivateKey.Bytes()) {
+		t.Fatal("backup does not hold the old key")
+	}
+	current, err := Load(link, "pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsSymlink(link) || !bytes.Equal(current.PrivateKey.Bytes(), kp.PrivateKey.Bytes()) {
+		t.Fatal("link does not lead to the new key")
+	}
+}
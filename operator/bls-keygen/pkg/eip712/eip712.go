@@ -0,0 +1,487 @@
+// Package eip712 computes EIP-712 digests of typed structured data given in
+// the JSON layout of eth_signTypedData_v4: types, primaryType, domain and
+// message.
+//
+// Values are checked against their declared types as they are encoded, and
+// errors name the offending field by its path, e.g. message.to.wallet.
+package eip712
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// domainType is the type of the domain separator's struct.
+const domainType = "EIP712Domain"
+
+// ErrInvalid is matched by every error about the structure of typed data
+// or a value that does not encode as its declared type.
+var ErrInvalid = errors.New("eip712: invalid typed data")
+
+// Field is one member of a struct type.
+type Field struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// TypedData is an EIP-712 typed data document.
+type TypedData struct {
+	Types       map[string][]Field `json:"types"`
+	PrimaryType string             `json:"primaryType"`
+	Domain      map[string]any     `json:"domain"`
+	Message     map[string]any     `json:"message"`
+}
+
+// Parse decodes a typed data document. Numbers are kept exact, so integers
+// wider than a float64 can be given as JSON numbers. It checks the type
+// definitions; values are checked by Digest.
+func Parse(data []byte) (*TypedData, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	dec.DisallowUnknownFields()
+	var td TypedData
+	if err := dec.Decode(&td); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("%w: trailing data after the JSON object", ErrInvalid)
+	}
+	if err := td.validate(); err != nil {
+		return nil, err
+	}
+	return &td, nil
+}
+
+var identifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// validate checks that the types are well formed and that every type they,
+// the domain and the primary type refer to is defined.
+func (td *TypedData) validate() error {
+	if td.Types == nil {
+		return fmt.Errorf("%w: types is missing", ErrInvalid)
+	}
+	if _, ok := td.Types[domainType]; !ok {
+		return fmt.Errorf("%w: types.%s is missing", ErrInvalid, domainType)
+	}
+	if td.PrimaryType == "" {
+		return fmt.Errorf("%w: primaryType is missing", ErrInvalid)
+	}
+	if _, ok := td.Types[td.PrimaryType]; !ok {
+		return fmt.Errorf("%w: primaryType %q is not defined in types", ErrInvalid, td.PrimaryType)
+	}
+	if td.Domain == nil {
+		return fmt.Errorf("%w: domain is missing", ErrInvalid)
+	}
+	if td.Message == nil && td.PrimaryType != domainType {
+		return fmt.Errorf("%w: message is missing", ErrInvalid)
+	}
+	for name, fields := range td.Types {
+		if !identifier.MatchString(name) {
+			return fmt.Errorf("%w: type name %q is not an identifier", ErrInvalid, name)
+		}
+		if _, err := parseBasicType(name); err == nil {
+			return fmt.Errorf("%w: type name %q is a built-in type", ErrInvalid, name)
+		}
+		seen := make(map[string]bool, len(fields))
+		for _, f := range fields {
+			if !identifier.MatchString(f.Name) {
+				return fmt.Errorf("%w: types.%s: field name %q is not an identifier", ErrInvalid, name, f.Name)
+			}
+			if seen[f.Name] {
+				return fmt.Errorf("%w: types.%s: field %q is declared twice", ErrInvalid, name, f.Name)
+			}
+			seen[f.Name] = true
+			base, _, err := splitArray(f.Type)
+			if err != nil {
+				return fmt.Errorf("%w: types.%s.%s: %v", ErrInvalid, name, f.Name, err)
+			}
+			if _, ok := td.Types[base]; ok {
+				continue
+			}
+			if _, err := parseBasicType(base); err != nil {
+				return fmt.Errorf("%w: types.%s.%s: %v", ErrInvalid, name, f.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Digest returns keccak256("\x19\x01" || domainSeparator || hashStruct(message)),
+// the hash an EIP-712 signer signs. With primaryType EIP712Domain there is
+// no message and the digest covers the domain separator alone.
+func (td *TypedData) Digest() ([32]byte, error) {
+	var digest [32]byte
+	domain, err := td.DomainSeparator()
+	if err != nil {
+		return digest, err
+	}
+	parts := [][]byte{{0x19, 0x01}, domain[:]}
+	if td.PrimaryType != domainType {
+		msg, err := td.HashStruct(td.PrimaryType, td.Message, "message")
+		if err != nil {
+			return digest, err
+		}
+		parts = append(parts, msg[:])
+	}
+	copy(digest[:], keccak256(parts...))
+	return digest, nil
+}
+
+// DomainSeparator returns hashStruct(domain).
+func (td *TypedData) DomainSeparator() ([32]byte, error) {
+	return td.HashStruct(domainType, td.Domain, "domain")
+}
+
+// HashStruct returns keccak256(typeHash || encodeData(value)) for the
+// struct type typ. path names the value in errors.
+func (td *TypedData) HashStruct(typ string, value map[string]any, path string) ([32]byte, error) {
+	var h [32]byte
+	enc, err := td.encodeData(typ, value, path)
+	if err != nil {
+		return h, err
+	}
+	copy(h[:], keccak256(enc))
+	return h, nil
+}
+
+// TypeHash returns keccak256(encodeType(typ)).
+func (td *TypedData) TypeHash(typ string) [32]byte {
+	var h [32]byte
+	copy(h[:], keccak256([]byte(td.EncodeType(typ))))
+	return h
+}
+
+// EncodeType returns the type string of typ, e.g.
+// "Mail(Person from,Person to,string contents)Person(string name,address wallet)":
+// typ's own definition followed by those of the struct types it refers to,
+// directly or not, sorted by name.
+func (td *TypedData) EncodeType(typ string) string {
+	deps := map[string]bool{}
+	td.dependencies(typ, deps)
+	delete(deps, typ)
+	names := make([]string, 0, len(deps))
+	for name := range deps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range append([]string{typ}, names...) {
+		b.WriteString(name)
+		b.WriteByte('(')
+		for i, f := range td.Types[name] {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(f.Type)
+			b.WriteByte(' ')
+			b.WriteString(f.Name)
+		}
+		b.WriteByte(')')
+	}
+	return b.String()
+}
+
+func (td *TypedData) dependencies(typ string, found map[string]bool) {
+	if found[typ] {
+		return
+	}
+	if _, ok := td.Types[typ]; !ok {
+		return
+	}
+	found[typ] = true
+	for _, f := range td.Types[typ] {
+		base, _, _ := splitArray(f.Type)
+		td.dependencies(base, found)
+	}
+}
+
+// encodeData is typeHash followed by the 32-byte encoding of each field.
+// Every declared field must be present and no others.
+func (td *TypedData) encodeData(typ string, value map[string]any, path string) ([]byte, error) {
+	fields := td.Types[typ]
+	typeHash := td.TypeHash(typ)
+	out := append(make([]byte, 0, 32*(len(fields)+1)), typeHash[:]...)
+	for _, f := range fields {
+		v, ok := value[f.Name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s.%s: missing %s value", ErrInvalid, path, f.Name, f.Type)
+		}
+		enc, err := td.encodeValue(f.Type, v, path+"."+f.Name)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, enc...)
+	}
+	if len(value) > len(fields) {
+		for name := range value {
+			if !hasField(fields, name) {
+				return nil, fmt.Errorf("%w: %s.%s: not a field of %s", ErrInvalid, path, name, typ)
+			}
+		}
+	}
+	return out, nil
+}
+
+func hasField(fields []Field, name string) bool {
+	for _, f := range fields {
+		if f.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeValue returns the 32-byte encoding of v as typ.
+func (td *TypedData) encodeValue(typ string, v any, path string) ([]byte, error) {
+	base, dims, _ := splitArray(typ)
+	if len(dims) > 0 {
+		// Arrays encode as the hash of their elements' encodings.
+		elemType := typ[:strings.LastIndexByte(typ, '[')]
+		items, ok := v.([]any)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s: want an array for %s, got %s", ErrInvalid, path, typ, describe(v))
+		}
+		if n := dims[len(dims)-1]; n >= 0 && len(items) != n {
+			return nil, fmt.Errorf("%w: %s: %s has %d elements, want %d", ErrInvalid, path, typ, len(items), n)
+		}
+		var enc []byte
+		for i, item := range items {
+			e, err := td.encodeValue(elemType, item, fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			enc = append(enc, e...)
+		}
+		return keccak256(enc), nil
+	}
+	if _, ok := td.Types[base]; ok {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s: want an object for %s, got %s", ErrInvalid, path, typ, describe(v))
+		}
+		h, err := td.HashStruct(base, m, path)
+		return h[:], err
+	}
+	bt, err := parseBasicType(base)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalid, path, err)
+	}
+	enc, err := bt.encode(v)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %s: %v", ErrInvalid, path, typ, err)
+	}
+	return enc, nil
+}
+
+// splitArray splits "T[2][]" into "T" and the array lengths, -1 for a
+// dynamic dimension.
+func splitArray(typ string) (base string, dims []int, err error) {
+	base = typ
+	for strings.HasSuffix(base, "]") {
+		open := strings.LastIndexByte(base, '[')
+		if open < 0 {
+			return "", nil, fmt.Errorf("malformed array type %q", typ)
+		}
+		n := -1
+		if size := base[open+1 : len(base)-1]; size != "" {
+			v, err := strconv.Atoi(size)
+			if err != nil || v <= 0 {
+				return "", nil, fmt.Errorf("malformed array length in %q", typ)
+			}
+			n = v
+		}
+		dims = append([]int{n}, dims...)
+		base = base[:open]
+	}
+	if base == "" {
+		return "", nil, fmt.Errorf("malformed type %q", typ)
+	}
+	return base, dims, nil
+}
+
+// basicType is an atomic or dynamic Solidity type.
+type basicType struct {
+	kind string // "uint", "int", "bytesN", "bytes", "string", "address", "bool"
+	size int    // bits for integers, bytes for bytesN
+}
+
+func parseBasicType(typ string) (basicType, error) {
+	switch typ {
+	case "address", "bool", "string", "bytes":
+		return basicType{kind: typ}, nil
+	}
+	for _, kind := range []string{"uint", "int", "bytes"} {
+		size, ok := strings.CutPrefix(typ, kind)
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(size)
+		if err != nil || size != strconv.Itoa(n) {
+			break
+		}
+		if kind == "bytes" {
+			if n < 1 || n > 32 {
+				return basicType{}, fmt.Errorf("unknown type %q: bytesN needs 1 <= N <= 32", typ)
+			}
+			return basicType{kind: "bytesN", size: n}, nil
+		}
+		if n < 8 || n > 256 || n%8 != 0 {
+			return basicType{}, fmt.Errorf("unknown type %q: %sN needs N a multiple of 8 up to 256", typ, kind)
+		}
+		return basicType{kind: kind, size: n}, nil
+	}
+	return basicType{}, fmt.Errorf("unknown type %q", typ)
+}
+
+func (t basicType) encode(v any) ([]byte, error) {
+	word := make([]byte, 32)
+	switch t.kind {
+	case "string":
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("want a string, got %s", describe(v))
+		}
+		return keccak256([]byte(s)), nil
+	case "bytes":
+		b, err := hexValue(v)
+		if err != nil {
+			return nil, err
+		}
+		return keccak256(b), nil
+	case "bytesN":
+		b, err := hexValue(v)
+		if err != nil {
+			return nil, err
+		}
+		if len(b) != t.size {
+			return nil, fmt.Errorf("value is %d bytes, want %d", len(b), t.size)
+		}
+		copy(word, b)
+		return word, nil
+	case "address":
+		b, err := hexValue(v)
+		if err != nil {
+			return nil, err
+		}
+		if len(b) != 20 {
+			return nil, fmt.Errorf("address is %d bytes, want 20", len(b))
+		}
+		copy(word[12:], b)
+		return word, nil
+	case "bool":
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("want true or false, got %s", describe(v))
+		}
+		if b {
+			word[31] = 1
+		}
+		return word, nil
+	default:
+		n, err := integerValue(v)
+		if err != nil {
+			return nil, err
+		}
+		return t.encodeInteger(n)
+	}
+}
+
+// encodeInteger range-checks n and returns its 32-byte two's complement
+// encoding.
+func (t basicType) encodeInteger(n *big.Int) ([]byte, error) {
+	word := make([]byte, 32)
+	if t.kind == "uint" {
+		if n.Sign() < 0 || n.BitLen() > t.size {
+			return nil, fmt.Errorf("%s is out of range", n)
+		}
+		return n.FillBytes(word), nil
+	}
+	limit := new(big.Int).Lsh(big.NewInt(1), uint(t.size-1))
+	if n.Cmp(limit) >= 0 || n.Cmp(new(big.Int).Neg(limit)) < 0 {
+		return nil, fmt.Errorf("%s is out of range", n)
+	}
+	if n.Sign() >= 0 {
+		return n.FillBytes(word), nil
+	}
+	twos := new(big.Int).Add(new(big.Int).Lsh(big.NewInt(1), 256), n)
+	return twos.FillBytes(word), nil
+}
+
+// integerValue accepts a JSON number, or a string in decimal or 0x hex.
+func integerValue(v any) (*big.Int, error) {
+	var s string
+	switch v := v.(type) {
+	case json.Number:
+		s = v.String()
+	case string:
+		s = v
+	default:
+		return nil, fmt.Errorf("want an integer, got %s", describe(v))
+	}
+	n, ok := new(big.Int), false
+	if digits, hex := strings.CutPrefix(s, "0x"); hex {
+		n, ok = n.SetString(digits, 16)
+	} else {
+		n, ok = n.SetString(s, 10)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%q is not an integer", s)
+	}
+	return n, nil
+}
+
+// hexValue decodes a 0x-prefixed hex string.
+func hexValue(v any) ([]byte, error) {
+	s, ok := v.(string)
+	if !ok {
+		return nil, fmt.Errorf("want a 0x hex string, got %s", describe(v))
+	}
+	digits, ok := strings.CutPrefix(s, "0x")
+	if !ok {
+		return nil, fmt.Errorf("%q is not 0x-prefixed hex", s)
+	}
+	b, err := hex.DecodeString(digits)
+	if err != nil {
+		return nil, fmt.Errorf("%q is not hex: %v", s, err)
+	}
+	return b, nil
+}
+
+// describe names the JSON type of v for errors.
+func describe(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return fmt.Sprintf("string %q", v)
+	case json.Number:
+		return "number " + v.String()
+	case bool:
+		return "boolean"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+func keccak256(data ...[]byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	for _, d := range data {
+		h.Write(d)
+	}
+	return h.Sum(nil)
+}
@@ -0,0 +1,154 @@
+package eip712
+
+import (
+	"encoding/hex"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func readMail(t *testing.T) []byte {
+	t.Helper()
+	data, err := os.ReadFile("testdata/mail.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestDigestMail(t *testing.T) {
+	// The example of the EIP-712 specification.
+	td, err := Parse(readMail(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := td.EncodeType("Mail"), "Mail(Person from,Person to,string contents)Person(string name,address wallet)"; got != want {
+		t.Errorf("EncodeType = %q, want %q", got, want)
+	}
+	typeHash := td.TypeHash("Mail")
+	domain, err := td.DomainSeparator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := td.HashStruct("Mail", td.Message, "message")
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest, err := td.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		name string
+		got  [32]byte
+		want string
+	}{
+		{"type hash", typeHash, "a0cedeb2dc280ba39b857546d74f5549c3a1d7bdc2dd96bf881f76108e23dac2"},
+		{"domain separator", domain, "f2cee375fa42b42143804025fc449deafd50cc031ca257e0b194a650a912090f"},
+		{"message hash", msg, "c52c0ee5d84264471806290a3f2c4cecfc5490626bf912d01f240d7a274b371e"},
+		{"digest", digest, "be609aee343fb3c4b28e1df9e632fca64fcfaede20f02e86244efddf30957bd2"},
+	} {
+		if got := hex.EncodeToString(c.got[:]); got != c.want {
+			t.Errorf("%s = %s, want %s", c.name, got, c.want)
+		}
+	}
+}
+
+func TestDigestErrors(t *testing.T) {
+	mail := string(readMail(t))
+	for _, c := range []struct {
+		old, new string
+		want     string
+	}{
+		{`"wallet": "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826"`, `"wallet": "0xCD2a"`, "message.from.wallet: address: address is 2 bytes, want 20"},
+		{`"wallet": "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826"`, `"wallet": 7`, "message.from.wallet: address: want a 0x hex string, got number 7"},
+		{`"name": "Bob", `, ``, "message.to.name: missing string value"},
+		{`"contents": "Hello, Bob!"`, `"contents": "Hello, Bob!", "cc": "Alice"`, "message.cc: not a field of Mail"},
+		{`"chainId": 1,`, `"chainId": -1,`, "domain.chainId: uint256: -1 is out of range"},
+		{`"chainId": 1,`, `"chainId": "one",`, `domain.chainId: uint256: "one" is not an integer`},
+		{`"to": {"name": "Bob", "wallet": "0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB"}`, `"to": ["Bob"]`, "message.to: want an object for Person, got array"},
+	} {
+		data := strings.Replace(mail, c.old, c.new, 1)
+		if data == mail {
+			t.Fatalf("%q does not occur in the test data", c.old)
+		}
+		td, err := Parse([]byte(data))
+		if err == nil {
+			_, err = td.Digest()
+		}
+		if !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: got %v, want ErrInvalid", c.want, err)
+			continue
+		}
+		if !strings.Contains(err.Error(), c.want) {
+			t.Errorf("error %q does not contain %q", err, c.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	mail := string(readMail(t))
+	for _, c := range []struct {
+		old, new string
+		want     string
+	}{
+		{`"primaryType": "Mail"`, `"primaryType": "Letter"`, `primaryType "Letter" is not defined in types`},
+		{`{"name": "contents", "type": "string"}`, `{"name": "contents", "type": "Text"}`, `types.Mail.contents: unknown type "Text"`},
+		{`{"name": "contents", "type": "string"}`, `{"name": "contents", "type": "uint7"}`, `types.Mail.contents: unknown type "uint7"`},
+		{`{"name": "contents", "type": "string"}`, `{"name": "contents", "type": "bytes33"}`, `types.Mail.contents: unknown type "bytes33"`},
+		{`{"name": "contents", "type": "string"}`, `{"name": "to", "type": "string"}`, `types.Mail: field "to" is declared twice`},
+		{`"primaryType": "Mail",`, `"primaryType": "Mail", "extra": 1,`, `unknown field "extra"`},
+		{`"EIP712Domain": [`, `"Domain": [`, "types.EIP712Domain is missing"},
+	} {
+		data := strings.Replace(mail, c.old, c.new, 1)
+		_, err := Parse([]byte(data))
+		if !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: got %v, want ErrInvalid", c.want, err)
+			continue
+		}
+		if !strings.Contains(err.Error(), c.want) {
+			t.Errorf("error %q does not contain %q", err, c.want)
+		}
+	}
+}
+
+func TestEncodeValues(t *testing.T) {
+	td := &TypedData{Types: map[string][]Field{}}
+	for _, c := range []struct {
+		typ  string
+		v    any
+		want string
+	}{
+		{"uint8", "0xff", "00000000000000000000000000000000000000000000000000000000000000ff"},
+		{"int8", "-1", "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"},
+		{"bool", true, "0000000000000000000000000000000000000000000000000000000000000001"},
+		{"bytes4", "0xdeadbeef", "deadbeef00000000000000000000000000000000000000000000000000000000"},
+		// keccak256 of the empty string.
+		{"bytes", "0x", "c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470"},
+		{"string", "", "c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470"},
+	} {
+		enc, err := td.encodeValue(c.typ, c.v, "v")
+		if err != nil {
+			t.Errorf("%s: %v", c.typ, err)
+			continue
+		}
+		if got := hex.EncodeToString(enc); got != c.want {
+			t.Errorf("%s %v = %s, want %s", c.typ, c.v, got, c.want)
+		}
+	}
+	for _, c := range []struct {
+		typ string
+		v   any
+	}{
+		{"uint8", "256"},
+		{"int8", "-129"},
+		{"int8", "128"},
+		{"bytes4", "0xdead"},
+		{"uint8[2]", []any{"1"}},
+	} {
+		if _, err := td.encodeValue(c.typ, c.v, "v"); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s %v: got %v, want ErrInvalid", c.typ, c.v, err)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.26;
+
+import "forge-std/Test.sol";
+import {ERC1967Proxy} from "@openzeppelin/contracts/proxy/ERC1967/ERC1967Proxy.sol";
+import {BastionServiceManager} from "../src/avs/BastionServiceManager.sol";
+import {BastionTaskManager} from "../src/avs/BastionTaskManager.sol";
+
+/// @title TaskResponseHash Test
+/// @notice Feeds operator/bls-keygen/pkg/bls/testdata/task_responses.json to a
+/// deployed BastionTaskManager, so the Go HashTaskResponse,
+/// TaskResponseMessageHash and EthSignedMessageHash fixtures are checked by
+/// the contract itself rather than by a copy of its encoding
+contract TaskResponseHashTest is Test {
+    string constant FIXTURE = "operator/bls-keygen/pkg/bls/testdata/task_responses.json";
+
+    uint256 constant OPERATOR_KEY = 0xB0B;
+    uint256 constant STAKE = 1 ether;
+    uint32 constant QUORUM = 6600;
+
+    BastionServiceManager public serviceManager;
+    BastionTaskManager public taskManager;
+    address public operator;
+    string public fixture;
+
+    function setUp() public {
+        serviceManager = BastionServiceManager(
+            address(
+                new ERC1967Proxy(
+                    address(new BastionServiceManager()),
+                    abi.encodeCall(BastionServiceManager.initialize, (makeAddr("avsDirectory"), STAKE, address(this)))
+                )
+            )
+        );
+        taskManager = BastionTaskManager(
+            address(
+                new ERC1967Proxy(
+                    address(new BastionTaskManager()),
+                    abi.encodeCall(BastionTaskManager.initialize, (address(serviceManager), 100, QUORUM, address(this)))
+                )
+            )
+        );
+        serviceManager.setTaskManager(address(taskManager));
+
+        operator = vm.addr(OPERATOR_KEY);
+        vm.prank(operator);
+        serviceManager.registerOperator(STAKE);
+
+        fixture = vm.readFile(FIXTURE);
+    }
+
+    /// @dev Creates tasks until taskIndex exists
+    function _createTasksThrough(uint32 taskIndex) internal {
+        while (taskManager.latestTaskNum() <= taskIndex) {
+            taskManager.createDepegCheckTask(makeAddr("stETH"), QUORUM, hex"00");
+        }
+    }
+
+    /// @dev Responds to the fixture case at i with a signature over its
+    /// eth_signed_message_hash; respondToTask reverts with InvalidSignature
+    /// unless that is the hash it derives from the task index and response
+    function _check(uint256 i) internal {
+        string memory c = string.concat(".[", vm.toString(i), "]");
+        uint32 taskIndex = uint32(vm.parseJsonUint(fixture, string.concat(c, ".task_index")));
+        bytes memory responseData = vm.parseJsonBytes(fixture, string.concat(c, ".response_data"));
+        bytes32 signedHash = vm.parseJsonBytes32(fixture, string.concat(c, ".eth_signed_message_hash"));
+        bytes32 responseHash = vm.parseJsonBytes32(fixture, string.concat(c, ".response_hash"));
+
+        _createTasksThrough(taskIndex);
+        (uint8 v, bytes32 r, bytes32 s) = vm.sign(OPERATOR_KEY, signedHash);
+        vm.prank(operator);
+        taskManager.respondToTask(taskIndex, responseData, abi.encodePacked(r, s, v));
+
+        // The only operator holds all stake, so the response reaches quorum
+        // and the contract records its own response hash
+        assertEq(taskManager.getAggregatedResponse(taskIndex).responseHash, responseHash, "response hash");
+    }
+
+    function test_Empty() public {
+        _check(0);
+    }
+
+    function test_DepegCheck() public {
+        _check(1);
+    }
+
+    function test_VolatilityCalc() public {
+        _check(2);
+    }
+
+    /// @dev A signature over the message hash without the eth-signed prefix
+    /// is refused, so the fixture's prefix is not optional
+    function test_UnprefixedHashRejected() public {
+        _createTasksThrough(7);
+        bytes memory responseData = vm.parseJsonBytes(fixture, ".[1].response_data");
+        bytes32 messageHash = vm.parseJsonBytes32(fixture, ".[1].message_hash");
+        (uint8 v, bytes32 r, bytes32 s) = vm.sign(OPERATOR_KEY, messageHash);
+        vm.prank(operator);
+        vm.expectRevert(BastionTaskManager.InvalidSignature.selector);
+        taskManager.respondToTask(7, responseData, abi.encodePacked(r, s, v));
+    }
+}